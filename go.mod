@@ -2,7 +2,11 @@ module github.com/kinde-oss/workflows-runtime
 
 go 1.22.6
 
-require github.com/dop251/goja v0.0.0-20240919115326-6c7d1df7ff05
+require (
+	github.com/grafana/sobek v0.0.0-20260429085637-a66d4790012b
+	github.com/klauspost/compress v1.17.11
+	github.com/tetratelabs/wazero v1.8.2
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
@@ -14,7 +18,7 @@ require (
 )
 
 require (
-	github.com/dlclark/regexp2 v1.11.4 // indirect
+	github.com/dlclark/regexp2 v1.12.0 // indirect
 	github.com/evanw/esbuild v0.24.0
 	github.com/go-sourcemap/sourcemap v2.1.4+incompatible // indirect
 	github.com/google/pprof v0.0.0-20240910150728-a0b0bb1d4134 // indirect
@@ -0,0 +1,208 @@
+package abort
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
+)
+
+func createVM() *goja.Runtime {
+	vm := goja.New()
+	new(require.Registry).Enable(vm)
+	Enable(vm)
+	return vm
+}
+
+func runTests(t *testing.T, vm *goja.Runtime, tests []struct {
+	script   string
+	expected string
+}) {
+	t.Helper()
+	for _, tc := range tests {
+		result, err := vm.RunString(tc.script)
+		if err != nil {
+			t.Fatalf("Failed to run %s: %v", tc.script, err)
+		}
+		if result.String() != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.script, tc.expected, result.String())
+		}
+	}
+}
+
+func TestAbortController(t *testing.T) {
+	runTests(t, createVM(), []struct {
+		script   string
+		expected string
+	}{
+		{`const c = new AbortController(); c.signal.aborted`, "false"},
+		{`c.signal === c.signal`, "true"},
+		{`String(c.signal.reason)`, "undefined"},
+		{`c.signal.throwIfAborted(); "ok"`, "ok"},
+		{`const calls = []; c.signal.addEventListener("abort", (e) => calls.push("listener:" + e.type + ":" + (e.target === c.signal)));
+		  c.signal.onabort = () => calls.push("onabort"); c.abort(); calls.join(",")`, "listener:abort:true,onabort"},
+		{`c.signal.aborted`, "true"},
+		{`c.signal.reason.name`, "AbortError"},
+		{`c.signal.reason.message`, "This operation was aborted"},
+		{`c.abort("again"); c.signal.reason.name`, "AbortError"},
+		{`try { c.signal.throwIfAborted() } catch (e) { e.name }`, "AbortError"},
+		{`const c2 = new AbortController(); c2.abort("why"); c2.signal.reason`, "why"},
+		{`Object.prototype.toString.call(c2.signal) + Object.prototype.toString.call(c2)`, "[object AbortSignal][object AbortController]"},
+		{`try { new AbortSignal() } catch (e) { e.code }`, "ERR_ILLEGAL_CONSTRUCTOR"},
+		{`AbortSignal.name + " " + AbortController.name`, "AbortSignal AbortController"},
+	})
+}
+
+func TestAbortListeners(t *testing.T) {
+	runTests(t, createVM(), []struct {
+		script   string
+		expected string
+	}{
+		{`const c = new AbortController(); let count = 0; const inc = () => count++;
+		  c.signal.addEventListener("abort", inc); c.signal.addEventListener("abort", inc);
+		  c.signal.addEventListener("abort", { handleEvent() { count += 10 } });
+		  c.signal.addEventListener("abort", () => { throw new Error("ignored") });
+		  c.abort(); count`, "11"},
+		{`const removed = new AbortController(); let called = false; const fn = () => called = true;
+		  removed.signal.addEventListener("abort", fn); removed.signal.removeEventListener("abort", fn); removed.abort(); called`, "false"},
+		{`const once = new AbortController(); let n = 0; once.signal.addEventListener("ping", () => n++, { once: true });
+		  const { Event, EventTarget } = require("web");
+		  once.signal.dispatchEvent(new Event("ping")); once.signal.dispatchEvent(new Event("ping")); n`, "1"},
+		{`once.signal instanceof EventTarget`, "true"},
+		{`const owner = new AbortController(), target = new AbortController(); let fired = false;
+		  target.signal.addEventListener("abort", () => fired = true, { signal: owner.signal });
+		  owner.abort(); target.abort(); fired`, "false"},
+		{`const late = AbortSignal.abort(); let lateFired = false; late.addEventListener("abort", () => lateFired = true); lateFired`, "false"},
+	})
+}
+
+func TestAbortSignalStatics(t *testing.T) {
+	vm := createVM()
+	runTests(t, vm, []struct {
+		script   string
+		expected string
+	}{
+		{`const a = AbortSignal.abort(); a.aborted + " " + a.reason.name`, "true AbortError"},
+		{`AbortSignal.abort(42).reason`, "42"},
+		{`const c1 = new AbortController(), c2 = new AbortController(); const any = AbortSignal.any([c1.signal, c2.signal]);
+		  const order = []; any.onabort = () => order.push("any:" + c2.signal.aborted); c2.signal.onabort = () => order.push("c2:" + any.aborted);
+		  c2.abort("second"); order.join(",") + " " + any.reason`, "c2:true,any:true second"},
+		{`const nested = AbortSignal.any([AbortSignal.any(new Set([c1.signal]))]); c1.abort("first"); nested.reason`, "first"},
+		{`AbortSignal.any([AbortSignal.abort("done"), new AbortController().signal]).reason`, "done"},
+		{`try { AbortSignal.any([{}]) } catch (e) { e.code }`, "ERR_INVALID_ARG_TYPE"},
+		{`try { AbortSignal.timeout(-1) } catch (e) { e.name + " " + e.code }`, "RangeError ERR_OUT_OF_RANGE"},
+		{`try { AbortSignal.timeout("1") } catch (e) { e.code }`, "ERR_INVALID_ARG_TYPE"},
+		{`var timeout = AbortSignal.timeout(10); var anyTimeout = AbortSignal.any([timeout]); var timedOut = [];
+		  anyTimeout.onabort = () => timedOut.push("any"); timeout.aborted`, "false"},
+	})
+
+	time.Sleep(50 * time.Millisecond)
+	runTests(t, vm, []struct {
+		script   string
+		expected string
+	}{
+		{`anyTimeout.aborted + " " + timedOut.join(",")`, "true any"},
+		{`timeout.reason.name + ": " + timeout.reason.message`, "TimeoutError: The operation was aborted due to timeout"},
+	})
+}
+
+func TestFollow(t *testing.T) {
+	vm := createVM()
+	source := NewSignal()
+	vm.Set("signal", Follow(vm, source))
+	runTests(t, vm, []struct {
+		script   string
+		expected string
+	}{
+		{`var reasons = []; signal.addEventListener("abort", () => reasons.push(signal.reason.name)); signal.aborted`, "false"},
+	})
+
+	if !source.Abort(NewTimeoutError("time is up")) || source.Abort(nil) {
+		t.Fatal("expected only the first Abort to succeed")
+	}
+	if err := Sync(vm.Get("signal")); err != nil {
+		t.Fatal(err)
+	}
+	runTests(t, vm, []struct {
+		script   string
+		expected string
+	}{
+		{`reasons.join(",") + " " + signal.reason.message`, "TimeoutError time is up"},
+		{`signal.aborted; reasons.length`, "1"},
+	})
+
+	interrupted := NewSignal()
+	vm.Set("interrupted", Follow(vm, interrupted))
+	if _, err := vm.RunString(`interrupted.onabort = () => { while (true) {} }`); err != nil {
+		t.Fatal(err)
+	}
+	interrupted.Abort(nil)
+	time.AfterFunc(20*time.Millisecond, func() { vm.Interrupt("stop") })
+	var interruptedErr *goja.InterruptedError
+	if err := Sync(vm.Get("interrupted")); !errors.As(err, &interruptedErr) {
+		t.Fatalf("expected the listener to be interrupted, got %v", err)
+	}
+}
+
+func TestSignalContext(t *testing.T) {
+	s := NewSignal()
+	ctx, release := WithSignal(context.Background(), s)
+	defer release()
+	if found, ok := FromContext(ctx); !ok || found != s {
+		t.Fatal("signal not found in context")
+	}
+	if ctx.Err() != nil || s.Aborted() {
+		t.Fatal("expected the context to be active")
+	}
+
+	s.Abort(NewTimeoutError("too slow"))
+	<-ctx.Done()
+	if cause := context.Cause(ctx); !errors.Is(cause, ErrTimeout) || cause.Error() != "too slow" {
+		t.Fatalf("unexpected cause %v", cause)
+	}
+	if !errors.Is(s.Err(), ErrTimeout) || errors.Is(ErrAborted, ErrTimeout) {
+		t.Fatal("unexpected error matching")
+	}
+
+	aborted := NewSignal()
+	aborted.Abort(nil)
+	ctx, release = WithSignal(context.Background(), aborted)
+	release()
+	if cause := context.Cause(ctx); cause != ErrAborted {
+		t.Fatalf("expected an already aborted signal to cancel the context, got %v", cause)
+	}
+
+	released, release := WithSignal(context.Background(), NewSignal())
+	release()
+	if cause := context.Cause(released); cause != context.Canceled {
+		t.Fatalf("expected a released context to be cancelled, got %v", cause)
+	}
+}
+
+func TestTimers(t *testing.T) {
+	vm := createVM()
+	timers := NewTimers(vm)
+	runTests(t, vm, []struct {
+		script   string
+		expected string
+	}{
+		{`var fired = []; var short = AbortSignal.timeout(10), long = AbortSignal.timeout(10000);
+		  short.onabort = () => fired.push(short.reason.name); long.onabort = () => fired.push("long"); fired.length`, "0"},
+	})
+
+	time.Sleep(50 * time.Millisecond)
+	if err := timers.Run(); err != nil {
+		t.Fatal(err)
+	}
+	timers.Stop()
+	runTests(t, vm, []struct {
+		script   string
+		expected string
+	}{
+		{`fired.join(",") + " " + long.aborted`, "TimeoutError false"},
+		{`AbortSignal.timeout(0); fired.length`, "1"},
+	})
+}
@@ -0,0 +1,259 @@
+package abort
+
+import (
+	goerrors "errors"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/web"
+)
+
+const ModuleName = "abort"
+
+var (
+	reflectTypeSignal     = reflect.TypeOf((*abortSignal)(nil))
+	reflectTypeController = reflect.TypeOf((*abortController)(nil))
+)
+
+type abortModule struct {
+	r *goja.Runtime
+
+	signalPrototype     *goja.Object
+	controllerPrototype *goja.Object
+}
+
+func Require(runtime *goja.Runtime, module *goja.Object) {
+	exports := module.Get("exports").(*goja.Object)
+	m := &abortModule{
+		r: runtime,
+	}
+	exports.Set("AbortSignal", m.createSignalConstructor())
+	exports.Set("AbortController", m.createControllerConstructor())
+}
+
+// Enable installs AbortController and AbortSignal as globals.
+func Enable(runtime *goja.Runtime) *goja.Object {
+	m := require.Require(runtime, ModuleName).ToObject(runtime)
+	runtime.Set("AbortSignal", m.Get("AbortSignal"))
+	runtime.Set("AbortController", m.Get("AbortController"))
+	return m
+}
+
+// Follow creates an AbortSignal which aborts once source is aborted. As the VM cannot be entered from another
+// goroutine the JS signal notices the abort when it is inspected by the script or when Sync is called.
+func Follow(runtime *goja.Runtime, source *Signal) *goja.Object {
+	constructor := require.Require(runtime, ModuleName).ToObject(runtime).Get("AbortSignal").ToObject(runtime)
+	m := &abortModule{
+		r:               runtime,
+		signalPrototype: constructor.Get("prototype").ToObject(runtime),
+	}
+	signal := m.newSignal()
+	signal.source = source
+	return signal.object
+}
+
+// Sync aborts the JS signal v when the Go signal it follows was aborted, running its abort listeners.
+// It must be called on the goroutine running the VM, the error returned is the one interrupting a listener.
+func Sync(v goja.Value) error {
+	if v == nil || v.ExportType() != reflectTypeSignal {
+		return nil
+	}
+	return interruptible(v.Export().(*abortSignal).sync)
+}
+
+// interruptible runs fn, returning the error interrupting the VM rather than panicking with it.
+func interruptible(fn func()) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			interrupted, ok := recovered.(*goja.InterruptedError)
+			if !ok {
+				panic(recovered)
+			}
+			err = interrupted
+		}
+	}()
+	fn()
+	return nil
+}
+
+func init() {
+	require.RegisterNativeModule(ModuleName, Require)
+}
+
+func (m *abortModule) defineGetter(p *goja.Object, name string, getter func(call goja.FunctionCall) goja.Value) {
+	p.DefineAccessorProperty(name, m.r.ToValue(getter), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+}
+
+func (m *abortModule) createSignalConstructor() goja.Value {
+	f := m.r.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeIllegalConstructor, "Illegal constructor"))
+	}).(*goja.Object)
+
+	m.signalPrototype = m.createSignalPrototype()
+	f.DefineDataProperty("name", m.r.ToValue("AbortSignal"), goja.FLAG_FALSE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+	f.Set("prototype", m.signalPrototype)
+	m.signalPrototype.DefineDataProperty("constructor", f, goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+
+	f.Set("abort", m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		s := m.newSignal()
+		s.aborted = true
+		s.reason = m.reasonOrDefault(call.Argument(0))
+		return s.object
+	}))
+
+	f.Set("timeout", m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		delay := m.validateDelay(call.Argument(0))
+		s := m.newSignal()
+		s.source = NewSignal()
+		if timers := timersOf(m.r); timers != nil {
+			timers.start(s, delay)
+			return s.object
+		}
+		time.AfterFunc(delay, func() {
+			s.source.Abort(ErrTimeout)
+		})
+		return s.object
+	}))
+
+	f.Set("any", m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		var signals []goja.Value
+		if err := m.r.ExportTo(call.Argument(0), &signals); err != nil {
+			panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "signals" argument must be an instance of Array.`))
+		}
+		sources := make([]*abortSignal, len(signals))
+		for i, signal := range signals {
+			sources[i] = m.toSignalArgument(signal, "signals["+strconv.Itoa(i)+"]")
+		}
+		return m.anySignal(sources).object
+	}))
+
+	return f
+}
+
+func (m *abortModule) createSignalPrototype() *goja.Object {
+	p := m.r.NewObject()
+	p.SetPrototype(web.EventTargetPrototype(m.r))
+
+	m.defineGetter(p, "aborted", func(call goja.FunctionCall) goja.Value {
+		s := m.toSignal(call.This)
+		s.sync()
+		return m.r.ToValue(s.aborted)
+	})
+
+	m.defineGetter(p, "reason", func(call goja.FunctionCall) goja.Value {
+		s := m.toSignal(call.This)
+		s.sync()
+		if s.reason == nil {
+			return goja.Undefined()
+		}
+		return s.reason
+	})
+
+	web.DefineEventHandler(m.r, p, "abort")
+
+	p.Set("throwIfAborted", m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		s := m.toSignal(call.This)
+		s.sync()
+		if s.aborted {
+			panic(s.reason)
+		}
+		return goja.Undefined()
+	}))
+
+	p.DefineDataPropertySymbol(goja.SymToStringTag, m.r.ToValue("AbortSignal"), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	return p
+}
+
+func (m *abortModule) createControllerConstructor() goja.Value {
+	f := m.r.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		c := &abortController{signal: m.newSignal()}
+		res := m.r.ToValue(c).(*goja.Object)
+		res.SetPrototype(call.This.Prototype())
+		return res
+	}).(*goja.Object)
+
+	m.controllerPrototype = m.createControllerPrototype()
+	f.DefineDataProperty("name", m.r.ToValue("AbortController"), goja.FLAG_FALSE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+	f.Set("prototype", m.controllerPrototype)
+	m.controllerPrototype.DefineDataProperty("constructor", f, goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+	return f
+}
+
+func (m *abortModule) createControllerPrototype() *goja.Object {
+	p := m.r.NewObject()
+
+	m.defineGetter(p, "signal", func(call goja.FunctionCall) goja.Value {
+		return m.toController(call.This).signal.object
+	})
+
+	p.Set("abort", m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		m.toController(call.This).signal.abort(m.reasonOrDefault(call.Argument(0)))
+		return goja.Undefined()
+	}))
+
+	p.DefineDataPropertySymbol(goja.SymToStringTag, m.r.ToValue("AbortController"), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	return p
+}
+
+func (m *abortModule) toSignal(v goja.Value) *abortSignal {
+	if v.ExportType() == reflectTypeSignal {
+		if s := v.Export().(*abortSignal); s != nil {
+			return s
+		}
+	}
+	panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidThis, `Value of "this" must be of type AbortSignal`))
+}
+
+func (m *abortModule) toSignalArgument(v goja.Value, name string) *abortSignal {
+	if v != nil && v.ExportType() == reflectTypeSignal {
+		if s := v.Export().(*abortSignal); s != nil {
+			return s
+		}
+	}
+	panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "`+name+`" argument must be an instance of AbortSignal.`))
+}
+
+func (m *abortModule) toController(v goja.Value) *abortController {
+	if v.ExportType() == reflectTypeController {
+		if c := v.Export().(*abortController); c != nil {
+			return c
+		}
+	}
+	panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidThis, `Value of "this" must be of type AbortController`))
+}
+
+// reasonOrDefault returns reason, or the AbortError DOMException used when a signal is aborted without one.
+func (m *abortModule) reasonOrDefault(reason goja.Value) goja.Value {
+	if reason == nil || goja.IsUndefined(reason) {
+		return web.NewDOMException(m.r, ErrAborted.Error(), "AbortError")
+	}
+	return reason
+}
+
+// reasonFor converts the cause a Go signal was aborted with to the reason of the JS signals following it.
+func (m *abortModule) reasonFor(cause error) goja.Value {
+	if goerrors.Is(cause, ErrTimeout) {
+		return web.NewDOMException(m.r, cause.Error(), "TimeoutError")
+	}
+	return web.NewDOMException(m.r, cause.Error(), "AbortError")
+}
+
+func (m *abortModule) validateDelay(v goja.Value) time.Duration {
+	if _, ok := v.Export().(int64); !ok {
+		if _, ok := v.Export().(float64); !ok {
+			panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "delay" argument must be of type number.`))
+		}
+	}
+	delay := v.ToFloat()
+	if math.IsNaN(delay) || delay < 0 || delay > math.MaxInt64/float64(time.Millisecond) || delay != math.Trunc(delay) {
+		rangeError, _ := m.r.Get("RangeError").(*goja.Object)
+		panic(errors.NewError(m.r, rangeError, errors.ErrCodeOutOfRange,
+			`The value of "delay" is out of range. It must be an integer >= 0. Received %s`, v.String()))
+	}
+	return time.Duration(delay) * time.Millisecond
+}
@@ -0,0 +1,114 @@
+package abort
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrTimeout is matched by the causes of signals aborted because a time limit was reached,
+// their JS reason is a TimeoutError DOMException instead of an AbortError.
+var ErrTimeout = errors.New("The operation was aborted due to timeout")
+
+// ErrAborted is the cause of signals aborted without one.
+var ErrAborted = errors.New("This operation was aborted")
+
+type timeoutError struct {
+	msg string
+}
+
+func (e *timeoutError) Error() string {
+	return e.msg
+}
+
+func (e *timeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+// NewTimeoutError creates an abort cause with the given message that matches ErrTimeout.
+func NewTimeoutError(msg string) error {
+	return &timeoutError{msg: msg}
+}
+
+// Signal is the Go side of an AbortSignal. Unlike the JS object it is safe for concurrent use,
+// hosts abort it from any goroutine and the JS signals following it pick the abort up on the VM goroutine.
+type Signal struct {
+	lock      sync.Mutex
+	done      chan struct{}
+	cause     error
+	callbacks []func()
+}
+
+// NewSignal creates a signal which is not aborted.
+func NewSignal() *Signal {
+	return &Signal{done: make(chan struct{})}
+}
+
+// Abort aborts the signal with cause, ErrAborted when nil, and reports whether it was not aborted before.
+func (s *Signal) Abort(cause error) bool {
+	if cause == nil {
+		cause = ErrAborted
+	}
+	s.lock.Lock()
+	if s.cause != nil {
+		s.lock.Unlock()
+		return false
+	}
+	s.cause = cause
+	callbacks := s.callbacks
+	s.callbacks = nil
+	close(s.done)
+	s.lock.Unlock()
+
+	for _, callback := range callbacks {
+		callback()
+	}
+	return true
+}
+
+// Done returns a channel closed when the signal is aborted.
+func (s *Signal) Done() <-chan struct{} {
+	return s.done
+}
+
+// Aborted reports whether the signal was aborted.
+func (s *Signal) Aborted() bool {
+	return s.Err() != nil
+}
+
+// Err returns the cause the signal was aborted with, nil while it is not aborted.
+func (s *Signal) Err() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.cause
+}
+
+// afterAbort registers fn to be called once the signal is aborted, immediately if it already is.
+func (s *Signal) afterAbort(fn func()) {
+	s.lock.Lock()
+	if s.cause == nil {
+		s.callbacks = append(s.callbacks, fn)
+		s.lock.Unlock()
+		return
+	}
+	s.lock.Unlock()
+	fn()
+}
+
+type signalKey struct{}
+
+// WithSignal returns a copy of parent carrying s, which is cancelled with the cause of s once it is aborted.
+// Calling release cancels it too, it must be called once the context is no longer used, like a context.CancelFunc.
+func WithSignal(parent context.Context, s *Signal) (ctx context.Context, release context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(context.WithValue(parent, signalKey{}, s))
+	s.afterAbort(func() {
+		cancel(s.Err())
+	})
+	return ctx, func() { cancel(context.Canceled) }
+}
+
+// FromContext returns the signal carried by ctx, see WithSignal.
+func FromContext(ctx context.Context) (*Signal, bool) {
+	s, ok := ctx.Value(signalKey{}).(*Signal)
+	return s, ok
+}
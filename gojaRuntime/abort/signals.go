@@ -0,0 +1,116 @@
+package abort
+
+import (
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/web"
+)
+
+type abortController struct {
+	signal *abortSignal
+}
+
+type abortSignal struct {
+	m      *abortModule
+	object *goja.Object
+
+	aborted bool
+	reason  goja.Value
+
+	// source is the Go signal followed by signals created with Follow or AbortSignal.timeout
+	source *Signal
+
+	// dependent signals are created by AbortSignal.any and abort when one of their sources does
+	dependent  bool
+	sources    []*abortSignal
+	dependents []*abortSignal
+}
+
+func (m *abortModule) newSignal() *abortSignal {
+	s := &abortSignal{m: m}
+	s.object = m.r.ToValue(s).(*goja.Object)
+	s.object.SetPrototype(m.signalPrototype)
+	web.InitEventTarget(m.r, s.object)
+	return s
+}
+
+// anySignal creates the signal returned by AbortSignal.any, following the sources of dependent signals directly.
+func (m *abortModule) anySignal(signals []*abortSignal) *abortSignal {
+	s := m.newSignal()
+	for _, signal := range signals {
+		signal.sync()
+		if signal.aborted {
+			s.aborted = true
+			s.reason = signal.reason
+			return s
+		}
+	}
+
+	s.dependent = true
+	for _, signal := range signals {
+		if !signal.dependent {
+			s.addSource(signal)
+			continue
+		}
+		for _, source := range signal.sources {
+			s.addSource(source)
+		}
+	}
+	return s
+}
+
+func (s *abortSignal) addSource(source *abortSignal) {
+	for _, existing := range s.sources {
+		if existing == source {
+			return
+		}
+	}
+	s.sources = append(s.sources, source)
+	source.dependents = append(source.dependents, s)
+}
+
+// sync aborts the signal when the Go signal it follows, directly or through its sources, was aborted.
+func (s *abortSignal) sync() {
+	if s.aborted {
+		return
+	}
+	if s.source != nil {
+		if cause := s.source.Err(); cause != nil {
+			s.abort(s.m.reasonFor(cause))
+			return
+		}
+	}
+	for _, source := range s.sources {
+		if source.sync(); s.aborted {
+			return
+		}
+	}
+}
+
+// abort implements the "signal abort" steps of the DOM standard: dependent signals get their reason
+// before any abort event is dispatched.
+func (s *abortSignal) abort(reason goja.Value) {
+	if s.aborted {
+		return
+	}
+	s.aborted = true
+	s.reason = reason
+
+	var dependents []*abortSignal
+	for _, dependent := range s.dependents {
+		if !dependent.aborted {
+			dependent.aborted = true
+			dependent.reason = reason
+			dependents = append(dependents, dependent)
+		}
+	}
+	s.dependents = nil
+
+	s.runAbortSteps()
+	for _, dependent := range dependents {
+		dependent.runAbortSteps()
+	}
+}
+
+func (s *abortSignal) runAbortSteps() {
+	web.DispatchEvent(s.m.r, s.object, web.NewEvent(s.m.r, "abort"))
+}
@@ -0,0 +1,88 @@
+package abort
+
+import (
+	"sync"
+	"time"
+
+	goja "github.com/grafana/sobek"
+)
+
+// Timers fire the signals created by AbortSignal.timeout in a runtime from the event loop of its host, the abort
+// listeners of such signals cannot run when the timer elapses as the VM cannot be entered from another goroutine.
+type Timers struct {
+	runtime *goja.Runtime
+
+	lock    sync.Mutex
+	pending map[*abortSignal]*time.Timer
+	// due are the signals whose timer elapsed, their listeners run on the next call to Run
+	due     []*abortSignal
+	stopped bool
+}
+
+// runtimeTimers holds the Timers of the runtimes they were created for, until they are stopped
+var runtimeTimers sync.Map
+
+// NewTimers makes the AbortSignal.timeout signals of runtime fire through the returned Timers, Stop must be called
+// once the runtime is done with. Without Timers the signals abort when inspected, without running their listeners.
+func NewTimers(runtime *goja.Runtime) *Timers {
+	t := &Timers{
+		runtime: runtime,
+		pending: map[*abortSignal]*time.Timer{},
+	}
+	runtimeTimers.Store(runtime, t)
+	return t
+}
+
+func timersOf(runtime *goja.Runtime) *Timers {
+	if t, ok := runtimeTimers.Load(runtime); ok {
+		return t.(*Timers)
+	}
+	return nil
+}
+
+// start aborts the Go signal followed by signal once delay has elapsed, the JS signal aborts on the next call to Run.
+func (t *Timers) start(signal *abortSignal, delay time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.stopped {
+		return
+	}
+	t.pending[signal] = time.AfterFunc(delay, func() {
+		t.lock.Lock()
+		defer t.lock.Unlock()
+		if _, ok := t.pending[signal]; !ok {
+			return
+		}
+		delete(t.pending, signal)
+		signal.source.Abort(ErrTimeout)
+		t.due = append(t.due, signal)
+	})
+}
+
+// Run aborts the signals whose timer elapsed, running their abort listeners. It must be called on the goroutine
+// running the VM, the error returned is the one interrupting a listener.
+func (t *Timers) Run() error {
+	t.lock.Lock()
+	due := t.due
+	t.due = nil
+	t.lock.Unlock()
+
+	return interruptible(func() {
+		for _, signal := range due {
+			signal.sync()
+		}
+	})
+}
+
+// Stop stops the timers which did not elapse yet, their signals never abort.
+func (t *Timers) Stop() {
+	runtimeTimers.CompareAndDelete(t.runtime, t)
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.stopped = true
+	for signal, timer := range t.pending {
+		timer.Stop()
+		delete(t.pending, signal)
+	}
+	t.due = nil
+}
@@ -0,0 +1,151 @@
+package goja_runtime
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	goja "github.com/grafana/sobek"
+	jsErrors "github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
+)
+
+const ErrCodeBindingNotRequested = "ERR_BINDING_NOT_REQUESTED"
+
+// bindingNotRequestedError is returned when a workflow imports a binding it did not request.
+type bindingNotRequestedError struct {
+	path string
+}
+
+func (e *bindingNotRequestedError) Error() string {
+	return fmt.Sprintf("binding %v is not requested by the workflow, add it to the bindings of its settings", e.path)
+}
+
+func init() {
+	jsErrors.RegisterErrorTypeMapping[*bindingNotRequestedError](jsErrors.Mapping{Code: ErrCodeBindingNotRequested})
+}
+
+// BindingSpecifiers returns the import specifiers of the registered native APIs as patterns, see RootSpecifiers.
+func BindingSpecifiers() []string {
+	roots := make([]string, 0, len(__nativeModules.registered))
+	for root := range __nativeModules.registered {
+		roots = append(roots, root)
+	}
+	return RootSpecifiers(roots...)
+}
+
+// RootSpecifiers returns the import specifiers naming the native APIs with the given roots as patterns, e.g. kinde:*
+// and @kinde/runtime for kinde, for bundlers to leave them to the runtime.
+func RootSpecifiers(roots ...string) []string {
+	var specifiers []string
+	for _, root := range roots {
+		specifiers = append(specifiers, root+":*", "@"+root+"/runtime")
+	}
+	sort.Strings(specifiers)
+	return slices.Compact(specifiers)
+}
+
+// bindingPath maps an import specifier to the binding path it names: "kinde:idToken" is kinde.idToken and
+// "@kinde/runtime" is kinde. ok is false when the specifier does not name a native API.
+func (nm *nativeModules) bindingPath(workflow runtimesRegistry.WorkflowDescriptor, specifier string) (string, bool) {
+	var root, path string
+	if rest, ok := strings.CutPrefix(specifier, "@"); ok {
+		scope, name, _ := strings.Cut(rest, "/")
+		if name != "runtime" {
+			return "", false
+		}
+		root = scope
+	} else {
+		var ok bool
+		if root, path, ok = strings.Cut(specifier, ":"); !ok || path == "" {
+			return "", false
+		}
+	}
+	if !nm.isRoot(workflow, root) {
+		return "", false
+	}
+	if path == "" {
+		return root, true
+	}
+	return root + "." + path, true
+}
+
+// isRoot reports whether name is the root of a native API, registered or named by a binding the workflow requests.
+func (nm *nativeModules) isRoot(workflow runtimesRegistry.WorkflowDescriptor, name string) bool {
+	if _, ok := nm.registered[name]; ok {
+		return true
+	}
+	for requested := range workflow.RequestedBindings {
+		if root, _, _ := strings.Cut(requested, "."); root == name {
+			return true
+		}
+	}
+	return false
+}
+
+// bindingResolver resolves the specifiers naming native APIs to the objects RequestedBindings mounted in vm. While
+// introspecting, the bindings are not known yet: missing ones resolve to placeholders shaped like the registered
+// API, whose functions throw when called.
+func (nm *nativeModules) bindingResolver(vm *goja.Runtime, workflow runtimesRegistry.WorkflowDescriptor, introspecting bool) func(specifier string) (goja.Value, bool, error) {
+	return func(specifier string) (goja.Value, bool, error) {
+		path, ok := nm.bindingPath(workflow, specifier)
+		if !ok {
+			return nil, false, nil
+		}
+		var binding goja.Value = vm.GlobalObject()
+		for _, name := range strings.Split(path, ".") {
+			var value goja.Value
+			if o, ok := binding.(*goja.Object); ok {
+				value = o.Get(name)
+			}
+			if value == nil || goja.IsUndefined(value) {
+				if introspecting {
+					if placeholder, ok := nm.placeholder(vm, path); ok {
+						return placeholder, true, nil
+					}
+				}
+				return nil, true, &bindingNotRequestedError{path: path}
+			}
+			binding = value
+		}
+		return binding, true, nil
+	}
+}
+
+// placeholder builds the stand-in of the registered native API or function at path, see bindingResolver.
+func (nm *nativeModules) placeholder(vm *goja.Runtime, path string) (goja.Value, bool) {
+	names := strings.Split(path, ".")
+	module, ok := nm.registered[names[0]]
+	if !ok {
+		return nil, false
+	}
+	for i, name := range names[1:] {
+		if sub, ok := module.modules[name]; ok {
+			module = sub
+			continue
+		}
+		if _, ok := module.functions[name]; ok && i == len(names)-2 {
+			return placeholderFunction(vm, path), true
+		}
+		return nil, false
+	}
+	return module.placeholder(vm, path), true
+}
+
+func (module *NativeModule) placeholder(vm *goja.Runtime, path string) *goja.Object {
+	o := vm.NewObject()
+	for name := range module.functions {
+		o.Set(name, placeholderFunction(vm, path+"."+name))
+	}
+	for name, sub := range module.modules {
+		o.Set(name, sub.placeholder(vm, path+"."+name))
+	}
+	return o
+}
+
+func placeholderFunction(vm *goja.Runtime, path string) goja.Value {
+	return vm.ToValue(func(goja.FunctionCall) goja.Value {
+		panic(jsErrors.FromGoError(vm, &bindingNotRequestedError{path: path}))
+	})
+}
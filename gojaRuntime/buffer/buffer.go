@@ -0,0 +1,408 @@
+package buffer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/encoding"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+)
+
+// newBuffer creates a Buffer backed by data, without copying it.
+func (m *bufferModule) newBuffer(data []byte) *goja.Object {
+	return m.newView(m.r.ToValue(m.r.NewArrayBuffer(data)), 0, int64(len(data)))
+}
+
+// newView creates a Buffer sharing the memory of arrayBuffer.
+func (m *bufferModule) newView(arrayBuffer goja.Value, offset, length int64) *goja.Object {
+	o, err := m.r.New(m.uint8Array, arrayBuffer, m.r.ToValue(offset), m.r.ToValue(length))
+	if err != nil {
+		panic(err)
+	}
+	o.SetPrototype(m.BufferPrototype)
+	return o
+}
+
+func isString(v goja.Value) bool {
+	return v != nil && v.ExportType() != nil && v.ExportType().Kind() == reflect.String
+}
+
+func isNumber(v goja.Value) bool {
+	if v == nil || v.ExportType() == nil {
+		return false
+	}
+	kind := v.ExportType().Kind()
+	return kind == reflect.Int64 || kind == reflect.Float64
+}
+
+// uint8ArrayBytes returns the memory of a Uint8Array (including Buffers).
+func (m *bufferModule) uint8ArrayBytes(v goja.Value) ([]byte, bool) {
+	o, ok := v.(*goja.Object)
+	if !ok {
+		return nil, false
+	}
+	if _, ok := o.Export().([]byte); !ok {
+		return nil, false
+	}
+	return encoding.BufferSourceBytes(m.r, o)
+}
+
+func (m *bufferModule) mustUint8ArrayBytes(v goja.Value, name string) []byte {
+	data, ok := m.uint8ArrayBytes(v)
+	if !ok {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "%s" argument must be an instance of Buffer or Uint8Array.`, name))
+	}
+	return data
+}
+
+func (m *bufferModule) encodingArg(v goja.Value) string {
+	if goja.IsUndefined(v) || goja.IsNull(v) {
+		return encodingUTF8
+	}
+	enc, ok := normalizeEncoding(v.String())
+	if !ok {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeUnknownEncoding, "Unknown encoding: %s", v.String()))
+	}
+	return enc
+}
+
+func (m *bufferModule) rangeError(code string, format string, args ...interface{}) *goja.Object {
+	rangeError, _ := m.r.Get("RangeError").(*goja.Object)
+	return errors.NewError(m.r, rangeError, code, append([]interface{}{format}, args...)...)
+}
+
+// relativeIndex resolves a slice index the way TypedArray.prototype.subarray does.
+func relativeIndex(v goja.Value, length, def int64) int64 {
+	if goja.IsUndefined(v) {
+		return def
+	}
+	n := v.ToInteger()
+	if n < 0 {
+		n += length
+		if n < 0 {
+			n = 0
+		}
+	} else if n > length {
+		n = length
+	}
+	return n
+}
+
+func (m *bufferModule) arrayLikeBytes(o *goja.Object) []byte {
+	length := o.Get("length").ToInteger()
+	if length < 0 {
+		length = 0
+	}
+	if length > MaxLength {
+		panic(m.rangeError(errors.ErrCodeOutOfRange, `The value of "length" is out of range. It must be <= %d. Received %d`, MaxLength, length))
+	}
+	data := make([]byte, length)
+	for i := range data {
+		if v := o.Get(strconv.Itoa(i)); v != nil {
+			data[i] = byte(v.ToInteger())
+		}
+	}
+	return data
+}
+
+func (m *bufferModule) fromValue(value, encodingOrOffset, length goja.Value) *goja.Object {
+	if isString(value) {
+		return m.newBuffer(encodeString(value.String(), m.encodingArg(encodingOrOffset)))
+	}
+
+	o, ok := value.(*goja.Object)
+	if !ok {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, "The first argument must be of type string or an instance of Buffer, ArrayBuffer, or Array or an Array-like Object."))
+	}
+
+	if arrayBuffer, ok := o.Export().(goja.ArrayBuffer); ok {
+		size := int64(len(arrayBuffer.Bytes()))
+		offset := int64(0)
+		if !goja.IsUndefined(encodingOrOffset) {
+			offset = encodingOrOffset.ToInteger()
+		}
+		if offset < 0 || offset > size {
+			panic(m.rangeError(errors.ErrCodeBufferOutOfBounds, `"offset" is outside of buffer bounds`))
+		}
+		count := size - offset
+		if !goja.IsUndefined(length) {
+			count = length.ToInteger()
+		}
+		if count < 0 || offset+count > size {
+			panic(m.rangeError(errors.ErrCodeBufferOutOfBounds, `"length" is outside of buffer bounds`))
+		}
+		return m.newView(o, offset, count)
+	}
+
+	if data, ok := encoding.BufferSourceBytes(m.r, o); ok {
+		return m.newBuffer(bytes.Clone(data))
+	}
+
+	if t := o.Get("type"); t != nil && t.String() == "Buffer" {
+		if data, ok := o.Get("data").(*goja.Object); ok && data.ClassName() == "Array" {
+			return m.newBuffer(m.arrayLikeBytes(data))
+		}
+	}
+
+	if l := o.Get("length"); l != nil && !goja.IsUndefined(l) {
+		return m.newBuffer(m.arrayLikeBytes(o))
+	}
+
+	panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, "The first argument must be of type string or an instance of Buffer, ArrayBuffer, or Array or an Array-like Object."))
+}
+
+func (m *bufferModule) from(call goja.FunctionCall) goja.Value {
+	return m.fromValue(call.Argument(0), call.Argument(1), call.Argument(2))
+}
+
+func (m *bufferModule) allocSize(v goja.Value) int {
+	if !isNumber(v) {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "size" argument must be of type number.`))
+	}
+	size := v.ToFloat()
+	if math.IsNaN(size) || size < 0 || size > MaxLength {
+		panic(m.rangeError(errors.ErrCodeOutOfRange, `The value of "size" is out of range. It must be >= 0 && <= %d. Received %s`, MaxLength, v.String()))
+	}
+	return int(size)
+}
+
+func (m *bufferModule) alloc(call goja.FunctionCall) goja.Value {
+	data := make([]byte, m.allocSize(call.Argument(0)))
+
+	fill := call.Argument(1)
+	if goja.IsUndefined(fill) || len(data) == 0 {
+		return m.newBuffer(data)
+	}
+
+	var pattern []byte
+	switch {
+	case isString(fill):
+		pattern = encodeString(fill.String(), m.encodingArg(call.Argument(2)))
+	case isNumber(fill):
+		pattern = []byte{byte(fill.ToInteger() & 0xFF)}
+	default:
+		pattern = m.mustUint8ArrayBytes(fill, "fill")
+	}
+	if len(pattern) == 0 {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgValue, `The argument "value" is invalid. Received %s`, fill.String()))
+	}
+	for i := 0; i < len(data); i += len(pattern) {
+		copy(data[i:], pattern)
+	}
+	return m.newBuffer(data)
+}
+
+func (m *bufferModule) allocUnsafe(call goja.FunctionCall) goja.Value {
+	return m.newBuffer(make([]byte, m.allocSize(call.Argument(0))))
+}
+
+func (m *bufferModule) byteLength(call goja.FunctionCall) goja.Value {
+	value := call.Argument(0)
+	if isString(value) {
+		return m.r.ToValue(len(encodeString(value.String(), m.encodingArg(call.Argument(1)))))
+	}
+	data, ok := encoding.BufferSourceBytes(m.r, value)
+	if !ok {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "string" argument must be of type string or an instance of Buffer or ArrayBuffer.`))
+	}
+	return m.r.ToValue(len(data))
+}
+
+func (m *bufferModule) concat(call goja.FunctionCall) goja.Value {
+	list, ok := call.Argument(0).(*goja.Object)
+	if !ok || list.ClassName() != "Array" {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "list" argument must be an instance of Array.`))
+	}
+
+	count := list.Get("length").ToInteger()
+	parts := make([][]byte, 0, count)
+	total := 0
+	for i := int64(0); i < count; i++ {
+		part := m.mustUint8ArrayBytes(list.Get(strconv.FormatInt(i, 10)), "list["+strconv.FormatInt(i, 10)+"]")
+		parts = append(parts, part)
+		total += len(part)
+	}
+
+	if totalLength := call.Argument(1); !goja.IsUndefined(totalLength) {
+		total = m.allocSize(totalLength)
+	}
+	if total > MaxLength {
+		panic(m.rangeError(errors.ErrCodeOutOfRange, `The total length of "list" is out of range. It must be <= %d. Received %d`, MaxLength, total))
+	}
+
+	data := make([]byte, total)
+	offset := 0
+	for _, part := range parts {
+		if offset >= total {
+			break
+		}
+		offset += copy(data[offset:], part)
+	}
+	return m.newBuffer(data)
+}
+
+func (m *bufferModule) compare(call goja.FunctionCall) goja.Value {
+	a := m.mustUint8ArrayBytes(call.Argument(0), "buf1")
+	b := m.mustUint8ArrayBytes(call.Argument(1), "buf2")
+	return m.r.ToValue(bytes.Compare(a, b))
+}
+
+func (m *bufferModule) isBuffer(call goja.FunctionCall) goja.Value {
+	o, ok := call.Argument(0).(*goja.Object)
+	if !ok {
+		return m.r.ToValue(false)
+	}
+	for p := o.Prototype(); p != nil; p = p.Prototype() {
+		if p == m.BufferPrototype {
+			return m.r.ToValue(true)
+		}
+	}
+	return m.r.ToValue(false)
+}
+
+func (m *bufferModule) isEncoding(call goja.FunctionCall) goja.Value {
+	value := call.Argument(0)
+	if !isString(value) {
+		return m.r.ToValue(false)
+	}
+	_, ok := normalizeEncoding(value.String())
+	return m.r.ToValue(ok)
+}
+
+func (m *bufferModule) thisBytes(call goja.FunctionCall) []byte {
+	data, ok := m.uint8ArrayBytes(call.This)
+	if !ok {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidThis, `Value of "this" must be of type Buffer or Uint8Array`))
+	}
+	return data
+}
+
+func (m *bufferModule) createBufferPrototype() *goja.Object {
+	p := m.r.NewObject()
+	p.SetPrototype(m.uint8Array.Get("prototype").ToObject(m.r))
+
+	p.Set("toString", m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		data := m.thisBytes(call)
+		enc := m.encodingArg(call.Argument(0))
+
+		start, end := int64(0), int64(len(data))
+		if arg := call.Argument(1); !goja.IsUndefined(arg) {
+			start = max(0, arg.ToInteger())
+		}
+		if arg := call.Argument(2); !goja.IsUndefined(arg) {
+			end = min(end, arg.ToInteger())
+		}
+		if end <= start {
+			return m.r.ToValue("")
+		}
+		return m.r.ToValue(decodeBytes(data[start:end], enc))
+	}))
+
+	p.Set("equals", m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		data := m.thisBytes(call)
+		return m.r.ToValue(bytes.Equal(data, m.mustUint8ArrayBytes(call.Argument(0), "otherBuffer")))
+	}))
+
+	p.Set("compare", m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		data := m.thisBytes(call)
+		return m.r.ToValue(bytes.Compare(data, m.mustUint8ArrayBytes(call.Argument(0), "target")))
+	}))
+
+	// unlike TypedArray.prototype.slice, Buffer.prototype.slice shares memory with the original buffer
+	slice := m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		length := int64(len(m.thisBytes(call)))
+		this := call.This.ToObject(m.r)
+		start := relativeIndex(call.Argument(0), length, 0)
+		end := relativeIndex(call.Argument(1), length, length)
+		if end < start {
+			end = start
+		}
+		return m.newView(this.Get("buffer"), this.Get("byteOffset").ToInteger()+start, end-start)
+	})
+	p.Set("slice", slice)
+	p.Set("subarray", slice)
+
+	p.Set("toJSON", m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		data := m.thisBytes(call)
+		values := make([]interface{}, len(data))
+		for i, b := range data {
+			values[i] = int64(b)
+		}
+		result := m.r.NewObject()
+		result.Set("type", "Buffer")
+		result.Set("data", m.r.NewArray(values...))
+		return result
+	}))
+
+	if custom := m.symbolFor("nodejs.util.inspect.custom"); custom != nil {
+		p.DefineDataPropertySymbol(custom, m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+			data := m.thisBytes(call)
+			shown := data[:min(len(data), inspectMaxBytes)]
+			contents := make([]string, len(shown))
+			for i, b := range shown {
+				contents[i] = hex.EncodeToString([]byte{b})
+			}
+			str := strings.Join(contents, " ")
+			if remaining := len(data) - len(shown); remaining > 0 {
+				str += fmt.Sprintf(" ... %d more byte", remaining)
+				if remaining > 1 {
+					str += "s"
+				}
+			}
+			name := "Buffer"
+			if constructor, ok := call.This.ToObject(m.r).Get("constructor").(*goja.Object); ok {
+				name = constructor.Get("name").String()
+			}
+			return m.r.ToValue("<" + name + " " + str + ">")
+		}), goja.FLAG_TRUE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+	}
+
+	return p
+}
+
+// symbolFor returns Symbol.for(key) from the runtime's symbol registry.
+func (m *bufferModule) symbolFor(key string) *goja.Symbol {
+	symbol, ok := m.r.Get("Symbol").(*goja.Object)
+	if !ok {
+		return nil
+	}
+	symbolFor, ok := goja.AssertFunction(symbol.Get("for"))
+	if !ok {
+		return nil
+	}
+	res, err := symbolFor(symbol, m.r.ToValue(key))
+	if err != nil {
+		return nil
+	}
+	s, _ := res.(*goja.Symbol)
+	return s
+}
+
+func (m *bufferModule) createBufferConstructor() goja.Value {
+	f := m.r.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		return m.fromValue(call.Argument(0), call.Argument(1), call.Argument(2))
+	}).(*goja.Object)
+
+	m.BufferPrototype = m.createBufferPrototype()
+	f.DefineDataProperty("name", m.r.ToValue("Buffer"), goja.FLAG_FALSE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+	f.Set("prototype", m.BufferPrototype)
+	m.BufferPrototype.DefineDataProperty("constructor", f, goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+	f.SetPrototype(m.uint8Array)
+
+	f.Set("from", m.from)
+	f.Set("alloc", m.alloc)
+	f.Set("allocUnsafe", m.allocUnsafe)
+	f.Set("allocUnsafeSlow", m.allocUnsafe)
+	f.Set("byteLength", m.byteLength)
+	f.Set("concat", m.concat)
+	f.Set("compare", m.compare)
+	f.Set("isBuffer", m.isBuffer)
+	f.Set("isEncoding", m.isEncoding)
+	return f
+}
@@ -0,0 +1,123 @@
+package buffer
+
+import (
+	"testing"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
+)
+
+func createVM() *goja.Runtime {
+	vm := goja.New()
+	new(require.Registry).Enable(vm)
+	Enable(vm)
+	return vm
+}
+
+func TestBufferModule(t *testing.T) {
+	vm := createVM()
+
+	for _, script := range []string{
+		`require("buffer").Buffer === Buffer`,
+		`require("node:buffer").Buffer === Buffer`,
+		`Buffer.from("abc") instanceof Uint8Array`,
+		`Buffer.from("abc") instanceof Buffer`,
+		`Buffer.isBuffer(Buffer.alloc(1)) && !Buffer.isBuffer(new Uint8Array(1))`,
+	} {
+		result, err := vm.RunString(script)
+		if err != nil {
+			t.Fatalf("Failed to run %s: %v", script, err)
+		}
+		if !result.ToBoolean() {
+			t.Fatalf("%s: expected true", script)
+		}
+	}
+}
+
+func TestBufferEncodings(t *testing.T) {
+	tests := []struct {
+		script   string
+		expected string
+	}{
+		{`Buffer.from("héllo €").toString()`, "héllo €"},
+		{`Buffer.from("héllo").length`, "6"},
+		{`Buffer.from("hello").toString("hex")`, "68656c6c6f"},
+		{`Buffer.from("68656C6c6fzz12", "hex").toString()`, "hello"},
+		{`Buffer.from("hello").toString("base64")`, "aGVsbG8="},
+		{`Buffer.from("aGVs bG8", "base64").toString()`, "hello"},
+		{`Buffer.from([251, 255]).toString("base64url")`, "-_8"},
+		{`Buffer.from("-_8=", "base64url").join(",")`, "251,255"},
+		{`Buffer.from("+/8", "base64").join(",")`, "251,255"},
+		{`Buffer.from("ÿa", "latin1").join(",")`, "255,97"},
+		{`Buffer.from([0xe9, 0x61]).toString("binary")`, "éa"},
+		{`Buffer.from("ab", "utf16le").join(",")`, "97,0,98,0"},
+		{`Buffer.from([97, 0, 98, 0]).toString("ucs2")`, "ab"},
+		{`Buffer.from([0xE2, 0x82]).toString()`, "�"},
+		{`Buffer.from("hello").toString("utf8", 1, 3)`, "el"},
+		{`Buffer.from("hello").toString("utf8", -1, 100)`, "hello"},
+		{`Buffer.isEncoding("UTF-8") && Buffer.isEncoding("base64url") && !Buffer.isEncoding("utf32")`, "true"},
+		{`try { Buffer.from("x", "utf32") } catch (e) { [e instanceof TypeError, e.code].join(",") }`, "true,ERR_UNKNOWN_ENCODING"},
+		{`try { Buffer.from("x").toString("nope") } catch (e) { e.code }`, "ERR_UNKNOWN_ENCODING"},
+	}
+
+	for _, tc := range tests {
+		vm := createVM()
+		result, err := vm.RunString(tc.script)
+		if err != nil {
+			t.Fatalf("Failed to run %s: %v", tc.script, err)
+		}
+		if result.String() != tc.expected {
+			t.Fatalf("%s: expected '%s', got '%s'", tc.script, tc.expected, result.String())
+		}
+	}
+}
+
+func TestBufferOperations(t *testing.T) {
+	tests := []struct {
+		script   string
+		expected string
+	}{
+		{`Buffer.from(new Uint8Array([1, 2, 3])).join(",")`, "1,2,3"},
+		{`const u = new Uint8Array([1, 2]); const b = Buffer.from(u); b[0] = 9; u[0]`, "1"},
+		{`const ab = new Uint8Array([1, 2, 3, 4]).buffer; const b = Buffer.from(ab, 1, 2); new Uint8Array(ab)[1] = 7; b.join(",")`, "7,3"},
+		{`try { Buffer.from(new ArrayBuffer(2), 3) } catch (e) { [e instanceof RangeError, e.code].join(",") }`, "true,ERR_BUFFER_OUT_OF_BOUNDS"},
+		{`Buffer.from(Buffer.from("hi").toJSON()).toString()`, "hi"},
+		{`JSON.stringify(Buffer.from([1, 2]))`, `{"type":"Buffer","data":[1,2]}`},
+		{`try { Buffer.from(42) } catch (e) { [e instanceof TypeError, e.code].join(",") }`, "true,ERR_INVALID_ARG_TYPE"},
+		{`new Buffer("hi").toString("hex")`, "6869"},
+		{`Buffer.alloc(5, "ab").toString()`, "ababa"},
+		{`Buffer.alloc(3, 257).join(",")`, "1,1,1"},
+		{`Buffer.alloc(2).join(",")`, "0,0"},
+		{`try { Buffer.alloc(-1) } catch (e) { [e instanceof RangeError, e.code].join(",") }`, "true,ERR_OUT_OF_RANGE"},
+		{`try { Buffer.alloc(require("buffer").kMaxLength + 1) } catch (e) { [e instanceof RangeError, e.code].join(",") }`, "true,ERR_OUT_OF_RANGE"},
+		{`try { Buffer.from({ length: 2 ** 40 }) } catch (e) { [e instanceof RangeError, e.code].join(",") }`, "true,ERR_OUT_OF_RANGE"},
+		{`try { Buffer.concat([], 2 ** 31) } catch (e) { [e instanceof RangeError, e.code].join(",") }`, "true,ERR_OUT_OF_RANGE"},
+		{`String(require("buffer").kMaxLength === require("buffer").constants.MAX_LENGTH)`, "true"},
+		{`try { Buffer.alloc("1") } catch (e) { e.code }`, "ERR_INVALID_ARG_TYPE"},
+		{`Buffer.concat([Buffer.from("ab"), new Uint8Array([99]), Buffer.from("d")]).toString()`, "abcd"},
+		{`Buffer.concat([Buffer.from("ab"), Buffer.from("cd")], 3).toString()`, "abc"},
+		{`Buffer.concat([Buffer.from("ab")], 4).join(",")`, "97,98,0,0"},
+		{`try { Buffer.concat([Buffer.from("ab"), "cd"]) } catch (e) { [e.code, e.message].join("|") }`, `ERR_INVALID_ARG_TYPE|The "list[1]" argument must be an instance of Buffer or Uint8Array.`},
+		{`try { Buffer.concat("ab") } catch (e) { e.code }`, "ERR_INVALID_ARG_TYPE"},
+		{`const b = Buffer.from("hello"); const s = b.slice(1, -1); s[0] = 69; [s.toString(), b.toString(), Buffer.isBuffer(s)].join(",")`, "Ell,hEllo,true"},
+		{`const b = Buffer.from("hello"); b.subarray(-2).toString()`, "lo"},
+		{`const b = Buffer.from("hello").slice(1); b.slice(1, 3).toString()`, "ll"},
+		{`Buffer.from("abc").equals(Buffer.from("abc")) && !Buffer.from("abc").equals(new Uint8Array([97]))`, "true"},
+		{`try { Buffer.from("abc").equals("abc") } catch (e) { e.code }`, "ERR_INVALID_ARG_TYPE"},
+		{`[Buffer.compare(Buffer.from("a"), Buffer.from("b")), Buffer.from("b").compare(Buffer.from("a"))].join(",")`, "-1,1"},
+		{`[Buffer.byteLength("héllo"), Buffer.byteLength("aGVsbG8=", "base64"), Buffer.byteLength(new ArrayBuffer(3))].join(",")`, "6,5,3"},
+		{`Buffer.from("hi")[Symbol.for("nodejs.util.inspect.custom")]()`, "<Buffer 68 69>"},
+		{`Buffer.alloc(52)[Symbol.for("nodejs.util.inspect.custom")]().endsWith("00 ... 2 more bytes>")`, "true"},
+	}
+
+	for _, tc := range tests {
+		vm := createVM()
+		result, err := vm.RunString(tc.script)
+		if err != nil {
+			t.Fatalf("Failed to run %s: %v", tc.script, err)
+		}
+		if result.String() != tc.expected {
+			t.Fatalf("%s: expected '%s', got '%s'", tc.script, tc.expected, result.String())
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package buffer
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/encoding"
+)
+
+const (
+	encodingUTF8      = "utf8"
+	encodingUTF16LE   = "utf16le"
+	encodingLatin1    = "latin1"
+	encodingASCII     = "ascii"
+	encodingHex       = "hex"
+	encodingBase64    = "base64"
+	encodingBase64URL = "base64url"
+)
+
+// normalizeEncoding maps the encoding names accepted by Node.js to their canonical form.
+func normalizeEncoding(name string) (string, bool) {
+	switch strings.ToLower(name) {
+	case "utf8", "utf-8":
+		return encodingUTF8, true
+	case "ucs2", "ucs-2", "utf16le", "utf-16le":
+		return encodingUTF16LE, true
+	case "latin1", "binary":
+		return encodingLatin1, true
+	case "ascii":
+		return encodingASCII, true
+	case "hex":
+		return encodingHex, true
+	case "base64":
+		return encodingBase64, true
+	case "base64url":
+		return encodingBase64URL, true
+	}
+	return "", false
+}
+
+func encodeString(s string, enc string) []byte {
+	switch enc {
+	case encodingUTF16LE:
+		units := utf16.Encode([]rune(s))
+		data := make([]byte, 0, len(units)*2)
+		for _, u := range units {
+			data = append(data, byte(u), byte(u>>8))
+		}
+		return data
+	case encodingLatin1, encodingASCII:
+		units := utf16.Encode([]rune(s))
+		data := make([]byte, len(units))
+		for i, u := range units {
+			data[i] = byte(u)
+		}
+		return data
+	case encodingHex:
+		return decodeHex(s)
+	case encodingBase64, encodingBase64URL:
+		return decodeBase64(s)
+	}
+	return []byte(s)
+}
+
+func decodeBytes(data []byte, enc string) string {
+	switch enc {
+	case encodingUTF16LE:
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = uint16(data[2*i]) | uint16(data[2*i+1])<<8
+		}
+		return string(utf16.Decode(units))
+	case encodingLatin1:
+		var sb strings.Builder
+		sb.Grow(len(data))
+		for _, b := range data {
+			sb.WriteRune(rune(b))
+		}
+		return sb.String()
+	case encodingASCII:
+		var sb strings.Builder
+		sb.Grow(len(data))
+		for _, b := range data {
+			sb.WriteByte(b & 0x7F)
+		}
+		return sb.String()
+	case encodingHex:
+		return hex.EncodeToString(data)
+	case encodingBase64:
+		return base64.StdEncoding.EncodeToString(data)
+	case encodingBase64URL:
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	return encoding.DecodeUTF8(data)
+}
+
+// decodeHex decodes pairs of hex digits, stopping at the first invalid pair like Node.js does.
+func decodeHex(s string) []byte {
+	data := make([]byte, 0, len(s)/2)
+	for i := 0; i+1 < len(s); i += 2 {
+		b, err := hex.DecodeString(s[i : i+2])
+		if err != nil {
+			break
+		}
+		data = append(data, b[0])
+	}
+	return data
+}
+
+// decodeBase64 accepts both the standard and the URL safe alphabets, ignores characters outside of them
+// and stops at the first padding character, which matches the lenient Node.js decoder.
+func decodeBase64(s string) []byte {
+	var sb strings.Builder
+	sb.Grow(len(s))
+loop:
+	for _, c := range s {
+		switch {
+		case c == '=':
+			break loop
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '+', c == '/':
+			sb.WriteRune(c)
+		case c == '-':
+			sb.WriteByte('+')
+		case c == '_':
+			sb.WriteByte('/')
+		}
+	}
+
+	cleaned := sb.String()
+	if len(cleaned)%4 == 1 {
+		cleaned = cleaned[:len(cleaned)-1]
+	}
+	data, err := base64.RawStdEncoding.DecodeString(cleaned)
+	if err != nil {
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,47 @@
+package buffer
+
+import (
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
+)
+
+const ModuleName = "buffer"
+
+// MaxLength is the largest buffer that can be allocated from a workflow, it is exposed as buffer.kMaxLength.
+// Allocations are made on the host, so it is far below the limit of node.
+const MaxLength = 64 << 20
+
+// inspectMaxBytes is the number of bytes util.inspect shows for a buffer.
+const inspectMaxBytes = 50
+
+type bufferModule struct {
+	r *goja.Runtime
+
+	BufferPrototype *goja.Object
+	uint8Array      *goja.Object
+}
+
+func Require(runtime *goja.Runtime, module *goja.Object) {
+	exports := module.Get("exports").(*goja.Object)
+	m := &bufferModule{
+		r: runtime,
+	}
+	m.uint8Array, _ = runtime.Get("Uint8Array").(*goja.Object)
+	exports.Set("Buffer", m.createBufferConstructor())
+	exports.Set("kMaxLength", MaxLength)
+	exports.Set("INSPECT_MAX_BYTES", inspectMaxBytes)
+
+	constants := runtime.NewObject()
+	constants.Set("MAX_LENGTH", MaxLength)
+	exports.Set("constants", constants)
+}
+
+// Enable installs the Buffer global into the runtime.
+func Enable(runtime *goja.Runtime) {
+	m := require.Require(runtime, ModuleName).ToObject(runtime)
+	runtime.Set("Buffer", m.Get("Buffer"))
+}
+
+func init() {
+	require.RegisterCoreModule(ModuleName, Require)
+}
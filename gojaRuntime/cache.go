@@ -1,14 +1,35 @@
 package goja_runtime
 
 import (
+	"container/list"
+	"crypto/sha256"
 	"sync"
 
-	"github.com/dop251/goja"
+	goja "github.com/grafana/sobek"
+	"github.com/grafana/sobek/ast"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
+	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
 )
 
+// maxCachedSources is how many decoded binary sources a runner keeps
+const maxCachedSources = 32
+
 type gojaCache struct {
 	cache map[string]*goja.Program
-	lock  sync.Mutex
+	// modules holds the syntax trees of ES modules, their records are linked to a runtime and cannot be shared
+	modules map[string]*ast.Program
+	// registries holds the require registries of workflows shipping their own modules
+	registries map[string]*require.Registry
+	// sources holds the text of binary sources, keyed by the hash of their envelope
+	sources map[[sha256.Size]byte]*list.Element
+	// recentSources orders the cached sources from the most to the least recently used
+	recentSources *list.List
+	lock          sync.Mutex
+}
+
+type cachedSource struct {
+	key    [sha256.Size]byte
+	source runtimesRegistry.SourceDescriptor
 }
 
 func (cache *gojaCache) cacheProgram(key string, loader func() (*goja.Program, error)) (*goja.Program, error) {
@@ -26,3 +47,63 @@ func (cache *gojaCache) cacheProgram(key string, loader func() (*goja.Program, e
 
 	return program, nil
 }
+
+func (cache *gojaCache) cacheModule(key string, loader func() (*ast.Program, error)) (*ast.Program, error) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	if module, ok := cache.modules[key]; ok {
+		return module, nil
+	}
+
+	module, err := loader()
+	if err != nil {
+		return nil, err
+	}
+	if cache.modules == nil {
+		cache.modules = map[string]*ast.Program{}
+	}
+	cache.modules[key] = module
+
+	return module, nil
+}
+
+func (cache *gojaCache) cacheRegistry(key string, create func() *require.Registry) *require.Registry {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	if registry, ok := cache.registries[key]; ok {
+		return registry
+	}
+	if cache.registries == nil {
+		cache.registries = map[string]*require.Registry{}
+	}
+	registry := create()
+	cache.registries[key] = registry
+	return registry
+}
+
+// cacheSource keeps the maxCachedSources sources used last, evicting the least recently used one.
+func (cache *gojaCache) cacheSource(key [sha256.Size]byte, loader func() (runtimesRegistry.SourceDescriptor, error)) (runtimesRegistry.SourceDescriptor, error) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	if element, ok := cache.sources[key]; ok {
+		cache.recentSources.MoveToFront(element)
+		return element.Value.(*cachedSource).source, nil
+	}
+
+	source, err := loader()
+	if err != nil {
+		return source, err
+	}
+	if cache.sources == nil {
+		cache.sources = map[[sha256.Size]byte]*list.Element{}
+		cache.recentSources = list.New()
+	}
+	cache.sources[key] = cache.recentSources.PushFront(&cachedSource{key: key, source: source})
+	for cache.recentSources.Len() > maxCachedSources {
+		oldest := cache.recentSources.Back()
+		cache.recentSources.Remove(oldest)
+		delete(cache.sources, oldest.Value.(*cachedSource).key)
+	}
+
+	return source, nil
+}
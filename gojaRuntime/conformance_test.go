@@ -0,0 +1,11 @@
+package goja_runtime
+
+import (
+	"testing"
+
+	"github.com/kinde-oss/workflows-runtime/runnertest"
+)
+
+func TestRunnerConformance(t *testing.T) {
+	runnertest.Run(t, newGojaRunner)
+}
@@ -0,0 +1,283 @@
+package goja_runtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
+)
+
+var ErrReadOnlyContextKey = errors.New("context key is read-only")
+
+type (
+	// JsContext is the execution context shared between the workflow bindings.
+	// It is safe for concurrent use through its methods. Values are copied in and out of the context, a binding
+	// changing a value it read must write it back with SetValue.
+	JsContext interface {
+		// GetValue returns a deep copy of the value of key, changing it does not affect the context
+		GetValue(key string) interface{}
+		// CopyValue is GetValue, named for the bindings which change the value before writing it back
+		CopyValue(key string) interface{}
+		SetValue(key string, value interface{}) error
+		// DeleteValue removes key, Diff reports top level keys removed this way
+		DeleteValue(key string) error
+		// Namespace returns a view of the context whose keys are stored under the given name,
+		// e.g. Namespace("idToken").SetValue("claim", v) results in {"idToken": {"claim": v}}
+		Namespace(name string) JsContext
+	}
+
+	// jsContext holds the context values of a single execution
+	jsContext struct {
+		lock     sync.RWMutex
+		data     map[string]interface{}
+		initial  map[string]interface{}
+		readOnly map[string]bool
+		writers  map[string]string
+	}
+
+	// jsContextView is a JsContext scoped to a namespace and attributed to the binding writing through it
+	jsContextView struct {
+		store     *jsContext
+		namespace []string
+		writer    string
+	}
+)
+
+func newJsContext(initial map[string]interface{}, readOnly map[string]interface{}) *jsContext {
+	result := &jsContext{
+		data:     map[string]interface{}{},
+		readOnly: map[string]bool{},
+		writers:  map[string]string{},
+	}
+	for key, value := range initial {
+		result.data[key] = deepCopy(value)
+	}
+	for key, value := range readOnly {
+		result.data[key] = deepCopy(value)
+		result.readOnly[key] = true
+	}
+	result.initial = deepCopy(result.data).(map[string]interface{})
+	return result
+}
+
+func (j *jsContext) writtenBy(modulePath string) JsContext {
+	return jsContextView{store: j, writer: modulePath}
+}
+
+// GetValue implements JsContext.
+func (j *jsContext) GetValue(key string) interface{} {
+	return jsContextView{store: j}.GetValue(key)
+}
+
+// CopyValue implements JsContext.
+func (j *jsContext) CopyValue(key string) interface{} {
+	return jsContextView{store: j}.CopyValue(key)
+}
+
+// SetValue implements JsContext.
+func (j *jsContext) SetValue(key string, value interface{}) error {
+	return jsContextView{store: j}.SetValue(key, value)
+}
+
+// DeleteValue implements JsContext.
+func (j *jsContext) DeleteValue(key string) error {
+	return jsContextView{store: j}.DeleteValue(key)
+}
+
+// Namespace implements JsContext.
+func (j *jsContext) Namespace(name string) JsContext {
+	return jsContextView{store: j}.Namespace(name)
+}
+
+// GetValues implements runtimesRegistry.RuntimeContext.
+func (j *jsContext) GetValues() map[string]interface{} {
+	return j.Snapshot()
+}
+
+// GetValueAsMap implements runtime_registry.RuntimeContext.
+func (j *jsContext) GetValueAsMap(key string) (map[string]interface{}, error) {
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+	if value, ok := j.data[key]; ok {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			return deepCopy(v).(map[string]interface{}), nil
+		default:
+			return nil, fmt.Errorf("value is not a map")
+		}
+	}
+	return nil, fmt.Errorf("key not found")
+}
+
+// Snapshot implements runtimesRegistry.RuntimeContext.
+func (j *jsContext) Snapshot() map[string]interface{} {
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+	return deepCopy(j.data).(map[string]interface{})
+}
+
+// Diff implements runtimesRegistry.RuntimeContext.
+func (j *jsContext) Diff() runtimesRegistry.ContextDiff {
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+
+	diff := runtimesRegistry.ContextDiff{
+		Added:     map[string]interface{}{},
+		Changed:   map[string]interface{}{},
+		WrittenBy: map[string]string{},
+	}
+	for key, value := range j.data {
+		initialValue, existed := j.initial[key]
+		switch {
+		case !existed:
+			diff.Added[key] = deepCopy(value)
+		case !reflect.DeepEqual(initialValue, value):
+			diff.Changed[key] = deepCopy(value)
+		default:
+			continue
+		}
+		if writer, ok := j.writers[key]; ok {
+			diff.WrittenBy[key] = writer
+		}
+	}
+	for key := range j.initial {
+		if _, ok := j.data[key]; ok {
+			continue
+		}
+		diff.Removed = append(diff.Removed, key)
+		if writer, ok := j.writers[key]; ok {
+			diff.WrittenBy[key] = writer
+		}
+	}
+	sort.Strings(diff.Removed)
+	return diff
+}
+
+// MarshalJSON serializes the context values.
+func (j *jsContext) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.Snapshot())
+}
+
+// GetValue implements JsContext.
+func (v jsContextView) GetValue(key string) interface{} {
+	return v.CopyValue(key)
+}
+
+// CopyValue implements JsContext.
+func (v jsContextView) CopyValue(key string) interface{} {
+	v.store.lock.RLock()
+	defer v.store.lock.RUnlock()
+	scope := namespaceIn(v.store.data, v.namespace)
+	if scope == nil {
+		return nil
+	}
+	return deepCopy(scope[key])
+}
+
+// SetValue implements JsContext.
+func (v jsContextView) SetValue(key string, value interface{}) error {
+	return v.write(key, func(data map[string]interface{}, path []string) map[string]interface{} {
+		return setIn(data, path, deepCopy(value))
+	})
+}
+
+// DeleteValue implements JsContext.
+func (v jsContextView) DeleteValue(key string) error {
+	return v.write(key, deleteIn)
+}
+
+// write replaces the context data with what change returns for the path of key, unless its top level key is read-only
+func (v jsContextView) write(key string, change func(data map[string]interface{}, path []string) map[string]interface{}) error {
+	path := append(append([]string{}, v.namespace...), key)
+
+	v.store.lock.Lock()
+	defer v.store.lock.Unlock()
+	if v.store.readOnly[path[0]] {
+		return fmt.Errorf("%w: %v", ErrReadOnlyContextKey, strings.Join(path, "."))
+	}
+	v.store.data = change(v.store.data, path)
+	if v.writer != "" {
+		v.store.writers[path[0]] = v.writer
+	}
+	return nil
+}
+
+// Namespace implements JsContext.
+func (v jsContextView) Namespace(name string) JsContext {
+	return jsContextView{
+		store:     v.store,
+		namespace: append(append([]string{}, v.namespace...), name),
+		writer:    v.writer,
+	}
+}
+
+// namespaceIn returns the map found at path, or nil when missing
+func namespaceIn(data map[string]interface{}, path []string) map[string]interface{} {
+	for _, name := range path {
+		next, ok := data[name].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		data = next
+	}
+	return data
+}
+
+// setIn returns a copy of data with value stored at path, maps along the path are copied rather than mutated
+// so values handed out before the write stay unchanged.
+func setIn(data map[string]interface{}, path []string, value interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(data)+1)
+	for k, v := range data {
+		result[k] = v
+	}
+	if len(path) == 1 {
+		result[path[0]] = value
+		return result
+	}
+	child, _ := data[path[0]].(map[string]interface{})
+	result[path[0]] = setIn(child, path[1:], value)
+	return result
+}
+
+// deleteIn returns a copy of data without the value at path, copying maps along the path like setIn
+func deleteIn(data map[string]interface{}, path []string) map[string]interface{} {
+	child, isMap := data[path[0]].(map[string]interface{})
+	if len(path) > 1 && !isMap {
+		return data
+	}
+	result := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		result[k] = v
+	}
+	if len(path) == 1 {
+		delete(result, path[0])
+		return result
+	}
+	result[path[0]] = deleteIn(child, path[1:])
+	return result
+}
+
+// deepCopy copies the maps and slices making up JSON like values, other values are shared
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = deepCopy(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = deepCopy(item)
+		}
+		return result
+	default:
+		return value
+	}
+}
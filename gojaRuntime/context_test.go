@@ -0,0 +1,131 @@
+package goja_runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsContextNamespaces(t *testing.T) {
+	store := newJsContext(
+		map[string]interface{}{"idToken": map[string]interface{}{"existing": "claim"}},
+		map[string]interface{}{"user": map[string]interface{}{"id": "kp_123"}},
+	)
+	assert := assert.New(t)
+
+	idToken := store.writtenBy("kinde.idToken.setCustomClaim").Namespace("idToken")
+	assert.Nil(idToken.SetValue("random", "test"))
+	assert.Equal("claim", idToken.GetValue("existing"))
+
+	err := store.writtenBy("kinde.user.set").Namespace("user").SetValue("id", "changed")
+	assert.True(errors.Is(err, ErrReadOnlyContextKey))
+	assert.True(errors.Is(store.SetValue("user", nil), ErrReadOnlyContextKey))
+	assert.True(errors.Is(store.DeleteValue("user"), ErrReadOnlyContextKey))
+
+	snapshot := store.Snapshot()
+	assert.Nil(store.writtenBy("kinde.fetch").SetValue("fetched", []interface{}{"a"}))
+	assert.NotContains(snapshot, "fetched")
+
+	diff := store.Diff()
+	assert.Equal(map[string]interface{}{"fetched": []interface{}{"a"}}, diff.Added)
+	assert.Equal(map[string]interface{}{
+		"idToken": map[string]interface{}{"existing": "claim", "random": "test"},
+	}, diff.Changed)
+	assert.Equal(map[string]string{
+		"idToken": "kinde.idToken.setCustomClaim",
+		"fetched": "kinde.fetch",
+	}, diff.WrittenBy)
+}
+
+func TestJsContextValues(t *testing.T) {
+	store := newJsContext(map[string]interface{}{
+		"accessToken": map[string]interface{}{"scope": "read"},
+		"session":     "s1",
+		"flags":       map[string]interface{}{"beta": true, "legacy": true},
+	}, nil)
+	assert := assert.New(t)
+
+	// values are copied out of the context, changes only apply once written back
+	store.GetValue("accessToken").(map[string]interface{})["scope"] = "admin"
+	copied := store.CopyValue("accessToken").(map[string]interface{})
+	copied["scope"] = "write"
+	assert.Equal("read", store.GetValue("accessToken").(map[string]interface{})["scope"])
+	assert.Nil(store.writtenBy("kinde.accessToken.set").SetValue("accessToken", copied))
+	assert.Equal("write", store.GetValue("accessToken").(map[string]interface{})["scope"])
+
+	assert.Nil(store.writtenBy("kinde.session.end").DeleteValue("session"))
+	assert.Nil(store.writtenBy("kinde.flags.unset").Namespace("flags").DeleteValue("legacy"))
+	assert.Nil(store.DeleteValue("missing"))
+	assert.Nil(store.GetValue("session"))
+
+	diff := store.Diff()
+	assert.Equal([]string{"session"}, diff.Removed)
+	assert.Equal(map[string]interface{}{
+		"accessToken": map[string]interface{}{"scope": "write"},
+		"flags":       map[string]interface{}{"beta": true},
+	}, diff.Changed)
+	assert.Equal("kinde.session.end", diff.WrittenBy["session"])
+	assert.Equal("kinde.flags.unset", diff.WrittenBy["flags"])
+}
+
+func TestJsContextConcurrentWrites(t *testing.T) {
+	store := newJsContext(nil, nil)
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			namespace := store.writtenBy(fmt.Sprintf("binding%d", i%5)).Namespace(fmt.Sprintf("ns%d", i%5))
+			namespace.SetValue(fmt.Sprint(i), i)
+			namespace.GetValue(fmt.Sprint(i))
+			store.Snapshot()
+		}(i)
+	}
+	wg.Wait()
+
+	total := 0
+	for _, ns := range store.Snapshot() {
+		total += len(ns.(map[string]interface{}))
+	}
+	assert.Equal(t, 50, total)
+}
+
+func TestExecutionContextSeeding(t *testing.T) {
+	RegisterNativeAPI("contextTest").RegisterNativeFunction("setClaim", func(ctx context.Context, binding runtimesRegistry.BindingSettings, jsContext JsContext, args ...interface{}) (interface{}, error) {
+		return nil, jsContext.Namespace(args[0].(string)).SetValue(args[1].(string), args[2])
+	})
+
+	result, err := newGojaRunner().Execute(context.Background(), runtimesRegistry.WorkflowDescriptor{
+		Limits: runtimesRegistry.RuntimeLimits{
+			MaxExecutionDuration: 5 * time.Second,
+		},
+		ProcessedSource: runtimesRegistry.SourceDescriptor{
+			Source: []byte(`module.exports = { default: async function() {
+				contextTest.setClaim("idToken", "claim", "value");
+				try { contextTest.setClaim("tenant", "id", "other") } catch (e) { return e.message }
+			}}`),
+		},
+		RequestedBindings: map[string]runtimesRegistry.BindingSettings{
+			"contextTest": {},
+		},
+	}, runtimesRegistry.StartOptions{
+		InitialContext:  map[string]interface{}{"idToken": map[string]interface{}{}},
+		ReadOnlyContext: map[string]interface{}{"tenant": map[string]interface{}{"id": "t1"}},
+	})
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal("context key is read-only: tenant.id", result.GetExitResult())
+
+	diff := result.GetContext().Diff()
+	assert.Equal(map[string]interface{}{"idToken": map[string]interface{}{"claim": "value"}}, diff.Changed)
+	assert.Equal("contextTest.setClaim", diff.WrittenBy["idToken"])
+	assert.Equal("t1", result.GetContext().Snapshot()["tenant"].(map[string]interface{})["id"])
+}
@@ -0,0 +1,262 @@
+package crypto
+
+import (
+	"bytes"
+	gocrypto "crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"math/big"
+	"testing"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/buffer"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/encoding"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
+)
+
+func createVM(options Options) *goja.Runtime {
+	vm := goja.New()
+	new(require.Registry).Enable(vm)
+	buffer.Enable(vm)
+	encoding.Enable(vm)
+	Enable(vm, options)
+	return vm
+}
+
+// runAsync runs script as the body of an async function and returns what it resolved to,
+// or the name and message of the rejection.
+func runAsync(t *testing.T, vm *goja.Runtime, script string) string {
+	t.Helper()
+	_, err := vm.RunString(`(async () => {` + script + `})().then(v => globalThis.out = String(v), e => globalThis.out = "rejected: " + e.name + ": " + e.message)`)
+	if err != nil {
+		t.Fatalf("Failed to run %s: %v", script, err)
+	}
+	return vm.Get("out").String()
+}
+
+func TestRandom(t *testing.T) {
+	source := bytes.NewReader(bytes.Repeat([]byte{0xAB}, 64))
+
+	tests := []struct {
+		script   string
+		expected string
+	}{
+		{`crypto.randomUUID()`, "abababab-abab-4bab-abab-abababababab"},
+		{`crypto.getRandomValues(new Uint8Array(3)).join(",")`, "171,171,171"},
+		{`crypto.getRandomValues(new Uint16Array(1))[0]`, "43947"},
+		{`crypto.randomBytes(2).toString("hex")`, "abab"},
+		{`try { crypto.getRandomValues(new Float32Array(1)) } catch (e) { e.name }`, "TypeMismatchError"},
+		{`try { crypto.getRandomValues(new Uint8Array(65537)) } catch (e) { e.name }`, "QuotaExceededError"},
+		{`try { crypto.randomBytes(-1) } catch (e) { e.code }`, "ERR_OUT_OF_RANGE"},
+		{`try { crypto.randomBytes(2 ** 31 - 1) } catch (e) { e.code }`, "ERR_OUT_OF_RANGE"},
+	}
+
+	for _, tc := range tests {
+		vm := createVM(Options{RandomSource: source})
+		result, err := vm.RunString(tc.script)
+		if err != nil {
+			t.Fatalf("Failed to run %s: %v", tc.script, err)
+		}
+		if result.String() != tc.expected {
+			t.Fatalf("%s: expected '%s', got '%s'", tc.script, tc.expected, result.String())
+		}
+	}
+}
+
+func TestNodeHashes(t *testing.T) {
+	tests := []struct {
+		script   string
+		expected string
+	}{
+		{`crypto.createHash("sha256").update("abc").digest("hex")`, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{`crypto.createHash("SHA1").update("a").update(Buffer.from("bc")).digest("base64")`, "qZk+NkcGgWq6PiVxeFDCbJzQ2J0="},
+		{`crypto.createHash("md5").update("").digest().length`, "16"},
+		{`crypto.createHmac("sha256", "key").update("The quick brown fox jumps over the lazy dog").digest("hex")`, "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"},
+		{`crypto.createHmac("sha256", Buffer.from("6b6579", "hex")).update("The quick brown fox jumps over the lazy dog").digest("base64url")`, "97yD9DBThCSxMpjmqm-xQ-9NWaFJRhdZl0edvC0aPNg"},
+		{`try { crypto.createHash("sha3") } catch (e) { e.code }`, "ERR_CRYPTO_INVALID_DIGEST"},
+		{`const h = crypto.createHash("sha256"); h.digest(); try { h.update("x") } catch (e) { e.code }`, "ERR_CRYPTO_HASH_FINALIZED"},
+		{`crypto.timingSafeEqual(Buffer.from("abc"), Buffer.from("abc")) && !crypto.timingSafeEqual(Buffer.from("abc"), Buffer.from("abd"))`, "true"},
+		{`try { crypto.timingSafeEqual(Buffer.from("a"), Buffer.from("ab")) } catch (e) { e.code }`, "ERR_CRYPTO_TIMING_SAFE_EQUAL_LENGTH"},
+	}
+
+	for _, tc := range tests {
+		vm := createVM(Options{})
+		result, err := vm.RunString(tc.script)
+		if err != nil {
+			t.Fatalf("Failed to run %s: %v", tc.script, err)
+		}
+		if result.String() != tc.expected {
+			t.Fatalf("%s: expected '%s', got '%s'", tc.script, tc.expected, result.String())
+		}
+	}
+}
+
+func TestSubtleDigestAndHMAC(t *testing.T) {
+	tests := []struct {
+		script   string
+		expected string
+	}{
+		{`return Buffer.from(await crypto.subtle.digest("SHA-256", new TextEncoder().encode("abc"))).toString("hex")`, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{`return (await crypto.subtle.digest({name: "sha-512"}, new Uint8Array())).byteLength`, "64"},
+		{`return await crypto.subtle.digest("MD5", new Uint8Array())`, "rejected: NotSupportedError: Unrecognized algorithm name"},
+		{`
+			const key = await crypto.subtle.importKey("raw", Buffer.from("key"), {name: "HMAC", hash: "SHA-256"}, false, ["sign", "verify"]);
+			const data = Buffer.from("The quick brown fox jumps over the lazy dog");
+			const signature = await crypto.subtle.sign("HMAC", key, data);
+			const valid = await crypto.subtle.verify("HMAC", key, signature, data);
+			const invalid = await crypto.subtle.verify("HMAC", key, signature, Buffer.from("tampered"));
+			return [Buffer.from(signature).toString("hex"), valid, invalid, key.type, key.algorithm.hash.name, key.algorithm.length, key.usages.join("|")].join(",");
+		`, "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8,true,false,secret,SHA-256,24,sign|verify"},
+		{`
+			const key = await crypto.subtle.importKey("jwk", {kty: "oct", k: "a2V5"}, {name: "HMAC", hash: {name: "SHA-1"}}, true, ["sign"]);
+			const jwk = await crypto.subtle.exportKey("jwk", key);
+			const raw = await crypto.subtle.exportKey("raw", key);
+			return [jwk.kty, jwk.k, jwk.alg, jwk.ext, jwk.key_ops.join("|"), Buffer.from(raw).toString(), key instanceof crypto.CryptoKey].join(",");
+		`, "oct,a2V5,HS1,true,sign,key,true"},
+		{`
+			const key = await crypto.subtle.importKey("raw", Buffer.from("key"), {name: "HMAC", hash: "SHA-256"}, false, ["sign"]);
+			return await crypto.subtle.exportKey("raw", key);
+		`, "rejected: InvalidAccessError: key is not extractable"},
+		{`
+			const key = await crypto.subtle.importKey("raw", Buffer.from("key"), {name: "HMAC", hash: "SHA-256"}, false, ["sign"]);
+			return await crypto.subtle.verify("HMAC", key, new Uint8Array(32), Buffer.from("data"));
+		`, "rejected: InvalidAccessError: The key does not support the verify operation"},
+		{`return await crypto.subtle.importKey("raw", Buffer.from("key"), {name: "HMAC", hash: "SHA-256"}, false, ["encrypt"])`, "rejected: SyntaxError: Unsupported key usage for a HMAC secret key"},
+		{`return await crypto.subtle.importKey("raw", Buffer.from("key"), "HMAC", false, ["sign"])`, "rejected: TypeError: HMAC: hash: Missing or not an AlgorithmIdentifier"},
+	}
+
+	for _, tc := range tests {
+		vm := createVM(Options{})
+		if result := runAsync(t, vm, tc.script); result != tc.expected {
+			t.Fatalf("%s: expected '%s', got '%s'", tc.script, tc.expected, result)
+		}
+	}
+}
+
+func TestSubtleAsymmetricKeys(t *testing.T) {
+	data := []byte("signed payload")
+	digest := sha256.Sum256(data)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs1Signature, _ := rsa.SignPKCS1v15(rand.Reader, rsaKey, gocrypto.SHA256, digest[:])
+	pssSignature, _ := rsa.SignPSS(rand.Reader, rsaKey, gocrypto.SHA256, digest[:], &rsa.PSSOptions{SaltLength: 32})
+	rsaSPKI, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	rsaPKCS8, _ := x509.MarshalPKCS8PrivateKey(rsaKey)
+
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	r, s, _ := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+	ecSignature := make([]byte, 64)
+	r.FillBytes(ecSignature[:32])
+	s.FillBytes(ecSignature[32:])
+	ecPKCS8, _ := x509.MarshalPKCS8PrivateKey(ecKey)
+
+	edPublic, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	edSignature := ed25519.Sign(edPrivate, data)
+	edPKCS8, _ := x509.MarshalPKCS8PrivateKey(edPrivate)
+
+	tests := []struct {
+		script   string
+		expected string
+	}{
+		{`
+			const key = await crypto.subtle.importKey("spki", rsaSPKI, {name: "RSASSA-PKCS1-v1_5", hash: "SHA-256"}, true, ["verify"]);
+			return [await crypto.subtle.verify("RSASSA-PKCS1-v1_5", key, pkcs1Signature, data), await crypto.subtle.verify("RSASSA-PKCS1-v1_5", key, pkcs1Signature, Buffer.from("x")), key.algorithm.modulusLength, key.algorithm.publicExponent.join(".")].join(",");
+		`, "true,false,2048,1.0.1"},
+		{`
+			const key = await crypto.subtle.importKey("spki", rsaSPKI, {name: "RSA-PSS", hash: "SHA-256"}, false, ["verify"]);
+			return await crypto.subtle.verify({name: "RSA-PSS", saltLength: 32}, key, pssSignature, data);
+		`, "true"},
+		{`
+			const key = await crypto.subtle.importKey("pkcs8", rsaPKCS8, {name: "RSASSA-PKCS1-v1_5", hash: "SHA-256"}, true, ["sign"]);
+			const jwk = await crypto.subtle.exportKey("jwk", key);
+			const publicKey = await crypto.subtle.importKey("jwk", {kty: jwk.kty, n: jwk.n, e: jwk.e}, {name: "RSASSA-PKCS1-v1_5", hash: "SHA-256"}, true, ["verify"]);
+			const signature = await crypto.subtle.sign("RSASSA-PKCS1-v1_5", key, data);
+			return [jwk.alg, await crypto.subtle.verify("RSASSA-PKCS1-v1_5", publicKey, signature, data)].join(",");
+		`, "RS256,true"},
+		{`
+			const key = await crypto.subtle.importKey("raw", ecPoint, {name: "ECDSA", namedCurve: "P-256"}, true, ["verify"]);
+			const jwk = await crypto.subtle.exportKey("jwk", key);
+			return [await crypto.subtle.verify({name: "ECDSA", hash: "SHA-256"}, key, ecSignature, data), key.algorithm.namedCurve, jwk.crv, Buffer.from(await crypto.subtle.exportKey("raw", key)).equals(Buffer.from(ecPoint))].join(",");
+		`, "true,P-256,P-256,true"},
+		{`
+			const key = await crypto.subtle.importKey("pkcs8", ecPKCS8, {name: "ECDSA", namedCurve: "P-256"}, true, ["sign"]);
+			const jwk = await crypto.subtle.exportKey("jwk", key);
+			const publicKey = await crypto.subtle.importKey("jwk", {kty: "EC", crv: jwk.crv, x: jwk.x, y: jwk.y}, {name: "ECDSA", namedCurve: "P-256"}, true, ["verify"]);
+			const signature = await crypto.subtle.sign({name: "ECDSA", hash: "SHA-256"}, key, data);
+			return [signature.byteLength, await crypto.subtle.verify({name: "ECDSA", hash: "SHA-256"}, publicKey, signature, data)].join(",");
+		`, "64,true"},
+		{`return await crypto.subtle.importKey("raw", ecPoint, {name: "ECDSA", namedCurve: "P-384"}, true, ["verify"])`, "rejected: DataError: Invalid keyData: invalid EC point"},
+		{`
+			const key = await crypto.subtle.importKey("raw", edPublic, "Ed25519", true, ["verify"]);
+			const spki = await crypto.subtle.exportKey("spki", key);
+			return [await crypto.subtle.verify("Ed25519", key, edSignature, data), await crypto.subtle.verify("Ed25519", key, edSignature, Buffer.from("x")), spki.byteLength].join(",");
+		`, "true,false,44"},
+		{`
+			const key = await crypto.subtle.importKey("pkcs8", edPKCS8, {name: "Ed25519"}, true, ["sign"]);
+			const jwk = await crypto.subtle.exportKey("jwk", key);
+			const publicKey = await crypto.subtle.importKey("jwk", {kty: "OKP", crv: "Ed25519", x: jwk.x}, "Ed25519", false, ["verify"]);
+			const signature = await crypto.subtle.sign("Ed25519", key, data);
+			return [jwk.alg, key.type, await crypto.subtle.verify("Ed25519", publicKey, signature, data)].join(",");
+		`, "EdDSA,private,true"},
+		{`
+			const key = await crypto.subtle.importKey("raw", edPublic, "Ed25519", true, ["verify"]);
+			return await crypto.subtle.exportKey("pkcs8", key);
+		`, "rejected: InvalidAccessError: Unable to export a Ed25519 public key as pkcs8"},
+		{`return await crypto.subtle.importKey("raw", edPublic, "Ed25519", true, ["sign"])`, "rejected: SyntaxError: Unsupported key usage for a Ed25519 public key"},
+		{`
+			const key = await crypto.subtle.importKey("raw", edPublic, "Ed25519", true, ["verify"]);
+			return await crypto.subtle.verify({name: "ECDSA", hash: "SHA-256"}, key, edSignature, data);
+		`, "rejected: InvalidAccessError: The requested operation is not valid for the provided key"},
+	}
+
+	for _, tc := range tests {
+		vm := createVM(Options{})
+		vm.Set("data", vm.NewArrayBuffer(data))
+		vm.Set("rsaSPKI", vm.NewArrayBuffer(rsaSPKI))
+		vm.Set("rsaPKCS8", vm.NewArrayBuffer(rsaPKCS8))
+		vm.Set("pkcs1Signature", vm.NewArrayBuffer(pkcs1Signature))
+		vm.Set("pssSignature", vm.NewArrayBuffer(pssSignature))
+		vm.Set("ecPoint", vm.NewArrayBuffer(ecPoint(&ecKey.PublicKey)))
+		vm.Set("ecSignature", vm.NewArrayBuffer(ecSignature))
+		vm.Set("ecPKCS8", vm.NewArrayBuffer(ecPKCS8))
+		vm.Set("edPublic", vm.NewArrayBuffer(edPublic))
+		vm.Set("edSignature", vm.NewArrayBuffer(edSignature))
+		vm.Set("edPKCS8", vm.NewArrayBuffer(edPKCS8))
+
+		if result := runAsync(t, vm, tc.script); result != tc.expected {
+			t.Fatalf("%s: expected '%s', got '%s'", tc.script, tc.expected, result)
+		}
+	}
+}
+
+func TestParseJWK(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	jwk, err := jwkFromKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParseJWK(jwk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ecKey.Equal(key) {
+		t.Fatal("EC private key did not round trip")
+	}
+
+	jwk["x"] = jwk["y"]
+	if _, err := ParseJWK(jwk); err == nil {
+		t.Fatal("expected an error for a point that is not on the curve")
+	}
+
+	if _, err := ParseJWK(map[string]interface{}{"kty": "RSA", "n": "AQAB", "e": big.NewInt(1).String()}); err == nil {
+		t.Fatal("expected an error for an invalid RSA exponent")
+	}
+}
@@ -0,0 +1,267 @@
+package crypto
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+type namedCurve struct {
+	elliptic elliptic.Curve
+	ecdh     ecdh.Curve
+}
+
+var namedCurves = map[string]namedCurve{
+	"P-256": {elliptic.P256(), ecdh.P256()},
+	"P-384": {elliptic.P384(), ecdh.P384()},
+	"P-521": {elliptic.P521(), ecdh.P521()},
+}
+
+func curveName(curve elliptic.Curve) string {
+	return curve.Params().Name
+}
+
+func curveByteSize(curve elliptic.Curve) int {
+	return (curve.Params().BitSize + 7) / 8
+}
+
+// ecPoint encodes an EC public key as an uncompressed point.
+func ecPoint(key *ecdsa.PublicKey) []byte {
+	size := curveByteSize(key.Curve)
+	point := make([]byte, 1+2*size)
+	point[0] = 4
+	key.X.FillBytes(point[1 : 1+size])
+	key.Y.FillBytes(point[1+size:])
+	return point
+}
+
+// parseECPoint decodes an uncompressed point, making sure it is on the curve.
+func parseECPoint(name string, point []byte) (*ecdsa.PublicKey, error) {
+	curve, ok := namedCurves[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported curve %q", name)
+	}
+	size := curveByteSize(curve.elliptic)
+	if len(point) != 1+2*size || point[0] != 4 {
+		return nil, errors.New("invalid EC point")
+	}
+	if _, err := curve.ecdh.NewPublicKey(point); err != nil {
+		return nil, err
+	}
+	return &ecdsa.PublicKey{
+		Curve: curve.elliptic,
+		X:     new(big.Int).SetBytes(point[1 : 1+size]),
+		Y:     new(big.Int).SetBytes(point[1+size:]),
+	}, nil
+}
+
+func jwkString(jwk map[string]interface{}, name string) (string, bool) {
+	v, ok := jwk[name].(string)
+	return v, ok
+}
+
+func jwkBytes(jwk map[string]interface{}, name string) ([]byte, error) {
+	v, ok := jwkString(jwk, name)
+	if !ok {
+		return nil, fmt.Errorf("JWK member %q is missing", name)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(v, "="))
+	if err != nil {
+		return nil, fmt.Errorf("JWK member %q is not valid base64url: %w", name, err)
+	}
+	return data, nil
+}
+
+func jwkBigInt(jwk map[string]interface{}, name string) (*big.Int, error) {
+	data, err := jwkBytes(jwk, name)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+func encodeBigInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+// ParseJWK converts a JSON Web Key into the matching Go key: []byte for "oct" keys, *rsa.PublicKey or
+// *rsa.PrivateKey for "RSA" keys, *ecdsa.PublicKey or *ecdsa.PrivateKey for "EC" keys and
+// ed25519.PublicKey or ed25519.PrivateKey for "OKP" keys.
+func ParseJWK(jwk map[string]interface{}) (interface{}, error) {
+	kty, _ := jwkString(jwk, "kty")
+	_, private := jwk["d"]
+
+	switch kty {
+	case "oct":
+		return jwkBytes(jwk, "k")
+
+	case "RSA":
+		n, err := jwkBigInt(jwk, "n")
+		if err != nil {
+			return nil, err
+		}
+		e, err := jwkBigInt(jwk, "e")
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 || e.Int64() < 3 {
+			return nil, errors.New("invalid RSA public exponent")
+		}
+		public := rsa.PublicKey{N: n, E: int(e.Int64())}
+		if !private {
+			return &public, nil
+		}
+
+		key := &rsa.PrivateKey{PublicKey: public}
+		if key.D, err = jwkBigInt(jwk, "d"); err != nil {
+			return nil, err
+		}
+		p, err := jwkBigInt(jwk, "p")
+		if err != nil {
+			return nil, err
+		}
+		q, err := jwkBigInt(jwk, "q")
+		if err != nil {
+			return nil, err
+		}
+		key.Primes = []*big.Int{p, q}
+		if err := key.Validate(); err != nil {
+			return nil, err
+		}
+		key.Precompute()
+		return key, nil
+
+	case "EC":
+		crv, _ := jwkString(jwk, "crv")
+		curve, ok := namedCurves[crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", crv)
+		}
+		x, err := jwkBytes(jwk, "x")
+		if err != nil {
+			return nil, err
+		}
+		y, err := jwkBytes(jwk, "y")
+		if err != nil {
+			return nil, err
+		}
+		size := curveByteSize(curve.elliptic)
+		if len(x) != size || len(y) != size {
+			return nil, errors.New("invalid EC coordinates")
+		}
+		public, err := parseECPoint(crv, append(append([]byte{4}, x...), y...))
+		if err != nil {
+			return nil, err
+		}
+		if !private {
+			return public, nil
+		}
+
+		d, err := jwkBytes(jwk, "d")
+		if err != nil {
+			return nil, err
+		}
+		ecdhKey, err := curve.ecdh.NewPrivateKey(d)
+		if err != nil {
+			return nil, err
+		}
+		if !ecdhKey.PublicKey().Equal(mustECDHPublicKey(public)) {
+			return nil, errors.New("EC private key does not match its public key")
+		}
+		return &ecdsa.PrivateKey{PublicKey: *public, D: new(big.Int).SetBytes(d)}, nil
+
+	case "OKP":
+		if crv, _ := jwkString(jwk, "crv"); crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", crv)
+		}
+		x, err := jwkBytes(jwk, "x")
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 public key")
+		}
+		if !private {
+			return ed25519.PublicKey(x), nil
+		}
+
+		d, err := jwkBytes(jwk, "d")
+		if err != nil {
+			return nil, err
+		}
+		if len(d) != ed25519.SeedSize {
+			return nil, errors.New("invalid Ed25519 private key")
+		}
+		key := ed25519.NewKeyFromSeed(d)
+		if !key.Public().(ed25519.PublicKey).Equal(ed25519.PublicKey(x)) {
+			return nil, errors.New("Ed25519 private key does not match its public key")
+		}
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unsupported JWK key type %q", kty)
+}
+
+func mustECDHPublicKey(key *ecdsa.PublicKey) *ecdh.PublicKey {
+	public, err := key.ECDH()
+	if err != nil {
+		panic(err)
+	}
+	return public
+}
+
+// jwkFromKey is the inverse of ParseJWK.
+func jwkFromKey(key interface{}) (map[string]interface{}, error) {
+	switch k := key.(type) {
+	case []byte:
+		return map[string]interface{}{"kty": "oct", "k": base64.RawURLEncoding.EncodeToString(k)}, nil
+
+	case *rsa.PublicKey:
+		return map[string]interface{}{"kty": "RSA", "n": encodeBigInt(k.N), "e": encodeBigInt(big.NewInt(int64(k.E)))}, nil
+
+	case *rsa.PrivateKey:
+		jwk, _ := jwkFromKey(&k.PublicKey)
+		if len(k.Primes) != 2 {
+			return nil, errors.New("multi-prime RSA keys are not supported")
+		}
+		jwk["d"] = encodeBigInt(k.D)
+		jwk["p"] = encodeBigInt(k.Primes[0])
+		jwk["q"] = encodeBigInt(k.Primes[1])
+		jwk["dp"] = encodeBigInt(k.Precomputed.Dp)
+		jwk["dq"] = encodeBigInt(k.Precomputed.Dq)
+		jwk["qi"] = encodeBigInt(k.Precomputed.Qinv)
+		return jwk, nil
+
+	case *ecdsa.PublicKey:
+		size := curveByteSize(k.Curve)
+		point := ecPoint(k)
+		return map[string]interface{}{
+			"kty": "EC",
+			"crv": curveName(k.Curve),
+			"x":   base64.RawURLEncoding.EncodeToString(point[1 : 1+size]),
+			"y":   base64.RawURLEncoding.EncodeToString(point[1+size:]),
+		}, nil
+
+	case *ecdsa.PrivateKey:
+		jwk, _ := jwkFromKey(&k.PublicKey)
+		jwk["d"] = base64.RawURLEncoding.EncodeToString(k.D.FillBytes(make([]byte, curveByteSize(k.Curve))))
+		return jwk, nil
+
+	case ed25519.PublicKey:
+		return map[string]interface{}{"kty": "OKP", "crv": "Ed25519", "x": base64.RawURLEncoding.EncodeToString(k)}, nil
+
+	case ed25519.PrivateKey:
+		jwk, _ := jwkFromKey(k.Public())
+		jwk["d"] = base64.RawURLEncoding.EncodeToString(k.Seed())
+		return jwk, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %T", key)
+}
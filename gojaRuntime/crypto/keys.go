@@ -0,0 +1,145 @@
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"math/big"
+	"reflect"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/encoding"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+)
+
+const (
+	keyTypeSecret  = "secret"
+	keyTypePublic  = "public"
+	keyTypePrivate = "private"
+)
+
+var reflectTypeCryptoKey = reflect.TypeOf((*cryptoKey)(nil))
+
+// cryptoKey backs the CryptoKey objects handed to workflows. key holds the Go representation
+// as returned by ParseJWK.
+type cryptoKey struct {
+	kind        string
+	extractable bool
+	algorithm   algorithmParams
+	usages      []string
+	key         interface{}
+
+	algorithmObject *goja.Object
+	usagesObject    *goja.Object
+}
+
+// keyKind classifies a Go key as secret, public or private.
+func keyKind(key interface{}) string {
+	switch key.(type) {
+	case []byte:
+		return keyTypeSecret
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		return keyTypePrivate
+	}
+	return keyTypePublic
+}
+
+func (k *cryptoKey) hasUsage(usage string) bool {
+	for _, u := range k.usages {
+		if u == usage {
+			return true
+		}
+	}
+	return false
+}
+
+func toCryptoKey(r *goja.Runtime, v goja.Value, name string) *cryptoKey {
+	if v != nil && v.ExportType() == reflectTypeCryptoKey {
+		if k := v.Export().(*cryptoKey); k != nil {
+			return k
+		}
+	}
+	panic(errors.NewTypeError(r, errors.ErrCodeInvalidArgType, `The "%s" argument must be an instance of CryptoKey.`, name))
+}
+
+func (m *cryptoModule) newCryptoKey(k *cryptoKey) *goja.Object {
+	o := m.r.ToValue(k).(*goja.Object)
+	o.SetPrototype(m.CryptoKeyPrototype)
+	return o
+}
+
+func (m *cryptoModule) algorithmObject(k *cryptoKey) *goja.Object {
+	if k.algorithmObject != nil {
+		return k.algorithmObject
+	}
+
+	o := m.r.NewObject()
+	o.Set("name", k.algorithm.name)
+	if k.algorithm.hash != "" {
+		hash := m.r.NewObject()
+		hash.Set("name", k.algorithm.hash)
+		o.Set("hash", hash)
+	}
+
+	switch key := k.key.(type) {
+	case []byte:
+		o.Set("length", k.algorithm.length)
+	case *rsa.PublicKey:
+		o.Set("modulusLength", key.N.BitLen())
+		o.Set("publicExponent", encoding.NewUint8Array(m.r, big.NewInt(int64(key.E)).Bytes()))
+	case *rsa.PrivateKey:
+		o.Set("modulusLength", key.N.BitLen())
+		o.Set("publicExponent", encoding.NewUint8Array(m.r, big.NewInt(int64(key.E)).Bytes()))
+	case *ecdsa.PublicKey:
+		o.Set("namedCurve", curveName(key.Curve))
+	case *ecdsa.PrivateKey:
+		o.Set("namedCurve", curveName(key.Curve))
+	}
+
+	k.algorithmObject = o
+	return o
+}
+
+func (m *cryptoModule) createCryptoKeyPrototype() *goja.Object {
+	p := m.r.NewObject()
+
+	getter := func(name string, get func(k *cryptoKey) goja.Value) {
+		p.DefineAccessorProperty(name, m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+			return get(toCryptoKey(m.r, call.This, "this"))
+		}), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	}
+
+	getter("type", func(k *cryptoKey) goja.Value {
+		return m.r.ToValue(k.kind)
+	})
+	getter("extractable", func(k *cryptoKey) goja.Value {
+		return m.r.ToValue(k.extractable)
+	})
+	getter("algorithm", func(k *cryptoKey) goja.Value {
+		return m.algorithmObject(k)
+	})
+	getter("usages", func(k *cryptoKey) goja.Value {
+		if k.usagesObject == nil {
+			usages := make([]interface{}, len(k.usages))
+			for i, u := range k.usages {
+				usages[i] = u
+			}
+			k.usagesObject = m.r.NewArray(usages...)
+		}
+		return k.usagesObject
+	})
+
+	return p
+}
+
+func (m *cryptoModule) createCryptoKeyConstructor() goja.Value {
+	f := m.r.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		panic(m.r.NewTypeError("Illegal constructor"))
+	}).(*goja.Object)
+
+	m.CryptoKeyPrototype = m.createCryptoKeyPrototype()
+	f.DefineDataProperty("name", m.r.ToValue("CryptoKey"), goja.FLAG_FALSE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+	f.Set("prototype", m.CryptoKeyPrototype)
+	m.CryptoKeyPrototype.DefineDataProperty("constructor", f, goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+	return f
+}
@@ -0,0 +1,78 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"io"
+
+	goja "github.com/grafana/sobek"
+	_ "github.com/kinde-oss/workflows-runtime/gojaRuntime/buffer"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
+)
+
+const ModuleName = "crypto"
+
+// Options configure a crypto module instance.
+type Options struct {
+	// RandomSource is read by randomUUID, getRandomValues, randomBytes and signing, crypto/rand if nil.
+	// It is intended for tests that need deterministic output.
+	RandomSource io.Reader
+}
+
+type cryptoModule struct {
+	r            *goja.Runtime
+	randomSource io.Reader
+
+	CryptoKeyPrototype *goja.Object
+	buffer             *goja.Object
+}
+
+func Require(runtime *goja.Runtime, module *goja.Object) {
+	newModule(runtime, Options{}).exports(module.Get("exports").(*goja.Object))
+}
+
+func newModule(runtime *goja.Runtime, options Options) *cryptoModule {
+	m := &cryptoModule{
+		r:            runtime,
+		randomSource: options.RandomSource,
+	}
+	if m.randomSource == nil {
+		m.randomSource = rand.Reader
+	}
+	return m
+}
+
+func (m *cryptoModule) exports(exports *goja.Object) {
+	runtime := m.r
+	m.buffer = require.Require(runtime, "buffer").ToObject(runtime).Get("Buffer").ToObject(runtime)
+
+	cryptoKey := m.createCryptoKeyConstructor()
+
+	webcrypto := runtime.NewObject()
+	webcrypto.Set("getRandomValues", m.getRandomValues)
+	webcrypto.Set("randomUUID", m.randomUUID)
+	webcrypto.Set("subtle", m.createSubtle())
+
+	exports.Set("webcrypto", webcrypto)
+	exports.Set("CryptoKey", cryptoKey)
+	exports.Set("subtle", webcrypto.Get("subtle"))
+	exports.Set("getRandomValues", m.getRandomValues)
+	exports.Set("randomUUID", m.randomUUID)
+	exports.Set("randomBytes", m.randomBytes)
+	exports.Set("createHash", m.createHash)
+	exports.Set("createHmac", m.createHmac)
+	exports.Set("timingSafeEqual", m.timingSafeEqual)
+	exports.Set("getHashes", m.getHashes)
+}
+
+// Enable installs a crypto global configured with options into the runtime and returns it.
+// require("crypto") keeps returning an instance reading crypto/rand.
+func Enable(runtime *goja.Runtime, options Options) *goja.Object {
+	exports := runtime.NewObject()
+	newModule(runtime, options).exports(exports)
+	runtime.Set("crypto", exports)
+	return exports
+}
+
+func init() {
+	require.RegisterCoreModule(ModuleName, Require)
+}
@@ -0,0 +1,159 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"hash"
+	"reflect"
+	"sort"
+	"strings"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/buffer"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/encoding"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+)
+
+// maxRandomBytesLength is the largest buffer randomBytes allocates, the same cap as every other buffer
+const maxRandomBytesLength = buffer.MaxLength
+
+// nodeHashes are the digest names accepted by createHash and createHmac.
+var nodeHashes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha224": sha256.New224,
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+func isNumber(v goja.Value) bool {
+	if v == nil || v.ExportType() == nil {
+		return false
+	}
+	kind := v.ExportType().Kind()
+	return kind == reflect.Int64 || kind == reflect.Float64
+}
+
+func isString(v goja.Value) bool {
+	return v != nil && v.ExportType() != nil && v.ExportType().Kind() == reflect.String
+}
+
+func (m *cryptoModule) newBuffer(data []byte) goja.Value {
+	from, _ := goja.AssertFunction(m.buffer.Get("from"))
+	res, err := from(m.buffer, m.r.ToValue(m.r.NewArrayBuffer(data)))
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// dataBytes converts strings (decoded with the given encoding, utf8 by default) and buffer sources to bytes.
+func (m *cryptoModule) dataBytes(v goja.Value, enc goja.Value, name string) []byte {
+	if isString(v) {
+		from, _ := goja.AssertFunction(m.buffer.Get("from"))
+		buffer, err := from(m.buffer, v, enc)
+		if err != nil {
+			panic(err)
+		}
+		data, _ := encoding.BufferSourceBytes(m.r, buffer)
+		return data
+	}
+	if data, ok := encoding.BufferSourceBytes(m.r, v); ok {
+		return data
+	}
+	panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "%s" argument must be of type string or an instance of Buffer, TypedArray, or DataView.`, name))
+}
+
+// encodeOutput returns data as a Buffer or, when an encoding is given, as a string.
+func (m *cryptoModule) encodeOutput(data []byte, enc goja.Value) goja.Value {
+	buffer := m.newBuffer(data)
+	if goja.IsUndefined(enc) || goja.IsNull(enc) {
+		return buffer
+	}
+	toString, _ := goja.AssertFunction(buffer.ToObject(m.r).Get("toString"))
+	res, err := toString(buffer, enc)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+func (m *cryptoModule) newHash(algorithm goja.Value) func() hash.Hash {
+	newHash, ok := nodeHashes[strings.ToLower(algorithm.String())]
+	if !ok {
+		panic(errors.NewError(m.r, nil, errors.ErrCodeCryptoInvalidDigest, "Invalid digest: %s", algorithm.String()))
+	}
+	return newHash
+}
+
+// wrapHash exposes a hash.Hash with the update/digest interface of Node's Hash and Hmac classes.
+func (m *cryptoModule) wrapHash(h hash.Hash) *goja.Object {
+	o := m.r.NewObject()
+	finalized := false
+
+	o.Set("update", func(call goja.FunctionCall) goja.Value {
+		if finalized {
+			panic(errors.NewError(m.r, nil, errors.ErrCodeCryptoHashFinalized, "Digest already called"))
+		}
+		h.Write(m.dataBytes(call.Argument(0), call.Argument(1), "data"))
+		return o
+	})
+
+	o.Set("digest", func(call goja.FunctionCall) goja.Value {
+		if finalized {
+			panic(errors.NewError(m.r, nil, errors.ErrCodeCryptoHashFinalized, "Digest already called"))
+		}
+		finalized = true
+		return m.encodeOutput(h.Sum(nil), call.Argument(0))
+	})
+
+	return o
+}
+
+func (m *cryptoModule) createHash(call goja.FunctionCall) goja.Value {
+	return m.wrapHash(m.newHash(call.Argument(0))())
+}
+
+func (m *cryptoModule) createHmac(call goja.FunctionCall) goja.Value {
+	newHash := m.newHash(call.Argument(0))
+	key := call.Argument(1)
+	if k, ok := key.(*goja.Object); ok {
+		if secret, ok := k.Export().(*cryptoKey); ok {
+			if secret.kind != keyTypeSecret {
+				panic(errors.NewTypeError(m.r, errors.ErrCodeCryptoInvalidKeyObjectType, "Invalid key object type %s, expected secret.", secret.kind))
+			}
+			return m.wrapHash(hmac.New(newHash, secret.key.([]byte)))
+		}
+	}
+	return m.wrapHash(hmac.New(newHash, m.dataBytes(key, goja.Undefined(), "key")))
+}
+
+func (m *cryptoModule) timingSafeEqual(call goja.FunctionCall) goja.Value {
+	a, ok := encoding.BufferSourceBytes(m.r, call.Argument(0))
+	if !ok {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "buf1" argument must be an instance of ArrayBuffer, Buffer, TypedArray, or DataView.`))
+	}
+	b, ok := encoding.BufferSourceBytes(m.r, call.Argument(1))
+	if !ok {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "buf2" argument must be an instance of ArrayBuffer, Buffer, TypedArray, or DataView.`))
+	}
+	if len(a) != len(b) {
+		rangeError, _ := m.r.Get("RangeError").(*goja.Object)
+		panic(errors.NewError(m.r, rangeError, errors.ErrCodeCryptoTimingSafeEqualLength, "Input buffers must have the same byte length"))
+	}
+	return m.r.ToValue(subtle.ConstantTimeCompare(a, b) == 1)
+}
+
+func (m *cryptoModule) getHashes(call goja.FunctionCall) goja.Value {
+	names := make([]interface{}, 0, len(nodeHashes))
+	for name := range nodeHashes {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i].(string) < names[j].(string) })
+	return m.r.NewArray(names...)
+}
@@ -0,0 +1,72 @@
+package crypto
+
+import (
+	"fmt"
+	"io"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/encoding"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/web"
+)
+
+// maxRandomValuesLength is the quota of getRandomValues as defined by the Web Crypto API.
+const maxRandomValuesLength = 65536
+
+var integerTypedArrays = map[string]bool{
+	"Int8Array":         true,
+	"Uint8Array":        true,
+	"Uint8ClampedArray": true,
+	"Int16Array":        true,
+	"Uint16Array":       true,
+	"Int32Array":        true,
+	"Uint32Array":       true,
+	"BigInt64Array":     true,
+	"BigUint64Array":    true,
+}
+
+func (m *cryptoModule) random(size int) []byte {
+	data := make([]byte, size)
+	if _, err := io.ReadFull(m.randomSource, data); err != nil {
+		panic(web.NewDOMException(m.r, fmt.Sprintf("failed to read random bytes: %v", err), "OperationError"))
+	}
+	return data
+}
+
+func (m *cryptoModule) getRandomValues(call goja.FunctionCall) goja.Value {
+	array := call.Argument(0)
+	o, ok := array.(*goja.Object)
+	if !ok {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "typedArray" argument must be an instance of an integer TypedArray.`))
+	}
+	if tag := o.GetSymbol(goja.SymToStringTag); tag == nil || !integerTypedArrays[tag.String()] {
+		panic(web.NewDOMException(m.r, "The data argument must be an integer-type TypedArray", "TypeMismatchError"))
+	}
+
+	data, _ := encoding.BufferSourceBytes(m.r, o)
+	if len(data) > maxRandomValuesLength {
+		panic(web.NewDOMException(m.r, fmt.Sprintf("The ArrayBufferView's byte length (%d) exceeds the number of bytes of entropy available via this API (%d)", len(data), maxRandomValuesLength), "QuotaExceededError"))
+	}
+	copy(data, m.random(len(data)))
+	return array
+}
+
+func (m *cryptoModule) randomUUID(call goja.FunctionCall) goja.Value {
+	b := m.random(16)
+	b[6] = b[6]&0x0F | 0x40
+	b[8] = b[8]&0x3F | 0x80
+	return m.r.ToValue(fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]))
+}
+
+func (m *cryptoModule) randomBytes(call goja.FunctionCall) goja.Value {
+	size := call.Argument(0)
+	if !isNumber(size) {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "size" argument must be of type number.`))
+	}
+	n := size.ToInteger()
+	if n < 0 || n > maxRandomBytesLength {
+		rangeError, _ := m.r.Get("RangeError").(*goja.Object)
+		panic(errors.NewError(m.r, rangeError, errors.ErrCodeOutOfRange, `The value of "size" is out of range. It must be >= 0 && <= %d. Received %d`, maxRandomBytesLength, n))
+	}
+	return m.newBuffer(m.random(int(n)))
+}
@@ -0,0 +1,485 @@
+package crypto
+
+import (
+	gocrypto "crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"math/big"
+	"strings"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/encoding"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/web"
+)
+
+const (
+	algorithmSHA1     = "SHA-1"
+	algorithmSHA256   = "SHA-256"
+	algorithmSHA384   = "SHA-384"
+	algorithmSHA512   = "SHA-512"
+	algorithmHMAC     = "HMAC"
+	algorithmRSAPKCS1 = "RSASSA-PKCS1-v1_5"
+	algorithmRSAPSS   = "RSA-PSS"
+	algorithmECDSA    = "ECDSA"
+	algorithmEd25519  = "Ed25519"
+	usageSign         = "sign"
+	usageVerify       = "verify"
+	formatRaw         = "raw"
+	formatJWK         = "jwk"
+	formatPKCS8       = "pkcs8"
+	formatSPKI        = "spki"
+)
+
+var algorithmNames = map[string]string{}
+
+var digests = map[string]gocrypto.Hash{
+	algorithmSHA1:   gocrypto.SHA1,
+	algorithmSHA256: gocrypto.SHA256,
+	algorithmSHA384: gocrypto.SHA384,
+	algorithmSHA512: gocrypto.SHA512,
+}
+
+func init() {
+	for _, name := range []string{algorithmSHA1, algorithmSHA256, algorithmSHA384, algorithmSHA512, algorithmHMAC,
+		algorithmRSAPKCS1, algorithmRSAPSS, algorithmECDSA, algorithmEd25519} {
+		algorithmNames[strings.ToUpper(name)] = name
+	}
+}
+
+// algorithmParams is the normalized form of a Web Crypto algorithm identifier.
+type algorithmParams struct {
+	name          string
+	hash          string
+	namedCurve    string
+	length        int
+	saltLength    int
+	hasSaltLength bool
+}
+
+func (m *cryptoModule) notSupported(format string, args ...interface{}) *goja.Object {
+	return web.NewDOMException(m.r, fmt.Sprintf(format, args...), "NotSupportedError")
+}
+
+func (m *cryptoModule) dataError(format string, args ...interface{}) *goja.Object {
+	return web.NewDOMException(m.r, fmt.Sprintf(format, args...), "DataError")
+}
+
+func (m *cryptoModule) invalidAccess(format string, args ...interface{}) *goja.Object {
+	return web.NewDOMException(m.r, fmt.Sprintf(format, args...), "InvalidAccessError")
+}
+
+func (m *cryptoModule) algorithmName(v goja.Value) string {
+	name, ok := algorithmNames[strings.ToUpper(v.String())]
+	if !ok {
+		panic(m.notSupported("Unrecognized algorithm name"))
+	}
+	return name
+}
+
+func (m *cryptoModule) normalizeAlgorithm(v goja.Value) algorithmParams {
+	if isString(v) {
+		return algorithmParams{name: m.algorithmName(v)}
+	}
+	o, ok := v.(*goja.Object)
+	if !ok {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "algorithm" argument must be of type string or object.`))
+	}
+	name := o.Get("name")
+	if name == nil || goja.IsUndefined(name) {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, "Algorithm: name: Missing or not a string"))
+	}
+
+	params := algorithmParams{name: m.algorithmName(name)}
+	if hash := o.Get("hash"); hash != nil && !goja.IsUndefined(hash) {
+		params.hash = m.normalizeAlgorithm(hash).name
+		if _, ok := digests[params.hash]; !ok {
+			panic(m.notSupported("Unrecognized hash algorithm %s", params.hash))
+		}
+	}
+	if namedCurve := o.Get("namedCurve"); namedCurve != nil && !goja.IsUndefined(namedCurve) {
+		params.namedCurve = namedCurve.String()
+	}
+	if length := o.Get("length"); length != nil && !goja.IsUndefined(length) {
+		params.length = int(length.ToInteger())
+	}
+	if saltLength := o.Get("saltLength"); saltLength != nil && !goja.IsUndefined(saltLength) {
+		params.saltLength = int(saltLength.ToInteger())
+		params.hasSaltLength = true
+	}
+	return params
+}
+
+func (m *cryptoModule) sourceBytes(v goja.Value, name string) []byte {
+	data, ok := encoding.BufferSourceBytes(m.r, v)
+	if !ok {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "%s" argument must be an instance of ArrayBuffer, TypedArray, or DataView.`, name))
+	}
+	return data
+}
+
+func (m *cryptoModule) arrayBuffer(data []byte) goja.Value {
+	return m.r.ToValue(m.r.NewArrayBuffer(data))
+}
+
+// promise runs fn synchronously and settles the returned promise with its result, exceptions thrown
+// by fn reject the promise as the Web Crypto API never throws synchronously.
+func (m *cryptoModule) promise(fn func() goja.Value) goja.Value {
+	promise, resolve, reject := m.r.NewPromise()
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				switch e := r.(type) {
+				case *goja.Exception:
+					reject(e.Value())
+				case goja.Value:
+					reject(e)
+				default:
+					panic(r)
+				}
+			}
+		}()
+		resolve(fn())
+	}()
+	return m.r.ToValue(promise)
+}
+
+func (m *cryptoModule) createSubtle() *goja.Object {
+	subtle := m.r.NewObject()
+	subtle.Set("digest", func(call goja.FunctionCall) goja.Value {
+		return m.promise(func() goja.Value { return m.digest(call) })
+	})
+	subtle.Set("importKey", func(call goja.FunctionCall) goja.Value {
+		return m.promise(func() goja.Value { return m.importKey(call) })
+	})
+	subtle.Set("exportKey", func(call goja.FunctionCall) goja.Value {
+		return m.promise(func() goja.Value { return m.exportKey(call) })
+	})
+	subtle.Set("sign", func(call goja.FunctionCall) goja.Value {
+		return m.promise(func() goja.Value { return m.sign(call) })
+	})
+	subtle.Set("verify", func(call goja.FunctionCall) goja.Value {
+		return m.promise(func() goja.Value { return m.verify(call) })
+	})
+	return subtle
+}
+
+func (m *cryptoModule) digest(call goja.FunctionCall) goja.Value {
+	algorithm := m.normalizeAlgorithm(call.Argument(0))
+	hash, ok := digests[algorithm.name]
+	if !ok {
+		panic(m.notSupported("Unrecognized algorithm name"))
+	}
+	h := hash.New()
+	h.Write(m.sourceBytes(call.Argument(1), "data"))
+	return m.arrayBuffer(h.Sum(nil))
+}
+
+func (m *cryptoModule) keyUsages(v goja.Value) []string {
+	o, ok := v.(*goja.Object)
+	if !ok || o.ClassName() != "Array" {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "keyUsages" argument must be an instance of Array.`))
+	}
+	length := o.Get("length").ToInteger()
+	usages := make([]string, 0, length)
+	for i := int64(0); i < length; i++ {
+		usages = append(usages, o.Get(fmt.Sprint(i)).String())
+	}
+	return usages
+}
+
+// parseKeyData decodes keyData in the given format into a Go key.
+func (m *cryptoModule) parseKeyData(format string, keyData goja.Value, algorithm algorithmParams) interface{} {
+	var key interface{}
+	var err error
+
+	switch format {
+	case formatRaw:
+		data := m.sourceBytes(keyData, "keyData")
+		switch algorithm.name {
+		case algorithmHMAC:
+			key = append([]byte(nil), data...)
+		case algorithmECDSA:
+			key, err = parseECPoint(algorithm.namedCurve, data)
+		case algorithmEd25519:
+			if len(data) != ed25519.PublicKeySize {
+				panic(m.dataError("Invalid Ed25519 public key"))
+			}
+			key = ed25519.PublicKey(append([]byte(nil), data...))
+		default:
+			panic(m.notSupported("Unable to import %s key with format raw", algorithm.name))
+		}
+	case formatJWK:
+		jwk, ok := keyData.Export().(map[string]interface{})
+		if !ok {
+			panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "keyData" argument must be of type object.`))
+		}
+		key, err = ParseJWK(jwk)
+	case formatPKCS8:
+		key, err = x509.ParsePKCS8PrivateKey(m.sourceBytes(keyData, "keyData"))
+		if k, ok := key.(*rsa.PrivateKey); ok {
+			k.Precompute()
+		}
+	case formatSPKI:
+		key, err = x509.ParsePKIXPublicKey(m.sourceBytes(keyData, "keyData"))
+	default:
+		panic(m.notSupported("Unsupported key format %s", format))
+	}
+	if err != nil {
+		panic(m.dataError("Invalid keyData: %v", err))
+	}
+	return key
+}
+
+func (m *cryptoModule) importKey(call goja.FunctionCall) goja.Value {
+	format := call.Argument(0).String()
+	algorithm := m.normalizeAlgorithm(call.Argument(2))
+	extractable := call.Argument(3).ToBoolean()
+	usages := m.keyUsages(call.Argument(4))
+
+	switch algorithm.name {
+	case algorithmHMAC, algorithmRSAPKCS1, algorithmRSAPSS:
+		if algorithm.hash == "" {
+			panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, "%s: hash: Missing or not an AlgorithmIdentifier", algorithm.name))
+		}
+	case algorithmECDSA:
+		if _, ok := namedCurves[algorithm.namedCurve]; !ok {
+			panic(m.notSupported("Unrecognized namedCurve %q", algorithm.namedCurve))
+		}
+	case algorithmEd25519:
+	default:
+		panic(m.notSupported("Unrecognized algorithm name"))
+	}
+
+	key := m.parseKeyData(format, call.Argument(1), algorithm)
+	switch k := key.(type) {
+	case []byte:
+		if algorithm.name != algorithmHMAC {
+			panic(m.dataError("Invalid key type for %s", algorithm.name))
+		}
+		if len(k) == 0 {
+			panic(m.dataError("Zero-length key is not supported"))
+		}
+		if algorithm.length == 0 {
+			algorithm.length = len(k) * 8
+		}
+	case *rsa.PublicKey, *rsa.PrivateKey:
+		if algorithm.name != algorithmRSAPKCS1 && algorithm.name != algorithmRSAPSS {
+			panic(m.dataError("Invalid key type for %s", algorithm.name))
+		}
+	case *ecdsa.PublicKey, *ecdsa.PrivateKey:
+		if algorithm.name != algorithmECDSA {
+			panic(m.dataError("Invalid key type for %s", algorithm.name))
+		}
+		var public *ecdsa.PublicKey
+		if private, ok := k.(*ecdsa.PrivateKey); ok {
+			public = &private.PublicKey
+		} else {
+			public = k.(*ecdsa.PublicKey)
+		}
+		if curveName(public.Curve) != algorithm.namedCurve {
+			panic(m.dataError("Named curve mismatch"))
+		}
+	case ed25519.PublicKey, ed25519.PrivateKey:
+		if algorithm.name != algorithmEd25519 {
+			panic(m.dataError("Invalid key type for %s", algorithm.name))
+		}
+	default:
+		panic(m.dataError("Unsupported key type %T", key))
+	}
+
+	kind := keyKind(key)
+	for _, usage := range usages {
+		if usage == usageVerify && kind != keyTypePrivate || usage == usageSign && kind != keyTypePublic {
+			continue
+		}
+		panic(web.NewDOMException(m.r, fmt.Sprintf("Unsupported key usage for a %s %s key", algorithm.name, kind), "SyntaxError"))
+	}
+	if kind != keyTypePublic && len(usages) == 0 {
+		panic(web.NewDOMException(m.r, "Usages cannot be empty when importing a "+kind+" key.", "SyntaxError"))
+	}
+
+	return m.newCryptoKey(&cryptoKey{
+		kind:        kind,
+		extractable: extractable,
+		algorithm:   algorithm,
+		usages:      usages,
+		key:         key,
+	})
+}
+
+// jwkAlgorithm returns the "alg" member of exported JWKs.
+func jwkAlgorithm(k *cryptoKey) string {
+	bits := strings.TrimPrefix(k.algorithm.hash, "SHA-")
+	switch k.algorithm.name {
+	case algorithmHMAC:
+		return "HS" + bits
+	case algorithmRSAPKCS1:
+		return "RS" + bits
+	case algorithmRSAPSS:
+		return "PS" + bits
+	case algorithmEd25519:
+		return "EdDSA"
+	}
+	return ""
+}
+
+func (m *cryptoModule) exportKey(call goja.FunctionCall) goja.Value {
+	format := call.Argument(0).String()
+	k := toCryptoKey(m.r, call.Argument(1), "key")
+	if !k.extractable {
+		panic(m.invalidAccess("key is not extractable"))
+	}
+
+	switch format {
+	case formatRaw:
+		switch key := k.key.(type) {
+		case []byte:
+			return m.arrayBuffer(append([]byte(nil), key...))
+		case *ecdsa.PublicKey:
+			return m.arrayBuffer(ecPoint(key))
+		case ed25519.PublicKey:
+			return m.arrayBuffer(append([]byte(nil), key...))
+		}
+	case formatJWK:
+		jwk, err := jwkFromKey(k.key)
+		if err != nil {
+			panic(m.notSupported("%v", err))
+		}
+		o := m.r.NewObject()
+		for name, value := range jwk {
+			o.Set(name, value)
+		}
+		if alg := jwkAlgorithm(k); alg != "" {
+			o.Set("alg", alg)
+		}
+		usages := make([]interface{}, len(k.usages))
+		for i, u := range k.usages {
+			usages[i] = u
+		}
+		o.Set("key_ops", m.r.NewArray(usages...))
+		o.Set("ext", true)
+		return o
+	case formatPKCS8:
+		if k.kind == keyTypePrivate {
+			data, err := x509.MarshalPKCS8PrivateKey(k.key)
+			if err != nil {
+				panic(m.notSupported("%v", err))
+			}
+			return m.arrayBuffer(data)
+		}
+	case formatSPKI:
+		if k.kind == keyTypePublic {
+			data, err := x509.MarshalPKIXPublicKey(k.key)
+			if err != nil {
+				panic(m.notSupported("%v", err))
+			}
+			return m.arrayBuffer(data)
+		}
+	default:
+		panic(m.notSupported("Unsupported key format %s", format))
+	}
+	panic(m.invalidAccess("Unable to export a %s %s key as %s", k.algorithm.name, k.kind, format))
+}
+
+// signingKey checks that key may be used for usage with the requested algorithm.
+func (m *cryptoModule) signingKey(call goja.FunctionCall, usage string) (algorithmParams, *cryptoKey) {
+	algorithm := m.normalizeAlgorithm(call.Argument(0))
+	k := toCryptoKey(m.r, call.Argument(1), "key")
+	if algorithm.name != k.algorithm.name {
+		panic(m.invalidAccess("The requested operation is not valid for the provided key"))
+	}
+	if !k.hasUsage(usage) {
+		panic(m.invalidAccess("The key does not support the %s operation", usage))
+	}
+	return algorithm, k
+}
+
+func (m *cryptoModule) hashed(hashName string, data []byte) (gocrypto.Hash, []byte) {
+	hash, ok := digests[hashName]
+	if !ok {
+		panic(m.notSupported("Unrecognized hash algorithm %s", hashName))
+	}
+	h := hash.New()
+	h.Write(data)
+	return hash, h.Sum(nil)
+}
+
+func (m *cryptoModule) sign(call goja.FunctionCall) goja.Value {
+	algorithm, k := m.signingKey(call, usageSign)
+	data := m.sourceBytes(call.Argument(2), "data")
+
+	var signature []byte
+	var err error
+	switch key := k.key.(type) {
+	case []byte:
+		hash, _ := digests[k.algorithm.hash]
+		mac := hmac.New(hash.New, key)
+		mac.Write(data)
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		hash, digest := m.hashed(k.algorithm.hash, data)
+		if algorithm.name == algorithmRSAPSS {
+			if !algorithm.hasSaltLength {
+				panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, "RSA-PSS: saltLength: Missing or not a number"))
+			}
+			signature, err = rsa.SignPSS(m.randomSource, key, hash, digest, &rsa.PSSOptions{SaltLength: algorithm.saltLength})
+		} else {
+			signature, err = rsa.SignPKCS1v15(m.randomSource, key, hash, digest)
+		}
+	case *ecdsa.PrivateKey:
+		_, digest := m.hashed(algorithm.hash, data)
+		var r, s *big.Int
+		if r, s, err = ecdsa.Sign(m.randomSource, key, digest); err == nil {
+			size := curveByteSize(key.Curve)
+			signature = make([]byte, 2*size)
+			r.FillBytes(signature[:size])
+			s.FillBytes(signature[size:])
+		}
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(key, data)
+	}
+	if err != nil {
+		panic(web.NewDOMException(m.r, err.Error(), "OperationError"))
+	}
+	return m.arrayBuffer(signature)
+}
+
+func (m *cryptoModule) verify(call goja.FunctionCall) goja.Value {
+	algorithm, k := m.signingKey(call, usageVerify)
+	signature := m.sourceBytes(call.Argument(2), "signature")
+	data := m.sourceBytes(call.Argument(3), "data")
+
+	switch key := k.key.(type) {
+	case []byte:
+		hash, _ := digests[k.algorithm.hash]
+		mac := hmac.New(hash.New, key)
+		mac.Write(data)
+		return m.r.ToValue(hmac.Equal(signature, mac.Sum(nil)))
+	case *rsa.PublicKey:
+		hash, digest := m.hashed(k.algorithm.hash, data)
+		if algorithm.name == algorithmRSAPSS {
+			if !algorithm.hasSaltLength {
+				panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, "RSA-PSS: saltLength: Missing or not a number"))
+			}
+			return m.r.ToValue(rsa.VerifyPSS(key, hash, digest, signature, &rsa.PSSOptions{SaltLength: algorithm.saltLength}) == nil)
+		}
+		return m.r.ToValue(rsa.VerifyPKCS1v15(key, hash, digest, signature) == nil)
+	case *ecdsa.PublicKey:
+		_, digest := m.hashed(algorithm.hash, data)
+		size := curveByteSize(key.Curve)
+		if len(signature) != 2*size {
+			return m.r.ToValue(false)
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		return m.r.ToValue(ecdsa.Verify(key, digest, r, s))
+	case ed25519.PublicKey:
+		return m.r.ToValue(ed25519.Verify(key, data, signature))
+	}
+	return m.r.ToValue(false)
+}
@@ -0,0 +1,102 @@
+package encoding
+
+import (
+	"encoding/base64"
+	"strings"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/web"
+)
+
+func newInvalidCharacterError(r *goja.Runtime, msg string) *goja.Object {
+	return web.NewDOMException(r, msg, "InvalidCharacterError")
+}
+
+// isASCIIWhitespace as per https://infra.spec.whatwg.org/#ascii-whitespace
+func isASCIIWhitespace(c rune) bool {
+	return c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+// forgivingBase64Decode implements https://infra.spec.whatwg.org/#forgiving-base64-decode
+func forgivingBase64Decode(s string) ([]byte, bool) {
+	s = strings.Map(func(c rune) rune {
+		if isASCIIWhitespace(c) {
+			return -1
+		}
+		return c
+	}, s)
+
+	if len(s)%4 == 0 {
+		s = strings.TrimSuffix(s, "=")
+		s = strings.TrimSuffix(s, "=")
+	}
+	if len(s)%4 == 1 {
+		return nil, false
+	}
+	for _, c := range s {
+		if !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '+' || c == '/') {
+			return nil, false
+		}
+	}
+
+	data, err := base64.RawStdEncoding.Strict().DecodeString(s)
+	if err != nil {
+		// non zero trailing bits are ignored by the forgiving decoder
+		data, err = base64.RawStdEncoding.DecodeString(s)
+	}
+	return data, err == nil
+}
+
+func (m *encodingModule) atob(call goja.FunctionCall) goja.Value {
+	if len(call.Arguments) == 0 {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeMissingArgs, `The "data" argument must be specified`))
+	}
+	data, ok := forgivingBase64Decode(call.Argument(0).String())
+	if !ok {
+		panic(newInvalidCharacterError(m.r, "The string to be decoded is not correctly encoded."))
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(data))
+	for _, b := range data {
+		sb.WriteRune(rune(b))
+	}
+	return m.r.ToValue(sb.String())
+}
+
+func (m *encodingModule) btoa(call goja.FunctionCall) goja.Value {
+	if len(call.Arguments) == 0 {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeMissingArgs, `The "data" argument must be specified`))
+	}
+	input := call.Argument(0).String()
+	data := make([]byte, 0, len(input))
+	for _, c := range input {
+		if c > 0xFF {
+			panic(newInvalidCharacterError(m.r, "Invalid character"))
+		}
+		data = append(data, byte(c))
+	}
+	return m.r.ToValue(base64.StdEncoding.EncodeToString(data))
+}
+
+func (m *encodingModule) base64UrlEncode(call goja.FunctionCall) goja.Value {
+	input := call.Argument(0)
+	data, ok := BufferSourceBytes(m.r, input)
+	if !ok {
+		if _, isObject := input.(*goja.Object); isObject || goja.IsUndefined(input) || goja.IsNull(input) {
+			panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "data" argument must be of type string or an instance of ArrayBuffer or ArrayBufferView.`))
+		}
+		data = []byte(input.String())
+	}
+	return m.r.ToValue(base64.RawURLEncoding.EncodeToString(data))
+}
+
+func (m *encodingModule) base64UrlDecode(call goja.FunctionCall) goja.Value {
+	input := strings.TrimRight(call.Argument(0).String(), "=")
+	data, err := base64.RawURLEncoding.DecodeString(input)
+	if err != nil {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgValue, "The string to be decoded is not correctly base64url encoded."))
+	}
+	return NewUint8Array(m.r, data)
+}
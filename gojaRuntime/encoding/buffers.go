@@ -0,0 +1,57 @@
+package encoding
+
+import (
+	goja "github.com/grafana/sobek"
+)
+
+// BufferSourceBytes returns the bytes viewed by an ArrayBuffer, a TypedArray or a DataView.
+// The returned slice shares memory with the JS object, writes to it are visible from JS.
+func BufferSourceBytes(r *goja.Runtime, v goja.Value) ([]byte, bool) {
+	o, ok := v.(*goja.Object)
+	if !ok {
+		return nil, false
+	}
+
+	if buffer, ok := o.Export().(goja.ArrayBuffer); ok {
+		return buffer.Bytes(), true
+	}
+
+	if !isArrayBufferView(r, o) {
+		return nil, false
+	}
+
+	buffer, ok := o.Get("buffer").Export().(goja.ArrayBuffer)
+	if !ok {
+		return nil, false
+	}
+	offset := o.Get("byteOffset").ToInteger()
+	length := o.Get("byteLength").ToInteger()
+	data := buffer.Bytes()
+	if offset+length > int64(len(data)) {
+		return nil, false
+	}
+	return data[offset : offset+length], true
+}
+
+// NewUint8Array creates a Uint8Array backed by data, without copying it.
+func NewUint8Array(r *goja.Runtime, data []byte) *goja.Object {
+	ctor, _ := r.Get("Uint8Array").(*goja.Object)
+	o, err := r.New(ctor, r.ToValue(r.NewArrayBuffer(data)))
+	if err != nil {
+		panic(err)
+	}
+	return o
+}
+
+func isArrayBufferView(r *goja.Runtime, o *goja.Object) bool {
+	arrayBuffer, ok := r.Get("ArrayBuffer").(*goja.Object)
+	if !ok {
+		return false
+	}
+	isView, ok := goja.AssertFunction(arrayBuffer.Get("isView"))
+	if !ok {
+		return false
+	}
+	res, err := isView(arrayBuffer, o)
+	return err == nil && res.ToBoolean()
+}
@@ -0,0 +1,159 @@
+package encoding
+
+import (
+	"testing"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/util"
+)
+
+func createVM() *goja.Runtime {
+	vm := goja.New()
+	new(require.Registry).Enable(vm)
+	Enable(vm)
+	return vm
+}
+
+func TestEncodingGlobals(t *testing.T) {
+	vm := createVM()
+
+	for _, name := range []string{"TextEncoder", "TextDecoder", "atob", "btoa"} {
+		if c := vm.Get(name); c == nil {
+			t.Fatalf("%s not found", name)
+		}
+	}
+
+	if _, err := vm.RunString(`const m = require("encoding"); m.base64url.encode("test");`); err != nil {
+		t.Fatal("Failed to require encoding.", err)
+	}
+}
+
+func TestTextEncoder(t *testing.T) {
+	vm := createVM()
+
+	tests := []struct {
+		script   string
+		expected string
+	}{
+		{`new TextEncoder().encoding`, "utf-8"},
+		{`Array.from(new TextEncoder().encode("a€😀")).join(",")`, "97,226,130,172,240,159,152,128"},
+		{`new TextEncoder().encode().length`, "0"},
+		{`new TextEncoder().encode("\uD800").join(",")`, "239,191,189"},
+		{`const dest = new Uint8Array(5); const r = new TextEncoder().encodeInto("a€😀", dest); [r.read, r.written, dest.join(",")].join("|")`, "2|4|97,226,130,172,0"},
+		{`new TextEncoder().encode("x") instanceof Uint8Array`, "true"},
+	}
+
+	for _, tc := range tests {
+		result, err := vm.RunString(tc.script)
+		if err != nil {
+			t.Fatalf("Failed to run %s: %v", tc.script, err)
+		}
+		if result.String() != tc.expected {
+			t.Fatalf("%s: expected '%s', got '%s'", tc.script, tc.expected, result.String())
+		}
+	}
+}
+
+func TestTextDecoder(t *testing.T) {
+	tests := []struct {
+		script   string
+		expected string
+	}{
+		{`new TextDecoder().decode(new Uint8Array([226, 130, 172]))`, "€"},
+		{`new TextDecoder("UTF8 ").encoding`, "utf-8"},
+		{`new TextDecoder().decode(new Uint8Array([0xEF, 0xBB, 0xBF, 0x61]))`, "a"},
+		{`new TextDecoder("utf-8", {ignoreBOM: true}).decode(new Uint8Array([0xEF, 0xBB, 0xBF, 0x61])).length`, "2"},
+		{`new TextDecoder().decode(new Uint8Array([0xE2, 0x82]))`, "�"},
+		{`new TextDecoder().decode(new Uint8Array([0xF0, 0x80, 0x80, 0x61]))`, "���a"},
+		{`new TextDecoder().decode(new Uint8Array([0xED, 0xA0, 0x80]))`, "���"},
+		{`new TextDecoder().decode(new Uint8Array([0x61, 0x62, 0x63]).buffer)`, "abc"},
+		{`new TextDecoder().decode(new DataView(new Uint8Array([0x61, 0x62, 0x63]).buffer, 1))`, "bc"},
+		{`new TextDecoder().decode()`, ""},
+		{`
+			const d = new TextDecoder();
+			const parts = [d.decode(new Uint8Array([0xE2]), {stream: true}), d.decode(new Uint8Array([0x82]), {stream: true}), d.decode(new Uint8Array([0xAC]))];
+			parts.join("|");
+		`, "||€"},
+		{`
+			const d = new TextDecoder();
+			d.decode(new Uint8Array([0xE2, 0x82]), {stream: true}) + d.decode();
+		`, "�"},
+		{`
+			try { new TextDecoder("utf-8", {fatal: true}).decode(new Uint8Array([0xFF])) } catch (e) { [e instanceof TypeError, e.code].join(",") }
+		`, "true,ERR_ENCODING_INVALID_ENCODED_DATA"},
+		{`
+			try { new TextDecoder("latin1") } catch (e) { [e instanceof RangeError, e.code].join(",") }
+		`, "true,ERR_ENCODING_NOT_SUPPORTED"},
+		{`new TextDecoder("utf-8", {fatal: true}).fatal`, "true"},
+	}
+
+	for _, tc := range tests {
+		vm := createVM()
+		result, err := vm.RunString(tc.script)
+		if err != nil {
+			t.Fatalf("Failed to run %s: %v", tc.script, err)
+		}
+		if result.String() != tc.expected {
+			t.Fatalf("%s: expected '%s', got '%s'", tc.script, tc.expected, result.String())
+		}
+	}
+}
+
+func TestBase64(t *testing.T) {
+	tests := []struct {
+		script   string
+		expected string
+	}{
+		{`btoa("hello")`, "aGVsbG8="},
+		{`btoa("\xff\xfe")`, "//4="},
+		{`atob("aGVsbG8=")`, "hello"},
+		{`atob(" aGVs\nbG8 ")`, "hello"},
+		{`atob("aGVsbG8")`, "hello"},
+		{`atob("//4=").charCodeAt(0)`, "255"},
+		{`try { atob("a") } catch (e) { e.name }`, "InvalidCharacterError"},
+		{`try { atob("aGVs*G8=") } catch (e) { e.name }`, "InvalidCharacterError"},
+		{`try { btoa("€") } catch (e) { e.name }`, "InvalidCharacterError"},
+		{`try { btoa() } catch (e) { e.code }`, "ERR_MISSING_ARGS"},
+		{`const { base64url } = require("encoding"); base64url.encode("client:secret?>")`, "Y2xpZW50OnNlY3JldD8-"},
+		{`const { base64url } = require("encoding"); base64url.encode(new Uint8Array([251, 255]))`, "-_8"},
+		{`const { base64url } = require("encoding"); new TextDecoder().decode(base64url.decode("Y2xpZW50OnNlY3JldD8-"))`, "client:secret?>"},
+		{`const { base64url } = require("encoding"); base64url.decode("-_8=").join(",")`, "251,255"},
+		{`const { base64url } = require("encoding"); try { base64url.decode("a+/") } catch (e) { e.code }`, "ERR_INVALID_ARG_VALUE"},
+	}
+
+	for _, tc := range tests {
+		vm := createVM()
+		result, err := vm.RunString(tc.script)
+		if err != nil {
+			t.Fatalf("Failed to run %s: %v", tc.script, err)
+		}
+		if result.String() != tc.expected {
+			t.Fatalf("%s: expected '%s', got '%s'", tc.script, tc.expected, result.String())
+		}
+	}
+}
+
+func TestInspectEncoders(t *testing.T) {
+	vm := createVM()
+	vm.Set("util", require.Require(vm, util.ModuleName))
+	result, err := vm.RunString(`util.inspect([new TextEncoder(), new TextDecoder()])`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "[\n  TextEncoder { encoding: 'utf-8' },\n  TextDecoder { encoding: 'utf-8', fatal: false, ignoreBOM: false }\n]"
+	if result.String() != expected {
+		t.Fatalf("Unexpected result: '%s'", result.String())
+	}
+}
+
+func TestInvalidCharacterError(t *testing.T) {
+	vm := createVM()
+	result, err := vm.RunString(`try { atob("*") } catch (e) { [e instanceof require("web").DOMException, e.name, e.code].join(" ") }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.String() != "true InvalidCharacterError 5" {
+		t.Fatalf("Unexpected result: '%s'", result.String())
+	}
+}
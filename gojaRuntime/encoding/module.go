@@ -0,0 +1,46 @@
+package encoding
+
+import (
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
+)
+
+const ModuleName = "encoding"
+
+type encodingModule struct {
+	r *goja.Runtime
+
+	TextEncoderPrototype *goja.Object
+	TextDecoderPrototype *goja.Object
+}
+
+func Require(runtime *goja.Runtime, module *goja.Object) {
+	exports := module.Get("exports").(*goja.Object)
+	m := &encodingModule{
+		r: runtime,
+	}
+	exports.Set("TextEncoder", m.createTextEncoderConstructor())
+	exports.Set("TextDecoder", m.createTextDecoderConstructor())
+	exports.Set("atob", m.atob)
+	exports.Set("btoa", m.btoa)
+
+	base64url := runtime.NewObject()
+	base64url.Set("encode", m.base64UrlEncode)
+	base64url.Set("decode", m.base64UrlDecode)
+	exports.Set("base64url", base64url)
+}
+
+// Enable installs the WHATWG encoding globals (TextEncoder, TextDecoder, atob and btoa) into the runtime
+// and returns the module exports.
+func Enable(runtime *goja.Runtime) *goja.Object {
+	m := require.Require(runtime, ModuleName).ToObject(runtime)
+	runtime.Set("TextEncoder", m.Get("TextEncoder"))
+	runtime.Set("TextDecoder", m.Get("TextDecoder"))
+	runtime.Set("atob", m.Get("atob"))
+	runtime.Set("btoa", m.Get("btoa"))
+	return m
+}
+
+func init() {
+	require.RegisterNativeModule(ModuleName, Require)
+}
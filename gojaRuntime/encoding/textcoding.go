@@ -0,0 +1,329 @@
+package encoding
+
+import (
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+)
+
+var (
+	reflectTypeTextEncoder = reflect.TypeOf((*textEncoder)(nil))
+	reflectTypeTextDecoder = reflect.TypeOf((*textDecoder)(nil))
+)
+
+// utf-8 is the only encoding required by the WHATWG Encoding standard for TextEncoder,
+// TextDecoder is limited to it as well
+var utf8Labels = map[string]bool{
+	"unicode-1-1-utf-8": true,
+	"unicode11utf8":     true,
+	"unicode20utf8":     true,
+	"utf-8":             true,
+	"utf8":              true,
+	"x-unicode20utf8":   true,
+}
+
+type textEncoder struct{}
+
+type textDecoder struct {
+	fatal     bool
+	ignoreBOM bool
+	bomSeen   bool
+	decoder   utf8Decoder
+}
+
+// utf8Decoder implements the streaming UTF-8 decoder of the WHATWG Encoding standard,
+// which differs from utf8.DecodeRune in how many replacement characters invalid sequences produce.
+type utf8Decoder struct {
+	codePoint   rune
+	bytesSeen   int
+	bytesNeeded int
+	lower       byte
+	upper       byte
+}
+
+func toTextEncoder(r *goja.Runtime, v goja.Value) *textEncoder {
+	if v.ExportType() == reflectTypeTextEncoder {
+		if e := v.Export().(*textEncoder); e != nil {
+			return e
+		}
+	}
+	panic(errors.NewTypeError(r, errors.ErrCodeInvalidThis, `Value of "this" must be of type TextEncoder`))
+}
+
+func toTextDecoder(r *goja.Runtime, v goja.Value) *textDecoder {
+	if v.ExportType() == reflectTypeTextDecoder {
+		if d := v.Export().(*textDecoder); d != nil {
+			return d
+		}
+	}
+	panic(errors.NewTypeError(r, errors.ErrCodeInvalidThis, `Value of "this" must be of type TextDecoder`))
+}
+
+func (m *encodingModule) defineGetter(p *goja.Object, name string, getter func(call goja.FunctionCall) goja.Value) {
+	p.DefineAccessorProperty(name, m.r.ToValue(getter), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+}
+
+func (m *encodingModule) createTextEncoderPrototype() *goja.Object {
+	p := m.r.NewObject()
+
+	m.defineGetter(p, "encoding", func(call goja.FunctionCall) goja.Value {
+		toTextEncoder(m.r, call.This)
+		return m.r.ToValue("utf-8")
+	})
+
+	p.Set("encode", m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		toTextEncoder(m.r, call.This)
+		input := ""
+		if arg := call.Argument(0); !goja.IsUndefined(arg) {
+			input = arg.String()
+		}
+		return NewUint8Array(m.r, []byte(input))
+	}))
+
+	p.Set("encodeInto", m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		toTextEncoder(m.r, call.This)
+		source := call.Argument(0).String()
+		destination := call.Argument(1)
+		if _, ok := destination.Export().([]byte); !ok {
+			panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "dest" argument must be an instance of Uint8Array.`))
+		}
+		buffer, _ := BufferSourceBytes(m.r, destination)
+
+		read, written := 0, 0
+		for _, c := range source {
+			size := utf8.RuneLen(c)
+			if written+size > len(buffer) {
+				break
+			}
+			utf8.EncodeRune(buffer[written:], c)
+			written += size
+			if c >= 0x10000 {
+				read += 2
+			} else {
+				read++
+			}
+		}
+
+		result := m.r.NewObject()
+		result.Set("read", read)
+		result.Set("written", written)
+		return result
+	}))
+
+	return p
+}
+
+func (m *encodingModule) createTextEncoderConstructor() goja.Value {
+	f := m.r.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		res := m.r.ToValue(&textEncoder{}).(*goja.Object)
+		res.SetPrototype(call.This.Prototype())
+		return res
+	}).(*goja.Object)
+
+	m.TextEncoderPrototype = m.createTextEncoderPrototype()
+	f.DefineDataProperty("name", m.r.ToValue("TextEncoder"), goja.FLAG_FALSE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+	f.Set("prototype", m.TextEncoderPrototype)
+	m.TextEncoderPrototype.DefineDataProperty("constructor", f, goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+	return f
+}
+
+func (m *encodingModule) createTextDecoderPrototype() *goja.Object {
+	p := m.r.NewObject()
+
+	m.defineGetter(p, "encoding", func(call goja.FunctionCall) goja.Value {
+		toTextDecoder(m.r, call.This)
+		return m.r.ToValue("utf-8")
+	})
+
+	m.defineGetter(p, "fatal", func(call goja.FunctionCall) goja.Value {
+		return m.r.ToValue(toTextDecoder(m.r, call.This).fatal)
+	})
+
+	m.defineGetter(p, "ignoreBOM", func(call goja.FunctionCall) goja.Value {
+		return m.r.ToValue(toTextDecoder(m.r, call.This).ignoreBOM)
+	})
+
+	p.Set("decode", m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		d := toTextDecoder(m.r, call.This)
+
+		var input []byte
+		if arg := call.Argument(0); !goja.IsUndefined(arg) {
+			bytes, ok := BufferSourceBytes(m.r, arg)
+			if !ok {
+				panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "input" argument must be an instance of ArrayBuffer or ArrayBufferView.`))
+			}
+			input = bytes
+		}
+
+		stream := false
+		if options, ok := call.Argument(1).(*goja.Object); ok {
+			if s := options.Get("stream"); s != nil {
+				stream = s.ToBoolean()
+			}
+		}
+
+		output, ok := d.decode(input, stream)
+		if !ok {
+			panic(errors.NewTypeError(m.r, errors.ErrCodeEncodingInvalidData, "The encoded data was not valid for encoding utf-8"))
+		}
+		return m.r.ToValue(output)
+	}))
+
+	return p
+}
+
+func (m *encodingModule) createTextDecoderConstructor() goja.Value {
+	f := m.r.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		label := "utf-8"
+		if arg := call.Argument(0); !goja.IsUndefined(arg) {
+			label = arg.String()
+		}
+		if !utf8Labels[strings.ToLower(strings.Trim(label, "\t\n\f\r "))] {
+			rangeError, _ := m.r.Get("RangeError").(*goja.Object)
+			panic(errors.NewError(m.r, rangeError, errors.ErrCodeEncodingNotSupported, `The "%s" encoding is not supported`, label))
+		}
+
+		d := &textDecoder{}
+		if options, ok := call.Argument(1).(*goja.Object); ok {
+			if fatal := options.Get("fatal"); fatal != nil {
+				d.fatal = fatal.ToBoolean()
+			}
+			if ignoreBOM := options.Get("ignoreBOM"); ignoreBOM != nil {
+				d.ignoreBOM = ignoreBOM.ToBoolean()
+			}
+		}
+		d.decoder.reset()
+
+		res := m.r.ToValue(d).(*goja.Object)
+		res.SetPrototype(call.This.Prototype())
+		return res
+	}).(*goja.Object)
+
+	m.TextDecoderPrototype = m.createTextDecoderPrototype()
+	f.DefineDataProperty("name", m.r.ToValue("TextDecoder"), goja.FLAG_FALSE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+	f.Set("prototype", m.TextDecoderPrototype)
+	m.TextDecoderPrototype.DefineDataProperty("constructor", f, goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+	return f
+}
+
+// decode decodes input, keeping incomplete sequences for the next call when stream is set.
+// It returns false on invalid data in fatal mode.
+func (d *textDecoder) decode(input []byte, stream bool) (string, bool) {
+	var sb strings.Builder
+	sb.Grow(len(input))
+
+	emit := func(c rune) {
+		if !d.bomSeen {
+			d.bomSeen = true
+			if c == 0xFEFF && !d.ignoreBOM {
+				return
+			}
+		}
+		sb.WriteRune(c)
+	}
+
+	valid := true
+	for i := 0; i < len(input); {
+		c, consumed, ok := d.decoder.next(input[i])
+		if consumed {
+			i++
+		}
+		if !ok {
+			valid = false
+			if d.fatal {
+				break
+			}
+			emit(utf8.RuneError)
+			continue
+		}
+		if c >= 0 {
+			emit(c)
+		}
+	}
+
+	if !stream {
+		if d.decoder.bytesNeeded != 0 {
+			valid = false
+			if !d.fatal {
+				emit(utf8.RuneError)
+			}
+		}
+		d.decoder.reset()
+		d.bomSeen = false
+	}
+
+	if !valid && d.fatal {
+		d.decoder.reset()
+		d.bomSeen = false
+		return "", false
+	}
+	return sb.String(), true
+}
+
+func (u *utf8Decoder) reset() {
+	u.codePoint, u.bytesSeen, u.bytesNeeded = 0, 0, 0
+	u.lower, u.upper = 0x80, 0xBF
+}
+
+// next feeds a single byte, returning the decoded code point (-1 if more bytes are needed),
+// whether the byte was consumed (it is not when it has to be processed again after an error)
+// and false when an invalid sequence was found.
+func (u *utf8Decoder) next(b byte) (c rune, consumed bool, ok bool) {
+	if u.bytesNeeded == 0 {
+		switch {
+		case b <= 0x7F:
+			return rune(b), true, true
+		case b >= 0xC2 && b <= 0xDF:
+			u.bytesNeeded = 1
+			u.codePoint = rune(b & 0x1F)
+		case b >= 0xE0 && b <= 0xEF:
+			if b == 0xE0 {
+				u.lower = 0xA0
+			}
+			if b == 0xED {
+				u.upper = 0x9F
+			}
+			u.bytesNeeded = 2
+			u.codePoint = rune(b & 0xF)
+		case b >= 0xF0 && b <= 0xF4:
+			if b == 0xF0 {
+				u.lower = 0x90
+			}
+			if b == 0xF4 {
+				u.upper = 0x8F
+			}
+			u.bytesNeeded = 3
+			u.codePoint = rune(b & 0x7)
+		default:
+			return -1, true, false
+		}
+		return -1, true, true
+	}
+
+	if b < u.lower || b > u.upper {
+		u.reset()
+		return -1, false, false
+	}
+
+	u.lower, u.upper = 0x80, 0xBF
+	u.codePoint = u.codePoint<<6 | rune(b&0x3F)
+	u.bytesSeen++
+	if u.bytesSeen != u.bytesNeeded {
+		return -1, true, true
+	}
+	c = u.codePoint
+	u.reset()
+	return c, true, true
+}
+
+// DecodeUTF8 decodes data the way a non-fatal TextDecoder does, replacing invalid sequences with U+FFFD.
+// A leading byte order mark is kept.
+func DecodeUTF8(data []byte) string {
+	d := textDecoder{ignoreBOM: true}
+	d.decoder.reset()
+	s, _ := d.decode(data, false)
+	return s
+}
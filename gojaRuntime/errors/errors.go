@@ -3,14 +3,35 @@ package errors
 import (
 	"fmt"
 
-	"github.com/dop251/goja"
+	goja "github.com/grafana/sobek"
 )
 
 const (
-	ErrCodeInvalidArgType  = "ERR_INVALID_ARG_TYPE"
-	ErrCodeInvalidArgValue = "ERR_INVALID_ARG_VALUE"
-	ErrCodeInvalidThis     = "ERR_INVALID_THIS"
-	ErrCodeMissingArgs     = "ERR_MISSING_ARGS"
+	ErrCodeInvalidArgType              = "ERR_INVALID_ARG_TYPE"
+	ErrCodeInvalidArgValue             = "ERR_INVALID_ARG_VALUE"
+	ErrCodeInvalidThis                 = "ERR_INVALID_THIS"
+	ErrCodeMissingArgs                 = "ERR_MISSING_ARGS"
+	ErrCodeEncodingNotSupported        = "ERR_ENCODING_NOT_SUPPORTED"
+	ErrCodeEncodingInvalidData         = "ERR_ENCODING_INVALID_ENCODED_DATA"
+	ErrCodeUnknownEncoding             = "ERR_UNKNOWN_ENCODING"
+	ErrCodeOutOfRange                  = "ERR_OUT_OF_RANGE"
+	ErrCodeBufferOutOfBounds           = "ERR_BUFFER_OUT_OF_BOUNDS"
+	ErrCodeCryptoInvalidDigest         = "ERR_CRYPTO_INVALID_DIGEST"
+	ErrCodeCryptoHashFinalized         = "ERR_CRYPTO_HASH_FINALIZED"
+	ErrCodeCryptoInvalidKeyObjectType  = "ERR_CRYPTO_INVALID_KEY_OBJECT_TYPE"
+	ErrCodeCryptoTimingSafeEqualLength = "ERR_CRYPTO_TIMING_SAFE_EQUAL_LENGTH"
+	ErrCodeIllegalConstructor          = "ERR_ILLEGAL_CONSTRUCTOR"
+	ErrCodeUnhandledError              = "ERR_UNHANDLED_ERROR"
+	ErrCodeAbort                       = "ABORT_ERR"
+	ErrCodeUnknownBuiltinModule        = "ERR_UNKNOWN_BUILTIN_MODULE"
+	ErrCodeModuleNotFound              = "MODULE_NOT_FOUND"
+	ErrCodeESMModuleNotFound           = "ERR_MODULE_NOT_FOUND"
+	ErrCodePackagePathNotExported      = "ERR_PACKAGE_PATH_NOT_EXPORTED"
+	ErrCodePackageImportNotDefined     = "ERR_PACKAGE_IMPORT_NOT_DEFINED"
+	ErrCodeInvalidPackageTarget        = "ERR_INVALID_PACKAGE_TARGET"
+	ErrCodeInvalidPackageConfig        = "ERR_INVALID_PACKAGE_CONFIG"
+	ErrCodeInvalidModuleSpecifier      = "ERR_INVALID_MODULE_SPECIFIER"
+	ErrCodeMemoryLimitExceeded         = "ERR_MEMORY_LIMIT_EXCEEDED"
 )
 
 func error_toString(call goja.FunctionCall, r *goja.Runtime) goja.Value {
@@ -0,0 +1,141 @@
+package errors
+
+import (
+	goerrors "errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+
+	goja "github.com/grafana/sobek"
+)
+
+const ErrCodeNativePanic = "ERR_NATIVE_PANIC"
+
+// Mapping describes the JS error thrown in place of a matching Go error.
+type Mapping struct {
+	// Name of the JS error, e.g. RateLimitError, the constructor name is kept when empty
+	Name string
+	// Code exposed as the code property of the JS error
+	Code string
+	// Constructor is the name of the global error constructor to use, e.g. TypeError, defaults to Error
+	Constructor string
+	// Properties are copied to the JS error
+	Properties map[string]interface{}
+	// PropertiesFrom, if set, derives additional properties from the matched Go error
+	PropertiesFrom func(err error) map[string]interface{}
+}
+
+type errorMatcher struct {
+	match   func(err error) (error, bool)
+	mapping Mapping
+}
+
+var (
+	gojaPkgPath = reflect.TypeOf((*goja.Runtime)(nil)).Elem().PkgPath()
+
+	mappings     []*errorMatcher
+	mappingsLock sync.RWMutex
+)
+
+// RegisterErrorMapping maps Go errors matching the target sentinel (as per errors.Is) to a JS error.
+// The returned func removes the mapping again, e.g. as a test cleanup.
+func RegisterErrorMapping(target error, mapping Mapping) (unregister func()) {
+	return registerMatcher(func(err error) (error, bool) {
+		if goerrors.Is(err, target) {
+			return target, true
+		}
+		return nil, false
+	}, mapping)
+}
+
+// RegisterErrorTypeMapping maps Go errors of type T (as per errors.As) to a JS error.
+// The returned func removes the mapping again, e.g. as a test cleanup.
+func RegisterErrorTypeMapping[T error](mapping Mapping) (unregister func()) {
+	return registerMatcher(func(err error) (error, bool) {
+		var target T
+		if goerrors.As(err, &target) {
+			return target, true
+		}
+		return nil, false
+	}, mapping)
+}
+
+func registerMatcher(match func(err error) (error, bool), mapping Mapping) func() {
+	matcher := &errorMatcher{match: match, mapping: mapping}
+	mappingsLock.Lock()
+	defer mappingsLock.Unlock()
+	mappings = append(mappings, matcher)
+
+	return func() {
+		mappingsLock.Lock()
+		defer mappingsLock.Unlock()
+		mappings = slices.DeleteFunc(mappings, func(registered *errorMatcher) bool {
+			return registered == matcher
+		})
+	}
+}
+
+// FromGoError converts an error returned by native code into the JS value to be thrown.
+// Registered mappings are consulted in registration order, JS exceptions are rethrown unchanged
+// and any other error becomes a GoError.
+func FromGoError(r *goja.Runtime, err error) goja.Value {
+	var exception *goja.Exception
+	if goerrors.As(err, &exception) {
+		return exception.Value()
+	}
+
+	mappingsLock.RLock()
+	defer mappingsLock.RUnlock()
+	for _, matcher := range mappings {
+		if matched, ok := matcher.match(err); ok {
+			return newMappedError(r, err, matched, matcher.mapping)
+		}
+	}
+	return r.NewGoError(err)
+}
+
+// FromPanic converts a value recovered from a panicking native function into the JS value to be thrown.
+// Panics raised by goja itself, including thrown JS values and interrupts, are returned unchanged.
+func FromPanic(r *goja.Runtime, recovered interface{}) interface{} {
+	switch v := recovered.(type) {
+	case goja.Value, *goja.Exception, *goja.InterruptedError, *goja.StackOverflowError:
+		return recovered
+	case error:
+		return NewError(r, nil, ErrCodeNativePanic, "native function panicked: %v", v.Error())
+	}
+
+	if t := reflect.TypeOf(recovered); t != nil {
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.PkgPath() == gojaPkgPath {
+			return recovered
+		}
+	}
+	return NewError(r, nil, ErrCodeNativePanic, "native function panicked: %v", fmt.Sprint(recovered))
+}
+
+func newMappedError(r *goja.Runtime, err error, matched error, mapping Mapping) *goja.Object {
+	var ctor *goja.Object
+	if mapping.Constructor != "" {
+		ctor, _ = r.Get(mapping.Constructor).(*goja.Object)
+	}
+
+	e := NewError(r, ctor, mapping.Code, "%s", err.Error())
+	if mapping.Code == "" {
+		e.Delete("code")
+	}
+	if mapping.Name != "" {
+		e.Set("name", mapping.Name)
+	}
+	for name, value := range mapping.Properties {
+		e.Set(name, value)
+	}
+	if mapping.PropertiesFrom != nil {
+		for name, value := range mapping.PropertiesFrom(matched) {
+			e.Set(name, value)
+		}
+	}
+	return e
+}
@@ -0,0 +1,112 @@
+package errors
+
+import (
+	goerrors "errors"
+	"fmt"
+	"testing"
+
+	goja "github.com/grafana/sobek"
+)
+
+var errRateLimited = goerrors.New("rate limited")
+
+type upstreamError struct {
+	Status int
+}
+
+func (e *upstreamError) Error() string {
+	return fmt.Sprintf("upstream returned %d", e.Status)
+}
+
+func TestErrorMappings(t *testing.T) {
+	t.Cleanup(RegisterErrorMapping(errRateLimited, Mapping{
+		Name:       "RateLimitError",
+		Code:       "RATE_LIMITED",
+		Properties: map[string]interface{}{"retryable": true},
+	}))
+	t.Cleanup(RegisterErrorTypeMapping[*upstreamError](Mapping{
+		Name:        "UpstreamError",
+		Code:        "UPSTREAM_FAILED",
+		Constructor: "TypeError",
+		PropertiesFrom: func(err error) map[string]interface{} {
+			return map[string]interface{}{"status": err.(*upstreamError).Status}
+		},
+	}))
+
+	vm := goja.New()
+	vm.Set("fail", func(call goja.FunctionCall) goja.Value {
+		switch call.Argument(0).String() {
+		case "sentinel":
+			panic(FromGoError(vm, fmt.Errorf("calling api: %w", errRateLimited)))
+		case "type":
+			panic(FromGoError(vm, fmt.Errorf("calling api: %w", &upstreamError{Status: 503})))
+		default:
+			panic(FromGoError(vm, goerrors.New("unmapped")))
+		}
+	})
+
+	res, err := vm.RunString(`
+		const results = [];
+		try { fail("sentinel") } catch (e) {
+			results.push(e instanceof Error, e.name, e.code, e.retryable, e.message);
+		}
+		try { fail("type") } catch (e) {
+			results.push(e instanceof TypeError, e.name, e.code, e.status);
+		}
+		try { fail("other") } catch (e) {
+			results.push(e.name, e.code);
+		}
+		results.join(",");
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "true,RateLimitError,RATE_LIMITED,true,calling api: rate limited,true,UpstreamError,UPSTREAM_FAILED,503,GoError,"
+	if res.String() != expected {
+		t.Fatalf("Unexpected result: '%s'", res.String())
+	}
+}
+
+func TestFromPanic(t *testing.T) {
+	vm := goja.New()
+	vm.Set("explode", func(call goja.FunctionCall) goja.Value {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				panic(FromPanic(vm, recovered))
+			}
+		}()
+		if call.Argument(0).ToBoolean() {
+			panic(vm.NewTypeError("thrown from js"))
+		}
+		var m map[string]int
+		m["nil map"] = 1
+		return goja.Undefined()
+	})
+
+	res, err := vm.RunString(`
+		const results = [];
+		try { explode(false) } catch (e) { results.push(e.code) }
+		try { explode(true) } catch (e) { results.push(e instanceof TypeError, e.message) }
+		results.join(",");
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.String() != ErrCodeNativePanic+",true,thrown from js" {
+		t.Fatalf("Unexpected result: '%s'", res.String())
+	}
+}
+
+func TestUnregisterErrorMapping(t *testing.T) {
+	unregister := RegisterErrorMapping(errRateLimited, Mapping{Name: "RateLimitError"})
+	vm := goja.New()
+	if name := FromGoError(vm, errRateLimited).ToObject(vm).Get("name").String(); name != "RateLimitError" {
+		t.Fatalf("Unexpected name: '%s'", name)
+	}
+
+	unregister()
+	if name := FromGoError(vm, errRateLimited).ToObject(vm).Get("name").String(); name != "GoError" {
+		t.Fatalf("Unexpected name after unregistering: '%s'", name)
+	}
+}
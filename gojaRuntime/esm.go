@@ -0,0 +1,296 @@
+package goja_runtime
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	goja "github.com/grafana/sobek"
+	"github.com/grafana/sobek/ast"
+	"github.com/grafana/sobek/parser"
+	jsErrors "github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
+)
+
+// mainModuleName is the name the workflow source is evaluated under, as a script or as a module.
+const mainModuleName = "main"
+
+// nativeExportNames caches the names exported by native modules, keyed by specifier. They are found once by
+// requiring the module in a runtime of its own.
+var nativeExportNames sync.Map
+
+// esModuleNotFoundError is returned when an import names neither a native module nor a module of the workflow.
+type esModuleNotFoundError struct {
+	specifier string
+	referrer  string
+}
+
+func (e *esModuleNotFoundError) Error() string {
+	return fmt.Sprintf("Cannot find module '%v' imported from %v", e.specifier, e.referrer)
+}
+
+// esmLoader resolves the imports of an ES module workflow and the import() calls of workflows in either format.
+// Relative specifiers name the modules shipped in WorkflowDescriptor.Modules, other specifiers name native modules,
+// which are read through require.
+type esmLoader struct {
+	modules map[string]runtimesRegistry.SourceDescriptor
+	// cache keeps the parsed modules of the workflow with the given hash across executions
+	cache *gojaCache
+	hash  string
+	// bindings resolves the specifiers naming native APIs, see nativeModules.bindingResolver
+	bindings func(specifier string) (goja.Value, bool, error)
+	// records holds the modules loaded so far by path or specifier, names the other way round
+	records map[string]goja.ModuleRecord
+	names   map[interface{}]string
+}
+
+func newESMLoader(workflow runtimesRegistry.WorkflowDescriptor, workflowHash string, cache *gojaCache, bindings func(specifier string) (goja.Value, bool, error)) *esmLoader {
+	loader := &esmLoader{
+		cache:    cache,
+		hash:     workflowHash,
+		bindings: bindings,
+		modules:  make(map[string]runtimesRegistry.SourceDescriptor, len(workflow.Modules)),
+		records:  map[string]goja.ModuleRecord{},
+		names:    map[interface{}]string{},
+	}
+	for p, module := range workflow.Modules {
+		loader.modules[modulePath(p)] = module
+	}
+	return loader
+}
+
+// modulePath normalises the path of a workflow module, relative to the workflow source.
+func modulePath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
+
+func isESM(p string, module runtimesRegistry.SourceDescriptor) bool {
+	return module.ModuleFormat == runtimesRegistry.ModuleFormatESM || path.Ext(p) == ".mjs"
+}
+
+// parseMain parses the workflow source as the main module.
+func (l *esmLoader) parseMain(source []byte) (*goja.SourceTextModuleRecord, error) {
+	record, err := l.parse(mainModuleName, source)
+	if err != nil {
+		return nil, err
+	}
+	l.records[mainModuleName] = record
+	l.names[record] = mainModuleName
+	return record, nil
+}
+
+// parse returns a record of the module name of the workflow, parsing its source once per workflow.
+func (l *esmLoader) parse(name string, source []byte) (*goja.SourceTextModuleRecord, error) {
+	body, err := l.cache.cacheModule(l.hash+"\x00"+name, func() (*ast.Program, error) {
+		return goja.Parse(name, string(source), parser.IsModule)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return goja.ModuleFromAST(body, l.resolve)
+}
+
+// resolve implements goja.HostResolveImportedModuleFunc.
+func (l *esmLoader) resolve(referrer interface{}, specifier string) (goja.ModuleRecord, error) {
+	from, ok := l.names[referrer]
+	if !ok {
+		from = mainModuleName
+	}
+	if !strings.HasPrefix(specifier, "./") && !strings.HasPrefix(specifier, "../") && !strings.HasPrefix(specifier, "/") {
+		return l.resolveNative(specifier, from)
+	}
+
+	base := path.Dir(from)
+	if strings.HasPrefix(specifier, "/") {
+		base = "/"
+	}
+	p := modulePath(path.Join(base, specifier))
+	for _, candidate := range []string{p, p + ".js", p + ".mjs"} {
+		if record, ok := l.records[candidate]; ok {
+			return record, nil
+		}
+		module, ok := l.modules[candidate]
+		if !ok {
+			continue
+		}
+		var record goja.ModuleRecord
+		if isESM(candidate, module) {
+			parsed, err := l.parse(candidate, module.Source)
+			if err != nil {
+				return nil, err
+			}
+			record = parsed
+		} else {
+			// CommonJS modules are required by their absolute path, their named exports are read on access
+			record = &requireModuleRecord{specifier: "/" + candidate, names: []string{"default"}, resolve: l.resolve}
+		}
+		l.records[candidate] = record
+		l.names[record] = candidate
+		return record, nil
+	}
+	return nil, &esModuleNotFoundError{specifier: specifier, referrer: from}
+}
+
+func (l *esmLoader) resolveNative(specifier, from string) (goja.ModuleRecord, error) {
+	if record, ok := l.records[specifier]; ok {
+		return record, nil
+	}
+	binding, ok, err := l.bindings(specifier)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	if ok {
+		names = exportNames(binding)
+	} else if names, err = nativeModuleExports(specifier); err != nil {
+		return nil, &esModuleNotFoundError{specifier: specifier, referrer: from}
+	}
+	record := &requireModuleRecord{specifier: specifier, names: names, resolve: l.resolve}
+	l.records[specifier] = record
+	l.names[record] = specifier
+	return record, nil
+}
+
+// nativeModuleExports returns the names a native module exports, "default" being its exports object.
+func nativeModuleExports(specifier string) ([]string, error) {
+	if names, ok := nativeExportNames.Load(specifier); ok {
+		return names.([]string), nil
+	}
+	vm := goja.New()
+	registry.Enable(vm)
+	requireFn, _ := goja.AssertFunction(vm.Get("require"))
+	exports, err := requireFn(goja.Undefined(), vm.ToValue(specifier))
+	if err != nil {
+		return nil, err
+	}
+	names := exportNames(exports)
+	nativeExportNames.Store(specifier, names)
+	return names, nil
+}
+
+// exportNames lists "default" and the keys of exports, the names a module read through require exports.
+func exportNames(exports goja.Value) []string {
+	names := []string{"default"}
+	if o, ok := exports.(*goja.Object); ok {
+		for _, key := range o.Keys() {
+			if key != "default" {
+				names = append(names, key)
+			}
+		}
+	}
+	return names
+}
+
+// enable sets up import.meta and dynamic import() for the modules of the loader in vm.
+func (l *esmLoader) enable(vm *goja.Runtime) {
+	vm.SetGetImportMetaProperties(func(m goja.ModuleRecord) []goja.MetaProperty {
+		name := "/" + l.names[m]
+		return []goja.MetaProperty{
+			{Key: "url", Value: vm.ToValue("file://" + name)},
+			{Key: "filename", Value: vm.ToValue(name)},
+			{Key: "dirname", Value: vm.ToValue(path.Dir(name))},
+		}
+	})
+	vm.SetImportModuleDynamically(func(referrer interface{}, specifier goja.Value, capability interface{}) {
+		record, err := l.resolve(referrer, specifier.String())
+		if err != nil {
+			if notFound, ok := err.(*esModuleNotFoundError); ok {
+				vm.FinishLoadingImportModule(referrer, specifier, capability, nil, jsErrors.NewError(vm, nil, jsErrors.ErrCodeESMModuleNotFound, "%s", notFound.Error()))
+				return
+			}
+			vm.FinishLoadingImportModule(referrer, specifier, capability, nil, jsErrors.FromGoError(vm, err))
+			return
+		}
+		vm.FinishLoadingImportModule(referrer, specifier, capability, record, nil)
+	})
+}
+
+// evaluate links and evaluates the main module, returning its namespace object. A module using top-level await
+// is waited for until it settles or signal aborts.
+func (l *esmLoader) evaluate(vm *goja.Runtime, record *goja.SourceTextModuleRecord, signal *executionSignal) (*goja.Object, error) {
+	if err := record.Link(); err != nil {
+		return nil, err
+	}
+	promise := vm.CyclicModuleRecordEvaluate(record, l.resolve)
+	if err := signal.settle(promise); err != nil {
+		return nil, err
+	}
+	if promise.State() == goja.PromiseStateRejected {
+		return nil, rejectionError(vm, promise)
+	}
+	return vm.NamespaceObjectFor(record), nil
+}
+
+// requireModuleRecord is a module whose bindings are read from the exports of require(specifier), for the native
+// modules, the native APIs and the CommonJS modules of a workflow. "default" is the exports object itself.
+type requireModuleRecord struct {
+	specifier string
+	names     []string
+	resolve   goja.HostResolveImportedModuleFunc
+}
+
+var _ goja.CyclicModuleRecord = &requireModuleRecord{}
+
+func (m *requireModuleRecord) GetExportedNames(callback func([]string), _ ...goja.ModuleRecord) bool {
+	callback(m.names)
+	return true
+}
+
+func (m *requireModuleRecord) ResolveExport(name string, _ ...goja.ResolveSetElement) (*goja.ResolvedBinding, bool) {
+	return &goja.ResolvedBinding{Module: m, BindingName: name}, false
+}
+
+func (m *requireModuleRecord) Link() error {
+	return nil
+}
+
+func (m *requireModuleRecord) Evaluate(vm *goja.Runtime) *goja.Promise {
+	return vm.CyclicModuleRecordEvaluate(m, m.resolve)
+}
+
+func (m *requireModuleRecord) RequestedModules() []string {
+	return nil
+}
+
+func (m *requireModuleRecord) InitializeEnvironment() error {
+	return nil
+}
+
+func (m *requireModuleRecord) Instantiate(_ *goja.Runtime) (goja.CyclicModuleInstance, error) {
+	return &requireModuleInstance{record: m}, nil
+}
+
+type requireModuleInstance struct {
+	record  *requireModuleRecord
+	exports goja.Value
+}
+
+func (i *requireModuleInstance) HasTLA() bool {
+	return false
+}
+
+func (i *requireModuleInstance) ExecuteModule(vm *goja.Runtime, _, _ func(interface{}) error) (goja.CyclicModuleInstance, error) {
+	requireFn, ok := goja.AssertFunction(vm.Get("require"))
+	if !ok {
+		return nil, fmt.Errorf("require is not enabled")
+	}
+	exports, err := requireFn(goja.Undefined(), vm.ToValue(i.record.specifier))
+	if err != nil {
+		return nil, err
+	}
+	i.exports = exports
+	return i, nil
+}
+
+func (i *requireModuleInstance) GetBindingValue(name string) goja.Value {
+	if name == "default" {
+		return i.exports
+	}
+	if o, ok := i.exports.(*goja.Object); ok {
+		if v := o.Get(name); v != nil {
+			return v
+		}
+	}
+	return goja.Undefined()
+}
@@ -0,0 +1,459 @@
+package events
+
+import (
+	"strconv"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/util"
+)
+
+// Emitters keep their state in the same own properties as in Node.js, _events maps an event name to a listener
+// or to an array of listeners. Libraries read them directly, and objects which mix EventEmitter.prototype in
+// without calling the constructor get them lazily.
+
+// init sets up the state of an emitter, objects sharing the _events of their prototype get their own.
+func (m *eventsModule) init(this *goja.Object) {
+	events := this.Get("_events")
+	if events == nil || goja.IsUndefined(events) || goja.IsNull(events) || m.sharesPrototypeEvents(this, events) {
+		this.Set("_events", m.newEvents())
+		this.Set("_eventsCount", 0)
+	}
+	if v := this.Get("_maxListeners"); v == nil || !v.ToBoolean() {
+		this.Set("_maxListeners", goja.Undefined())
+	}
+}
+
+func (m *eventsModule) sharesPrototypeEvents(this *goja.Object, events goja.Value) bool {
+	p := this.Prototype()
+	if p == nil {
+		return false
+	}
+	inherited := p.Get("_events")
+	return inherited != nil && inherited.SameAs(events)
+}
+
+func (m *eventsModule) newEvents() *goja.Object {
+	events := m.r.NewObject()
+	events.SetPrototype(nil)
+	return events
+}
+
+// events returns the _events of this, creating the emitter state when it is missing.
+func (m *eventsModule) events(this *goja.Object) *goja.Object {
+	events, ok := this.Get("_events").(*goja.Object)
+	if !ok {
+		this.Set("_events", m.newEvents())
+		this.Set("_eventsCount", 0)
+		return this.Get("_events").(*goja.Object)
+	}
+	return events
+}
+
+func get(o *goja.Object, key goja.Value) goja.Value {
+	if s, ok := key.(*goja.Symbol); ok {
+		return o.GetSymbol(s)
+	}
+	return o.Get(key.String())
+}
+
+func set(o *goja.Object, key, value goja.Value) {
+	if s, ok := key.(*goja.Symbol); ok {
+		o.SetSymbol(s, value)
+		return
+	}
+	o.Set(key.String(), value)
+}
+
+func remove(o *goja.Object, key goja.Value) {
+	if s, ok := key.(*goja.Symbol); ok {
+		o.DeleteSymbol(s)
+		return
+	}
+	o.Delete(key.String())
+}
+
+func isSet(v goja.Value) bool {
+	return v != nil && !goja.IsUndefined(v)
+}
+
+// listeners returns the listeners stored for an event, in the order they are called.
+func (m *eventsModule) listeners(entry goja.Value) []goja.Value {
+	if !isSet(entry) {
+		return nil
+	}
+	if _, ok := goja.AssertFunction(entry); ok {
+		return []goja.Value{entry}
+	}
+	var list []goja.Value
+	if err := m.r.ExportTo(entry, &list); err != nil {
+		panic(err)
+	}
+	return list
+}
+
+// store saves the listeners of an event, a single listener is kept as is and several in an array.
+// The array gets a new identity so that an emit in progress keeps calling the listeners it started with.
+func (m *eventsModule) store(events *goja.Object, key goja.Value, list []goja.Value, warned bool) {
+	if len(list) == 1 {
+		set(events, key, list[0])
+		return
+	}
+	array := m.r.NewArray(toInterfaces(list)...)
+	if warned {
+		array.Set("warned", true)
+	}
+	set(events, key, array)
+}
+
+func warned(entry goja.Value) bool {
+	o, ok := entry.(*goja.Object)
+	if !ok {
+		return false
+	}
+	v := o.Get("warned")
+	return v != nil && v.ToBoolean()
+}
+
+// unwrap returns the listener a once wrapper was created for.
+func unwrap(listener goja.Value) goja.Value {
+	if o, ok := listener.(*goja.Object); ok {
+		if original := o.Get("listener"); isSet(original) {
+			if _, ok := goja.AssertFunction(original); ok {
+				return original
+			}
+		}
+	}
+	return listener
+}
+
+func (m *eventsModule) checkListener(listener goja.Value) {
+	if _, ok := goja.AssertFunction(listener); !ok {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "listener" argument must be of type function.`))
+	}
+}
+
+func (m *eventsModule) maxListeners(this *goja.Object) float64 {
+	if v := this.Get("_maxListeners"); isSet(v) {
+		return v.ToFloat()
+	}
+	return m.defaultMaxListeners
+}
+
+func (m *eventsModule) addListener(this *goja.Object, key, listener goja.Value, prepend bool) goja.Value {
+	m.checkListener(listener)
+	events := m.events(this)
+	if isSet(events.Get("newListener")) {
+		m.invoke(this, "emit", m.r.ToValue("newListener"), key, unwrap(listener))
+		// a newListener listener may have replaced _events
+		events = m.events(this)
+	}
+
+	existing := get(events, key)
+	list := m.listeners(existing)
+	if prepend {
+		list = append([]goja.Value{listener}, list...)
+	} else {
+		list = append(list, listener)
+	}
+	if !isSet(existing) {
+		this.Set("_eventsCount", this.Get("_eventsCount").ToInteger()+1)
+	}
+
+	alreadyWarned := warned(existing)
+	if max := m.maxListeners(this); max > 0 && float64(len(list)) > max && !alreadyWarned {
+		alreadyWarned = true
+		m.warn("MaxListenersExceededWarning", "Possible EventEmitter memory leak detected. "+strconv.Itoa(len(list))+" "+
+			m.eventName(key)+" listeners added to "+m.describe(this)+". MaxListeners is "+m.r.ToValue(max).String()+
+			". Use emitter.setMaxListeners() to increase limit")
+	}
+	m.store(events, key, list, alreadyWarned)
+	return this
+}
+
+func (m *eventsModule) eventName(key goja.Value) string {
+	if s, ok := key.(*goja.Symbol); ok {
+		return s.String()
+	}
+	return key.String()
+}
+
+// describe names an emitter the way util.inspect does at depth -1.
+func (m *eventsModule) describe(this *goja.Object) string {
+	name := "Object"
+	if constructor, ok := this.Get("constructor").(*goja.Object); ok {
+		if v := constructor.Get("name"); isSet(v) && v.String() != "" {
+			name = v.String()
+		}
+	}
+	return "[" + name + "]"
+}
+
+func (m *eventsModule) onceWrapper(this *goja.Object, key, listener goja.Value) goja.Value {
+	fired := false
+	var wrapper *goja.Object
+	wrapper = m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		if fired {
+			return goja.Undefined()
+		}
+		m.invoke(this, "removeListener", key, wrapper)
+		fired = true
+		fn, _ := goja.AssertFunction(listener)
+		res, err := fn(this, call.Arguments...)
+		if err != nil {
+			panic(err)
+		}
+		return res
+	}).(*goja.Object)
+	wrapper.Set("listener", listener)
+	return wrapper
+}
+
+func (m *eventsModule) removeListener(this *goja.Object, key, listener goja.Value) goja.Value {
+	m.checkListener(listener)
+	events, ok := this.Get("_events").(*goja.Object)
+	if !ok {
+		return this
+	}
+	existing := get(events, key)
+	list := m.listeners(existing)
+	position := -1
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i].SameAs(listener) || unwrap(list[i]).SameAs(listener) {
+			position = i
+			break
+		}
+	}
+	if position < 0 {
+		return this
+	}
+
+	removed := list[position]
+	list = append(list[:position:position], list[position+1:]...)
+	if len(list) == 0 {
+		if count := this.Get("_eventsCount").ToInteger() - 1; count <= 0 {
+			this.Set("_events", m.newEvents())
+			this.Set("_eventsCount", 0)
+		} else {
+			remove(events, key)
+			this.Set("_eventsCount", count)
+		}
+	} else {
+		m.store(events, key, list, warned(existing))
+	}
+
+	if events, ok := this.Get("_events").(*goja.Object); ok && isSet(events.Get("removeListener")) {
+		m.invoke(this, "emit", m.r.ToValue("removeListener"), key, unwrap(removed))
+	}
+	return this
+}
+
+func (m *eventsModule) removeAllListeners(this *goja.Object, args []goja.Value) goja.Value {
+	events, ok := this.Get("_events").(*goja.Object)
+	if !ok {
+		return this
+	}
+
+	// without removeListener listeners there is nobody to notify, the listeners can be dropped at once
+	if !isSet(events.Get("removeListener")) {
+		if len(args) == 0 {
+			this.Set("_events", m.newEvents())
+			this.Set("_eventsCount", 0)
+		} else if isSet(get(events, args[0])) {
+			if count := this.Get("_eventsCount").ToInteger() - 1; count <= 0 {
+				this.Set("_events", m.newEvents())
+				this.Set("_eventsCount", 0)
+			} else {
+				remove(events, args[0])
+				this.Set("_eventsCount", count)
+			}
+		}
+		return this
+	}
+
+	if len(args) == 0 {
+		for _, key := range m.eventNames(events) {
+			if key.String() != "removeListener" {
+				m.invoke(this, "removeAllListeners", key)
+			}
+		}
+		m.invoke(this, "removeAllListeners", m.r.ToValue("removeListener"))
+		this.Set("_events", m.newEvents())
+		this.Set("_eventsCount", 0)
+		return this
+	}
+
+	list := m.listeners(get(events, args[0]))
+	for i := len(list) - 1; i >= 0; i-- {
+		m.invoke(this, "removeListener", args[0], list[i])
+	}
+	return this
+}
+
+func (m *eventsModule) eventNames(events *goja.Object) []goja.Value {
+	var names []goja.Value
+	for _, key := range events.Keys() {
+		names = append(names, m.r.ToValue(key))
+	}
+	for _, symbol := range events.Symbols() {
+		names = append(names, symbol)
+	}
+	return names
+}
+
+// emit calls the listeners of an event synchronously. An error event without listeners throws its argument,
+// after the errorMonitor listeners have seen it.
+func (m *eventsModule) emit(this *goja.Object, key goja.Value, args []goja.Value) bool {
+	events, hasEvents := this.Get("_events").(*goja.Object)
+	isError := !isSymbol(key) && key.String() == "error"
+	if isError && hasEvents && isSet(events.GetSymbol(m.errorMonitor)) {
+		m.emit(this, m.errorMonitor, args)
+	}
+
+	var list []goja.Value
+	if hasEvents {
+		list = m.listeners(get(events, key))
+	}
+	if len(list) == 0 {
+		if isError {
+			panic(m.unhandledError(args))
+		}
+		return false
+	}
+
+	for _, listener := range list {
+		fn, ok := goja.AssertFunction(listener)
+		if !ok {
+			continue
+		}
+		if _, err := fn(this, args...); err != nil {
+			panic(err)
+		}
+	}
+	return true
+}
+
+func isSymbol(v goja.Value) bool {
+	_, ok := v.(*goja.Symbol)
+	return ok
+}
+
+// unhandledError is thrown for an error event nobody listens to: the error itself when it is one,
+// an ERR_UNHANDLED_ERROR describing the value otherwise.
+func (m *eventsModule) unhandledError(args []goja.Value) goja.Value {
+	var er goja.Value = goja.Undefined()
+	if len(args) > 0 {
+		er = args[0]
+	}
+	if o, ok := er.(*goja.Object); ok && o.ClassName() == "Error" {
+		return o
+	}
+
+	message := "Unhandled error."
+	if !goja.IsUndefined(er) {
+		message += " (" + m.util.Inspect(er, util.DefaultInspectOptions()) + ")"
+	}
+	e := errors.NewError(m.r, nil, errors.ErrCodeUnhandledError, "%s", message)
+	e.Set("context", er)
+	return e
+}
+
+func (m *eventsModule) createPrototype() *goja.Object {
+	r := m.r
+	p := r.NewObject()
+	this := func(call goja.FunctionCall) *goja.Object {
+		return call.This.ToObject(r)
+	}
+
+	p.Set("_events", goja.Undefined())
+	p.Set("_eventsCount", 0)
+	p.Set("_maxListeners", goja.Undefined())
+
+	addListener := r.ToValue(func(call goja.FunctionCall) goja.Value {
+		return m.addListener(this(call), call.Argument(0), call.Argument(1), false)
+	})
+	p.Set("addListener", addListener)
+	p.Set("on", addListener)
+	p.Set("prependListener", func(call goja.FunctionCall) goja.Value {
+		return m.addListener(this(call), call.Argument(0), call.Argument(1), true)
+	})
+	p.Set("once", func(call goja.FunctionCall) goja.Value {
+		m.checkListener(call.Argument(1))
+		emitter := this(call)
+		return m.invoke(emitter, "on", call.Argument(0), m.onceWrapper(emitter, call.Argument(0), call.Argument(1)))
+	})
+	p.Set("prependOnceListener", func(call goja.FunctionCall) goja.Value {
+		m.checkListener(call.Argument(1))
+		emitter := this(call)
+		return m.invoke(emitter, "prependListener", call.Argument(0), m.onceWrapper(emitter, call.Argument(0), call.Argument(1)))
+	})
+
+	removeListener := r.ToValue(func(call goja.FunctionCall) goja.Value {
+		return m.removeListener(this(call), call.Argument(0), call.Argument(1))
+	})
+	p.Set("removeListener", removeListener)
+	p.Set("off", removeListener)
+	p.Set("removeAllListeners", func(call goja.FunctionCall) goja.Value {
+		return m.removeAllListeners(this(call), call.Arguments)
+	})
+
+	p.Set("emit", func(call goja.FunctionCall) goja.Value {
+		var args []goja.Value
+		if len(call.Arguments) > 1 {
+			args = call.Arguments[1:]
+		}
+		return r.ToValue(m.emit(this(call), call.Argument(0), args))
+	})
+
+	p.Set("listeners", func(call goja.FunctionCall) goja.Value {
+		var list []interface{}
+		if events, ok := this(call).Get("_events").(*goja.Object); ok {
+			for _, listener := range m.listeners(get(events, call.Argument(0))) {
+				list = append(list, unwrap(listener))
+			}
+		}
+		return r.NewArray(list...)
+	})
+	p.Set("rawListeners", func(call goja.FunctionCall) goja.Value {
+		var list []goja.Value
+		if events, ok := this(call).Get("_events").(*goja.Object); ok {
+			list = m.listeners(get(events, call.Argument(0)))
+		}
+		return r.NewArray(toInterfaces(list)...)
+	})
+	p.Set("listenerCount", func(call goja.FunctionCall) goja.Value {
+		events, ok := this(call).Get("_events").(*goja.Object)
+		if !ok {
+			return r.ToValue(0)
+		}
+		list := m.listeners(get(events, call.Argument(0)))
+		listener := call.Argument(1)
+		if !isSet(listener) {
+			return r.ToValue(len(list))
+		}
+		count := 0
+		for _, l := range list {
+			if l.SameAs(listener) || unwrap(l).SameAs(listener) {
+				count++
+			}
+		}
+		return r.ToValue(count)
+	})
+	p.Set("eventNames", func(call goja.FunctionCall) goja.Value {
+		events, ok := this(call).Get("_events").(*goja.Object)
+		if !ok {
+			return r.NewArray()
+		}
+		return r.NewArray(toInterfaces(m.eventNames(events))...)
+	})
+
+	p.Set("setMaxListeners", func(call goja.FunctionCall) goja.Value {
+		emitter := this(call)
+		emitter.Set("_maxListeners", m.validateMaxListeners(call.Argument(0), "n"))
+		return emitter
+	})
+	p.Set("getMaxListeners", func(call goja.FunctionCall) goja.Value {
+		return r.ToValue(m.maxListeners(this(call)))
+	})
+	return p
+}
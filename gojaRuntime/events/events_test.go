@@ -0,0 +1,189 @@
+package events
+
+import (
+	"testing"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/abort"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/web"
+)
+
+func createVM() *goja.Runtime {
+	vm := goja.New()
+	new(require.Registry).Enable(vm)
+	vm.Set("EventEmitter", require.Require(vm, ModuleName))
+	return vm
+}
+
+func runTests(t *testing.T, vm *goja.Runtime, tests []struct {
+	script   string
+	expected string
+}) {
+	t.Helper()
+	for _, tc := range tests {
+		result, err := vm.RunString(tc.script)
+		if err != nil {
+			t.Fatalf("Failed to run %s: %v", tc.script, err)
+		}
+		if result.String() != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.script, tc.expected, result.String())
+		}
+	}
+}
+
+func TestRequire(t *testing.T) {
+	runTests(t, createVM(), []struct {
+		script   string
+		expected string
+	}{
+		{`require("node:events") === EventEmitter && EventEmitter.EventEmitter === EventEmitter`, "true"},
+		{`EventEmitter.name + " " + EventEmitter.defaultMaxListeners + " " + typeof EventEmitter.errorMonitor`, "EventEmitter 10 symbol"},
+	})
+}
+
+func TestEventEmitter(t *testing.T) {
+	runTests(t, createVM(), []struct {
+		script   string
+		expected string
+	}{
+		{`const e = new EventEmitter(); const calls = [];
+		  e.on("ping", function (a, b) { calls.push("on:" + a + b + (this === e)) });
+		  e.prependListener("ping", () => calls.push("first"));
+		  e.once("ping", (a) => calls.push("once:" + a));
+		  [e.emit("ping", 1, 2), e.emit("ping", 3, 4), e.emit("missing")].join() + " " + calls.join()`,
+			"true,true,false first,on:12true,once:1,first,on:34true"},
+		{`e.listenerCount("ping") + " " + e.eventNames().join()`, "2 ping"},
+		{`const fn = () => {}; e.once("x", fn); [e.listeners("x")[0] === fn, e.rawListeners("x")[0] === fn, e.rawListeners("x")[0].listener === fn, e.listenerCount("x", fn)].join()`, "true,false,true,1"},
+		{`e.off("x", fn); e.listenerCount("x") + " " + e.eventNames().join()`, "0 ping"},
+		{`const sym = Symbol("s"); e.on(sym, () => calls.push("symbol")); e.emit(sym); e.eventNames().length + " " + calls.at(-1)`, "2 symbol"},
+		{`e.removeAllListeners("ping"); e.eventNames().length`, "1"},
+		{`e.removeAllListeners(); e.eventNames().length + " " + e._eventsCount`, "0 0"},
+		{`const order = []; const twice = () => order.push("twice"); e.on("dup", twice); e.on("dup", () => order.push("other")); e.on("dup", twice);
+		  e.removeListener("dup", twice); e.emit("dup"); order.join()`, "twice,other"},
+		{`const during = []; const second = () => during.push("second"); e.on("mutate", () => { during.push("first"); e.off("mutate", second) }); e.on("mutate", second);
+		  e.emit("mutate"); e.emit("mutate"); during.join()`, "first,second,first"},
+		{`try { e.on("x", "nope") } catch (err) { err.code }`, "ERR_INVALID_ARG_TYPE"},
+		{`e.on("a", () => {}) === e && e.setMaxListeners(1) === e && e.getMaxListeners()`, "1"},
+		{`try { e.setMaxListeners(-1) } catch (err) { err.name + " " + err.code }`, "RangeError ERR_OUT_OF_RANGE"},
+	})
+}
+
+func TestNewAndRemoveListenerEvents(t *testing.T) {
+	runTests(t, createVM(), []struct {
+		script   string
+		expected string
+	}{
+		{`const e = new EventEmitter(); const log = []; const fn = () => {};
+		  e.on("newListener", (name, listener) => log.push("new:" + String(name) + ":" + (listener === fn)));
+		  e.on("removeListener", (name, listener) => log.push("removed:" + name + ":" + (listener === fn)));
+		  e.once("data", fn); e.emit("data"); log.join()`, "new:removeListener:false,new:data:true,removed:data:true"},
+		{`log.length = 0; e.on("a", fn); e.on("b", fn); e.removeAllListeners(); log.slice(2).join() + " " + e.eventNames().length`, "removed:newListener:false,removed:a:true,removed:b:true 0"},
+	})
+}
+
+func TestErrorEvents(t *testing.T) {
+	runTests(t, createVM(), []struct {
+		script   string
+		expected string
+	}{
+		{`const e = new EventEmitter(); try { e.emit("error", new TypeError("bad")) } catch (err) { err.name + ": " + err.message }`, "TypeError: bad"},
+		{`try { e.emit("error", "oops") } catch (err) { [err.code, err.message, err.context].join() }`, "ERR_UNHANDLED_ERROR,Unhandled error. ('oops'),oops"},
+		{`try { e.emit("error") } catch (err) { err.message }`, "Unhandled error."},
+		{`const seen = []; e.on(EventEmitter.errorMonitor, (err) => seen.push("monitor:" + err.message));
+		  try { e.emit("error", new Error("first")) } catch (err) { seen.push("thrown") } seen.join()`, "monitor:first,thrown"},
+		{`e.on("error", (err) => seen.push("handled:" + err.message)); e.emit("error", new Error("second")) + " " + seen.slice(2).join()`, "true monitor:second,handled:second"},
+		{`e.on("boom", () => { throw new Error("listener") }); try { e.emit("boom") } catch (err) { err.message }`, "listener"},
+	})
+}
+
+func TestInheritance(t *testing.T) {
+	vm := createVM()
+	vm.Set("util", require.Require(vm, "util"))
+	runTests(t, vm, []struct {
+		script   string
+		expected string
+	}{
+		{`class Client extends EventEmitter { connect() { this.emit("connect", "ok") } };
+		  const c = new Client(); let status; c.on("connect", (s) => status = s); c.connect(); (c instanceof EventEmitter) + " " + status`, "true ok"},
+		{`function Legacy() { EventEmitter.call(this) } util.inherits(Legacy, EventEmitter);
+		  const l = new Legacy(); let got; l.on("x", (v) => got = v); l.emit("x", 1); got + " " + Object.keys(l).join()`, "1 _events,_eventsCount,_maxListeners"},
+		{`const mixed = Object.assign({}, EventEmitter.prototype); let mixedGot; mixed.on("y", (v) => mixedGot = v); mixed.emit("y", 2); mixedGot`, "2"},
+		{`const child = Object.create(c); child.on("connect", () => {}); EventEmitter.call(child); child.listenerCount("connect") + " " + c.listenerCount("connect")`, "0 2"},
+	})
+}
+
+func TestMaxListenersWarning(t *testing.T) {
+	vm := createVM()
+	var warnings []string
+	console := vm.NewObject()
+	console.Set("warn", func(call goja.FunctionCall) goja.Value {
+		warnings = append(warnings, call.Argument(0).String())
+		return goja.Undefined()
+	})
+	vm.Set("console", console)
+	runTests(t, vm, []struct {
+		script   string
+		expected string
+	}{
+		{`const e = new EventEmitter(); for (let i = 0; i < 12; i++) e.on("data", () => {}); e.listenerCount("data")`, "12"},
+		{`const limited = new EventEmitter().setMaxListeners(1); limited.on("x", () => {}); limited.on("x", () => {}); limited.listenerCount("x")`, "2"},
+		{`const unlimited = new EventEmitter().setMaxListeners(0); for (let i = 0; i < 20; i++) unlimited.on("x", () => {}); "ok"`, "ok"},
+		{`EventEmitter.defaultMaxListeners = 1; class Named extends EventEmitter {}; const n = new Named(); n.on("y", () => {}); n.on("y", () => {}); n.getMaxListeners()`, "1"},
+		{`try { EventEmitter.defaultMaxListeners = "2" } catch (err) { err.code }`, "ERR_INVALID_ARG_TYPE"},
+	})
+
+	expected := []string{
+		"MaxListenersExceededWarning: Possible EventEmitter memory leak detected. 11 data listeners added to [EventEmitter]. MaxListeners is 10. Use emitter.setMaxListeners() to increase limit",
+		"MaxListenersExceededWarning: Possible EventEmitter memory leak detected. 2 x listeners added to [EventEmitter]. MaxListeners is 1. Use emitter.setMaxListeners() to increase limit",
+		"MaxListenersExceededWarning: Possible EventEmitter memory leak detected. 2 y listeners added to [Named]. MaxListeners is 1. Use emitter.setMaxListeners() to increase limit",
+	}
+	if len(warnings) != len(expected) {
+		t.Fatalf("expected %d warnings, got %q", len(expected), warnings)
+	}
+	for i := range expected {
+		if warnings[i] != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], warnings[i])
+		}
+	}
+}
+
+func TestOnce(t *testing.T) {
+	vm := createVM()
+	web.Enable(vm)
+	abort.Enable(vm)
+	runTests(t, vm, []struct {
+		script   string
+		expected string
+	}{
+		{`var results = []; const e = new EventEmitter();
+		  EventEmitter.once(e, "ready", {}).then((args) => results.push("ready:" + args.join("+") + ":" + e.listenerCount("error")));
+		  e.emit("ready", 1, 2); e.listenerCount("ready")`, "0"},
+		{`EventEmitter.once(e, "never").catch((err) => results.push("rejected:" + err.message + ":" + e.listenerCount("never")));
+		  e.emit("error", new Error("failed")); "ok"`, "ok"},
+		{`const target = new EventTarget(); EventEmitter.once(target, "ping").then(([event]) => results.push("target:" + event.type));
+		  target.dispatchEvent(new Event("ping")); EventEmitter.getEventListeners(target, "ping").length`, "0"},
+		{`const controller = new AbortController(); EventEmitter.once(e, "late", { signal: controller.signal })
+		    .catch((err) => results.push([err.name, err.code, err.cause.name, e.listenerCount("late")].join(":")));
+		  controller.abort(); "ok"`, "ok"},
+		{`EventEmitter.once(e, "x", { signal: AbortSignal.abort("why") }).catch((err) => results.push("pre:" + err.cause)); "ok"`, "ok"},
+		{`results.join()`, "ready:1+2:0,rejected:failed:0,target:ping,AbortError:ABORT_ERR:AbortError:0,pre:why"},
+		{`try { EventEmitter.once(e, "x", { signal: {} }) } catch (err) { err.code }`, "ERR_INVALID_ARG_TYPE"},
+	})
+}
+
+func TestStatics(t *testing.T) {
+	vm := createVM()
+	web.Enable(vm)
+	runTests(t, vm, []struct {
+		script   string
+		expected string
+	}{
+		{`const e = new EventEmitter(); const fn = () => {}; e.once("a", fn); e.on("a", () => {});
+		  EventEmitter.listenerCount(e, "a") + " " + (EventEmitter.getEventListeners(e, "a")[0] === fn)`, "2 true"},
+		{`const target = new EventTarget(); target.addEventListener("b", fn); EventEmitter.getEventListeners(target, "b")[0] === fn`, "true"},
+		{`EventEmitter.setMaxListeners(3, e, target); e.getMaxListeners()`, "3"},
+		{`EventEmitter.setMaxListeners(4); EventEmitter.defaultMaxListeners`, "4"},
+		{`try { EventEmitter.setMaxListeners(1, 5) } catch (err) { err.code }`, "ERR_INVALID_ARG_TYPE"},
+	})
+}
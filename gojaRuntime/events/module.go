@@ -0,0 +1,238 @@
+package events
+
+import (
+	"math"
+	"reflect"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/util"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/web"
+)
+
+const ModuleName = "events"
+
+// defaultMaxListeners is the number of listeners of one event above which an emitter warns about a possible leak.
+const defaultMaxListeners = 10
+
+type eventsModule struct {
+	r    *goja.Runtime
+	util *util.Util
+
+	constructor  *goja.Object
+	prototype    *goja.Object
+	errorMonitor *goja.Symbol
+
+	defaultMaxListeners float64
+}
+
+// validateMaxListeners checks a listener limit the way Node.js does, Infinity is allowed to disable the limit.
+func (m *eventsModule) validateMaxListeners(v goja.Value, name string) float64 {
+	if !isNumber(v) {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "`+name+`" argument must be of type number.`))
+	}
+	n := v.ToFloat()
+	if n < 0 || math.IsNaN(n) {
+		panic(errors.NewError(m.r, m.r.Get("RangeError").(*goja.Object), errors.ErrCodeOutOfRange,
+			`The value of "%s" is out of range. It must be a non-negative number. Received %s`, name, v.String()))
+	}
+	return n
+}
+
+func isNumber(v goja.Value) bool {
+	if v == nil || v.ExportType() == nil {
+		return false
+	}
+	kind := v.ExportType().Kind()
+	return kind == reflect.Int64 || kind == reflect.Float64
+}
+
+// warn reports a process warning through console.warn, which the runtime routes to the execution logger.
+func (m *eventsModule) warn(name, message string) {
+	console, ok := m.r.Get("console").(*goja.Object)
+	if !ok {
+		return
+	}
+	if warn, ok := goja.AssertFunction(console.Get("warn")); ok {
+		_, _ = warn(console, m.r.ToValue(name+": "+message))
+	}
+}
+
+func (m *eventsModule) createConstructor() *goja.Object {
+	f := m.r.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		m.init(call.This)
+		return call.This
+	}).(*goja.Object)
+
+	m.prototype = m.createPrototype()
+	f.DefineDataProperty("name", m.r.ToValue("EventEmitter"), goja.FLAG_FALSE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+	f.Set("prototype", m.prototype)
+	m.prototype.DefineDataProperty("constructor", f, goja.FLAG_TRUE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	f.DefineAccessorProperty("defaultMaxListeners", m.r.ToValue(func(goja.FunctionCall) goja.Value {
+		return m.r.ToValue(m.defaultMaxListeners)
+	}), m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		m.defaultMaxListeners = m.validateMaxListeners(call.Argument(0), "defaultMaxListeners")
+		return goja.Undefined()
+	}), goja.FLAG_FALSE, goja.FLAG_TRUE)
+	f.DefineDataProperty("errorMonitor", m.errorMonitor, goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	f.Set("EventEmitter", f)
+	f.Set("once", m.once)
+	f.Set("listenerCount", m.listenerCount)
+	f.Set("getEventListeners", m.getEventListeners)
+	f.Set("setMaxListeners", m.setMaxListeners)
+	return f
+}
+
+// once returns a promise fulfilled with the arguments of the next name event of an EventEmitter or EventTarget.
+// It is rejected by an error event of an EventEmitter and when options.signal aborts.
+func (m *eventsModule) once(call goja.FunctionCall) goja.Value {
+	r := m.r
+	emitter, ok := call.Argument(0).(*goja.Object)
+	if !ok {
+		panic(errors.NewTypeError(r, errors.ErrCodeInvalidArgType, `The "emitter" argument must be an instance of EventEmitter or EventTarget.`))
+	}
+	name := call.Argument(1)
+	var signal *goja.Object
+	if options, ok := call.Argument(2).(*goja.Object); ok {
+		if v := options.Get("signal"); v != nil && !goja.IsUndefined(v) {
+			if signal, ok = v.(*goja.Object); !ok || signal.Get("aborted") == nil {
+				panic(errors.NewTypeError(r, errors.ErrCodeInvalidArgType, `The "options.signal" property must be an instance of AbortSignal.`))
+			}
+		}
+	}
+
+	promise, resolve, reject := r.NewPromise()
+	if signal != nil && signal.Get("aborted").ToBoolean() {
+		reject(m.newAbortError(signal.Get("reason")))
+		return r.ToValue(promise)
+	}
+
+	var cleanup []func()
+	settle := func() {
+		for _, fn := range cleanup {
+			fn()
+		}
+	}
+	resolver := r.ToValue(func(call goja.FunctionCall) goja.Value {
+		settle()
+		resolve(r.NewArray(toInterfaces(call.Arguments)...))
+		return goja.Undefined()
+	})
+
+	if _, isTarget := web.EventListeners(emitter, ""); isTarget {
+		options := r.NewObject()
+		options.Set("once", true)
+		m.invoke(emitter, "addEventListener", name, resolver, options)
+		cleanup = append(cleanup, func() { m.invoke(emitter, "removeEventListener", name, resolver) })
+	} else {
+		m.invoke(emitter, "once", name, resolver)
+		cleanup = append(cleanup, func() { m.invoke(emitter, "removeListener", name, resolver) })
+		if name.String() != "error" {
+			errorListener := r.ToValue(func(call goja.FunctionCall) goja.Value {
+				settle()
+				reject(call.Argument(0))
+				return goja.Undefined()
+			})
+			m.invoke(emitter, "once", r.ToValue("error"), errorListener)
+			cleanup = append(cleanup, func() { m.invoke(emitter, "removeListener", r.ToValue("error"), errorListener) })
+		}
+	}
+
+	if signal != nil {
+		abortListener := r.ToValue(func(goja.FunctionCall) goja.Value {
+			settle()
+			reject(m.newAbortError(signal.Get("reason")))
+			return goja.Undefined()
+		})
+		options := r.NewObject()
+		options.Set("once", true)
+		m.invoke(signal, "addEventListener", r.ToValue("abort"), abortListener, options)
+		cleanup = append(cleanup, func() { m.invoke(signal, "removeEventListener", r.ToValue("abort"), abortListener) })
+	}
+	return r.ToValue(promise)
+}
+
+// invoke calls the method of o, so that emitters overriding the EventEmitter methods are honoured.
+func (m *eventsModule) invoke(o *goja.Object, method string, args ...goja.Value) goja.Value {
+	fn, ok := goja.AssertFunction(o.Get(method))
+	if !ok {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "emitter" argument must be an instance of EventEmitter or EventTarget.`))
+	}
+	res, err := fn(o, args...)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// newAbortError creates the AbortError Node.js rejects with when an operation is aborted by a signal.
+func (m *eventsModule) newAbortError(cause goja.Value) *goja.Object {
+	e := errors.NewError(m.r, nil, errors.ErrCodeAbort, "The operation was aborted")
+	e.Set("name", "AbortError")
+	if cause != nil && !goja.IsUndefined(cause) {
+		e.DefineDataProperty("cause", cause, goja.FLAG_TRUE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	}
+	return e
+}
+
+func (m *eventsModule) listenerCount(call goja.FunctionCall) goja.Value {
+	return m.invoke(call.Argument(0).ToObject(m.r), "listenerCount", call.Argument(1))
+}
+
+// getEventListeners returns a copy of the listeners of an EventEmitter or EventTarget for the given event.
+func (m *eventsModule) getEventListeners(call goja.FunctionCall) goja.Value {
+	emitter, ok := call.Argument(0).(*goja.Object)
+	if !ok {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "emitter" argument must be an instance of EventEmitter or EventTarget.`))
+	}
+	if listeners, ok := web.EventListeners(emitter, call.Argument(1).String()); ok {
+		return m.r.NewArray(toInterfaces(listeners)...)
+	}
+	return m.invoke(emitter, "listeners", call.Argument(1))
+}
+
+// setMaxListeners sets the limit of the given emitters, or the default limit when none are given.
+func (m *eventsModule) setMaxListeners(call goja.FunctionCall) goja.Value {
+	n := m.validateMaxListeners(call.Argument(0), "n")
+	if len(call.Arguments) < 2 {
+		m.defaultMaxListeners = n
+		return goja.Undefined()
+	}
+	for _, target := range call.Arguments[1:] {
+		// EventTargets never warn about their listeners, so they have no limit to set
+		if _, ok := web.EventListeners(target, ""); ok {
+			continue
+		}
+		o, ok := target.(*goja.Object)
+		if !ok {
+			panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "eventTargets" argument must be an instance of EventEmitter or EventTarget.`))
+		}
+		m.invoke(o, "setMaxListeners", call.Argument(0))
+	}
+	return goja.Undefined()
+}
+
+func toInterfaces(values []goja.Value) []interface{} {
+	items := make([]interface{}, len(values))
+	for i, v := range values {
+		items[i] = v
+	}
+	return items
+}
+
+func Require(runtime *goja.Runtime, module *goja.Object) {
+	m := &eventsModule{
+		r:                   runtime,
+		util:                util.New(runtime),
+		errorMonitor:        goja.NewSymbol("events.errorMonitor"),
+		defaultMaxListeners: defaultMaxListeners,
+	}
+	m.constructor = m.createConstructor()
+	module.Set("exports", m.constructor)
+}
+
+func init() {
+	require.RegisterCoreModule(ModuleName, Require)
+}
@@ -3,15 +3,29 @@ package goja_runtime
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/dop251/goja"
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/abort"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/buffer"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/crypto"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/encoding"
+	jsErrors "github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+	_ "github.com/kinde-oss/workflows-runtime/gojaRuntime/events"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/jwt"
+	_ "github.com/kinde-oss/workflows-runtime/gojaRuntime/path"
+	_ "github.com/kinde-oss/workflows-runtime/gojaRuntime/querystring"
 	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
 	urlModule "github.com/kinde-oss/workflows-runtime/gojaRuntime/url"
 	"github.com/kinde-oss/workflows-runtime/gojaRuntime/util"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/web"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/webassembly"
 	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
 )
 
@@ -26,10 +40,19 @@ type (
 	}
 
 	actionResult struct {
-		Context     *jsContext                          `json:"context"`
-		ExitResult  interface{}                         `json:"exit_result"`
-		RunMetadata *runtimesRegistry.ExecutionMetadata `json:"run_metadata"`
-		logger      runtimesRegistry.Logger
+		Context      *jsContext                          `json:"context"`
+		ExitResult   interface{}                         `json:"exit_result"`
+		RunMetadata  *runtimesRegistry.ExecutionMetadata `json:"run_metadata"`
+		AuditTrail   []runtimesRegistry.AuditRecord      `json:"audit_trail,omitempty"`
+		logger       runtimesRegistry.Logger
+		interceptors []NativeCallInterceptor
+		limiter      *callLimiter
+		overrides    bindingOverrides
+		signal       *executionSignal
+		limits       runtimesRegistry.RuntimeLimits
+		auditLock    sync.Mutex
+		// exports are the module.exports of a CommonJS workflow, or the namespace of an ES module one
+		exports *goja.Object
 	}
 	introspectedExport struct {
 		value    interface{}
@@ -39,14 +62,6 @@ type (
 	introspectionResult struct {
 		exports map[string]introspectedExport
 	}
-
-	JsContext interface {
-		GetValue(key string) interface{}
-		SetValue(key string, value interface{})
-	}
-	jsContext struct {
-		data map[string]interface{}
-	}
 )
 
 // ExecutionMetadata implements runtime_registry.ExecutionResult.
@@ -59,16 +74,6 @@ func (i introspectedExport) BindingsFrom(exportName string) map[string]runtimesR
 	return i.bindings
 }
 
-// GetValue implements JsContext.
-func (j jsContext) GetValue(key string) interface{} {
-	return j.data[key]
-}
-
-// SetValue implements JsContext.
-func (j jsContext) SetValue(key string, value interface{}) {
-	j.data[key] = value
-}
-
 // HasExport implements runtime_registry.IntrospectedExport.
 func (i introspectedExport) HasExport() bool {
 	return i.value != nil
@@ -122,28 +127,17 @@ func (a *actionResult) GetContext() runtimesRegistry.RuntimeContext {
 	return a.Context
 }
 
-// GetValue implements runtimesRegistry.RuntimeContext.
-func (j jsContext) GetValues() map[string]interface{} {
-	return j.data
-}
-
-// GetValueAsMap implements runtime_registry.RuntimeContext.
-func (j *jsContext) GetValueAsMap(key string) (map[string]interface{}, error) {
-	if value, ok := j.data[key]; ok {
-		switch v := value.(type) {
-		case map[string]interface{}:
-			return v, nil
-		default:
-			return nil, fmt.Errorf("value is not a map")
-		}
-	}
-	return nil, fmt.Errorf("key not found")
-}
-
 func (a *actionResult) GetExitResult() interface{} {
 	return a.ExitResult
 }
 
+// GetAuditTrail implements runtime_registry.ExecutionResult.
+func (a *actionResult) GetAuditTrail() []runtimesRegistry.AuditRecord {
+	a.auditLock.Lock()
+	defer a.auditLock.Unlock()
+	return append([]runtimesRegistry.AuditRecord(nil), a.AuditTrail...)
+}
+
 var registry = new(require.Registry)
 
 var builtInModules = map[string]func(e *GojaRunnerV1, vm *goja.Runtime, mountingPoint *goja.Object, result *actionResult, binding runtimesRegistry.BindingSettings){
@@ -155,10 +149,42 @@ var builtInModules = map[string]func(e *GojaRunnerV1, vm *goja.Runtime, mounting
 	"url": func(e *GojaRunnerV1, vm *goja.Runtime, _ *goja.Object, _ *actionResult, _ runtimesRegistry.BindingSettings) {
 		urlModule.Enable(vm)
 	},
+	"encoding": func(e *GojaRunnerV1, vm *goja.Runtime, _ *goja.Object, _ *actionResult, _ runtimesRegistry.BindingSettings) {
+		vm.Set("encoding", encoding.Enable(vm))
+	},
+	"buffer": func(e *GojaRunnerV1, vm *goja.Runtime, _ *goja.Object, _ *actionResult, _ runtimesRegistry.BindingSettings) {
+		buffer.Enable(vm)
+	},
+	"crypto": func(e *GojaRunnerV1, vm *goja.Runtime, _ *goja.Object, _ *actionResult, _ runtimesRegistry.BindingSettings) {
+		crypto.Enable(vm, crypto.Options{})
+	},
+	"jwt": func(e *GojaRunnerV1, vm *goja.Runtime, _ *goja.Object, _ *actionResult, binding runtimesRegistry.BindingSettings) {
+		jwt.EnableFromSettings(vm, binding.Settings)
+	},
 	"util": func(e *GojaRunnerV1, vm *goja.Runtime, _ *goja.Object, _ *actionResult, _ runtimesRegistry.BindingSettings) {
 		module := require.Require(vm, util.ModuleName).ToObject(vm)
 		vm.Set("util", module)
 	},
+	"abort": func(e *GojaRunnerV1, vm *goja.Runtime, _ *goja.Object, result *actionResult, _ runtimesRegistry.BindingSettings) {
+		abort.Enable(vm)
+		kinde, ok := vm.Get("kinde").(*goja.Object)
+		if !ok {
+			kinde = vm.NewObject()
+			vm.Set("kinde", kinde)
+		}
+		result.signal.js = abort.Follow(vm, result.signal.Signal)
+		kinde.Set("signal", result.signal.js)
+	},
+	"web": func(e *GojaRunnerV1, vm *goja.Runtime, _ *goja.Object, _ *actionResult, _ runtimesRegistry.BindingSettings) {
+		web.Enable(vm)
+	},
+	"webassembly": func(e *GojaRunnerV1, vm *goja.Runtime, _ *goja.Object, result *actionResult, _ runtimesRegistry.BindingSettings) {
+		// instantiating and calling modules is aborted with the execution
+		webassembly.Enable(vm, webassembly.Options{
+			Context:        result.signal.context(context.Background()),
+			MaxMemoryBytes: result.limits.MaxMemoryBytes,
+		})
+	},
 	"module": func(e *GojaRunnerV1, vm *goja.Runtime, mountingPoint *goja.Object, _ *actionResult, _ runtimesRegistry.BindingSettings) {
 		vm.Set("module", vm.NewObject())
 	},
@@ -169,6 +195,31 @@ func init() {
 	registry.RegisterNativeModule("url", urlModule.Require)
 }
 
+// registryFor returns the require registry for workflow. Workflows shipping modules or resolving with conditions get
+// a registry of their own, the compiled modules are cached by path and must not be shared with other workflows.
+func (runner *GojaRunnerV1) registryFor(workflow runtimesRegistry.WorkflowDescriptor) *require.Registry {
+	if len(workflow.Modules) == 0 && len(workflow.Conditions) == 0 {
+		return registry
+	}
+	key := workflow.GetHash() + "\x00" + strings.Join(workflow.Conditions, "\x00")
+	return runner.cache.cacheRegistry(key, func() *require.Registry {
+		options := []require.Option{}
+		if len(workflow.Conditions) > 0 {
+			options = append(options, require.WithConditions(append(slices.Clone(require.DefaultConditions), workflow.Conditions...)...))
+		}
+		if len(workflow.Modules) > 0 {
+			modules := make(map[string][]byte, len(workflow.Modules))
+			for p, module := range workflow.Modules {
+				modules[p] = module.Source
+			}
+			options = append(options, require.WithLoader(require.MapSourceLoader(modules)))
+		}
+		r := require.NewRegistry(options...)
+		r.RegisterNativeModule("url", urlModule.Require)
+		return r
+	})
+}
+
 var (
 	__nativeModules = nativeModules{
 		registered: map[string]*NativeModule{},
@@ -187,57 +238,129 @@ func newGojaRunner() runtimesRegistry.Runner {
 	return &runner
 }
 
-func (nm *NativeModule) setupModuleForVM(ctx context.Context, vm *goja.Runtime, actionResult *actionResult, parent *goja.Object, requestedName string, binding runtimesRegistry.BindingSettings) {
+func (nm *NativeModule) setupModuleForVM(ctx context.Context, vm *goja.Runtime, actionResult *actionResult, parent *goja.Object, modulePath string, requestedName string, binding runtimesRegistry.BindingSettings) {
 	for _, name := range strings.Split(requestedName, ".")[:1] {
-		if function, ok := nm.functions[name]; ok {
-			wrappedFunc := func(binding runtimesRegistry.BindingSettings, jsContext jsContext) func(args ...interface{}) (interface{}, error) {
-				return func(args ...interface{}) (interface{}, error) {
-					return function(ctx, binding, jsContext, args...)
-				}
-			}
-			parent.Set(name, vm.ToValue(wrappedFunc(binding, *actionResult.Context)))
+		if function, ok := actionResult.overrides.function(modulePath+"."+name, nm.functions[name]); ok {
+			parent.Set(name, bindNativeFunction(ctx, vm, actionResult, modulePath+"."+name, function, binding))
 		}
 
 		if name == "" {
 			for fname, function := range nm.functions {
-
-				wrappedFunc := func(binding runtimesRegistry.BindingSettings, jsContext jsContext) func(args ...interface{}) (interface{}, error) {
-					return func(args ...interface{}) (interface{}, error) {
-						return function(ctx, binding, jsContext, args...)
-					}
+				function, _ = actionResult.overrides.function(modulePath+"."+fname, function)
+				parent.Set(fname, bindNativeFunction(ctx, vm, actionResult, modulePath+"."+fname, function, binding))
+			}
+			// overrides may add functions the module does not register
+			for fname, function := range actionResult.overrides.functionsIn(modulePath) {
+				if _, registered := nm.functions[fname]; !registered {
+					parent.Set(fname, bindNativeFunction(ctx, vm, actionResult, modulePath+"."+fname, function, binding))
 				}
-
-				parent.Set(fname, vm.ToValue(wrappedFunc(binding, *actionResult.Context)))
 			}
 			return
 		}
 
-		if module, ok := nm.modules[name]; ok {
+		if module, ok := actionResult.overrides.module(modulePath+"."+name, nm.modules[name]); ok {
 			registeredModule := vm.NewObject()
 			parent.Set(name, registeredModule)
-			module.setupModuleForVM(ctx, vm, actionResult, registeredModule, strings.Join(strings.Split(requestedName, ".")[1:], "."), binding)
+			module.setupModuleForVM(ctx, vm, actionResult, registeredModule, modulePath+"."+name, strings.Join(strings.Split(requestedName, ".")[1:], "."), binding)
 		}
 	}
 }
 
+// bindNativeFunction wraps a native function so every call made from JS goes through the registered interceptors.
+// Returned Go errors and panics are thrown as JS exceptions, see jsErrors.FromGoError.
+func bindNativeFunction(ctx context.Context, vm *goja.Runtime, actionResult *actionResult, modulePath string, function NativeFunction, binding runtimesRegistry.BindingSettings) goja.Value {
+	return vm.ToValue(func(args ...interface{}) (interface{}, error) {
+		// the limits are checked within the interceptors, which see the rejected calls
+		release := func() {}
+		limited := func(ctx context.Context, binding runtimesRegistry.BindingSettings, jsContext JsContext, args ...interface{}) (interface{}, error) {
+			acquired, breach := actionResult.limiter.acquire(modulePath)
+			if breach != nil {
+				return nil, &LimitError{Breach: *breach}
+			}
+			release = acquired
+			return function(ctx, binding, jsContext, args...)
+		}
+		// a call returning a pending promise is in flight until the promise settles
+		pending := false
+		defer func() {
+			if !pending {
+				release()
+			}
+		}()
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				panic(jsErrors.FromPanic(vm, recovered))
+			}
+		}()
+
+		call := &NativeCall{
+			ModulePath: modulePath,
+			Arguments:  args,
+			Binding:    binding,
+			JsContext:  actionResult.Context.writtenBy(modulePath),
+			result:     actionResult,
+		}
+		result, err := actionResult.invokeNative(ctx, call, limited)
+		var limitErr *LimitError
+		if errors.As(err, &limitErr) {
+			panic(jsErrors.NewError(vm, nil, limitErr.Breach.Code, "%s", limitErr.Error()))
+		}
+		if err != nil {
+			panic(jsErrors.FromGoError(vm, err))
+		}
+		pending = releaseOnSettle(vm, result, release)
+		return result, nil
+	})
+}
+
+// releaseOnSettle calls release once result settles, when it is a pending promise, and tells whether it did so.
+func releaseOnSettle(vm *goja.Runtime, result interface{}, release func()) bool {
+	var promise *goja.Promise
+	switch value := result.(type) {
+	case *goja.Promise:
+		promise = value
+	case *goja.Object:
+		// checking the export type first, exporting other objects copies them
+		if value.ExportType() == reflect.TypeOf(promise) {
+			promise, _ = value.Export().(*goja.Promise)
+		}
+	}
+	if promise == nil || promise.State() != goja.PromiseStatePending {
+		return false
+	}
+
+	then, ok := goja.AssertFunction(vm.ToValue(promise).ToObject(vm).Get("then"))
+	if !ok {
+		return false
+	}
+	settled := vm.ToValue(func() { release() })
+	if _, err := then(vm.ToValue(promise), settled, settled); err != nil {
+		return false
+	}
+	return true
+}
+
 func (nm *nativeModules) setupModuleForVM(ctx context.Context, vm *goja.Runtime, actionResult *actionResult, requestedName string, binding runtimesRegistry.BindingSettings) {
 
 	for _, name := range strings.Split(requestedName, ".")[:1] {
-		if module, ok := nm.registered[name]; ok {
+		if module, ok := actionResult.overrides.module(name, nm.registered[name]); ok {
 			registeredModule := vm.Get(name)
 			if registeredModule == nil {
 				registeredModule = vm.NewObject()
 				vm.Set(name, registeredModule)
 			}
-			module.setupModuleForVM(ctx, vm, actionResult, registeredModule.(*goja.Object), strings.Join(strings.Split(requestedName, ".")[1:], "."), binding)
+			module.setupModuleForVM(ctx, vm, actionResult, registeredModule.(*goja.Object), name, strings.Join(strings.Split(requestedName, ".")[1:], "."), binding)
 		}
 	}
 
 }
 
+// NativeFunction is the signature of a Go function exposed to workflows through a NativeModule.
+type NativeFunction func(ctx context.Context, binding runtimesRegistry.BindingSettings, jsContext JsContext, args ...interface{}) (interface{}, error)
+
 // NativeModule represents a native module that can be registered and used in the runtime.
 type NativeModule struct {
-	functions map[string]func(ctx context.Context, binding runtimesRegistry.BindingSettings, jsContext JsContext, args ...interface{}) (interface{}, error)
+	functions map[string]NativeFunction
 	modules   map[string]*NativeModule
 	name      string
 }
@@ -245,7 +368,7 @@ type NativeModule struct {
 // RegisterNativeAPI registers a new native API which could be bound to and used at run-time.
 func RegisterNativeAPI(name string) *NativeModule {
 	result := &NativeModule{
-		functions: map[string]func(ctx context.Context, binding runtimesRegistry.BindingSettings, jsContext JsContext, args ...interface{}) (interface{}, error){},
+		functions: map[string]NativeFunction{},
 		modules:   map[string]*NativeModule{},
 		name:      name,
 	}
@@ -253,7 +376,17 @@ func RegisterNativeAPI(name string) *NativeModule {
 	return result
 }
 
+// NewNativeModule creates a native module which is not registered globally, e.g. to be used as a binding override in StartOptions.
+func NewNativeModule(name string) *NativeModule {
+	return &NativeModule{
+		functions: map[string]NativeFunction{},
+		modules:   map[string]*NativeModule{},
+		name:      name,
+	}
+}
+
 // AfterVMSetupFunc allows to set a function that will be called after the VM is setup.
+// The VM is a *sobek.Runtime, hosts written against dop251/goja must switch their import, see the README.
 func AfterVMSetupFunc(afterVmSetup func(ctx context.Context, vm *goja.Runtime)) {
 	if afterVmSetup != nil {
 		__afterVmSetupFunc = afterVmSetup
@@ -261,6 +394,7 @@ func AfterVMSetupFunc(afterVmSetup func(ctx context.Context, vm *goja.Runtime))
 }
 
 // BeforeVMSetupFunc allows to set a function that will be called before the VM is setup.
+// The VM is a *sobek.Runtime, hosts written against dop251/goja must switch their import, see the README.
 func BeforeVMSetupFunc(beforeVmSetup func(ctx context.Context, vm *goja.Runtime) context.Context) {
 	if beforeVmSetup != nil {
 		__beforeVmSetupFunc = beforeVmSetup
@@ -268,7 +402,7 @@ func BeforeVMSetupFunc(beforeVmSetup func(ctx context.Context, vm *goja.Runtime)
 }
 
 // RegisterNativeFunction registers a new native function which could be bound to and used at run-time.
-func (module *NativeModule) RegisterNativeFunction(name string, fn func(ctx context.Context, binding runtimesRegistry.BindingSettings, jsContext JsContext, args ...interface{}) (interface{}, error)) {
+func (module *NativeModule) RegisterNativeFunction(name string, fn NativeFunction) {
 
 	module.functions[name] = fn
 }
@@ -276,7 +410,7 @@ func (module *NativeModule) RegisterNativeFunction(name string, fn func(ctx cont
 // RegisterNativeAPI registers a new native API which could be bound to and used at run-time.
 func (module *NativeModule) RegisterNativeAPI(name string) *NativeModule {
 	result := &NativeModule{
-		functions: map[string]func(ctx context.Context, binding runtimesRegistry.BindingSettings, jsContext JsContext, args ...interface{}) (interface{}, error){},
+		functions: map[string]NativeFunction{},
 		modules:   map[string]*NativeModule{},
 		name:      name,
 	}
@@ -287,14 +421,19 @@ func (module *NativeModule) RegisterNativeAPI(name string) *NativeModule {
 func (e *GojaRunnerV1) Introspect(ctx context.Context, workflow runtimesRegistry.WorkflowDescriptor, options runtimesRegistry.IntrospectionOptions) (runtimesRegistry.IntrospectionResult, error) {
 	vm := goja.New()
 	ctx = __beforeVmSetupFunc(ctx, vm)
-	_, returnErr := e.setupVM(ctx, vm, workflow, options.Logger)
+	setupResult, returnErr := e.setupVM(ctx, vm, workflow, runtimesRegistry.StartOptions{Loggger: options.Logger}, true)
 	__afterVmSetupFunc(ctx, vm)
+	if setupResult != nil {
+		setupResult.signal.finish()
+	}
 
 	if returnErr != nil {
 		return nil, returnErr
 	}
-	module := vm.Get("module").ToObject(vm)
-	exports := module.Get("exports").ToObject(vm)
+	exports := setupResult.exports
+	if exports == nil {
+		exports = vm.NewObject()
+	}
 
 	introspectionResult := introspectionResult{
 		exports: map[string]introspectedExport{},
@@ -327,12 +466,13 @@ func (e *GojaRunnerV1) Execute(ctx context.Context, workflow runtimesRegistry.Wo
 
 	vm := goja.New()
 	ctx = __beforeVmSetupFunc(ctx, vm)
-	executionResult, returnErr := e.setupVM(ctx, vm, workflow, startOptions.Loggger)
+	executionResult, returnErr := e.setupVM(ctx, vm, workflow, startOptions, false)
 	__afterVmSetupFunc(ctx, vm)
 
 	if returnErr != nil {
 		return executionResult, returnErr
 	}
+	defer executionResult.signal.finish()
 
 	vmExecFunction := func(ctx context.Context) error {
 
@@ -340,12 +480,10 @@ func (e *GojaRunnerV1) Execute(ctx context.Context, workflow runtimesRegistry.Wo
 			executionResult.RunMetadata.ExecutionDuration = time.Since(startedAt)
 		}(executionResult.RunMetadata.StartedAt)
 
-		module := vm.Get("module").ToObject(vm)
-		exportsJs := module.Get("exports")
-		if exportsJs == nil {
+		exports := executionResult.exports
+		if exports == nil {
 			return fmt.Errorf("no exports found")
 		}
-		exports := exportsJs.ToObject(vm)
 
 		defaultExport := exports.Get("default")
 		if defaultExport == nil {
@@ -375,26 +513,12 @@ func (e *GojaRunnerV1) Execute(ctx context.Context, workflow runtimesRegistry.Wo
 		}
 
 		promise := result.Export().(*goja.Promise)
-		for {
-			if promise.State() == goja.PromiseStateRejected {
-				returnedError := promise.Result().String()
-
-				returnedError = strings.ReplaceAll(returnedError, "GoError: ", "")
-
-				exportedResult := promise.Result().ToObject(vm)
-				stackExport := exportedResult.Get("stack")
-				if exportedResult != nil && stackExport != nil {
-					errorText := fmt.Sprintf("%v", stackExport.Export())
-					errorText = strings.ReplaceAll(errorText, "GoError: ", "")
-					return fmt.Errorf("%v", errorText)
-				}
-
-				return fmt.Errorf("%v", returnedError)
-			}
-			if promise.State() != goja.PromiseStatePending {
-				break
-			}
-			time.Sleep(1 * time.Millisecond)
+		// the workflow is waiting for its promise, on abort it gets to settle it from its abort listeners
+		if err := executionResult.signal.settle(promise); err != nil {
+			return err
+		}
+		if promise.State() == goja.PromiseStateRejected {
+			return rejectionError(vm, promise)
 		}
 
 		executionResult.ExitResult = promise.Result().Export()
@@ -407,21 +531,55 @@ func (e *GojaRunnerV1) Execute(ctx context.Context, workflow runtimesRegistry.Wo
 	return executionResult, err
 }
 
-func (runner *GojaRunnerV1) setupVM(ctx context.Context, vm *goja.Runtime, workflow runtimesRegistry.WorkflowDescriptor, logger runtimesRegistry.Logger) (*actionResult, error) {
-	registry.Enable(vm)
+// rejectionError describes the reason of a rejected promise, with its stack when it is an Error.
+func rejectionError(vm *goja.Runtime, promise *goja.Promise) error {
+	returnedError := promise.Result().String()
+
+	returnedError = strings.ReplaceAll(returnedError, "GoError: ", "")
+
+	exportedResult := promise.Result().ToObject(vm)
+	stackExport := exportedResult.Get("stack")
+	if exportedResult != nil && stackExport != nil {
+		errorText := fmt.Sprintf("%v", stackExport.Export())
+		errorText = strings.ReplaceAll(errorText, "GoError: ", "")
+		return fmt.Errorf("%v", errorText)
+	}
+
+	return fmt.Errorf("%v", returnedError)
+}
 
-	runner.maxExecutionTimeout(ctx, vm, workflow.Limits.MaxExecutionDuration)
+func (runner *GojaRunnerV1) setupVM(ctx context.Context, vm *goja.Runtime, workflow runtimesRegistry.WorkflowDescriptor, startOptions runtimesRegistry.StartOptions, introspecting bool) (*actionResult, error) {
+	workflow, err := textWorkflow(runner.cache, workflow)
+	if err != nil {
+		return nil, err
+	}
+	requireModule := runner.registryFor(workflow).Enable(vm)
+
+	signal := newExecutionSignal(vm)
+	signal.watch(ctx, workflow.Limits.MaxExecutionDuration)
+	// native functions see the execution aborted through their context, including when the time limit is reached
+	ctx = signal.context(ctx)
 	vm.SetTimeSource(func() time.Time { return time.Now() })
 
 	executionResult := &actionResult{
-		logger: logger,
-		Context: &jsContext{
-			data: map[string]interface{}{},
-		},
+		logger:       startOptions.Loggger,
+		interceptors: __nativeCallInterceptors,
+		Context:      newJsContext(startOptions.InitialContext, startOptions.ReadOnlyContext),
 		RunMetadata: &runtimesRegistry.ExecutionMetadata{
 			StartedAt: time.Now(),
 		},
+		signal: signal,
+		limits: workflow.Limits,
+	}
+
+	executionResult.limiter = newCallLimiter(workflow, executionResult.RunMetadata)
+
+	overrides, err := newBindingOverrides(startOptions.BindingOverrides)
+	if err != nil {
+		signal.finish()
+		return nil, err
 	}
+	executionResult.overrides = overrides
 
 	for name, binding := range workflow.RequestedBindings {
 		if module, ok := builtInModules[name]; ok {
@@ -433,13 +591,36 @@ func (runner *GojaRunnerV1) setupVM(ctx context.Context, vm *goja.Runtime, workf
 		runner.nativeModules.setupModuleForVM(ctx, vm, executionResult, requestedName, requestedBinding)
 	}
 
+	// the requested bindings can also be imported, e.g. from "kinde:idToken" or "@kinde/runtime"
+	bindings := runner.nativeModules.bindingResolver(vm, workflow, introspecting)
+	requireModule.SetModuleResolver(bindings)
+
+	// import() is available to ES modules and scripts alike
+	workflowHash := workflow.GetHash()
+	loader := newESMLoader(workflow, workflowHash, runner.cache, bindings)
+	loader.enable(vm)
+
+	if workflow.ProcessedSource.ModuleFormat == runtimesRegistry.ModuleFormatESM {
+		record, err := loader.parseMain(workflow.ProcessedSource.Source)
+		if err != nil {
+			signal.finish()
+			return nil, fmt.Errorf("error parsing %w", err)
+		}
+		// top-level await runs during evaluation, the time it takes counts against MaxExecutionDuration
+		executionResult.exports, err = loader.evaluate(vm, record, signal)
+		if err != nil {
+			signal.finish()
+			return nil, err
+		}
+		return executionResult, nil
+	}
+
 	if vm.Get("module") == nil { //esModules prerequisite
 		vm.Set("module", vm.NewObject())
 	}
 
-	workflowHash := workflow.GetHash()
 	program, err := runner.cache.cacheProgram(workflowHash, func() (*goja.Program, error) {
-		ast, err := goja.Parse("main", string(workflow.ProcessedSource.Source))
+		ast, err := goja.Parse(mainModuleName, string(workflow.ProcessedSource.Source))
 
 		if err != nil {
 			return nil, fmt.Errorf("error parsing %w", err)
@@ -456,40 +637,39 @@ func (runner *GojaRunnerV1) setupVM(ctx context.Context, vm *goja.Runtime, workf
 	})
 
 	if err != nil {
+		signal.finish()
 		return nil, err
 	}
 
 	_, err = vm.RunProgram(program)
 	if err != nil {
+		signal.finish()
 		return nil, fmt.Errorf("%v", err.Error())
 	}
+	if module, ok := vm.Get("module").(*goja.Object); ok {
+		executionResult.exports, _ = module.Get("exports").(*goja.Object)
+	}
 	return executionResult, nil
 }
 
-func (*GojaRunnerV1) maxExecutionTimeout(ctx context.Context, vm *goja.Runtime, maxExecutionDuration time.Duration) {
-	go func() {
-		timer := time.NewTimer(maxExecutionDuration)
-		select {
-		case <-ctx.Done():
-			vm.Interrupt("execution cancelled by user")
-			if !timer.Stop() {
-				<-timer.C
-			}
-		case <-timer.C:
-			vm.Interrupt("execution time exceeded")
-		}
-	}()
-}
+func (*GojaRunnerV1) consoleEmulation(vm *goja.Runtime, mountingPoint *goja.Object, result *actionResult, _ runtimesRegistry.BindingSettings) {
+	inspector := util.New(vm)
 
-func (*GojaRunnerV1) consoleEmulation(_ *goja.Runtime, mountingPoint *goja.Object, result *actionResult, _ runtimesRegistry.BindingSettings) {
-
-	logFunc := func(level runtimesRegistry.LogLevel) func(arguments ...interface{}) (interface{}, error) {
-		return func(arguments ...interface{}) (interface{}, error) {
-			if result.logger == nil {
-				return arguments, nil
+	logFunc := func(level runtimesRegistry.LogLevel) func(call goja.FunctionCall) goja.Value {
+		return func(call goja.FunctionCall) goja.Value {
+			// primitives are logged as their Go values, objects the way util.inspect prints them
+			arguments := make([]interface{}, len(call.Arguments))
+			for i, arg := range call.Arguments {
+				if _, isObject := arg.(*goja.Object); isObject {
+					arguments[i] = inspector.Inspect(arg, util.DefaultInspectOptions())
+				} else {
+					arguments[i] = arg.Export()
+				}
+			}
+			if result.logger != nil {
+				result.logger.Log(level, arguments...)
 			}
-			result.logger.Log(level, arguments...)
-			return arguments, nil
+			return vm.ToValue(arguments)
 		}
 	}
 
@@ -0,0 +1,91 @@
+package goja_runtime
+
+import (
+	"context"
+	"time"
+
+	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
+)
+
+type (
+	// NativeCall describes a single invocation of a native function as seen by interceptors.
+	NativeCall struct {
+		// ModulePath is the fully qualified name of the called function, e.g. kinde.idToken.setCustomClaim
+		ModulePath string
+		// Arguments passed from JS, interceptors may replace them before calling next
+		Arguments []interface{}
+		// Binding settings the function was mounted with
+		Binding   runtimesRegistry.BindingSettings
+		JsContext JsContext
+		result    *actionResult
+	}
+
+	// NativeCallHandler continues the call chain, either with the next interceptor or the native function itself.
+	NativeCallHandler func(ctx context.Context, call *NativeCall) (interface{}, error)
+
+	// NativeCallInterceptor runs around every native function call.
+	// It may inspect or modify the call, deny it by returning an error without calling next, or alter the result.
+	NativeCallInterceptor func(ctx context.Context, call *NativeCall, next NativeCallHandler) (interface{}, error)
+)
+
+var __nativeCallInterceptors []NativeCallInterceptor
+
+// RegisterNativeCallInterceptor adds an interceptor to every native call of subsequent executions.
+// Interceptors run in registration order, the first registered being the outermost.
+// Not thread safe, should be called as part of init.
+func RegisterNativeCallInterceptor(interceptor NativeCallInterceptor) {
+	if interceptor != nil {
+		__nativeCallInterceptors = append(__nativeCallInterceptors, interceptor)
+	}
+}
+
+// Audit appends a record to the audit trail of the current execution, see ExecutionResult.GetAuditTrail.
+func (call *NativeCall) Audit(record runtimesRegistry.AuditRecord) {
+	if call.result == nil {
+		return
+	}
+	call.result.auditLock.Lock()
+	defer call.result.auditLock.Unlock()
+	call.result.AuditTrail = append(call.result.AuditTrail, record)
+}
+
+// AuditInterceptor returns an interceptor recording every native call to the execution audit trail.
+// redact, if not nil, is given a copy of the call arguments and returns the values to be recorded instead.
+func AuditInterceptor(redact func(modulePath string, args []interface{}) []interface{}) NativeCallInterceptor {
+	return func(ctx context.Context, call *NativeCall, next NativeCallHandler) (interface{}, error) {
+		// the record keeps its own slice, neither redact nor later interceptors change what the function receives
+		record := runtimesRegistry.AuditRecord{
+			ModulePath: call.ModulePath,
+			Arguments:  append([]interface{}(nil), call.Arguments...),
+			StartedAt:  time.Now(),
+		}
+		if redact != nil {
+			record.Arguments = redact(call.ModulePath, record.Arguments)
+		}
+
+		result, err := next(ctx, call)
+
+		record.Duration = time.Since(record.StartedAt)
+		record.Result = result
+		if err != nil {
+			record.Error = err.Error()
+		}
+		call.Audit(record)
+		return result, err
+	}
+}
+
+func (a *actionResult) invokeNative(ctx context.Context, call *NativeCall, function NativeFunction) (interface{}, error) {
+	handler := func(ctx context.Context, call *NativeCall) (interface{}, error) {
+		return function(ctx, call.Binding, call.JsContext, call.Arguments...)
+	}
+
+	for i := len(a.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := a.interceptors[i], handler
+		handler = func(ctx context.Context, call *NativeCall) (interface{}, error) {
+			return interceptor(ctx, call, next)
+		}
+	}
+
+	return handler(ctx, call)
+}
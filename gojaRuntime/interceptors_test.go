@@ -0,0 +1,117 @@
+package goja_runtime
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNativeCallInterceptors(t *testing.T) {
+	defer func(registered []NativeCallInterceptor) {
+		__nativeCallInterceptors = registered
+	}(__nativeCallInterceptors)
+	__nativeCallInterceptors = nil
+
+	api := RegisterNativeAPI("interceptorTest")
+	api.RegisterNativeFunction("echo", func(ctx context.Context, binding runtimesRegistry.BindingSettings, jsContext JsContext, args ...interface{}) (interface{}, error) {
+		return args[0], nil
+	})
+	api.RegisterNativeFunction("forbidden", func(ctx context.Context, binding runtimesRegistry.BindingSettings, jsContext JsContext, args ...interface{}) (interface{}, error) {
+		panic("must not be called")
+	})
+
+	seenBindings := []runtimesRegistry.BindingSettings{}
+	RegisterNativeCallInterceptor(AuditInterceptor(func(modulePath string, args []interface{}) []interface{} {
+		// redacting in place must not change the arguments of the call
+		for i := range args {
+			args[i] = "redacted"
+		}
+		return args
+	}))
+	RegisterNativeCallInterceptor(func(ctx context.Context, call *NativeCall, next NativeCallHandler) (interface{}, error) {
+		seenBindings = append(seenBindings, call.Binding)
+		if call.ModulePath == "interceptorTest.forbidden" {
+			return nil, fmt.Errorf("call to %v denied", call.ModulePath)
+		}
+		return next(ctx, call)
+	})
+
+	result, err := newGojaRunner().Execute(context.Background(), runtimesRegistry.WorkflowDescriptor{
+		Limits: runtimesRegistry.RuntimeLimits{
+			MaxExecutionDuration: 5 * time.Second,
+		},
+		ProcessedSource: runtimesRegistry.SourceDescriptor{
+			Source: []byte(`module.exports = { default: async function() {
+				let denied = false;
+				try { interceptorTest.forbidden() } catch (e) { denied = true }
+				return interceptorTest.echo("secret") + (denied ? " denied" : "");
+			}}`),
+		},
+		RequestedBindings: map[string]runtimesRegistry.BindingSettings{
+			"interceptorTest": {Settings: map[string]interface{}{"tenant": "a"}},
+		},
+	}, runtimesRegistry.StartOptions{})
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal("secret denied", result.GetExitResult())
+	assert.Len(seenBindings, 2)
+	assert.Equal("a", seenBindings[0].Settings["tenant"])
+
+	trail := result.GetAuditTrail()
+	if assert.Len(trail, 2) {
+		assert.Equal("interceptorTest.forbidden", trail[0].ModulePath)
+		assert.Equal("call to interceptorTest.forbidden denied", trail[0].Error)
+		assert.Equal("interceptorTest.echo", trail[1].ModulePath)
+		assert.Equal([]interface{}{"redacted"}, trail[1].Arguments)
+		assert.Equal("secret", trail[1].Result)
+	}
+}
+
+func TestRejectedCallsAreAudited(t *testing.T) {
+	defer func(registered []NativeCallInterceptor) {
+		__nativeCallInterceptors = registered
+	}(__nativeCallInterceptors)
+	__nativeCallInterceptors = nil
+
+	calls := 0
+	RegisterNativeAPI("auditLimitTest").RegisterNativeFunction("ping", func(ctx context.Context, binding runtimesRegistry.BindingSettings, jsContext JsContext, args ...interface{}) (interface{}, error) {
+		calls++
+		return "pong", nil
+	})
+	RegisterNativeCallInterceptor(AuditInterceptor(nil))
+
+	result, err := newGojaRunner().Execute(context.Background(), runtimesRegistry.WorkflowDescriptor{
+		Limits: runtimesRegistry.RuntimeLimits{
+			MaxExecutionDuration: 5 * time.Second,
+			BindingLimits: map[string]runtimesRegistry.BindingLimit{
+				"auditLimitTest.ping": {MaxCalls: 1},
+			},
+		},
+		ProcessedSource: runtimesRegistry.SourceDescriptor{
+			Source: []byte(`module.exports = { default: async function() {
+				auditLimitTest.ping();
+				try { auditLimitTest.ping() } catch (e) { return e.code }
+			}}`),
+		},
+		RequestedBindings: map[string]runtimesRegistry.BindingSettings{
+			"auditLimitTest": {},
+		},
+	}, runtimesRegistry.StartOptions{})
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal(ErrCodeCallLimitExceeded, result.GetExitResult())
+	assert.Equal(1, calls)
+
+	trail := result.GetAuditTrail()
+	if assert.Len(trail, 2) {
+		assert.Equal("pong", trail[0].Result)
+		assert.Equal("auditLimitTest.ping", trail[1].ModulePath)
+		assert.Equal("auditLimitTest.ping may not be called more than 1 times per execution", trail[1].Error)
+	}
+}
@@ -0,0 +1,136 @@
+package jwt
+
+import (
+	gocrypto "crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+type algorithm struct {
+	family string
+	hash   gocrypto.Hash
+}
+
+const (
+	familyHMAC    = "HS"
+	familyRSA     = "RS"
+	familyRSAPSS  = "PS"
+	familyECDSA   = "ES"
+	familyEd25519 = "EdDSA"
+)
+
+var algorithms = map[string]algorithm{
+	"HS256": {familyHMAC, gocrypto.SHA256},
+	"HS384": {familyHMAC, gocrypto.SHA384},
+	"HS512": {familyHMAC, gocrypto.SHA512},
+	"RS256": {familyRSA, gocrypto.SHA256},
+	"RS384": {familyRSA, gocrypto.SHA384},
+	"RS512": {familyRSA, gocrypto.SHA512},
+	"PS256": {familyRSAPSS, gocrypto.SHA256},
+	"PS384": {familyRSAPSS, gocrypto.SHA384},
+	"PS512": {familyRSAPSS, gocrypto.SHA512},
+	"ES256": {familyECDSA, gocrypto.SHA256},
+	"ES384": {familyECDSA, gocrypto.SHA384},
+	"ES512": {familyECDSA, gocrypto.SHA512},
+	"EdDSA": {familyEd25519, 0},
+}
+
+var errInvalidSignature = errors.New("invalid signature")
+
+func (a algorithm) digest(data []byte) []byte {
+	h := a.hash.New()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// fits reports whether key can be used with the algorithm family, both for signing and verification.
+func (a algorithm) fits(key interface{}) bool {
+	switch key.(type) {
+	case []byte:
+		return a.family == familyHMAC
+	case *rsa.PublicKey, *rsa.PrivateKey:
+		return a.family == familyRSA || a.family == familyRSAPSS
+	case *ecdsa.PublicKey, *ecdsa.PrivateKey:
+		return a.family == familyECDSA
+	case ed25519.PublicKey, ed25519.PrivateKey:
+		return a.family == familyEd25519
+	}
+	return false
+}
+
+func ecdsaSize(key *ecdsa.PublicKey) int {
+	return (key.Curve.Params().BitSize + 7) / 8
+}
+
+func (a algorithm) verify(key interface{}, signingInput, signature []byte) error {
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(a.hash.New, k)
+		mac.Write(signingInput)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return errInvalidSignature
+		}
+		return nil
+	case *rsa.PrivateKey:
+		return a.verify(&k.PublicKey, signingInput, signature)
+	case *rsa.PublicKey:
+		if a.family == familyRSAPSS {
+			return rsa.VerifyPSS(k, a.hash, a.digest(signingInput), signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		return rsa.VerifyPKCS1v15(k, a.hash, a.digest(signingInput), signature)
+	case *ecdsa.PrivateKey:
+		return a.verify(&k.PublicKey, signingInput, signature)
+	case *ecdsa.PublicKey:
+		size := ecdsaSize(k)
+		if len(signature) != 2*size {
+			return errInvalidSignature
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(k, a.digest(signingInput), r, s) {
+			return errInvalidSignature
+		}
+		return nil
+	case ed25519.PrivateKey:
+		return a.verify(k.Public(), signingInput, signature)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, signingInput, signature) {
+			return errInvalidSignature
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported key type %T", key)
+}
+
+func (a algorithm) sign(key interface{}, signingInput []byte) ([]byte, error) {
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(a.hash.New, k)
+		mac.Write(signingInput)
+		return mac.Sum(nil), nil
+	case *rsa.PrivateKey:
+		if a.family == familyRSAPSS {
+			return rsa.SignPSS(rand.Reader, k, a.hash, a.digest(signingInput), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		return rsa.SignPKCS1v15(nil, k, a.hash, a.digest(signingInput))
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, a.digest(signingInput))
+		if err != nil {
+			return nil, err
+		}
+		size := ecdsaSize(&k.PublicKey)
+		signature := make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+		return signature, nil
+	case ed25519.PrivateKey:
+		return ed25519.Sign(k, signingInput), nil
+	}
+	return nil, fmt.Errorf("a %T cannot be used for signing", key)
+}
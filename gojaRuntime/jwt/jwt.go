@@ -0,0 +1,370 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	goja "github.com/grafana/sobek"
+	jsErrors "github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+)
+
+const (
+	ErrCodeMalformed            = "ERR_JWT_MALFORMED"
+	ErrCodeInvalidSignature     = "ERR_JWT_INVALID_SIGNATURE"
+	ErrCodeKeyNotFound          = "ERR_JWT_KEY_NOT_FOUND"
+	ErrCodeAlgorithmNotAllowed  = "ERR_JWT_ALGORITHM_NOT_ALLOWED"
+	ErrCodeExpired              = "ERR_JWT_EXPIRED"
+	ErrCodeNotActive            = "ERR_JWT_NOT_ACTIVE"
+	ErrCodeClaimInvalid         = "ERR_JWT_CLAIM_INVALID"
+	ErrCodeSigningDisabled      = "ERR_JWT_SIGNING_DISABLED"
+	ErrCodeInvalidConfiguration = "ERR_JWT_INVALID_CONFIGURATION"
+)
+
+const headerTypeJWT = "JWT"
+
+var errMalformedToken = errors.New("token must consist of three base64url encoded parts")
+
+type parsedToken struct {
+	header       Header
+	headerJSON   []byte
+	payloadJSON  []byte
+	claims       map[string]interface{}
+	signingInput []byte
+	signature    []byte
+}
+
+type verifyOptions struct {
+	algorithms     []string
+	issuer         []string
+	audience       []string
+	subject        string
+	clockTolerance time.Duration
+}
+
+func decodeSegment(segment string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(segment)
+}
+
+func parseToken(token string) (*parsedToken, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errMalformedToken
+	}
+
+	parsed := &parsedToken{signingInput: []byte(parts[0] + "." + parts[1])}
+	var err error
+	if parsed.headerJSON, err = decodeSegment(parts[0]); err != nil {
+		return nil, errMalformedToken
+	}
+	if parsed.payloadJSON, err = decodeSegment(parts[1]); err != nil {
+		return nil, errMalformedToken
+	}
+	if parsed.signature, err = decodeSegment(parts[2]); err != nil {
+		return nil, errMalformedToken
+	}
+	if err := json.Unmarshal(parsed.headerJSON, &parsed.header); err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+	if err := json.Unmarshal(parsed.payloadJSON, &parsed.claims); err != nil || parsed.claims == nil {
+		return nil, errors.New("payload must be a JSON object")
+	}
+	return parsed, nil
+}
+
+func defaultAlgorithm(key interface{}) string {
+	switch k := key.(type) {
+	case []byte:
+		return "HS256"
+	case *rsa.PrivateKey, *rsa.PublicKey:
+		return "RS256"
+	case *ecdsa.PrivateKey:
+		return map[int]string{256: "ES256", 384: "ES384", 521: "ES512"}[k.Curve.Params().BitSize]
+	case ed25519.PrivateKey:
+		return "EdDSA"
+	}
+	return ""
+}
+
+func isPublicKey(key interface{}) bool {
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return true
+	}
+	return false
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *jwtModule) throw(code string, format string, args ...interface{}) {
+	panic(jsErrors.NewError(m.r, nil, code, append([]interface{}{format}, args...)...))
+}
+
+func (m *jwtModule) checkConfiguration() {
+	if m.configurationError != nil {
+		m.throw(ErrCodeInvalidConfiguration, "%s", m.configurationError.Error())
+	}
+}
+
+func (m *jwtModule) parseJSON(data []byte) goja.Value {
+	json := m.r.Get("JSON").ToObject(m.r)
+	parse, _ := goja.AssertFunction(json.Get("parse"))
+	v, err := parse(json, m.r.ToValue(string(data)))
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (m *jwtModule) stringify(v goja.Value) string {
+	json := m.r.Get("JSON").ToObject(m.r)
+	stringify, _ := goja.AssertFunction(json.Get("stringify"))
+	res, err := stringify(json, v)
+	if err != nil {
+		panic(err)
+	}
+	return res.String()
+}
+
+func (m *jwtModule) optionStrings(options *goja.Object, name string) []string {
+	v := options.Get(name)
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return nil
+	}
+	list, err := stringOrList(v.Export())
+	if err != nil {
+		panic(jsErrors.NewTypeError(m.r, jsErrors.ErrCodeInvalidArgType, `The "options.%s" property must be a string or an array of strings.`, name))
+	}
+	return list
+}
+
+func (m *jwtModule) optionSeconds(options *goja.Object, name string) (time.Duration, bool) {
+	v := options.Get(name)
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return 0, false
+	}
+	return time.Duration(v.ToFloat() * float64(time.Second)), true
+}
+
+func (m *jwtModule) parse(call goja.FunctionCall) *parsedToken {
+	token := call.Argument(0)
+	if _, ok := token.Export().(string); !ok {
+		panic(jsErrors.NewTypeError(m.r, jsErrors.ErrCodeInvalidArgType, `The "token" argument must be of type string.`))
+	}
+	parsed, err := parseToken(token.String())
+	if err != nil {
+		m.throw(ErrCodeMalformed, "%s", err.Error())
+	}
+	return parsed
+}
+
+func (m *jwtModule) result(parsed *parsedToken) *goja.Object {
+	result := m.r.NewObject()
+	result.Set("header", m.parseJSON(parsed.headerJSON))
+	result.Set("payload", m.parseJSON(parsed.payloadJSON))
+	return result
+}
+
+func (m *jwtModule) decode(call goja.FunctionCall) goja.Value {
+	parsed := m.parse(call)
+	result := m.result(parsed)
+	result.Set("signature", base64.RawURLEncoding.EncodeToString(parsed.signature))
+	return result
+}
+
+// verifySignature tries the configured keys first and falls back to the registered key providers.
+func (m *jwtModule) verifySignature(parsed *parsedToken, alg algorithm) {
+	found := false
+	for _, key := range m.keys {
+		if parsed.header.KeyID != "" && key.kid != "" && key.kid != parsed.header.KeyID {
+			continue
+		}
+		if key.alg != "" && key.alg != parsed.header.Algorithm || !alg.fits(key.key) {
+			continue
+		}
+		found = true
+		if alg.verify(key.key, parsed.signingInput, parsed.signature) == nil {
+			return
+		}
+	}
+
+	if !found {
+		for _, provider := range __keyProviders {
+			key, err := provider.VerificationKey(parsed.header)
+			if err != nil {
+				m.throw(ErrCodeKeyNotFound, "%s", err.Error())
+			}
+			if key == nil || !alg.fits(key) {
+				continue
+			}
+			found = true
+			if alg.verify(key, parsed.signingInput, parsed.signature) == nil {
+				return
+			}
+		}
+	}
+
+	if !found {
+		m.throw(ErrCodeKeyNotFound, "no key found to verify a %s token", parsed.header.Algorithm)
+	}
+	m.throw(ErrCodeInvalidSignature, "signature verification failed")
+}
+
+func (m *jwtModule) numericClaim(claims map[string]interface{}, name string) (float64, bool) {
+	v, ok := claims[name]
+	if !ok {
+		return 0, false
+	}
+	n, ok := v.(float64)
+	if !ok {
+		m.throw(ErrCodeClaimInvalid, `"%s" claim must be a number`, name)
+	}
+	return n, true
+}
+
+func (m *jwtModule) verifyClaims(claims map[string]interface{}, options verifyOptions) {
+	now := float64(m.options.Now().UnixNano()) / float64(time.Second)
+	tolerance := options.clockTolerance.Seconds()
+
+	if exp, ok := m.numericClaim(claims, "exp"); ok && now >= exp+tolerance {
+		m.throw(ErrCodeExpired, "token expired at %s", time.Unix(int64(exp), 0).UTC().Format(time.RFC3339))
+	}
+	if nbf, ok := m.numericClaim(claims, "nbf"); ok && now+tolerance < nbf {
+		m.throw(ErrCodeNotActive, "token is not valid before %s", time.Unix(int64(nbf), 0).UTC().Format(time.RFC3339))
+	}
+
+	if len(options.issuer) > 0 {
+		iss, _ := claims["iss"].(string)
+		if !contains(options.issuer, iss) {
+			m.throw(ErrCodeClaimInvalid, `unexpected "iss" claim value`)
+		}
+	}
+
+	if len(options.audience) > 0 {
+		audience, err := stringOrList(claims["aud"])
+		matched := false
+		for _, aud := range audience {
+			matched = matched || contains(options.audience, aud)
+		}
+		if err != nil || !matched {
+			m.throw(ErrCodeClaimInvalid, `unexpected "aud" claim value`)
+		}
+	}
+
+	if options.subject != "" {
+		if sub, _ := claims["sub"].(string); sub != options.subject {
+			m.throw(ErrCodeClaimInvalid, `unexpected "sub" claim value`)
+		}
+	}
+}
+
+func (m *jwtModule) verify(call goja.FunctionCall) goja.Value {
+	m.checkConfiguration()
+	parsed := m.parse(call)
+
+	options := verifyOptions{
+		issuer:         m.options.Issuer,
+		audience:       m.options.Audience,
+		clockTolerance: m.options.ClockTolerance,
+	}
+	if o, ok := call.Argument(1).(*goja.Object); ok {
+		options.algorithms = m.optionStrings(o, "algorithms")
+		if issuer := m.optionStrings(o, "issuer"); issuer != nil {
+			options.issuer = issuer
+		}
+		if audience := m.optionStrings(o, "audience"); audience != nil {
+			options.audience = audience
+		}
+		if subject := o.Get("subject"); subject != nil && !goja.IsUndefined(subject) {
+			options.subject = subject.String()
+		}
+		if tolerance, ok := m.optionSeconds(o, "clockTolerance"); ok {
+			options.clockTolerance = tolerance
+		}
+	}
+
+	alg, ok := algorithms[parsed.header.Algorithm]
+	if !ok || options.algorithms != nil && !contains(options.algorithms, parsed.header.Algorithm) {
+		m.throw(ErrCodeAlgorithmNotAllowed, "algorithm %q is not allowed", parsed.header.Algorithm)
+	}
+
+	m.verifySignature(parsed, alg)
+	m.verifyClaims(parsed.claims, options)
+	return m.result(parsed)
+}
+
+func (m *jwtModule) sign(call goja.FunctionCall) goja.Value {
+	m.checkConfiguration()
+	if m.signing == nil {
+		m.throw(ErrCodeSigningDisabled, "signing is not enabled, the binding configuration does not provide a signing key")
+	}
+
+	payload, ok := call.Argument(0).(*goja.Object)
+	if !ok {
+		panic(jsErrors.NewTypeError(m.r, jsErrors.ErrCodeInvalidArgType, `The "payload" argument must be of type object.`))
+	}
+	claims := map[string]interface{}{}
+	decoder := json.NewDecoder(strings.NewReader(m.stringify(payload)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&claims); err != nil {
+		panic(jsErrors.NewTypeError(m.r, jsErrors.ErrCodeInvalidArgType, `The "payload" argument must be of type object.`))
+	}
+
+	now := m.options.Now().Unix()
+	if _, ok := claims["iat"]; !ok {
+		claims["iat"] = now
+	}
+	if o, ok := call.Argument(1).(*goja.Object); ok {
+		if expiresIn, ok := m.optionSeconds(o, "expiresIn"); ok {
+			claims["exp"] = now + int64(expiresIn.Seconds())
+		}
+		if notBefore, ok := m.optionSeconds(o, "notBefore"); ok {
+			claims["nbf"] = now + int64(notBefore.Seconds())
+		}
+		if issuer := o.Get("issuer"); issuer != nil && !goja.IsUndefined(issuer) {
+			claims["iss"] = issuer.String()
+		}
+		if subject := o.Get("subject"); subject != nil && !goja.IsUndefined(subject) {
+			claims["sub"] = subject.String()
+		}
+		if audience := m.optionStrings(o, "audience"); audience != nil {
+			if len(audience) == 1 {
+				claims["aud"] = audience[0]
+			} else {
+				claims["aud"] = audience
+			}
+		}
+	}
+
+	header, _ := json.Marshal(Header{Algorithm: m.signing.alg, KeyID: m.signing.kid, Type: headerTypeJWT})
+	body, err := json.Marshal(claims)
+	if err != nil {
+		panic(jsErrors.NewTypeError(m.r, jsErrors.ErrCodeInvalidArgValue, "The payload could not be serialized: %v", err))
+	}
+
+	var token bytes.Buffer
+	token.WriteString(base64.RawURLEncoding.EncodeToString(header))
+	token.WriteByte('.')
+	token.WriteString(base64.RawURLEncoding.EncodeToString(body))
+	signature, err := algorithms[m.signing.alg].sign(m.signing.key, token.Bytes())
+	if err != nil {
+		m.throw(ErrCodeInvalidConfiguration, "%s", err.Error())
+	}
+	token.WriteByte('.')
+	token.WriteString(base64.RawURLEncoding.EncodeToString(signature))
+	return m.r.ToValue(token.String())
+}
@@ -0,0 +1,193 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
+)
+
+var (
+	testNow    = time.Unix(1700000000, 0)
+	testSecret = []byte("super-secret-signing-key")
+	testJWK    = map[string]interface{}{"kty": "oct", "kid": "hmac-1", "k": base64.RawURLEncoding.EncodeToString(testSecret)}
+)
+
+type staticKeyProvider struct {
+	kid string
+	key interface{}
+}
+
+func (p staticKeyProvider) VerificationKey(header Header) (interface{}, error) {
+	if header.KeyID == p.kid {
+		return p.key, nil
+	}
+	return nil, nil
+}
+
+func createToken(t *testing.T, alg string, kid string, key interface{}, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(Header{Algorithm: alg, KeyID: kid, Type: headerTypeJWT})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	if alg == "none" {
+		return input + "."
+	}
+	signature, err := algorithms[alg].sign(key, []byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func createVM(options Options) *goja.Runtime {
+	vm := goja.New()
+	new(require.Registry).Enable(vm)
+	options.Now = func() time.Time { return testNow }
+	Enable(vm, options)
+	return vm
+}
+
+func TestDecode(t *testing.T) {
+	vm := createVM(Options{})
+	vm.Set("token", createToken(t, "HS256", "hmac-1", testSecret, map[string]interface{}{"sub": "kp_123", "nested": map[string]interface{}{"a": 1}}))
+
+	tests := []struct {
+		script   string
+		expected string
+	}{
+		{`const { header, payload } = jwt.decode(token); [header.alg, header.kid, payload.sub, payload.nested.a].join(",")`, "HS256,hmac-1,kp_123,1"},
+		{`require("jwt").decode(token).payload.sub`, "kp_123"},
+		{`try { jwt.decode("a.b") } catch (e) { e.code }`, ErrCodeMalformed},
+		{`try { jwt.decode("e30.!!!.e30") } catch (e) { e.code }`, ErrCodeMalformed},
+		{`try { jwt.decode(42) } catch (e) { e.code }`, "ERR_INVALID_ARG_TYPE"},
+	}
+
+	for _, tc := range tests {
+		result, err := vm.RunString(tc.script)
+		if err != nil {
+			t.Fatalf("Failed to run %s: %v", tc.script, err)
+		}
+		if result.String() != tc.expected {
+			t.Fatalf("%s: expected '%s', got '%s'", tc.script, tc.expected, result.String())
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	RegisterKeyProvider(staticKeyProvider{kid: "provided", key: &ecKey.PublicKey})
+
+	now := testNow.Unix()
+	valid := map[string]interface{}{"iss": "https://example.kinde.com", "aud": []string{"api", "other"}, "sub": "kp_123", "exp": now + 60, "nbf": now - 60}
+	expired := map[string]interface{}{"exp": now - 10}
+	notActive := map[string]interface{}{"nbf": now + 10}
+
+	options := Options{
+		JWKS:   []map[string]interface{}{testJWK},
+		Issuer: []string{"https://example.kinde.com"},
+	}
+
+	tests := []struct {
+		token    string
+		script   string
+		expected string
+	}{
+		{createToken(t, "HS256", "hmac-1", testSecret, valid), `jwt.verify(token, {audience: "api"}).payload.sub`, "kp_123"},
+		{createToken(t, "HS384", "", testSecret, valid), `jwt.verify(token).header.alg`, "HS384"},
+		{createToken(t, "HS256", "hmac-1", testSecret, valid), `try { jwt.verify(token, {audience: "admin"}) } catch (e) { e.code }`, ErrCodeClaimInvalid},
+		{createToken(t, "HS256", "hmac-1", testSecret, valid), `try { jwt.verify(token, {issuer: ["https://evil.example"]}) } catch (e) { e.code }`, ErrCodeClaimInvalid},
+		{createToken(t, "HS256", "hmac-1", testSecret, valid), `try { jwt.verify(token, {subject: "kp_456"}) } catch (e) { e.code }`, ErrCodeClaimInvalid},
+		{createToken(t, "HS256", "hmac-1", testSecret, valid), `try { jwt.verify(token, {algorithms: ["RS256"]}) } catch (e) { e.code }`, ErrCodeAlgorithmNotAllowed},
+		{createToken(t, "HS256", "hmac-1", testSecret, expired), `try { jwt.verify(token) } catch (e) { e.code }`, ErrCodeExpired},
+		{createToken(t, "HS256", "hmac-1", testSecret, expired), `try { jwt.verify(token) } catch (e) { e.message }`, "token expired at 2023-11-14T22:13:10Z"},
+		{createToken(t, "HS256", "hmac-1", testSecret, expired), `jwt.verify(token, {clockTolerance: 30, issuer: []}).payload.exp > 0`, "true"},
+		{createToken(t, "HS256", "hmac-1", testSecret, notActive), `try { jwt.verify(token, {issuer: []}) } catch (e) { e.code }`, ErrCodeNotActive},
+		{createToken(t, "HS256", "hmac-1", []byte("wrong"), valid), `try { jwt.verify(token) } catch (e) { e.code }`, ErrCodeInvalidSignature},
+		{createToken(t, "HS256", "hmac-2", testSecret, valid), `try { jwt.verify(token) } catch (e) { e.code }`, ErrCodeKeyNotFound},
+		{createToken(t, "none", "", nil, valid), `try { jwt.verify(token) } catch (e) { e.code }`, ErrCodeAlgorithmNotAllowed},
+		{createToken(t, "ES256", "provided", ecKey, valid), `jwt.verify(token).payload.iss`, "https://example.kinde.com"},
+		{createToken(t, "ES256", "provided", otherKey, valid), `try { jwt.verify(token) } catch (e) { e.code }`, ErrCodeInvalidSignature},
+		{createToken(t, "ES256", "unknown", ecKey, valid), `try { jwt.verify(token) } catch (e) { e.code }`, ErrCodeKeyNotFound},
+	}
+
+	for _, tc := range tests {
+		vm := createVM(options)
+		vm.Set("token", tc.token)
+		result, err := vm.RunString(tc.script)
+		if err != nil {
+			t.Fatalf("Failed to run %s: %v", tc.script, err)
+		}
+		if result.String() != tc.expected {
+			t.Fatalf("%s: expected '%s', got '%s'", tc.script, tc.expected, result.String())
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	vm := createVM(Options{JWKS: []map[string]interface{}{testJWK}})
+	result, err := vm.RunString(`try { jwt.sign({sub: "kp_123"}) } catch (e) { [jwt.canSign, e.code].join(",") }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.String() != "false,"+ErrCodeSigningDisabled {
+		t.Fatalf("expected signing to be disabled, got %s", result.String())
+	}
+
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	settings := map[string]interface{}{
+		SettingSigningKey: map[string]interface{}{
+			"kty": "EC", "crv": "P-256", "kid": "signing-1",
+			"x": base64.RawURLEncoding.EncodeToString(ecKey.X.FillBytes(make([]byte, 32))),
+			"y": base64.RawURLEncoding.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32))),
+			"d": base64.RawURLEncoding.EncodeToString(ecKey.D.FillBytes(make([]byte, 32))),
+		},
+		SettingJWKS: map[string]interface{}{"keys": []interface{}{map[string]interface{}{
+			"kty": "EC", "crv": "P-256", "kid": "signing-1", "use": "sig",
+			"x": base64.RawURLEncoding.EncodeToString(ecKey.X.FillBytes(make([]byte, 32))),
+			"y": base64.RawURLEncoding.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32))),
+		}}},
+		SettingAudience: "api",
+	}
+
+	vm = goja.New()
+	EnableFromSettings(vm, settings)
+	result, err = vm.RunString(`
+		const token = jwt.sign({sub: "kp_123", roles: ["admin"]}, {expiresIn: 300, audience: "api", issuer: "https://example.kinde.com"});
+		const { header, payload } = jwt.verify(token);
+		[jwt.canSign, header.alg, header.kid, header.typ, payload.sub, payload.roles[0], payload.exp - payload.iat, payload.aud].join(",");
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.String() != "true,ES256,signing-1,JWT,kp_123,admin,300,api" {
+		t.Fatalf("unexpected signed token content %s", result.String())
+	}
+}
+
+func TestInvalidConfiguration(t *testing.T) {
+	for _, settings := range []map[string]interface{}{
+		{SettingJWKS: "not a key set"},
+		{SettingJWKS: []interface{}{map[string]interface{}{"kty": "RSA"}}},
+		{SettingSigningKey: map[string]interface{}{"kty": "oct", "k": "a2V5", "alg": "RS256"}},
+		{SettingSigningKey: map[string]interface{}{"kty": "OKP", "crv": "Ed25519", "x": base64.RawURLEncoding.EncodeToString(make([]byte, 32))}},
+		{SettingClockTolerance: "soon"},
+	} {
+		vm := goja.New()
+		EnableFromSettings(vm, settings)
+		result, err := vm.RunString(`try { jwt.verify("e30.e30.") } catch (e) { e.code }`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.String() != ErrCodeInvalidConfiguration {
+			t.Fatalf("%v: expected %s, got %s", settings, ErrCodeInvalidConfiguration, result.String())
+		}
+	}
+}
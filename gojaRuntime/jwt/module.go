@@ -0,0 +1,263 @@
+package jwt
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/crypto"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
+)
+
+const ModuleName = "jwt"
+
+// Binding settings understood by OptionsFromSettings.
+const (
+	SettingJWKS           = "jwks"
+	SettingSigningKey     = "signing_key"
+	SettingIssuer         = "issuer"
+	SettingAudience       = "audience"
+	SettingClockTolerance = "clock_tolerance"
+)
+
+type (
+	// Header is the JOSE header of a token.
+	Header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid,omitempty"`
+		Type      string `json:"typ,omitempty"`
+	}
+
+	// KeyProvider supplies verification keys from Go, e.g. from a JWKS cached by the host.
+	KeyProvider interface {
+		// VerificationKey returns the key for a token, as returned by crypto.ParseJWK, or nil when unknown.
+		VerificationKey(header Header) (interface{}, error)
+	}
+
+	// Options configure a jwt module instance.
+	Options struct {
+		// JWKS holds the keys tokens may be verified with, checked before the registered key providers.
+		JWKS []map[string]interface{}
+		// SigningKey enables jwt.sign when set, it is a JWK with an optional "alg" and "kid".
+		SigningKey map[string]interface{}
+		// Issuer and Audience are the defaults for the issuer and audience verify options.
+		Issuer   []string
+		Audience []string
+		// ClockTolerance is the leeway applied to the exp and nbf claims.
+		ClockTolerance time.Duration
+		// Now returns the current time, time.Now if nil.
+		Now func() time.Time
+	}
+
+	verificationKey struct {
+		kid string
+		alg string
+		key interface{}
+	}
+
+	jwtModule struct {
+		r       *goja.Runtime
+		options Options
+		keys    []verificationKey
+		signing *verificationKey
+		// configurationError is reported by verify and sign, an invalid configuration must not disable checks silently
+		configurationError error
+	}
+)
+
+var __keyProviders []KeyProvider
+
+// RegisterKeyProvider adds a key provider consulted by jwt.verify when none of the configured keys match.
+// Not thread safe, should be called as part of init.
+func RegisterKeyProvider(provider KeyProvider) {
+	if provider != nil {
+		__keyProviders = append(__keyProviders, provider)
+	}
+}
+
+func stringOrList(v interface{}) ([]string, error) {
+	switch value := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{value}, nil
+	case []string:
+		return value, nil
+	case []interface{}:
+		result := make([]string, 0, len(value))
+		for _, item := range value {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a string, got %T", item)
+			}
+			result = append(result, s)
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("expected a string or a list of strings, got %T", v)
+}
+
+// normalizeJSON converts v to the generic types produced by encoding/json, settings may hold Go values.
+func normalizeJSON(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// OptionsFromSettings reads the jwt configuration from binding settings. The "jwks" setting is either
+// a JWK set ({"keys": [...]}) or a list of JWKs, "signing_key" is a single JWK, "issuer" and "audience"
+// are strings or lists of strings and "clock_tolerance" is in seconds.
+func OptionsFromSettings(settings map[string]interface{}) (Options, error) {
+	options := Options{}
+
+	if jwks, ok := settings[SettingJWKS]; ok && jwks != nil {
+		keys, err := normalizeJSON(jwks)
+		if err != nil {
+			return options, fmt.Errorf("invalid %s: %w", SettingJWKS, err)
+		}
+		if object, ok := keys.(map[string]interface{}); ok {
+			keys = object["keys"]
+		}
+		list, ok := keys.([]interface{})
+		if !ok {
+			return options, fmt.Errorf("invalid %s: expected a JWK set or a list of keys", SettingJWKS)
+		}
+		for _, key := range list {
+			jwk, ok := key.(map[string]interface{})
+			if !ok {
+				return options, fmt.Errorf("invalid %s: expected a JWK, got %T", SettingJWKS, key)
+			}
+			options.JWKS = append(options.JWKS, jwk)
+		}
+	}
+
+	if signingKey, ok := settings[SettingSigningKey]; ok && signingKey != nil {
+		jwk, err := normalizeJSON(signingKey)
+		if err != nil {
+			return options, fmt.Errorf("invalid %s: %w", SettingSigningKey, err)
+		}
+		if options.SigningKey, ok = jwk.(map[string]interface{}); !ok {
+			return options, fmt.Errorf("invalid %s: expected a JWK", SettingSigningKey)
+		}
+	}
+
+	var err error
+	if options.Issuer, err = stringOrList(settings[SettingIssuer]); err != nil {
+		return options, fmt.Errorf("invalid %s: %w", SettingIssuer, err)
+	}
+	if options.Audience, err = stringOrList(settings[SettingAudience]); err != nil {
+		return options, fmt.Errorf("invalid %s: %w", SettingAudience, err)
+	}
+
+	switch tolerance := settings[SettingClockTolerance].(type) {
+	case nil:
+	case float64:
+		options.ClockTolerance = time.Duration(tolerance * float64(time.Second))
+	case int:
+		options.ClockTolerance = time.Duration(tolerance) * time.Second
+	case int64:
+		options.ClockTolerance = time.Duration(tolerance) * time.Second
+	default:
+		return options, fmt.Errorf("invalid %s: expected a number of seconds, got %T", SettingClockTolerance, tolerance)
+	}
+
+	return options, nil
+}
+
+func parseKey(jwk map[string]interface{}) (verificationKey, error) {
+	if use, ok := jwk["use"].(string); ok && use != "sig" {
+		return verificationKey{}, fmt.Errorf("key use %q is not sig", use)
+	}
+	key, err := crypto.ParseJWK(jwk)
+	if err != nil {
+		return verificationKey{}, err
+	}
+	result := verificationKey{key: key}
+	result.kid, _ = jwk["kid"].(string)
+	result.alg, _ = jwk["alg"].(string)
+	return result, nil
+}
+
+func newModule(runtime *goja.Runtime, options Options) *jwtModule {
+	m := &jwtModule{
+		r:       runtime,
+		options: options,
+	}
+	if m.options.Now == nil {
+		m.options.Now = time.Now
+	}
+
+	for _, jwk := range options.JWKS {
+		key, err := parseKey(jwk)
+		if err != nil {
+			m.configurationError = fmt.Errorf("invalid verification key: %w", err)
+			return m
+		}
+		m.keys = append(m.keys, key)
+	}
+
+	if options.SigningKey != nil {
+		key, err := parseKey(options.SigningKey)
+		if err == nil && key.alg == "" {
+			key.alg = defaultAlgorithm(key.key)
+		}
+		if err == nil && isPublicKey(key.key) {
+			err = fmt.Errorf("a public key cannot be used for signing")
+		}
+		if err == nil {
+			if alg, ok := algorithms[key.alg]; !ok || !alg.fits(key.key) {
+				err = fmt.Errorf("algorithm %q cannot be used with this key", key.alg)
+			}
+		}
+		if err != nil {
+			m.configurationError = fmt.Errorf("invalid signing key: %w", err)
+			return m
+		}
+		m.signing = &key
+	}
+	return m
+}
+
+func (m *jwtModule) exports(exports *goja.Object) {
+	exports.Set("decode", m.decode)
+	exports.Set("verify", m.verify)
+	exports.Set("sign", m.sign)
+	exports.Set("canSign", m.signing != nil)
+}
+
+func Require(runtime *goja.Runtime, module *goja.Object) {
+	newModule(runtime, Options{}).exports(module.Get("exports").(*goja.Object))
+}
+
+// Enable installs a jwt global configured with options into the runtime and returns it.
+// require("jwt") keeps returning an instance without configured keys.
+func Enable(runtime *goja.Runtime, options Options) *goja.Object {
+	return enable(runtime, newModule(runtime, options))
+}
+
+// EnableFromSettings is Enable with the options read from binding settings, see OptionsFromSettings.
+// Invalid settings make verify and sign throw rather than failing the whole execution.
+func EnableFromSettings(runtime *goja.Runtime, settings map[string]interface{}) *goja.Object {
+	options, err := OptionsFromSettings(settings)
+	m := newModule(runtime, options)
+	if err != nil {
+		m.configurationError = err
+	}
+	return enable(runtime, m)
+}
+
+func enable(runtime *goja.Runtime, m *jwtModule) *goja.Object {
+	exports := runtime.NewObject()
+	m.exports(exports)
+	runtime.Set(ModuleName, exports)
+	return exports
+}
+
+func init() {
+	require.RegisterNativeModule(ModuleName, Require)
+}
@@ -0,0 +1,124 @@
+package goja_runtime
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
+)
+
+const (
+	ErrCodeCallLimitExceeded        = "ERR_BINDING_CALL_LIMIT_EXCEEDED"
+	ErrCodeConcurrencyLimitExceeded = "ERR_BINDING_CONCURRENCY_LIMIT_EXCEEDED"
+)
+
+// callLimiter enforces BindingLimits for the native calls of a single execution.
+type callLimiter struct {
+	lock     sync.Mutex
+	limits   map[string]runtimesRegistry.BindingLimit
+	calls    map[string]int
+	inflight map[string]int
+	metadata *runtimesRegistry.ExecutionMetadata
+}
+
+func newCallLimiter(workflow runtimesRegistry.WorkflowDescriptor, metadata *runtimesRegistry.ExecutionMetadata) *callLimiter {
+	limiter := &callLimiter{
+		limits:   map[string]runtimesRegistry.BindingLimit{},
+		calls:    map[string]int{},
+		inflight: map[string]int{},
+		metadata: metadata,
+	}
+
+	for name, limit := range workflow.Limits.BindingLimits {
+		limiter.addLimit(name, limit)
+	}
+	for name, binding := range workflow.RequestedBindings {
+		if limit, ok := binding.Limits(); ok {
+			limiter.addLimit(name, limit)
+		}
+	}
+	return limiter
+}
+
+// addLimit registers a limit, keeping the stricter value when one was already declared for the binding
+func (l *callLimiter) addLimit(name string, limit runtimesRegistry.BindingLimit) {
+	existing := l.limits[name]
+	l.limits[name] = runtimesRegistry.BindingLimit{
+		MaxCalls:      stricterLimit(existing.MaxCalls, limit.MaxCalls),
+		MaxConcurrent: stricterLimit(existing.MaxConcurrent, limit.MaxConcurrent),
+	}
+}
+
+func stricterLimit(a, b int) int {
+	if a <= 0 {
+		return b
+	}
+	if b <= 0 || a < b {
+		return a
+	}
+	return b
+}
+
+// acquire accounts for a call to modulePath, the returned release func must be called once the call completes, which
+// for a call returning a pending promise is when the promise settles.
+// A non nil breach means the call must not proceed.
+func (l *callLimiter) acquire(modulePath string) (release func(), breach *runtimesRegistry.LimitBreach) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	matching := []string{}
+	for name, limit := range l.limits {
+		if modulePath != name && !strings.HasPrefix(modulePath, name+".") {
+			continue
+		}
+		if limit.MaxCalls > 0 && l.calls[name] >= limit.MaxCalls {
+			return nil, l.recordBreach(modulePath, name, ErrCodeCallLimitExceeded, limit.MaxCalls)
+		}
+		if limit.MaxConcurrent > 0 && l.inflight[name] >= limit.MaxConcurrent {
+			return nil, l.recordBreach(modulePath, name, ErrCodeConcurrencyLimitExceeded, limit.MaxConcurrent)
+		}
+		matching = append(matching, name)
+	}
+
+	for _, name := range matching {
+		l.calls[name]++
+		l.inflight[name]++
+	}
+
+	return func() {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		for _, name := range matching {
+			l.inflight[name]--
+		}
+	}, nil
+}
+
+func (l *callLimiter) recordBreach(modulePath string, binding string, code string, limit int) *runtimesRegistry.LimitBreach {
+	breach := runtimesRegistry.LimitBreach{
+		ModulePath: modulePath,
+		Binding:    binding,
+		Code:       code,
+		Limit:      limit,
+	}
+	l.metadata.LimitBreaches = append(l.metadata.LimitBreaches, breach)
+	return &breach
+}
+
+// LimitError is the error interceptors see for a native call rejected by a binding limit, the native function is not
+// called then.
+type LimitError struct {
+	Breach runtimesRegistry.LimitBreach
+}
+
+func (e *LimitError) Error() string {
+	return limitBreachMessage(&e.Breach)
+}
+
+func limitBreachMessage(breach *runtimesRegistry.LimitBreach) string {
+	if breach.Code == ErrCodeConcurrencyLimitExceeded {
+		return fmt.Sprintf("%v may not run more than %d times at once", breach.Binding, breach.Limit)
+	}
+	return fmt.Sprintf("%v may not be called more than %d times per execution", breach.Binding, breach.Limit)
+}
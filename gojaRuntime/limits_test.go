@@ -0,0 +1,115 @@
+package goja_runtime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	goja "github.com/grafana/sobek"
+	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBindingCallLimits(t *testing.T) {
+	api := RegisterNativeAPI("limitTest")
+	api.RegisterNativeFunction("ping", func(ctx context.Context, binding runtimesRegistry.BindingSettings, jsContext JsContext, args ...interface{}) (interface{}, error) {
+		return "pong", nil
+	})
+	api.RegisterNativeAPI("nested").RegisterNativeFunction("pong", func(ctx context.Context, binding runtimesRegistry.BindingSettings, jsContext JsContext, args ...interface{}) (interface{}, error) {
+		return "ping", nil
+	})
+
+	result, err := newGojaRunner().Execute(context.Background(), runtimesRegistry.WorkflowDescriptor{
+		Limits: runtimesRegistry.RuntimeLimits{
+			MaxExecutionDuration: 5 * time.Second,
+			BindingLimits: map[string]runtimesRegistry.BindingLimit{
+				"limitTest.ping": {MaxCalls: 2},
+			},
+		},
+		ProcessedSource: runtimesRegistry.SourceDescriptor{
+			Source: []byte(`module.exports = { default: async function() {
+				const codes = [];
+				for (let i = 0; i < 3; i++) {
+					try { limitTest.ping() } catch (e) { codes.push(e.code) }
+				}
+				for (let i = 0; i < 2; i++) {
+					try { limitTest.nested.pong() } catch (e) { codes.push(e.code) }
+				}
+				return codes;
+			}}`),
+		},
+		RequestedBindings: map[string]runtimesRegistry.BindingSettings{
+			"limitTest.ping": {},
+			"limitTest.nested": {Settings: map[string]interface{}{
+				"limits": map[string]interface{}{"max_calls": float64(1)},
+			}},
+		},
+	}, runtimesRegistry.StartOptions{})
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal([]interface{}{ErrCodeCallLimitExceeded, ErrCodeCallLimitExceeded}, result.GetExitResult())
+
+	breaches := result.ExecutionMetadata().LimitBreaches
+	if assert.Len(breaches, 2) {
+		assert.Equal(runtimesRegistry.LimitBreach{
+			ModulePath: "limitTest.ping",
+			Binding:    "limitTest.ping",
+			Code:       ErrCodeCallLimitExceeded,
+			Limit:      2,
+		}, breaches[0])
+		assert.Equal("limitTest.nested.pong", breaches[1].ModulePath)
+		assert.Equal("limitTest.nested", breaches[1].Binding)
+	}
+}
+
+func TestCallLimiterConcurrency(t *testing.T) {
+	limiter := newCallLimiter(runtimesRegistry.WorkflowDescriptor{
+		Limits: runtimesRegistry.RuntimeLimits{
+			BindingLimits: map[string]runtimesRegistry.BindingLimit{
+				"kinde":       {MaxConcurrent: 1},
+				"kinde.fetch": {MaxCalls: 5, MaxConcurrent: 3},
+			},
+		},
+	}, &runtimesRegistry.ExecutionMetadata{})
+
+	assert := assert.New(t)
+	release, breach := limiter.acquire("kinde.fetch")
+	assert.Nil(breach)
+
+	_, breach = limiter.acquire("kinde.idToken.setCustomClaim")
+	if assert.NotNil(breach) {
+		assert.Equal(ErrCodeConcurrencyLimitExceeded, breach.Code)
+		assert.Equal("kinde", breach.Binding)
+	}
+
+	release()
+	_, breach = limiter.acquire("kinde.idToken.setCustomClaim")
+	assert.Nil(breach)
+	_, breach = limiter.acquire("other.fetch")
+	assert.Nil(breach)
+}
+
+func TestReleaseOnSettle(t *testing.T) {
+	assert := assert.New(t)
+	vm := goja.New()
+	released := 0
+	release := func() { released++ }
+
+	promise, resolve, _ := vm.NewPromise()
+	assert.True(releaseOnSettle(vm, promise, release))
+	assert.Equal(0, released)
+	resolve("done")
+	assert.Equal(1, released)
+
+	promise, _, reject := vm.NewPromise()
+	assert.True(releaseOnSettle(vm, vm.ToValue(promise), release))
+	reject("failed")
+	assert.Equal(2, released)
+
+	// settled promises and other values are released by the caller
+	assert.False(releaseOnSettle(vm, promise, release))
+	assert.False(releaseOnSettle(vm, vm.NewObject(), release))
+	assert.False(releaseOnSettle(vm, "pong", release))
+	assert.Equal(2, released)
+}
@@ -0,0 +1,73 @@
+package goja_runtime
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
+)
+
+// bindingOverrides holds the per-execution replacements of native functions and modules, keyed by binding path
+type bindingOverrides struct {
+	functions map[string]NativeFunction
+	modules   map[string]*NativeModule
+}
+
+// newBindingOverrides validates StartOptions.BindingOverrides, the goja runtime accepts a NativeFunction
+// (or a func with the same signature) to replace a function and a *NativeModule to replace a whole module. Overrides
+// of functions which are not registered add them, wherever their path is requested.
+func newBindingOverrides(overrides map[string]interface{}) (bindingOverrides, error) {
+	result := bindingOverrides{
+		functions: map[string]NativeFunction{},
+		modules:   map[string]*NativeModule{},
+	}
+	for path, override := range overrides {
+		switch o := override.(type) {
+		case NativeFunction:
+			result.functions[path] = o
+		case func(ctx context.Context, binding runtimesRegistry.BindingSettings, jsContext JsContext, args ...interface{}) (interface{}, error):
+			result.functions[path] = o
+		case *NativeModule:
+			result.modules[path] = o
+		default:
+			return bindingOverrides{}, fmt.Errorf("unsupported binding override for %v: %T", path, override)
+		}
+	}
+	return result, nil
+}
+
+// function returns the implementation to mount at path, the override taking precedence over the registered one
+func (o bindingOverrides) function(path string, registered NativeFunction) (NativeFunction, bool) {
+	if override, ok := o.functions[path]; ok {
+		return override, true
+	}
+	return registered, registered != nil
+}
+
+// functionsIn returns the overrides of functions directly under modulePath, by function name
+func (o bindingOverrides) functionsIn(modulePath string) map[string]NativeFunction {
+	result := map[string]NativeFunction{}
+	for path, function := range o.functions {
+		if name, ok := strings.CutPrefix(path, modulePath+"."); ok && !strings.Contains(name, ".") {
+			result[name] = function
+		}
+	}
+	return result
+}
+
+// module returns the module to mount at path, the override taking precedence over the registered one. A path with
+// neither has an empty module when functions below it are overridden, so that they get mounted.
+func (o bindingOverrides) module(path string, registered *NativeModule) (*NativeModule, bool) {
+	if override, ok := o.modules[path]; ok {
+		return override, true
+	}
+	if registered == nil {
+		for functionPath := range o.functions {
+			if strings.HasPrefix(functionPath, path+".") {
+				return NewNativeModule(path[strings.LastIndex(path, ".")+1:]), true
+			}
+		}
+	}
+	return registered, registered != nil
+}
@@ -0,0 +1,101 @@
+package goja_runtime
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBindingOverrides(t *testing.T) {
+	api := RegisterNativeAPI("overrideTest")
+	api.RegisterNativeFunction("fetch", func(ctx context.Context, binding runtimesRegistry.BindingSettings, jsContext JsContext, args ...interface{}) (interface{}, error) {
+		return "registered fetch", nil
+	})
+	api.RegisterNativeAPI("claims").RegisterNativeFunction("get", func(ctx context.Context, binding runtimesRegistry.BindingSettings, jsContext JsContext, args ...interface{}) (interface{}, error) {
+		return "registered claims", nil
+	})
+
+	execute := func(overrides map[string]interface{}) (runtimesRegistry.ExecutionResult, error) {
+		return newGojaRunner().Execute(context.Background(), runtimesRegistry.WorkflowDescriptor{
+			Limits: runtimesRegistry.RuntimeLimits{
+				MaxExecutionDuration: 5 * time.Second,
+			},
+			ProcessedSource: runtimesRegistry.SourceDescriptor{
+				Source: []byte(`module.exports = { default: async function() {
+					return overrideTest.fetch() + ", " + overrideTest.claims.get();
+				}}`),
+			},
+			RequestedBindings: map[string]runtimesRegistry.BindingSettings{
+				"overrideTest.fetch":  {},
+				"overrideTest.claims": {},
+			},
+		}, runtimesRegistry.StartOptions{BindingOverrides: overrides})
+	}
+
+	tenantClaims := NewNativeModule("claims")
+	tenantClaims.RegisterNativeFunction("get", func(ctx context.Context, binding runtimesRegistry.BindingSettings, jsContext JsContext, args ...interface{}) (interface{}, error) {
+		return "tenant claims", nil
+	})
+
+	assert := assert.New(t)
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				result, err := execute(nil)
+				assert.Nil(err)
+				assert.Equal("registered fetch, registered claims", result.GetExitResult())
+				return
+			}
+
+			result, err := execute(map[string]interface{}{
+				"overrideTest.fetch": func(ctx context.Context, binding runtimesRegistry.BindingSettings, jsContext JsContext, args ...interface{}) (interface{}, error) {
+					return fmt.Sprintf("fetch double %d", i), nil
+				},
+				"overrideTest.claims": tenantClaims,
+			})
+			assert.Nil(err)
+			assert.Equal(fmt.Sprintf("fetch double %d, tenant claims", i), result.GetExitResult())
+		}(i)
+	}
+	wg.Wait()
+
+	_, err := execute(map[string]interface{}{"overrideTest.fetch": "not a function"})
+	assert.EqualError(err, "unsupported binding override for overrideTest.fetch: string")
+
+	// overrides of functions which are not registered are mounted too
+	result, err := newGojaRunner().Execute(context.Background(), runtimesRegistry.WorkflowDescriptor{
+		Limits: runtimesRegistry.RuntimeLimits{
+			MaxExecutionDuration: 5 * time.Second,
+		},
+		ProcessedSource: runtimesRegistry.SourceDescriptor{
+			Source: []byte(`module.exports = { default: async function() {
+				return [overrideTest.claims.set(), overrideTest.audit(), unregisteredTest.nested.ping()];
+			}}`),
+		},
+		RequestedBindings: map[string]runtimesRegistry.BindingSettings{
+			"overrideTest.claims":          {},
+			"overrideTest.audit":           {},
+			"unregisteredTest.nested.ping": {},
+		},
+	}, runtimesRegistry.StartOptions{BindingOverrides: map[string]interface{}{
+		"overrideTest.claims.set":      constant("set"),
+		"overrideTest.audit":           constant("audit"),
+		"unregisteredTest.nested.ping": constant("pong"),
+	}})
+	assert.Nil(err)
+	assert.Equal([]interface{}{"set", "audit", "pong"}, result.GetExitResult())
+}
+
+func constant(value interface{}) NativeFunction {
+	return func(ctx context.Context, binding runtimesRegistry.BindingSettings, jsContext JsContext, args ...interface{}) (interface{}, error) {
+		return value, nil
+	}
+}
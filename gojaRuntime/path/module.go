@@ -0,0 +1,132 @@
+package path
+
+import (
+	"reflect"
+	"strconv"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
+)
+
+const ModuleName = "path"
+
+type pathModule struct {
+	r *goja.Runtime
+}
+
+func (m *pathModule) validateString(v goja.Value, name string) string {
+	if _, isObject := v.(*goja.Object); isObject || v == nil || v.ExportType() == nil || v.ExportType().Kind() != reflect.String {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "`+name+`" argument must be of type string.`))
+	}
+	return v.String()
+}
+
+func (m *pathModule) paths(call goja.FunctionCall) []string {
+	paths := make([]string, len(call.Arguments))
+	for i, arg := range call.Arguments {
+		paths[i] = m.validateString(arg, "paths["+strconv.Itoa(i)+"]")
+	}
+	return paths
+}
+
+func (m *pathModule) resolve(call goja.FunctionCall) goja.Value {
+	return m.r.ToValue(resolve(m.paths(call)...))
+}
+
+func (m *pathModule) normalize(call goja.FunctionCall) goja.Value {
+	return m.r.ToValue(normalize(m.validateString(call.Argument(0), "path")))
+}
+
+func (m *pathModule) isAbsolute(call goja.FunctionCall) goja.Value {
+	return m.r.ToValue(isAbsolute(m.validateString(call.Argument(0), "path")))
+}
+
+func (m *pathModule) join(call goja.FunctionCall) goja.Value {
+	return m.r.ToValue(join(m.paths(call)...))
+}
+
+func (m *pathModule) relative(call goja.FunctionCall) goja.Value {
+	from := m.validateString(call.Argument(0), "from")
+	to := m.validateString(call.Argument(1), "to")
+	return m.r.ToValue(relative(from, to))
+}
+
+func (m *pathModule) toNamespacedPath(call goja.FunctionCall) goja.Value {
+	// namespaced paths only exist on Windows
+	return call.Argument(0)
+}
+
+func (m *pathModule) dirname(call goja.FunctionCall) goja.Value {
+	return m.r.ToValue(dirname(m.validateString(call.Argument(0), "path")))
+}
+
+func (m *pathModule) basename(call goja.FunctionCall) goja.Value {
+	path := m.validateString(call.Argument(0), "path")
+	suffix := ""
+	if v := call.Argument(1); !goja.IsUndefined(v) {
+		suffix = m.validateString(v, "suffix")
+	}
+	return m.r.ToValue(basename(path, suffix))
+}
+
+func (m *pathModule) extname(call goja.FunctionCall) goja.Value {
+	return m.r.ToValue(extname(m.validateString(call.Argument(0), "path")))
+}
+
+func (m *pathModule) parse(call goja.FunctionCall) goja.Value {
+	p := parse(m.validateString(call.Argument(0), "path"))
+	o := m.r.NewObject()
+	o.Set("root", p.root)
+	o.Set("dir", p.dir)
+	o.Set("base", p.base)
+	o.Set("ext", p.ext)
+	o.Set("name", p.name)
+	return o
+}
+
+func (m *pathModule) format(call goja.FunctionCall) goja.Value {
+	o, ok := call.Argument(0).(*goja.Object)
+	if !ok {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "pathObject" argument must be of type object.`))
+	}
+	field := func(name string) string {
+		if v := o.Get(name); v != nil && v.ToBoolean() {
+			return v.String()
+		}
+		return ""
+	}
+	return m.r.ToValue(format(parsedPath{
+		root: field("root"),
+		dir:  field("dir"),
+		base: field("base"),
+		ext:  field("ext"),
+		name: field("name"),
+	}))
+}
+
+// Require exports the POSIX implementation, which is also available as path.posix. There is no path.win32.
+func Require(runtime *goja.Runtime, module *goja.Object) {
+	exports := module.Get("exports").(*goja.Object)
+	m := &pathModule{
+		r: runtime,
+	}
+	exports.Set("resolve", m.resolve)
+	exports.Set("normalize", m.normalize)
+	exports.Set("isAbsolute", m.isAbsolute)
+	exports.Set("join", m.join)
+	exports.Set("relative", m.relative)
+	exports.Set("toNamespacedPath", m.toNamespacedPath)
+	exports.Set("dirname", m.dirname)
+	exports.Set("basename", m.basename)
+	exports.Set("extname", m.extname)
+	exports.Set("format", m.format)
+	exports.Set("parse", m.parse)
+	exports.Set("sep", "/")
+	exports.Set("delimiter", ":")
+	exports.Set("posix", exports)
+}
+
+func init() {
+	require.RegisterCoreModule(ModuleName, Require)
+}
@@ -0,0 +1,98 @@
+package path
+
+import (
+	"testing"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
+)
+
+func createVM() *goja.Runtime {
+	vm := goja.New()
+	new(require.Registry).Enable(vm)
+	vm.Set("path", require.Require(vm, ModuleName))
+	return vm
+}
+
+func runTests(t *testing.T, vm *goja.Runtime, tests []struct {
+	script   string
+	expected string
+}) {
+	t.Helper()
+	for _, tc := range tests {
+		result, err := vm.RunString(tc.script)
+		if err != nil {
+			t.Fatalf("Failed to run %s: %v", tc.script, err)
+		}
+		if result.String() != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.script, tc.expected, result.String())
+		}
+	}
+}
+
+func TestJoinAndNormalize(t *testing.T) {
+	runTests(t, createVM(), []struct {
+		script   string
+		expected string
+	}{
+		{`path.posix === path && require("node:path") === path`, "true"},
+		{`path.join("/foo", "bar", "baz/asdf", "quux", "..")`, "/foo/bar/baz/asdf"},
+		{`path.posix.join("a", "", "b/")`, "a/b/"},
+		{`path.join()`, "."},
+		{`path.join("", "")`, "."},
+		{`path.join("..", "../a")`, "../../a"},
+		{`path.join("/", "..", "a")`, "/a"},
+		{`path.normalize("/foo/bar//baz/asdf/quux/..")`, "/foo/bar/baz/asdf"},
+		{`path.normalize("./")`, "./"},
+		{`path.normalize("a/../..")`, ".."},
+		{`path.normalize("")`, "."},
+		{`path.normalize("//a")`, "/a"},
+		{`try { path.join("a", 1) } catch (e) { e.code + " " + e.message }`, `ERR_INVALID_ARG_TYPE The "paths[1]" argument must be of type string.`},
+	})
+}
+
+func TestResolveAndRelative(t *testing.T) {
+	runTests(t, createVM(), []struct {
+		script   string
+		expected string
+	}{
+		{`path.resolve("/foo/bar", "./baz")`, "/foo/bar/baz"},
+		{`path.resolve("/foo/bar", "/tmp/file/")`, "/tmp/file"},
+		{`path.resolve("wwwroot", "static_files/png/", "../gif/image.gif")`, "/wwwroot/static_files/gif/image.gif"},
+		{`path.resolve()`, "/"},
+		{`path.relative("/data/orandea/test/aaa", "/data/orandea/impl/bbb")`, "../../impl/bbb"},
+		{`path.relative("/foo/bar", "/foo/bar/baz")`, "baz"},
+		{`path.relative("/foo/bar/baz", "/foo/bar")`, ".."},
+		{`path.relative("/", "/foo")`, "foo"},
+		{`path.relative("/foo", "/")`, ".."},
+		{`path.relative("/a/b", "/a/b")`, ""},
+		{`path.relative("/foo/bar", "/foo/barbaz")`, "../barbaz"},
+		{`path.isAbsolute("/a") + " " + path.isAbsolute("a") + " " + path.isAbsolute("")`, "true false false"},
+		{`path.toNamespacedPath("/a")`, "/a"},
+	})
+}
+
+func TestPathParts(t *testing.T) {
+	runTests(t, createVM(), []struct {
+		script   string
+		expected string
+	}{
+		{`path.basename("/foo/bar/baz/asdf/quux.html")`, "quux.html"},
+		{`path.basename("/foo/bar/baz/asdf/quux.html", ".html")`, "quux"},
+		{`path.basename("/a/b/")`, "b"},
+		{`path.basename("aaa/bbb", "bbb")`, "bbb"},
+		{`path.basename("file.js", "file.js")`, ""},
+		{`path.basename("/")`, ""},
+		{`path.dirname("/foo/bar/baz/asdf/quux")`, "/foo/bar/baz/asdf"},
+		{`path.dirname("/a/") + " " + path.dirname("a") + " " + path.dirname("//a") + " " + path.dirname("")`, "/ . // ."},
+		{`[path.extname("index.html"), path.extname("index.coffee.md"), path.extname("index."), path.extname("index"), path.extname(".index"), path.extname(".index.md"), path.extname("..")].join("|")`, ".html|.md|.|||.md|"},
+		{`JSON.stringify(path.parse("/home/user/dir/file.txt"))`, `{"root":"/","dir":"/home/user/dir","base":"file.txt","ext":".txt","name":"file"}`},
+		{`JSON.stringify(path.parse("./.profile"))`, `{"root":"","dir":".","base":".profile","ext":"","name":".profile"}`},
+		{`JSON.stringify(path.parse("/"))`, `{"root":"/","dir":"/","base":"","ext":"","name":""}`},
+		{`path.format({ root: "/ignored", dir: "/home/user/dir", base: "file.txt" })`, "/home/user/dir/file.txt"},
+		{`path.format({ root: "/", base: "file.txt", ext: "ignored" })`, "/file.txt"},
+		{`path.format({ root: "/", name: "file", ext: "txt" })`, "/file.txt"},
+		{`try { path.format("x") } catch (e) { e.code }`, "ERR_INVALID_ARG_TYPE"},
+		{`path.sep + path.delimiter`, "/:"},
+	})
+}
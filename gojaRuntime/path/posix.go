@@ -0,0 +1,411 @@
+package path
+
+import "strings"
+
+// The functions below follow the POSIX implementation of the Node.js path module, which differs from the Go path
+// package: trailing separators survive normalize, ".." segments of relative paths are kept and "" is ".".
+
+// cwd is the directory relative paths are resolved against, workflows have no working directory of their own.
+const cwd = "/"
+
+// normalizeString resolves the "." and ".." segments of path, dropping the ones above the root unless
+// allowAboveRoot is set.
+func normalizeString(path string, allowAboveRoot bool) string {
+	res := ""
+	lastSegmentLength := 0
+	lastSlash := -1
+	dots := 0
+	var code byte
+	for i := 0; i <= len(path); i++ {
+		if i < len(path) {
+			code = path[i]
+		} else if code == '/' {
+			break
+		} else {
+			code = '/'
+		}
+
+		if code == '/' {
+			switch {
+			case lastSlash == i-1 || dots == 1:
+			case dots == 2:
+				if len(res) < 2 || lastSegmentLength != 2 || !strings.HasSuffix(res, "..") {
+					if len(res) > 2 {
+						if lastSlashIndex := strings.LastIndexByte(res, '/'); lastSlashIndex == -1 {
+							res = ""
+							lastSegmentLength = 0
+						} else {
+							res = res[:lastSlashIndex]
+							lastSegmentLength = len(res) - 1 - strings.LastIndexByte(res, '/')
+						}
+						lastSlash = i
+						dots = 0
+						continue
+					} else if len(res) != 0 {
+						res = ""
+						lastSegmentLength = 0
+						lastSlash = i
+						dots = 0
+						continue
+					}
+				}
+				if allowAboveRoot {
+					if len(res) > 0 {
+						res += "/.."
+					} else {
+						res = ".."
+					}
+					lastSegmentLength = 2
+				}
+			default:
+				if len(res) > 0 {
+					res += "/" + path[lastSlash+1:i]
+				} else {
+					res = path[lastSlash+1 : i]
+				}
+				lastSegmentLength = i - lastSlash - 1
+			}
+			lastSlash = i
+			dots = 0
+		} else if code == '.' && dots != -1 {
+			dots++
+		} else {
+			dots = -1
+		}
+	}
+	return res
+}
+
+func resolve(paths ...string) string {
+	resolvedPath := ""
+	resolvedAbsolute := false
+	for i := len(paths) - 1; i >= -1 && !resolvedAbsolute; i-- {
+		path := cwd
+		if i >= 0 {
+			path = paths[i]
+		}
+		if path == "" {
+			continue
+		}
+		resolvedPath = path + "/" + resolvedPath
+		resolvedAbsolute = path[0] == '/'
+	}
+
+	resolvedPath = normalizeString(resolvedPath, !resolvedAbsolute)
+	if resolvedAbsolute {
+		return "/" + resolvedPath
+	}
+	if resolvedPath == "" {
+		return "."
+	}
+	return resolvedPath
+}
+
+func normalize(path string) string {
+	if path == "" {
+		return "."
+	}
+	isAbsolute := path[0] == '/'
+	trailingSeparator := path[len(path)-1] == '/'
+
+	path = normalizeString(path, !isAbsolute)
+	if path == "" {
+		if isAbsolute {
+			return "/"
+		}
+		if trailingSeparator {
+			return "./"
+		}
+		return "."
+	}
+	if trailingSeparator {
+		path += "/"
+	}
+	if isAbsolute {
+		return "/" + path
+	}
+	return path
+}
+
+func isAbsolute(path string) bool {
+	return len(path) > 0 && path[0] == '/'
+}
+
+func join(paths ...string) string {
+	var parts []string
+	for _, path := range paths {
+		if path != "" {
+			parts = append(parts, path)
+		}
+	}
+	if len(parts) == 0 {
+		return "."
+	}
+	return normalize(strings.Join(parts, "/"))
+}
+
+func relative(from, to string) string {
+	if from == to {
+		return ""
+	}
+	from = resolve(from)
+	to = resolve(to)
+	if from == to {
+		return ""
+	}
+
+	const fromStart, toStart = 1, 1
+	fromEnd := len(from)
+	fromLen := fromEnd - fromStart
+	toLen := len(to) - toStart
+	length := min(fromLen, toLen)
+
+	lastCommonSep := -1
+	i := 0
+	for ; i < length; i++ {
+		fromCode := from[fromStart+i]
+		if fromCode != to[toStart+i] {
+			break
+		} else if fromCode == '/' {
+			lastCommonSep = i
+		}
+	}
+	if i == length {
+		if toLen > length {
+			if to[toStart+i] == '/' {
+				// from is the exact base path of to: "/foo/bar" and "/foo/bar/baz"
+				return to[toStart+i+1:]
+			}
+			if i == 0 {
+				// from is the root
+				return to[toStart+i:]
+			}
+		} else if fromLen > length {
+			if from[fromStart+i] == '/' {
+				// to is the exact base path of from: "/foo/bar/baz" and "/foo/bar"
+				lastCommonSep = i
+			} else if i == 0 {
+				// to is the root
+				lastCommonSep = 0
+			}
+		}
+	}
+
+	out := ""
+	for i = fromStart + lastCommonSep + 1; i <= fromEnd; i++ {
+		if i == fromEnd || from[i] == '/' {
+			if out == "" {
+				out = ".."
+			} else {
+				out += "/.."
+			}
+		}
+	}
+	return out + to[toStart+lastCommonSep:]
+}
+
+func dirname(path string) string {
+	if path == "" {
+		return "."
+	}
+	hasRoot := path[0] == '/'
+	end := -1
+	matchedSlash := true
+	for i := len(path) - 1; i >= 1; i-- {
+		if path[i] == '/' {
+			if !matchedSlash {
+				end = i
+				break
+			}
+		} else {
+			matchedSlash = false
+		}
+	}
+
+	switch {
+	case end == -1 && hasRoot:
+		return "/"
+	case end == -1:
+		return "."
+	case hasRoot && end == 1:
+		return "//"
+	}
+	return path[:end]
+}
+
+func basename(path, suffix string) string {
+	start := 0
+	end := -1
+	matchedSlash := true
+
+	if len(suffix) > 0 && len(suffix) <= len(path) {
+		if suffix == path {
+			return ""
+		}
+		extIdx := len(suffix) - 1
+		firstNonSlashEnd := -1
+		for i := len(path) - 1; i >= 0; i-- {
+			code := path[i]
+			if code == '/' {
+				// a separator which is not trailing ends the base name
+				if !matchedSlash {
+					start = i + 1
+					break
+				}
+				continue
+			}
+			if firstNonSlashEnd == -1 {
+				matchedSlash = false
+				firstNonSlashEnd = i + 1
+			}
+			if extIdx >= 0 {
+				if code == suffix[extIdx] {
+					if extIdx--; extIdx == -1 {
+						// the suffix matched, the base name ends before it
+						end = i
+					}
+				} else {
+					// the suffix does not match, the whole base name is kept
+					extIdx = -1
+					end = firstNonSlashEnd
+				}
+			}
+		}
+
+		if start == end {
+			end = firstNonSlashEnd
+		} else if end == -1 {
+			end = len(path)
+		}
+		return path[start:end]
+	}
+
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i] == '/' {
+			if !matchedSlash {
+				start = i + 1
+				break
+			}
+		} else if end == -1 {
+			matchedSlash = false
+			end = i + 1
+		}
+	}
+	if end == -1 {
+		return ""
+	}
+	return path[start:end]
+}
+
+// parsedPath holds the parts of a path as returned by path.parse.
+type parsedPath struct {
+	root, dir, base, ext, name string
+}
+
+// scanExtension finds the last dot of the base name of path[start:] which starts an extension, dot files such as
+// ".profile" have none. It returns the start of the base name, the dot, or -1, and the end of the base name, or -1.
+func scanExtension(path string, start int) (startPart, startDot, end int) {
+	startDot, end = -1, -1
+	matchedSlash := true
+	// preDotState tracks the characters preceding the dot: 0 none, 1 only dots, -1 others
+	preDotState := 0
+	for i := len(path) - 1; i >= start; i-- {
+		code := path[i]
+		if code == '/' {
+			if !matchedSlash {
+				startPart = i + 1
+				break
+			}
+			continue
+		}
+		if end == -1 {
+			matchedSlash = false
+			end = i + 1
+		}
+		if code == '.' {
+			if startDot == -1 {
+				startDot = i
+			} else if preDotState != 1 {
+				preDotState = 1
+			}
+		} else if startDot != -1 {
+			preDotState = -1
+		}
+	}
+
+	if startDot == -1 || end == -1 || preDotState == 0 ||
+		// the base name is ".."
+		(preDotState == 1 && startDot == end-1 && startDot == startPart+1) {
+		startDot = -1
+	}
+	return startPart, startDot, end
+}
+
+func extname(path string) string {
+	_, startDot, end := scanExtension(path, 0)
+	if startDot == -1 {
+		return ""
+	}
+	return path[startDot:end]
+}
+
+func parse(path string) parsedPath {
+	var ret parsedPath
+	if path == "" {
+		return ret
+	}
+	absolute := path[0] == '/'
+	start := 0
+	if absolute {
+		ret.root = "/"
+		start = 1
+	}
+
+	startPart, startDot, end := scanExtension(path, start)
+	if end != -1 {
+		if startPart == 0 && absolute {
+			start = 1
+		} else {
+			start = startPart
+		}
+		if startDot == -1 {
+			ret.base = path[start:end]
+			ret.name = ret.base
+		} else {
+			ret.name = path[start:startDot]
+			ret.base = path[start:end]
+			ret.ext = path[startDot:end]
+		}
+	}
+
+	if startPart > 0 {
+		ret.dir = path[:startPart-1]
+	} else if absolute {
+		ret.dir = "/"
+	}
+	return ret
+}
+
+func format(p parsedPath) string {
+	dir := p.dir
+	if dir == "" {
+		dir = p.root
+	}
+	base := p.base
+	if base == "" {
+		base = p.name
+		if p.ext != "" {
+			if p.ext[0] != '.' {
+				base += "."
+			}
+			base += p.ext
+		}
+	}
+	switch {
+	case dir == "":
+		return base
+	case dir == p.root:
+		return dir + base
+	}
+	return dir + "/" + base
+}
@@ -0,0 +1,177 @@
+package querystring
+
+import (
+	"math"
+	"reflect"
+	"strings"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/url"
+)
+
+const ModuleName = "querystring"
+
+// defaultMaxKeys is the number of pairs parse reads unless options.maxKeys says otherwise, 0 removes the limit.
+const defaultMaxKeys = 1000
+
+type querystringModule struct {
+	r *goja.Runtime
+}
+
+func isString(v goja.Value) bool {
+	_, isObject := v.(*goja.Object)
+	return !isObject && v != nil && v.ExportType() != nil && v.ExportType().Kind() == reflect.String
+}
+
+// stringifyPrimitive converts the values stringify can serialise, anything else becomes an empty string.
+func stringifyPrimitive(v goja.Value) string {
+	if _, isObject := v.(*goja.Object); isObject || v == nil || v.ExportType() == nil {
+		return ""
+	}
+	switch v.ExportType().Kind() {
+	case reflect.String, reflect.Bool:
+		return v.String()
+	case reflect.Int64, reflect.Float64:
+		if f := v.ToFloat(); math.IsInf(f, 0) || math.IsNaN(f) {
+			return ""
+		}
+		return v.String()
+	}
+	if _, isSymbol := v.(*goja.Symbol); !isSymbol && !goja.IsUndefined(v) && !goja.IsNull(v) {
+		// BigInt
+		return v.String()
+	}
+	return ""
+}
+
+// separator returns the separator argument at index i, falling back to def when it is missing or empty.
+func separator(call goja.FunctionCall, i int, def string) string {
+	if v := call.Argument(i); !goja.IsUndefined(v) && !goja.IsNull(v) {
+		if s := v.String(); s != "" {
+			return s
+		}
+	}
+	return def
+}
+
+// codec returns the function named name of options, or fallback when there is none.
+func (m *querystringModule) codec(options goja.Value, name string, fallback func(string) string) func(string) string {
+	o, ok := options.(*goja.Object)
+	if !ok {
+		return fallback
+	}
+	fn, ok := goja.AssertFunction(o.Get(name))
+	if !ok {
+		return fallback
+	}
+	return func(s string) string {
+		res, err := fn(goja.Undefined(), m.r.ToValue(s))
+		if err != nil {
+			panic(err)
+		}
+		return res.String()
+	}
+}
+
+// stringify serialises the own enumerable properties of an object, array values repeat their key.
+func (m *querystringModule) stringify(call goja.FunctionCall) goja.Value {
+	sep := separator(call, 1, "&")
+	eq := separator(call, 2, "=")
+	encode := m.codec(call.Argument(3), "encodeURIComponent", url.EscapeSearchParam)
+
+	o, ok := call.Argument(0).(*goja.Object)
+	if !ok {
+		return m.r.ToValue("")
+	}
+	var fields []string
+	for _, key := range o.Keys() {
+		prefix := encode(key) + eq
+		value := o.Get(key)
+		if values, ok := value.(*goja.Object); ok && values.ClassName() == "Array" {
+			var items []goja.Value
+			if err := m.r.ExportTo(values, &items); err != nil {
+				panic(err)
+			}
+			for _, item := range items {
+				fields = append(fields, prefix+encode(stringifyPrimitive(item)))
+			}
+			continue
+		}
+		fields = append(fields, prefix+encode(stringifyPrimitive(value)))
+	}
+	return m.r.ToValue(strings.Join(fields, sep))
+}
+
+// parse reads a query string into an object without prototype, keys seen several times get an array of values.
+func (m *querystringModule) parse(call goja.FunctionCall) goja.Value {
+	r := m.r
+	result := r.NewObject()
+	result.SetPrototype(nil)
+
+	query := call.Argument(0)
+	if !isString(query) || query.String() == "" {
+		return result
+	}
+	sep := separator(call, 1, "&")
+	eq := separator(call, 2, "=")
+	decode := m.codec(call.Argument(3), "decodeURIComponent", url.UnescapeSearchParam)
+	maxKeys := defaultMaxKeys
+	if options, ok := call.Argument(3).(*goja.Object); ok {
+		if v := options.Get("maxKeys"); v != nil && !goja.IsUndefined(v) {
+			maxKeys = int(v.ToInteger())
+		}
+	}
+
+	pairs := strings.Split(query.String(), sep)
+	if maxKeys > 0 && len(pairs) > maxKeys {
+		pairs = pairs[:maxKeys]
+	}
+	values := map[string][]interface{}{}
+	var keys []string
+	for _, pair := range pairs {
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, eq)
+		key, value = decode(key), decode(value)
+		if _, seen := values[key]; !seen {
+			keys = append(keys, key)
+		}
+		values[key] = append(values[key], value)
+	}
+
+	for _, key := range keys {
+		if v := values[key]; len(v) == 1 {
+			result.Set(key, v[0])
+		} else {
+			result.Set(key, r.NewArray(v...))
+		}
+	}
+	return result
+}
+
+func (m *querystringModule) escape(call goja.FunctionCall) goja.Value {
+	return m.r.ToValue(url.EscapeSearchParam(call.Argument(0).String()))
+}
+
+func (m *querystringModule) unescape(call goja.FunctionCall) goja.Value {
+	return m.r.ToValue(url.UnescapeSearchParam(call.Argument(0).String()))
+}
+
+func Require(runtime *goja.Runtime, module *goja.Object) {
+	exports := module.Get("exports").(*goja.Object)
+	m := &querystringModule{
+		r: runtime,
+	}
+	exports.Set("stringify", m.stringify)
+	exports.Set("encode", exports.Get("stringify"))
+	exports.Set("parse", m.parse)
+	exports.Set("decode", exports.Get("parse"))
+	exports.Set("escape", m.escape)
+	exports.Set("unescape", m.unescape)
+}
+
+func init() {
+	require.RegisterCoreModule(ModuleName, Require)
+}
@@ -0,0 +1,65 @@
+package querystring
+
+import (
+	"testing"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/url"
+)
+
+func createVM() *goja.Runtime {
+	vm := goja.New()
+	new(require.Registry).Enable(vm)
+	vm.Set("querystring", require.Require(vm, ModuleName))
+	url.Enable(vm)
+	return vm
+}
+
+func runTests(t *testing.T, vm *goja.Runtime, tests []struct {
+	script   string
+	expected string
+}) {
+	t.Helper()
+	for _, tc := range tests {
+		result, err := vm.RunString(tc.script)
+		if err != nil {
+			t.Fatalf("Failed to run %s: %v", tc.script, err)
+		}
+		if result.String() != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.script, tc.expected, result.String())
+		}
+	}
+}
+
+func TestStringify(t *testing.T) {
+	runTests(t, createVM(), []struct {
+		script   string
+		expected string
+	}{
+		{`querystring.stringify({ foo: "bar", baz: ["qux", "quux"], corge: "" })`, "foo=bar&baz=qux&baz=quux&corge="},
+		{`querystring.stringify({ foo: "bar", baz: "qux" }, ";", ":")`, "foo:bar;baz:qux"},
+		{`querystring.stringify({ n: 1, inf: Infinity, b: true, big: 10n, o: {}, u: undefined, f: () => 1 })`, "n=1&inf=&b=true&big=10&o=&u=&f="},
+		{`querystring.stringify({ "a b": "c&d=é" })`, "a+b=c%26d%3D%C3%A9"},
+		{`const value = "a b&c=d/é~*!'()"; querystring.stringify({ value }) === new URLSearchParams({ value }).toString()`, "true"},
+		{`querystring.stringify({ w: "中文" }, null, null, { encodeURIComponent: (s) => s.toUpperCase() })`, "W=中文"},
+		{`querystring.stringify("x") + querystring.encode(null)`, ""},
+		{`querystring.escape("a b/c") + " " + querystring.unescape("a+b%2Fc%zz")`, "a+b%2Fc a b/c%zz"},
+	})
+}
+
+func TestParse(t *testing.T) {
+	runTests(t, createVM(), []struct {
+		script   string
+		expected string
+	}{
+		{`const parsed = querystring.parse("foo=bar&abc=xyz&abc=123&empty&=v&&a+b=c%20d"); Object.getPrototypeOf(parsed)`, "null"},
+		{`JSON.stringify(parsed)`, `{"foo":"bar","abc":["xyz","123"],"empty":"","":"v","a b":"c d"}`},
+		{`JSON.stringify(querystring.parse("w:a;w:b;x:%E4%B8%AD", ";", ":"))`, `{"w":["a","b"],"x":"中"}`},
+		{`Object.keys(querystring.parse("a=1&b=2&c=3", null, null, { maxKeys: 2 })).join()`, "a,b"},
+		{`Object.keys(querystring.parse("a=1&b=2&c=3", null, null, { maxKeys: 0 })).join()`, "a,b,c"},
+		{`querystring.parse("a=B", null, null, { decodeURIComponent: (s) => s.toLowerCase() }).a`, "b"},
+		{`JSON.stringify(querystring.decode("")) + JSON.stringify(querystring.parse(5))`, "{}{}"},
+		{`JSON.stringify(querystring.parse("__proto__=x"))`, `{"__proto__":"x"}`},
+	})
+}
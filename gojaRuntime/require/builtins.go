@@ -0,0 +1,154 @@
+package require
+
+import (
+	"strings"
+
+	js "github.com/grafana/sobek"
+	jsErrors "github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+)
+
+// nodeBuiltin describes how a Node.js built-in module is provided to workflows.
+type nodeBuiltin struct {
+	// module is the core module implementing the built-in, empty when workflows cannot use it
+	module string
+	// export selects a property of the exports of module, for built-ins such as util/types
+	export string
+}
+
+// nodeBuiltins lists the Node.js built-in modules. Bare and node: prefixed specifiers naming them never fall
+// through to node_modules: they load the core module they map to, or fail with ERR_UNKNOWN_BUILTIN_MODULE.
+// Prefix-only built-ins, such as node:test, are listed with their prefix.
+var nodeBuiltins = map[string]nodeBuiltin{
+	"assert":              {},
+	"assert/strict":       {},
+	"async_hooks":         {},
+	"buffer":              {module: "buffer"},
+	"child_process":       {},
+	"cluster":             {},
+	"console":             {},
+	"constants":           {},
+	"crypto":              {module: "crypto"},
+	"dgram":               {},
+	"diagnostics_channel": {},
+	"dns":                 {},
+	"dns/promises":        {},
+	"domain":              {},
+	"events":              {module: "events"},
+	"fs":                  {},
+	"fs/promises":         {},
+	"http":                {},
+	"http2":               {},
+	"https":               {},
+	"inspector":           {},
+	"inspector/promises":  {},
+	"module":              {},
+	"net":                 {},
+	"os":                  {},
+	"path":                {module: "path"},
+	"path/posix":          {module: "path"},
+	"path/win32":          {},
+	"perf_hooks":          {},
+	"process":             {},
+	"punycode":            {},
+	"querystring":         {module: "querystring"},
+	"readline":            {},
+	"readline/promises":   {},
+	"repl":                {},
+	"stream":              {},
+	"stream/consumers":    {},
+	"stream/promises":     {},
+	"stream/web":          {},
+	"string_decoder":      {},
+	"sys":                 {module: "util"},
+	"timers":              {},
+	"timers/promises":     {},
+	"tls":                 {},
+	"trace_events":        {},
+	"tty":                 {},
+	"url":                 {module: "url"},
+	"util":                {module: "util"},
+	"util/types":          {module: "util", export: "types"},
+	"v8":                  {},
+	"vm":                  {},
+	"wasi":                {},
+	"worker_threads":      {},
+	"zlib":                {},
+	"node:sea":            {},
+	"node:sqlite":         {},
+	"node:test":           {},
+	"node:test/reporters": {},
+}
+
+// lookupNodeBuiltin finds the entry of a bare or node: prefixed specifier in nodeBuiltins.
+func lookupNodeBuiltin(name string) (nodeBuiltin, bool) {
+	if b, ok := nodeBuiltins[name]; ok {
+		return b, true
+	}
+	if strings.HasPrefix(name, NodePrefix) {
+		b, ok := nodeBuiltins[name[len(NodePrefix):]]
+		return b, ok
+	}
+	return nodeBuiltin{}, false
+}
+
+// UnknownBuiltinModuleError is returned when a script requires a Node.js built-in module workflows do not provide,
+// or a node: prefixed module which does not exist.
+type UnknownBuiltinModuleError struct {
+	// Name is the specifier passed to require
+	Name string
+	// From is the path of the module calling require
+	From string
+}
+
+func (e *UnknownBuiltinModuleError) Error() string {
+	msg := "No such built-in module: " + e.Name
+	if e.From != "" {
+		msg += " (required from " + e.From + ")"
+	}
+	return msg
+}
+
+func (e *UnknownBuiltinModuleError) Is(target error) bool {
+	return target == NoSuchBuiltInModuleError
+}
+
+// toJSError creates the Error thrown to the script for e.
+func (e *UnknownBuiltinModuleError) toJSError(runtime *js.Runtime) *js.Object {
+	err := jsErrors.NewError(runtime, nil, jsErrors.ErrCodeUnknownBuiltinModule, "%s", e.Error())
+	if e.From != "" {
+		err.Set("requireStack", runtime.NewArray(e.From))
+	}
+	return err
+}
+
+// loadNodeBuiltin loads the core module a Node.js built-in maps to. Specifiers which are not built-ins
+// give InvalidModuleError, so that they are looked up in node_modules.
+func (r *RequireModule) loadNodeBuiltin(name string) (*js.Object, error) {
+	b, ok := lookupNodeBuiltin(name)
+	if !ok {
+		if strings.HasPrefix(name, NodePrefix) {
+			return nil, r.unknownBuiltin(name)
+		}
+		return nil, InvalidModuleError
+	}
+	// the core module may also not be linked into this binary
+	if b.module == "" || (r.r.native[b.module] == nil && native[b.module] == nil && builtin[b.module] == nil) {
+		return nil, r.unknownBuiltin(name)
+	}
+
+	target, err := r.loadNative(b.module)
+	if err != nil {
+		return nil, err
+	}
+	module := target
+	if b.export != "" {
+		module = r.createModuleObject()
+		module.Set("exports", target.Get("exports").ToObject(r.runtime).Get(b.export))
+	}
+	r.modules[name] = module
+	return module, nil
+}
+
+func (r *RequireModule) unknownBuiltin(name string) error {
+	return &UnknownBuiltinModuleError{Name: name, From: r.getCurrentModuleFile()}
+}
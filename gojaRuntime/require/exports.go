@@ -0,0 +1,541 @@
+package require
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	js "github.com/grafana/sobek"
+	jsErrors "github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+)
+
+// The functions below implement the "exports" and "imports" fields of package.json as described by
+// https://nodejs.org/api/esm.html#resolution-algorithm-specification, with directories instead of URLs.
+
+// DefaultConditions are the conditions matched by "exports" and "imports" unless the registry was given others
+// with WithConditions. They are the ones Node.js uses for require(), "default" always matches.
+var DefaultConditions = []string{"node", "require"}
+
+// WithConditions sets the conditions matched by conditional "exports" and "imports" of package.json, in addition
+// to "default". The order of the keys in package.json decides which condition wins, not the order given here.
+func WithConditions(conditions ...string) Option {
+	return func(r *Registry) {
+		r.conditions = conditions
+	}
+}
+
+// jsError is implemented by the resolution errors thrown to scripts as Node.js errors with a code.
+type jsError interface {
+	error
+	toJSError(runtime *js.Runtime) *js.Object
+}
+
+// PackageResolveError is returned when a specifier cannot be resolved through the "exports" or "imports" of a
+// package.json, Code is the code of the matching Node.js error.
+type PackageResolveError struct {
+	Code    string
+	Message string
+}
+
+func (e *PackageResolveError) Error() string {
+	return e.Message
+}
+
+// toJSError creates the Error thrown to the script for e.
+func (e *PackageResolveError) toJSError(runtime *js.Runtime) *js.Object {
+	return jsErrors.NewError(runtime, nil, e.Code, "%s", e.Message)
+}
+
+// packageJSON holds the fields of package.json used to resolve specifiers, dir is the directory containing it.
+type packageJSON struct {
+	dir     string
+	Name    string          `json:"name"`
+	Exports json.RawMessage `json:"exports"`
+	Imports json.RawMessage `json:"imports"`
+}
+
+func (p *packageJSON) path() string {
+	return path.Join(p.dir, "package.json")
+}
+
+// readPackageJSON reads the package.json of dir, it returns nil when there is none.
+func (r *RequireModule) readPackageJSON(dir string) (*packageJSON, error) {
+	pkg := &packageJSON{dir: dir}
+	buf, err := r.r.getSource(pkg.path())
+	if err != nil {
+		return nil, nil
+	}
+	if err := json.Unmarshal(buf, pkg); err != nil {
+		return nil, &PackageResolveError{
+			Code:    jsErrors.ErrCodeInvalidPackageConfig,
+			Message: "Invalid package config " + pkg.path() + ". " + err.Error(),
+		}
+	}
+	return pkg, nil
+}
+
+// lookupPackageScope finds the package.json closest to dir, without leaving the package dir belongs to.
+func (r *RequireModule) lookupPackageScope(dir string) (*packageJSON, error) {
+	for path.Base(dir) != "node_modules" {
+		if pkg, err := r.readPackageJSON(dir); pkg != nil || err != nil {
+			return pkg, err
+		}
+		parent := path.Dir(dir)
+		if parent == dir || dir == ".." {
+			break
+		}
+		dir = parent
+	}
+	return nil, nil
+}
+
+func (r *RequireModule) conditions() []string {
+	if r.r.conditions != nil {
+		return r.r.conditions
+	}
+	return DefaultConditions
+}
+
+// splitPackageName splits a bare specifier into the name of its package, scoped or not, and the subpath "exports"
+// are matched against: "@scope/pkg/lib" gives "@scope/pkg" and "./lib".
+func splitPackageName(specifier string) (name, subpath string, ok bool) {
+	end := strings.IndexByte(specifier, '/')
+	if strings.HasPrefix(specifier, "@") {
+		if end == -1 {
+			return "", "", false
+		}
+		if next := strings.IndexByte(specifier[end+1:], '/'); next != -1 {
+			end += next + 1
+		} else {
+			end = -1
+		}
+	}
+	name = specifier
+	if end != -1 {
+		name = specifier[:end]
+	}
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `\%`) {
+		return "", "", false
+	}
+	return name, "." + specifier[len(name):], true
+}
+
+// loadPackageExports loads specifier from the package it names in the node_modules directory dir, ok is false when
+// that package has no "exports" and the legacy lookup applies.
+func (r *RequireModule) loadPackageExports(specifier, dir string) (module *js.Object, ok bool, err error) {
+	name, subpath, ok := splitPackageName(specifier)
+	if !ok {
+		return nil, false, nil
+	}
+	pkg, err := r.readPackageJSON(path.Join(dir, name))
+	if err != nil {
+		return nil, true, err
+	}
+	if pkg == nil || isJSONNull(pkg.Exports) {
+		return nil, false, nil
+	}
+	module, err = r.loadPackageSubpath(pkg, subpath)
+	return module, true, err
+}
+
+// loadPackageSelf loads specifier when it names the package the requiring module belongs to, through its "exports".
+func (r *RequireModule) loadPackageSelf(specifier, dir string) (module *js.Object, ok bool, err error) {
+	pkg, err := r.lookupPackageScope(dir)
+	if err != nil {
+		return nil, true, err
+	}
+	if pkg == nil || isJSONNull(pkg.Exports) {
+		return nil, false, nil
+	}
+	name, subpath, ok := splitPackageName(specifier)
+	if !ok || name != pkg.Name {
+		return nil, false, nil
+	}
+	module, err = r.loadPackageSubpath(pkg, subpath)
+	return module, true, err
+}
+
+func (r *RequireModule) loadPackageSubpath(pkg *packageJSON, subpath string) (*js.Object, error) {
+	res := &packageResolver{pkg: pkg, conditions: r.conditions(), request: subpath}
+	target, err := res.exportsResolve(subpath)
+	if err != nil {
+		return nil, err
+	}
+	return r.loadPackageTarget(target, pkg.dir)
+}
+
+// loadPackageImports loads a # specifier through the "imports" of the package the requiring module belongs to.
+func (r *RequireModule) loadPackageImports(specifier, dir string) (*js.Object, error) {
+	if specifier == "#" || strings.HasPrefix(specifier, "#/") {
+		return nil, &PackageResolveError{
+			Code:    jsErrors.ErrCodeInvalidModuleSpecifier,
+			Message: `Invalid module "` + specifier + `" is not a valid internal imports specifier name imported from ` + dir,
+		}
+	}
+	pkg, err := r.lookupPackageScope(dir)
+	if err != nil {
+		return nil, err
+	}
+	in := ""
+	if pkg != nil {
+		in = " in package " + pkg.path()
+		if jsonKind(pkg.Imports) == '{' {
+			imports, err := jsonMembers(pkg.Imports)
+			if err != nil {
+				return nil, err
+			}
+			res := &packageResolver{pkg: pkg, conditions: r.conditions(), request: specifier, isImports: true}
+			target, err := res.importsExportsResolve(specifier, imports)
+			if err != nil {
+				return nil, err
+			}
+			if !target.excluded() {
+				return r.loadPackageTarget(target, pkg.dir)
+			}
+		}
+	}
+	return nil, &PackageResolveError{
+		Code:    jsErrors.ErrCodePackageImportNotDefined,
+		Message: `Package import specifier "` + specifier + `" is not defined` + in + " imported from " + dir,
+	}
+}
+
+// loadPackageTarget loads the file a target resolved to, or the package an "imports" target maps to as seen from
+// the directory of the package.
+func (r *RequireModule) loadPackageTarget(target *packageTarget, dir string) (*js.Object, error) {
+	if target.specifier != "" {
+		module, err := r.loadNative(target.specifier)
+		if err != InvalidModuleError {
+			return module, err
+		}
+		if module, err = r.loadNodeModules(target.specifier, dir); module != nil || err != nil {
+			return module, err
+		}
+		return nil, moduleNotFound(target.specifier)
+	}
+	module, err := r.loadModule(target.path)
+	if module == nil && err == nil {
+		err = moduleNotFound(target.path)
+	}
+	return module, err
+}
+
+func moduleNotFound(name string) error {
+	return &PackageResolveError{
+		Code:    jsErrors.ErrCodeModuleNotFound,
+		Message: "Cannot find module '" + name + "'",
+	}
+}
+
+// packageTarget is a target of "exports" or "imports" resolved to a file, or to a bare specifier for "imports".
+// The zero value stands for a null target, which excludes the subpath.
+type packageTarget struct {
+	path      string
+	specifier string
+}
+
+func (t *packageTarget) excluded() bool {
+	return t == nil || (t.path == "" && t.specifier == "")
+}
+
+// packageResolver resolves one subpath or specifier against the "exports" or "imports" of pkg. The resolve
+// methods return a nil target when nothing matches, which Node.js calls undefined as opposed to null.
+type packageResolver struct {
+	pkg        *packageJSON
+	conditions []string
+	isImports  bool
+	// request is the subpath or specifier being resolved, for error messages
+	request string
+}
+
+func (res *packageResolver) exportsResolve(subpath string) (*packageTarget, error) {
+	exports := res.pkg.Exports
+	var members []jsonMember
+	subpaths := false
+	if jsonKind(exports) == '{' {
+		var err error
+		if members, err = jsonMembers(exports); err != nil {
+			return nil, err
+		}
+		dots := 0
+		for _, m := range members {
+			if strings.HasPrefix(m.key, ".") {
+				dots++
+			}
+		}
+		if dots != 0 && dots != len(members) {
+			return nil, res.invalidConfig(`"exports" cannot contain some keys starting with '.' and some not. ` +
+				"The exports object must either be an object of package subpath keys or an object of main entry condition name keys only.")
+		}
+		subpaths = dots != 0
+	}
+
+	if subpath == "." {
+		mainExport := exports
+		if subpaths {
+			mainExport = nil
+			if m, ok := memberOf(members, "."); ok {
+				mainExport = m
+			}
+		}
+		if mainExport != nil {
+			target, err := res.targetResolve(mainExport, nil)
+			if err != nil || !target.excluded() {
+				return target, err
+			}
+		}
+	} else if subpaths {
+		target, err := res.importsExportsResolve(subpath, members)
+		if err != nil || !target.excluded() {
+			return target, err
+		}
+	}
+
+	msg := "Package subpath '" + subpath + `' is not defined by "exports" in ` + res.pkg.path()
+	if subpath == "." {
+		msg = `No "exports" main defined in ` + res.pkg.path()
+	}
+	return nil, &PackageResolveError{Code: jsErrors.ErrCodePackagePathNotExported, Message: msg}
+}
+
+// importsExportsResolve matches key against the keys of an "exports" or "imports" object, exact keys first and
+// then patterns with a single "*", the longest prefix winning.
+func (res *packageResolver) importsExportsResolve(key string, members []jsonMember) (*packageTarget, error) {
+	if !strings.Contains(key, "*") {
+		if target, ok := memberOf(members, key); ok {
+			return res.targetResolve(target, nil)
+		}
+	}
+
+	var patterns []jsonMember
+	for _, m := range members {
+		if strings.Count(m.key, "*") == 1 {
+			patterns = append(patterns, m)
+		}
+	}
+	sort.SliceStable(patterns, func(i, j int) bool {
+		return patternKeyCompare(patterns[i].key, patterns[j].key) < 0
+	})
+	for _, m := range patterns {
+		base, trailer, _ := strings.Cut(m.key, "*")
+		if !strings.HasPrefix(key, base) || key == base {
+			continue
+		}
+		if trailer == "" || (strings.HasSuffix(key, trailer) && len(key) >= len(m.key)) {
+			match := key[len(base) : len(key)-len(trailer)]
+			return res.targetResolve(m.value, &match)
+		}
+	}
+	return nil, nil
+}
+
+// patternKeyCompare orders pattern keys from the most to the least specific.
+func patternKeyCompare(a, b string) int {
+	baseA := strings.IndexByte(a, '*') + 1
+	baseB := strings.IndexByte(b, '*') + 1
+	switch {
+	case baseA > baseB:
+		return -1
+	case baseB > baseA:
+		return 1
+	case baseA == 0:
+		return 1
+	case baseB == 0:
+		return -1
+	case len(a) > len(b):
+		return -1
+	case len(b) > len(a):
+		return 1
+	}
+	return 0
+}
+
+func (res *packageResolver) targetResolve(target json.RawMessage, patternMatch *string) (*packageTarget, error) {
+	switch jsonKind(target) {
+	case '"':
+		var s string
+		if err := json.Unmarshal(target, &s); err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(s, "./") {
+			if !res.isImports || strings.HasPrefix(s, "../") || strings.HasPrefix(s, "/") || isURL(s) {
+				return nil, res.invalidTarget(target)
+			}
+			if patternMatch != nil {
+				s = strings.ReplaceAll(s, "*", *patternMatch)
+			}
+			return &packageTarget{specifier: s}, nil
+		}
+		if hasInvalidSegment(s[2:]) {
+			return nil, res.invalidTarget(target)
+		}
+		resolved := path.Join(res.pkg.dir, s)
+		if patternMatch == nil {
+			return &packageTarget{path: resolved}, nil
+		}
+		if hasInvalidSegment(*patternMatch) {
+			return nil, &PackageResolveError{
+				Code:    jsErrors.ErrCodeInvalidModuleSpecifier,
+				Message: `Invalid module "` + res.request + `" request is not a valid match in pattern for the "` + res.field() + `" resolution of ` + res.pkg.path(),
+			}
+		}
+		return &packageTarget{path: strings.ReplaceAll(resolved, "*", *patternMatch)}, nil
+
+	case '{':
+		members, err := jsonMembers(target)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range members {
+			if _, err := strconv.ParseUint(m.key, 10, 32); err == nil {
+				return nil, res.invalidConfig(`"` + res.field() + `" cannot contain numeric property keys.`)
+			}
+		}
+		for _, m := range members {
+			if m.key != "default" && !slices.Contains(res.conditions, m.key) {
+				continue
+			}
+			resolved, err := res.targetResolve(m.value, patternMatch)
+			if err != nil || resolved != nil {
+				return resolved, err
+			}
+		}
+		return nil, nil
+
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(target, &items); err != nil {
+			return nil, err
+		}
+		if len(items) == 0 {
+			return &packageTarget{}, nil
+		}
+		// like Node.js, invalid targets and null fall back to the next item, the last of them is returned
+		var last *packageTarget
+		var lastErr error
+		for _, item := range items {
+			resolved, err := res.targetResolve(item, patternMatch)
+			if err != nil {
+				var resolveErr *PackageResolveError
+				if !errors.As(err, &resolveErr) || resolveErr.Code != jsErrors.ErrCodeInvalidPackageTarget {
+					return nil, err
+				}
+				last, lastErr = nil, err
+				continue
+			}
+			if resolved == nil {
+				continue
+			}
+			if resolved.excluded() {
+				last, lastErr = resolved, nil
+				continue
+			}
+			return resolved, nil
+		}
+		return last, lastErr
+
+	case 'n':
+		return &packageTarget{}, nil
+	}
+	return nil, res.invalidTarget(target)
+}
+
+func (res *packageResolver) field() string {
+	if res.isImports {
+		return "imports"
+	}
+	return "exports"
+}
+
+func (res *packageResolver) invalidTarget(target json.RawMessage) error {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, target); err != nil {
+		compact.Write(target)
+	}
+	return &PackageResolveError{
+		Code: jsErrors.ErrCodeInvalidPackageTarget,
+		Message: `Invalid "` + res.field() + `" target ` + compact.String() + " defined for '" + res.request +
+			"' in the package config " + res.pkg.path(),
+	}
+}
+
+func (res *packageResolver) invalidConfig(msg string) error {
+	return &PackageResolveError{
+		Code:    jsErrors.ErrCodeInvalidPackageConfig,
+		Message: "Invalid package config " + res.pkg.path() + ". " + msg,
+	}
+}
+
+// hasInvalidSegment reports whether p has a ".", ".." or "node_modules" segment, percent-encoded or not.
+func hasInvalidSegment(p string) bool {
+	for _, segment := range strings.FieldsFunc(p, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if s, err := url.PathUnescape(segment); err == nil {
+			segment = s
+		}
+		switch strings.ToLower(segment) {
+		case ".", "..", "node_modules":
+			return true
+		}
+	}
+	return false
+}
+
+func isURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != ""
+}
+
+// jsonMember is a key of a JSON object with its value. Objects are read as lists of members because the order of
+// conditions matters.
+type jsonMember struct {
+	key   string
+	value json.RawMessage
+}
+
+func jsonMembers(raw json.RawMessage) ([]jsonMember, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	var members []jsonMember
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		m := jsonMember{key: key.(string)}
+		if err := dec.Decode(&m.value); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+func memberOf(members []jsonMember, key string) (json.RawMessage, bool) {
+	for _, m := range members {
+		if m.key == key {
+			return m.value, true
+		}
+	}
+	return nil, false
+}
+
+// jsonKind returns the first character of a JSON value, which tells its type, or 'n' when it is missing.
+func jsonKind(raw json.RawMessage) byte {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return 'n'
+	}
+	return raw[0]
+}
+
+func isJSONNull(raw json.RawMessage) bool {
+	return jsonKind(raw) == 'n'
+}
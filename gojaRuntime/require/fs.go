@@ -0,0 +1,58 @@
+package require
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// fsPath maps a module path to a name valid in an fs.FS. Module paths are resolved against the root of the file
+// system, so "/lib/a.js", "./lib/a.js" and "lib/a.js" name the same file.
+func fsPath(p string) string {
+	p = strings.TrimPrefix(path.Clean("/"+p), "/")
+	if p == "" {
+		return "."
+	}
+	return p
+}
+
+// FSSourceLoader returns a SourceLoader reading modules from fsys, such as an embed.FS, a zip.Reader or an
+// fstest.MapFS. Missing files and directories give ModuleFileDoesNotExistError so the search continues.
+func FSSourceLoader(fsys fs.FS) SourceLoader {
+	return func(p string) ([]byte, error) {
+		name := fsPath(p)
+		info, err := fs.Stat(fsys, name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) {
+				return nil, ModuleFileDoesNotExistError
+			}
+			return nil, err
+		}
+		if info.IsDir() {
+			return nil, ModuleFileDoesNotExistError
+		}
+		return fs.ReadFile(fsys, name)
+	}
+}
+
+// MapSourceLoader returns a SourceLoader reading modules from memory, keyed by their path. Keys are resolved like
+// the paths of FSSourceLoader, so "lib/a.js" can be required as "./lib/a.js" from a module at the root.
+func MapSourceLoader(modules map[string][]byte) SourceLoader {
+	files := make(map[string][]byte, len(modules))
+	for p, src := range modules {
+		files[fsPath(p)] = src
+	}
+	return func(p string) ([]byte, error) {
+		src, ok := files[fsPath(p)]
+		if !ok {
+			return nil, ModuleFileDoesNotExistError
+		}
+		return src, nil
+	}
+}
+
+// WithFS makes the registry load modules from fsys, see FSSourceLoader.
+func WithFS(fsys fs.FS) Option {
+	return WithLoader(FSSourceLoader(fsys))
+}
@@ -7,8 +7,9 @@ import (
 	"sync"
 	"text/template"
 
-	js "github.com/dop251/goja"
-	"github.com/dop251/goja/parser"
+	js "github.com/grafana/sobek"
+	"github.com/grafana/sobek/parser"
+	jsErrors "github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
 )
 
 type ModuleLoader func(*js.Runtime, *js.Object)
@@ -35,13 +36,19 @@ type Registry struct {
 
 	srcLoader     SourceLoader
 	globalFolders []string
+	conditions    []string
 }
 
+// ModuleResolver provides modules which only exist in one runtime, such as host bindings. ok is false when name is
+// not one of its modules, the other resolution steps then apply.
+type ModuleResolver func(name string) (exports js.Value, ok bool, err error)
+
 type RequireModule struct {
 	r           *Registry
 	runtime     *js.Runtime
 	modules     map[string]*js.Object
 	nodeModules map[string]*js.Object
+	resolver    ModuleResolver
 }
 
 func NewRegistry(opts ...Option) *Registry {
@@ -96,6 +103,11 @@ func (r *Registry) Enable(runtime *js.Runtime) *RequireModule {
 	return rrt
 }
 
+// SetModuleResolver makes require() consult resolver for specifiers which are not paths, before native modules.
+func (r *RequireModule) SetModuleResolver(resolver ModuleResolver) {
+	r.resolver = resolver
+}
+
 func (r *Registry) RegisterNativeModule(name string, loader ModuleLoader) {
 	r.Lock()
 	defer r.Unlock()
@@ -181,10 +193,11 @@ func (r *Registry) getCompiledSource(p string) (*js.Program, error) {
 func (r *RequireModule) require(call js.FunctionCall) js.Value {
 	ret, err := r.Require(call.Argument(0).String())
 	if err != nil {
-		if _, ok := err.(*js.Exception); !ok {
-			panic(r.runtime.NewGoError(err))
+		var jsErr jsError
+		if errors.As(err, &jsErr) {
+			panic(jsErr.toJSError(r.runtime))
 		}
-		panic(err)
+		panic(jsErrors.FromGoError(r.runtime, err))
 	}
 	return ret
 }
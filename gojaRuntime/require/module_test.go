@@ -8,8 +8,10 @@ import (
 	"os"
 	"path"
 	"testing"
+	"testing/fstest"
 
-	js "github.com/dop251/goja"
+	js "github.com/grafana/sobek"
+	jsErrors "github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
 )
 
 func mapFileSystemSourceLoader(files map[string]string) SourceLoader {
@@ -137,6 +139,50 @@ func TestSourceLoader(t *testing.T) {
 	}
 }
 
+func TestFSSourceLoader(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app/main.js":             {Data: []byte(`exports.name = require("./lib").name + "," + require("/app/data.json").name`)},
+		"app/data.json":           {Data: []byte(`{"name": "data"}`)},
+		"app/lib/index.js":        {Data: []byte(`exports.name = require("dep").name`)},
+		"app/node_modules/dep.js": {Data: []byte(`exports.name = "dep"`)},
+	}
+	vm := js.New()
+	NewRegistry(WithFS(fsys)).Enable(vm)
+	v, err := vm.RunString(`require("./app/main.js").name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.String() != "dep,data" {
+		t.Fatalf("Unexpected result: %v", v)
+	}
+
+	// directories are missing files, not errors
+	load := FSSourceLoader(fsys)
+	for _, p := range []string{"app/lib", "app/missing.js"} {
+		if _, err := load(p); !errors.Is(err, ModuleFileDoesNotExistError) {
+			t.Errorf("%s: expected ModuleFileDoesNotExistError, got %v", p, err)
+		}
+	}
+}
+
+func TestMapSourceLoader(t *testing.T) {
+	vm := js.New()
+	NewRegistry(WithLoader(MapSourceLoader(map[string][]byte{
+		"/lib/shared.js": []byte(`exports.greet = (name) => "hello " + name`),
+		"./handler.js":   []byte(`exports.run = () => require("./lib/shared").greet("map")`),
+	}))).Enable(vm)
+	v, err := vm.RunString(`require("./handler").run()`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.String() != "hello map" {
+		t.Fatalf("Unexpected result: %v", v)
+	}
+	if _, err := vm.RunString(`require("./lib/missing")`); err == nil {
+		t.Fatal("expected missing module to fail")
+	}
+}
+
 func TestStrictModule(t *testing.T) {
 	const SCRIPT = `
 	var m = require("m.js");
@@ -275,6 +321,151 @@ func TestResolve(t *testing.T) {
 	}
 }
 
+func TestPackageExports(t *testing.T) {
+	fs := map[string]string{
+		"/app/node_modules/plain/package.json":           `{"main": "main.js", "exports": "./entry.js"}`,
+		"/app/node_modules/plain/entry.js":               `exports.name = "plain"`,
+		"/app/node_modules/plain/main.js":                `exports.name = "main"`,
+		"/app/node_modules/plain/other.js":               `exports.name = "other"`,
+		"/app/node_modules/cond/package.json":            `{"exports": {"worker": "./worker.js", "require": "./cjs.js", "default": "./default.js"}}`,
+		"/app/node_modules/cond/worker.js":               `exports.name = "worker"`,
+		"/app/node_modules/cond/cjs.js":                  `exports.name = "cjs"`,
+		"/app/node_modules/cond/default.js":              `exports.name = "default"`,
+		"/app/node_modules/@scope/sub/package.json":      `{"exports": {".": "./index.js", "./feature": {"import": "./feature.mjs", "default": "./feature.js"}, "./lib/*": "./src/*.js", "./lib/internal/*": null, "./lib/*.cjs": "./cjs/*.js", "./fallback": ["node:fs", "./fallback.js"]}}`,
+		"/app/node_modules/@scope/sub/index.js":          `exports.name = "sub"`,
+		"/app/node_modules/@scope/sub/feature.js":        `exports.name = "feature"`,
+		"/app/node_modules/@scope/sub/src/a/b.js":        `exports.name = "pattern"`,
+		"/app/node_modules/@scope/sub/src/internal/x.js": `exports.name = "internal"`,
+		"/app/node_modules/@scope/sub/cjs/c.js":          `exports.name = "trailer"`,
+		"/app/node_modules/@scope/sub/fallback.js":       `exports.name = "fallback"`,
+		"/app/node_modules/bad/package.json":             `{"exports": {"./x": "../outside.js", "./y": "./node_modules/y.js", "./z/*": "./z/*.js"}}`,
+		"/app/node_modules/mixed/package.json":           `{"exports": {".": "./index.js", "require": "./index.js"}}`,
+		"/app/node_modules/self/package.json":            `{"name": "self", "exports": {".": "./index.js", "./util": "./util.js"}}`,
+		"/app/node_modules/self/index.js":                `exports.name = require("self/util").name`,
+		"/app/node_modules/self/util.js":                 `exports.name = "self"`,
+		"/app/node_modules/legacy/package.json":          `{"main": "lib.js"}`,
+		"/app/node_modules/legacy/lib.js":                `exports.name = "legacy"`,
+		"/app/node_modules/missing/package.json":         `{"exports": "./gone.js"}`,
+		"/app/node_modules/invalid/package.json":         `{"exports": `,
+	}
+
+	for i, tc := range []struct {
+		conditions []string
+		path       string
+		ok         bool
+		value      string
+	}{
+		{nil, "plain", true, "plain"},
+		{nil, "plain/other.js", false, jsErrors.ErrCodePackagePathNotExported},
+		{nil, "cond", true, "cjs"},
+		{[]string{"worker", "require"}, "cond", true, "worker"},
+		{[]string{"import"}, "cond", true, "default"},
+		{nil, "@scope/sub", true, "sub"},
+		{nil, "@scope/sub/feature", true, "feature"},
+		{nil, "@scope/sub/lib/a/b", true, "pattern"},
+		{nil, "@scope/sub/lib/internal/x", false, jsErrors.ErrCodePackagePathNotExported},
+		{nil, "@scope/sub/lib/c.cjs", true, "trailer"},
+		{nil, "@scope/sub/fallback", true, "fallback"},
+		{nil, "@scope/sub/missing", false, jsErrors.ErrCodePackagePathNotExported},
+		{nil, "bad/x", false, jsErrors.ErrCodeInvalidPackageTarget},
+		{nil, "bad/y", false, jsErrors.ErrCodeInvalidPackageTarget},
+		{nil, "bad/z/node_modules/secret", false, jsErrors.ErrCodeInvalidModuleSpecifier},
+		{nil, "mixed", false, jsErrors.ErrCodeInvalidPackageConfig},
+		{nil, "self", true, "self"},
+		{nil, "legacy", true, "legacy"},
+		{nil, "missing", false, jsErrors.ErrCodeModuleNotFound},
+		{nil, "invalid", false, jsErrors.ErrCodeInvalidPackageConfig},
+	} {
+		vm := js.New()
+		r := NewRegistry(WithLoader(mapFileSystemSourceLoader(fs)))
+		if tc.conditions != nil {
+			r = NewRegistry(WithConditions(tc.conditions...), WithLoader(mapFileSystemSourceLoader(fs)))
+		}
+		r.Enable(vm)
+		ret, err := vm.RunScript("/app/test.js", fmt.Sprintf("require('%s').name", tc.path))
+		if err != nil {
+			if tc.ok {
+				t.Errorf("%d: require() failed: %v", i, err)
+			} else if code := exceptionCode(err); code != tc.value {
+				t.Errorf("%d: got error %v expected code %q", i, err, tc.value)
+			}
+			continue
+		}
+		if !tc.ok {
+			t.Errorf("%d: expected to fail, but did not", i)
+		} else if ret.String() != tc.value {
+			t.Errorf("%d: got %q expected %q", i, ret.String(), tc.value)
+		}
+	}
+}
+
+func TestPackageImports(t *testing.T) {
+	fs := map[string]string{
+		"/app/package.json":                    `{"imports": {"#dep": {"worker": "./dep-worker.js", "default": "./dep.js"}, "#internal/*": "./src/internal/*.js", "#lib": "lib", "#bad": "../bad.js"}}`,
+		"/app/dep.js":                          `exports.name = "dep"`,
+		"/app/dep-worker.js":                   `exports.name = "dep-worker"`,
+		"/app/src/internal/a.js":               `exports.name = require("#internal/b").name`,
+		"/app/src/internal/b.js":               `exports.name = "internal"`,
+		"/app/node_modules/lib/index.js":       `exports.name = "lib"`,
+		"/app/node_modules/lib/package.json":   `{"imports": {"#own": "./own.js"}}`,
+		"/app/node_modules/lib/own.js":         `exports.name = "own"`,
+		"/app/node_modules/lib/uses-parent.js": `exports.name = require("#dep").name`,
+		"/app/node_modules/lib/uses-own.js":    `exports.name = require("#own").name`,
+	}
+
+	for i, tc := range []struct {
+		conditions []string
+		path       string
+		ok         bool
+		value      string
+	}{
+		{nil, "#dep", true, "dep"},
+		{[]string{"worker"}, "#dep", true, "dep-worker"},
+		{nil, "#internal/a", true, "internal"},
+		{nil, "#lib", true, "lib"},
+		{nil, "#bad", false, jsErrors.ErrCodeInvalidPackageTarget},
+		{nil, "#missing", false, jsErrors.ErrCodePackageImportNotDefined},
+		{nil, "#/x", false, jsErrors.ErrCodeInvalidModuleSpecifier},
+		{nil, "lib/uses-own.js", true, "own"},
+		{nil, "lib/uses-parent.js", false, jsErrors.ErrCodePackageImportNotDefined},
+	} {
+		vm := js.New()
+		r := NewRegistry(WithLoader(mapFileSystemSourceLoader(fs)))
+		if tc.conditions != nil {
+			r = NewRegistry(WithConditions(tc.conditions...), WithLoader(mapFileSystemSourceLoader(fs)))
+		}
+		r.Enable(vm)
+		ret, err := vm.RunScript("/app/test.js", fmt.Sprintf("require('%s').name", tc.path))
+		if err != nil {
+			if tc.ok {
+				t.Errorf("%d: require() failed: %v", i, err)
+			} else if code := exceptionCode(err); code != tc.value {
+				t.Errorf("%d: got error %v expected code %q", i, err, tc.value)
+			}
+			continue
+		}
+		if !tc.ok {
+			t.Errorf("%d: expected to fail, but did not", i)
+		} else if ret.String() != tc.value {
+			t.Errorf("%d: got %q expected %q", i, ret.String(), tc.value)
+		}
+	}
+}
+
+// exceptionCode returns the code of the Error thrown by a script, or "" when it has none.
+func exceptionCode(err error) string {
+	var ex *js.Exception
+	if !errors.As(err, &ex) {
+		return ""
+	}
+	if o, ok := ex.Value().(*js.Object); ok {
+		if code := o.Get("code"); code != nil {
+			return code.String()
+		}
+	}
+	return ""
+}
+
 func TestRequireCycle(t *testing.T) {
 	vm := js.New()
 	r := NewRegistry(WithLoader(mapFileSystemSourceLoader(map[string]string{
@@ -295,6 +486,63 @@ func TestRequireCycle(t *testing.T) {
 	}
 }
 
+func TestNodeBuiltins(t *testing.T) {
+	RegisterCoreModule("util", func(runtime *js.Runtime, module *js.Object) {
+		types := runtime.NewObject()
+		types.Set("marker", "types")
+		module.Get("exports").(*js.Object).Set("types", types)
+	})
+
+	vm := js.New()
+	r := NewRegistry(WithLoader(mapFileSystemSourceLoader(map[string]string{
+		"/node_modules/fs/index.js":       `exports.shadowed = true;`,
+		"/node_modules/left-pad/index.js": `module.exports = "left-pad";`,
+	})))
+	rr := r.Enable(vm)
+
+	res, err := vm.RunScript("/app/main.js", `
+	var util = require("util");
+	[
+		require("node:util") === util,
+		require("sys") === util,
+		require("util/types") === util.types,
+		require("node:util/types").marker,
+		require("left-pad"),
+	].join();
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := res.String(); v != "true,true,true,types,left-pad" {
+		t.Fatalf("Unexpected result: %v", v)
+	}
+
+	for specifier, message := range map[string]string{
+		"fs":               "No such built-in module: fs (required from /app/main.js)",
+		"node:fs/promises": "No such built-in module: node:fs/promises (required from /app/main.js)",
+		"node:unknown":     "No such built-in module: node:unknown (required from /app/main.js)",
+		// the mapped core module is not linked into the tests
+		"querystring": "No such built-in module: querystring (required from /app/main.js)",
+	} {
+		res, err := vm.RunScript("/app/main.js", `
+		try { require("`+specifier+`") } catch (e) { [e.code, e.message, e.requireStack[0]].join("|") }
+		`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := "ERR_UNKNOWN_BUILTIN_MODULE|" + message + "|/app/main.js"; res.String() != expected {
+			t.Errorf("%s: expected %q, got %q", specifier, expected, res.String())
+		}
+	}
+
+	if _, err := rr.Require("node:fs"); !errors.Is(err, NoSuchBuiltInModuleError) || err.Error() != "No such built-in module: node:fs" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := rr.Require("not-installed"); err != InvalidModuleError {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
 func TestErrorPropagation(t *testing.T) {
 	vm := js.New()
 	r := NewRegistry(WithLoader(mapFileSystemSourceLoader(map[string]string{
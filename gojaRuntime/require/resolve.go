@@ -8,7 +8,7 @@ import (
 	"runtime"
 	"strings"
 
-	js "github.com/dop251/goja"
+	js "github.com/grafana/sobek"
 )
 
 const NodePrefix = "node:"
@@ -38,7 +38,12 @@ func (r *RequireModule) resolve(modpath string) (module *js.Object, err error) {
 		if err == nil && module != nil {
 			r.modules[p] = module
 		}
+	} else if strings.HasPrefix(origPath, "#") {
+		module, err = r.loadPackageImports(origPath, start)
 	} else {
+		if module, err = r.loadResolved(origPath); module != nil || err != nil {
+			return
+		}
 		module, err = r.loadNative(origPath)
 		if err == nil {
 			return
@@ -52,6 +57,13 @@ func (r *RequireModule) resolve(modpath string) (module *js.Object, err error) {
 		if module = r.nodeModules[p]; module != nil {
 			return
 		}
+		var ok bool
+		if module, ok, err = r.loadPackageSelf(modpath, start); ok {
+			if err == nil {
+				r.nodeModules[p] = module
+			}
+			return
+		}
 		module, err = r.loadNodeModules(modpath, start)
 		if err == nil && module != nil {
 			r.nodeModules[p] = module
@@ -64,6 +76,24 @@ func (r *RequireModule) resolve(modpath string) (module *js.Object, err error) {
 	return
 }
 
+// loadResolved loads a module provided by the ModuleResolver of the runtime, it returns nil when there is none.
+func (r *RequireModule) loadResolved(name string) (*js.Object, error) {
+	if r.resolver == nil {
+		return nil, nil
+	}
+	if module := r.modules[name]; module != nil {
+		return module, nil
+	}
+	exports, ok, err := r.resolver(name)
+	if !ok || err != nil {
+		return nil, err
+	}
+	module := r.createModuleObject()
+	module.Set("exports", exports)
+	r.modules[name] = module
+	return module, nil
+}
+
 func (r *RequireModule) loadNative(path string) (*js.Object, error) {
 	module := r.modules[path]
 	if module != nil {
@@ -80,9 +110,6 @@ func (r *RequireModule) loadNative(path string) (*js.Object, error) {
 		ldr = builtin[path]
 		if ldr == nil && strings.HasPrefix(path, NodePrefix) {
 			ldr = builtin[path[len(NodePrefix):]]
-			if ldr == nil {
-				return nil, NoSuchBuiltInModuleError
-			}
 			withPrefix = true
 		}
 		isBuiltIn = true
@@ -104,7 +131,7 @@ func (r *RequireModule) loadNative(path string) (*js.Object, error) {
 		return module, nil
 	}
 
-	return nil, InvalidModuleError
+	return r.loadNodeBuiltin(path)
 }
 
 func (r *RequireModule) loadAsFileOrDirectory(path string) (module *js.Object, err error) {
@@ -162,6 +189,9 @@ func (r *RequireModule) loadAsDirectory(modpath string) (module *js.Object, err
 }
 
 func (r *RequireModule) loadNodeModule(modpath, start string) (*js.Object, error) {
+	if module, ok, err := r.loadPackageExports(modpath, start); ok {
+		return module, err
+	}
 	return r.loadAsFileOrDirectory(path.Join(start, modpath))
 }
 
@@ -195,12 +225,21 @@ func (r *RequireModule) loadNodeModules(modpath, start string) (module *js.Objec
 }
 
 func (r *RequireModule) getCurrentModulePath() string {
+	file := r.getCurrentModuleFile()
+	if file == "" {
+		return "."
+	}
+	return path.Dir(file)
+}
+
+// getCurrentModuleFile returns the path of the module calling require, or "" when it is called from Go.
+func (r *RequireModule) getCurrentModuleFile() string {
 	var buf [2]js.StackFrame
 	frames := r.runtime.CaptureCallStack(2, buf[:0])
 	if len(frames) < 2 {
-		return "."
+		return ""
 	}
-	return path.Dir(frames[1].SrcName())
+	return frames[1].SrcName()
 }
 
 func (r *RequireModule) createModuleObject() *js.Object {
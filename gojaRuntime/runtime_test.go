@@ -2,13 +2,34 @@ package goja_runtime
 
 import (
 	"context"
+	"crypto/sha256"
 	"testing"
 	"time"
 
-	"github.com/dop251/goja"
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/abort"
+	jsErrors "github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
+	"github.com/kinde-oss/workflows-runtime/runnertest"
+	sourceEnvelope "github.com/kinde-oss/workflows-runtime/sourceEnvelope"
 	"github.com/stretchr/testify/assert"
 )
 
+// testWorkflow describes a workflow of the given format running source, see runnertest.Workflow, with the bindings it
+// requests.
+func testWorkflow(format runtimesRegistry.ModuleFormat, maxExecutionDuration time.Duration, source string, bindings ...string) runtimesRegistry.WorkflowDescriptor {
+	workflow := runnertest.Workflow(source)
+	workflow.ProcessedSource.ModuleFormat = format
+	workflow.Limits.MaxExecutionDuration = maxExecutionDuration
+	if len(bindings) > 0 {
+		workflow.RequestedBindings = make(map[string]runtimesRegistry.BindingSettings, len(bindings))
+		for _, binding := range bindings {
+			workflow.RequestedBindings[binding] = runtimesRegistry.BindingSettings{}
+		}
+	}
+	return workflow
+}
+
 func TestVmPanicHandling(t *testing.T) {
 
 	result := ""
@@ -27,3 +48,389 @@ func TestVmPanicHandling(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, "test after", result)
 }
+
+func TestNativePanicIsThrownToWorkflow(t *testing.T) {
+	RegisterNativeAPI("panicTest").RegisterNativeFunction("explode", func(ctx context.Context, binding runtimesRegistry.BindingSettings, jsContext JsContext, args ...interface{}) (interface{}, error) {
+		panic("boom")
+	})
+
+	result, err := newGojaRunner().Execute(context.Background(), testWorkflow("", 5*time.Second, `module.exports = { default: async function() {
+		try { panicTest.explode() } catch (e) { return e.code + ": " + e.message }
+	}}`, "panicTest"), runtimesRegistry.StartOptions{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, jsErrors.ErrCodeNativePanic+": native function panicked: boom", result.GetExitResult())
+}
+
+func TestJwtBindingUsesBindingSettings(t *testing.T) {
+	workflow := testWorkflow("", 5*time.Second, `module.exports = { default: async function() {
+		const token = jwt.sign({ sub: "kp_123" }, { expiresIn: 60 });
+		return jwt.verify(token).payload.sub;
+	}}`)
+	workflow.RequestedBindings = map[string]runtimesRegistry.BindingSettings{
+		"jwt": {Settings: map[string]interface{}{
+			"signing_key": map[string]interface{}{"kty": "oct", "k": "c2VjcmV0"},
+			"jwks":        []interface{}{map[string]interface{}{"kty": "oct", "k": "c2VjcmV0"}},
+		}},
+	}
+
+	result, err := newGojaRunner().Execute(context.Background(), workflow, runtimesRegistry.StartOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "kp_123", result.GetExitResult())
+
+	delete(workflow.RequestedBindings["jwt"].Settings, "signing_key")
+	workflow.ProcessedSource.Source = []byte(`module.exports = { default: async function() {
+		try { jwt.sign({ sub: "kp_123" }) } catch (e) { return e.code }
+	}}`)
+	result, err = newGojaRunner().Execute(context.Background(), workflow, runtimesRegistry.StartOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "ERR_JWT_SIGNING_DISABLED", result.GetExitResult())
+}
+
+type recordingLogger struct {
+	entries [][]interface{}
+}
+
+func (l *recordingLogger) Log(level runtimesRegistry.LogLevel, args ...interface{}) {
+	l.entries = append(l.entries, args)
+}
+
+func TestConsoleInspectsObjects(t *testing.T) {
+	logger := &recordingLogger{}
+	_, err := newGojaRunner().Execute(context.Background(), testWorkflow("", 5*time.Second, `module.exports = { default: async function() {
+		const user = { id: "kp_123", roles: ["admin"], created: new Date(0) };
+		user.self = user;
+		console.log("user", user, 42, new Map([["a", 1]]));
+	}}`, "console"), runtimesRegistry.StartOptions{Loggger: logger})
+
+	assert.Nil(t, err)
+	assert.Equal(t, [][]interface{}{{
+		"user",
+		"<ref *1> {\n  id: 'kp_123',\n  roles: [ 'admin' ],\n  created: 1970-01-01T00:00:00.000Z,\n  self: [Circular *1]\n}",
+		int64(42),
+		"Map(1) { 'a' => 1 }",
+	}}, logger.entries)
+}
+
+func TestWebBinding(t *testing.T) {
+	result, err := newGojaRunner().Execute(context.Background(), testWorkflow("", 5*time.Second, `module.exports = { default: async function() {
+		const copy = structuredClone({ at: new Date(0), tags: new Set(["a"]) });
+		const target = new EventTarget();
+		let detail;
+		target.addEventListener("ready", (e) => detail = e.detail);
+		target.dispatchEvent(new CustomEvent("ready", { detail: copy.at.getTime() }));
+		await new Promise((resolve) => queueMicrotask(resolve));
+		return [detail, copy.tags.has("a"), new DOMException("m", "DataCloneError").code].join();
+	}}`, "web"), runtimesRegistry.StartOptions{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "0,true,25", result.GetExitResult())
+}
+
+func TestEventsWarningsAreLogged(t *testing.T) {
+	logger := &recordingLogger{}
+	result, err := newGojaRunner().Execute(context.Background(), testWorkflow("", 5*time.Second, `const { EventEmitter } = require("events");
+		module.exports = { default: async function() {
+			const emitter = new EventEmitter().setMaxListeners(1);
+			emitter.on("data", () => {});
+			emitter.on("data", () => {});
+			return emitter.emit("data");
+		}}`, "console"), runtimesRegistry.StartOptions{Loggger: logger})
+
+	assert.Nil(t, err)
+	assert.Equal(t, true, result.GetExitResult())
+	assert.Equal(t, [][]interface{}{{
+		"MaxListenersExceededWarning: Possible EventEmitter memory leak detected. 2 data listeners added to [EventEmitter]. MaxListeners is 1. Use emitter.setMaxListeners() to increase limit",
+	}}, logger.entries)
+}
+
+func TestNodeBuiltinSpecifiers(t *testing.T) {
+	result, err := newGojaRunner().Execute(context.Background(), testWorkflow("", 5*time.Second, `const { URL } = require("node:url");
+		const util = require("node:util");
+		const path = require("node:path");
+		module.exports = { default: async function() {
+			let missing;
+			try { require("fs") } catch (e) { missing = e.code + ": " + e.message }
+			return [new URL("https://kinde.com/a").pathname, util.format("%d", 1), path.posix.join("a", "b"), missing].join(" ");
+		}}`), runtimesRegistry.StartOptions{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "/a 1 a/b ERR_UNKNOWN_BUILTIN_MODULE: No such built-in module: fs (required from main)", result.GetExitResult())
+}
+
+func TestExecutionSignal(t *testing.T) {
+	waitForAbort := `module.exports = { default: async function() {
+		if (kinde.signal.aborted || !(kinde.signal instanceof AbortSignal)) return "unexpected";
+		return new Promise((resolve) => kinde.signal.addEventListener("abort", () => resolve(kinde.signal.reason.name + ": " + kinde.signal.reason.message)));
+	}}`
+
+	result, err := newGojaRunner().Execute(context.Background(), testWorkflow("", 50*time.Millisecond, waitForAbort, "abort"), runtimesRegistry.StartOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "TimeoutError: execution time exceeded", result.GetExitResult())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	result, err = newGojaRunner().Execute(ctx, testWorkflow("", 5*time.Second, waitForAbort, "abort"), runtimesRegistry.StartOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "AbortError: execution cancelled by user", result.GetExitResult())
+
+	result, err = newGojaRunner().Execute(context.Background(), testWorkflow("", 5*time.Second, `module.exports = { default: async function() {
+		const signal = AbortSignal.timeout(10);
+		return new Promise((resolve) => signal.addEventListener("abort", () => resolve(signal.reason.name)));
+	}}`, "abort"), runtimesRegistry.StartOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "TimeoutError", result.GetExitResult())
+
+	_, err = newGojaRunner().Execute(context.Background(), testWorkflow("", 50*time.Millisecond, `module.exports = { default: async function() {
+		return new Promise(() => {});
+	}}`, "abort"), runtimesRegistry.StartOptions{})
+	assert.ErrorIs(t, err, ErrExecutionTimeExceeded)
+
+	_, err = newGojaRunner().Execute(context.Background(), testWorkflow("", 50*time.Millisecond, `module.exports = { default: async function() {
+		while (true) {}
+	}}`, "abort"), runtimesRegistry.StartOptions{})
+	assert.ErrorContains(t, err, "execution time exceeded")
+}
+
+func TestNativeFunctionsSeeExecutionAbort(t *testing.T) {
+	var cause error
+	var found bool
+	RegisterNativeAPI("signalTest").RegisterNativeFunction("wait", func(ctx context.Context, binding runtimesRegistry.BindingSettings, jsContext JsContext, args ...interface{}) (interface{}, error) {
+		_, found = abort.FromContext(ctx)
+		<-ctx.Done()
+		cause = context.Cause(ctx)
+		return nil, cause
+	})
+
+	_, err := newGojaRunner().Execute(context.Background(), testWorkflow("", 50*time.Millisecond, `module.exports = { default: async function() { signalTest.wait() }}`, "signalTest"), runtimesRegistry.StartOptions{})
+
+	assert.Error(t, err)
+	assert.True(t, found)
+	assert.ErrorIs(t, cause, ErrExecutionTimeExceeded)
+	assert.ErrorIs(t, cause, abort.ErrTimeout)
+}
+
+func TestNativeContextIsReleasedWhenExecutionFinishes(t *testing.T) {
+	var native context.Context
+	RegisterNativeAPI("releaseTest").RegisterNativeFunction("keep", func(ctx context.Context, binding runtimesRegistry.BindingSettings, jsContext JsContext, args ...interface{}) (interface{}, error) {
+		native = ctx
+		return nil, ctx.Err()
+	})
+
+	_, err := newGojaRunner().Execute(context.Background(), testWorkflow("", 5*time.Second, `module.exports = { default: async function() { releaseTest.keep() }}`, "releaseTest"), runtimesRegistry.StartOptions{})
+
+	assert.Nil(t, err)
+	if assert.NotNil(t, native) {
+		assert.ErrorIs(t, native.Err(), context.Canceled)
+	}
+}
+
+func TestWorkflowModules(t *testing.T) {
+	workflow := testWorkflow("", 5*time.Second, `const { greet } = require("./lib/shared");
+	module.exports = { default: async function(name) { return greet(name) }}`)
+	workflow.Modules = map[string]runtimesRegistry.SourceDescriptor{
+		"lib/shared.js":   {Source: []byte(`const { suffix } = require("./config.json"); exports.greet = (name) => "hello " + name + suffix`)},
+		"lib/config.json": {Source: []byte(`{"suffix": "!"}`)},
+	}
+
+	runner := newGojaRunner()
+	result, err := runner.Execute(context.Background(), workflow, runtimesRegistry.StartOptions{Arguments: []interface{}{"kinde"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "hello kinde!", result.GetExitResult())
+
+	// another workflow with a module at the same path gets its own
+	workflow.Modules = map[string]runtimesRegistry.SourceDescriptor{
+		"lib/shared.js": {Source: []byte(`exports.greet = (name) => "hi " + name`)},
+	}
+	result, err = runner.Execute(context.Background(), workflow, runtimesRegistry.StartOptions{Arguments: []interface{}{"kinde"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "hi kinde", result.GetExitResult())
+}
+
+func TestModuleConditions(t *testing.T) {
+	workflow := testWorkflow("", 5*time.Second, `module.exports = { default: async function() { return require("platform").name }}`)
+	workflow.Modules = map[string]runtimesRegistry.SourceDescriptor{
+		"node_modules/platform/package.json": {Source: []byte(`{"exports": {"worker": "./worker.js", "default": "./node.js"}}`)},
+		"node_modules/platform/worker.js":    {Source: []byte(`exports.name = "worker"`)},
+		"node_modules/platform/node.js":      {Source: []byte(`exports.name = "node"`)},
+	}
+
+	runner := newGojaRunner()
+	result, err := runner.Execute(context.Background(), workflow, runtimesRegistry.StartOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "node", result.GetExitResult())
+
+	workflow.Conditions = []string{"worker"}
+	result, err = runner.Execute(context.Background(), workflow, runtimesRegistry.StartOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "worker", result.GetExitResult())
+}
+
+func TestBinarySources(t *testing.T) {
+	envelope := func(source string) []byte {
+		encoded, _ := sourceEnvelope.Encode(sourceEnvelope.Envelope{Source: []byte(source)})
+		return encoded
+	}
+	workflow := testWorkflow("", 5*time.Second, "")
+	workflow.ProcessedSource = runtimesRegistry.SourceDescriptor{
+		Source:     envelope(`const { greet } = require("./lib/shared"); module.exports = { default: async (name) => greet(name) }`),
+		SourceType: runtimesRegistry.Source_ContentType_Binary,
+	}
+	workflow.Modules = map[string]runtimesRegistry.SourceDescriptor{
+		"lib/shared.js": {Source: envelope(`exports.greet = (name) => "hello " + name`), SourceType: runtimesRegistry.Source_ContentType_Binary},
+	}
+
+	runner := newGojaRunner()
+	result, err := runner.Execute(context.Background(), workflow, runtimesRegistry.StartOptions{Arguments: []interface{}{"kinde"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "hello kinde", result.GetExitResult())
+
+	// envelopes are decoded once
+	_, err = runner.Introspect(context.Background(), workflow, runtimesRegistry.IntrospectionOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, runner.(*GojaRunnerV1).cache.sources, 2)
+
+	workflow.ProcessedSource.Source = []byte(`module.exports = { default: async () => "text" }`)
+	_, err = runner.Execute(context.Background(), workflow, runtimesRegistry.StartOptions{})
+	assert.ErrorIs(t, err, sourceEnvelope.ErrNotAnEnvelope)
+}
+
+func TestSourceCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := &gojaCache{}
+	load := func(i int) {
+		cache.cacheSource(sha256.Sum256([]byte{byte(i)}), func() (runtimesRegistry.SourceDescriptor, error) {
+			return runtimesRegistry.SourceDescriptor{Source: []byte{byte(i)}}, nil
+		})
+	}
+	for i := 0; i < maxCachedSources; i++ {
+		load(i)
+	}
+	// using the first source keeps it, the second one is evicted instead
+	load(0)
+	load(maxCachedSources)
+	assert.Len(t, cache.sources, maxCachedSources)
+	assert.Contains(t, cache.sources, sha256.Sum256([]byte{0}))
+	assert.NotContains(t, cache.sources, sha256.Sum256([]byte{1}))
+}
+
+func TestESModules(t *testing.T) {
+	workflow := testWorkflow(runtimesRegistry.ModuleFormatESM, 5*time.Second, `import path, { join } from "node:path";
+		import { greet } from "./lib/greet.js";
+		import legacy from "./lib/legacy";
+		export const workflowSettings = { id: "esm", bindings: { "kinde.fetch": {} } };
+		export default async function(name) {
+			const { URL } = await import("node:url");
+			let missing;
+			try { await import("node:fs") } catch (e) { missing = e.code }
+			return [greet(name), join("a", "b"), path.sep, legacy.name, import.meta.url, new URL("https://kinde.com/x").pathname, missing].join(" ");
+		}`)
+	workflow.Modules = map[string]runtimesRegistry.SourceDescriptor{
+		"lib/greet.js":  {ModuleFormat: runtimesRegistry.ModuleFormatESM, Source: []byte(`export const greet = (name) => "hello " + name + " from " + import.meta.filename`)},
+		"lib/legacy.js": {Source: []byte(`exports.name = "legacy"`)},
+	}
+
+	runner := newGojaRunner()
+	result, err := runner.Execute(context.Background(), workflow, runtimesRegistry.StartOptions{Arguments: []interface{}{"kinde"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "hello kinde from /lib/greet.js a/b / legacy file:///main /x ERR_MODULE_NOT_FOUND", result.GetExitResult())
+
+	introspection, err := runner.Introspect(context.Background(), workflow, runtimesRegistry.IntrospectionOptions{Exports: []string{"workflowSettings"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "esm", introspection.GetExport("workflowSettings").ValueAsMap()["id"])
+
+	// the modules are parsed once, later executions link the cached syntax trees
+	assert.Len(t, runner.(*GojaRunnerV1).cache.modules, 2)
+	result, err = runner.Execute(context.Background(), workflow, runtimesRegistry.StartOptions{Arguments: []interface{}{"again"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "hello again from /lib/greet.js a/b / legacy file:///main /x ERR_MODULE_NOT_FOUND", result.GetExitResult())
+	assert.Len(t, runner.(*GojaRunnerV1).cache.modules, 2)
+
+	workflow.ProcessedSource.Source = []byte(`import { missing } from "./lib/missing.js"; export default async function() {}`)
+	_, err = runner.Execute(context.Background(), workflow, runtimesRegistry.StartOptions{})
+	assert.ErrorContains(t, err, "Cannot find module './lib/missing.js' imported from main")
+}
+
+func TestTopLevelAwait(t *testing.T) {
+	initialised := testWorkflow(runtimesRegistry.ModuleFormatESM, 5*time.Second, `const config = await Promise.resolve({ greeting: "hello" });
+		export let workflowSettings;
+		workflowSettings = { id: await import("node:path").then((path) => "tla" + path.sep) };
+		export default async function(name) { return config.greeting + " " + name }`)
+	runner := newGojaRunner()
+	result, err := runner.Execute(context.Background(), initialised, runtimesRegistry.StartOptions{Arguments: []interface{}{"kinde"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "hello kinde", result.GetExitResult())
+
+	introspection, err := runner.Introspect(context.Background(), initialised, runtimesRegistry.IntrospectionOptions{Exports: []string{"workflowSettings"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "tla/", introspection.GetExport("workflowSettings").ValueAsMap()["id"])
+
+	// initialisation counts against the execution time
+	_, err = runner.Execute(context.Background(), testWorkflow(runtimesRegistry.ModuleFormatESM, 50*time.Millisecond, `await new Promise(() => {}); export default async function() {}`), runtimesRegistry.StartOptions{})
+	assert.ErrorIs(t, err, ErrExecutionTimeExceeded)
+
+	_, err = runner.Execute(context.Background(), testWorkflow(runtimesRegistry.ModuleFormatESM, 50*time.Millisecond, `await null; while (true) {} export default async function() {}`), runtimesRegistry.StartOptions{})
+	assert.ErrorContains(t, err, "execution time exceeded")
+
+	_, err = runner.Execute(context.Background(), testWorkflow(runtimesRegistry.ModuleFormatESM, 5*time.Second, `await Promise.reject(new Error("config unavailable")); export default async function() {}`), runtimesRegistry.StartOptions{})
+	assert.ErrorContains(t, err, "config unavailable")
+}
+
+func TestImportBindings(t *testing.T) {
+	RegisterNativeAPI("importTest").RegisterNativeAPI("sub").RegisterNativeFunction("hello", func(ctx context.Context, binding runtimesRegistry.BindingSettings, jsContext JsContext, args ...interface{}) (interface{}, error) {
+		return "hello " + args[0].(string), nil
+	})
+	assert.Contains(t, BindingSpecifiers(), "importTest:*")
+	assert.Contains(t, BindingSpecifiers(), "@importTest/runtime")
+
+	runner := newGojaRunner()
+	result, err := runner.Execute(context.Background(), testWorkflow(runtimesRegistry.ModuleFormatCommonJS, 5*time.Second, `const { hello } = require("importTest:sub");
+	module.exports = { default: async function(name) { return hello(name) + " " + (require("@importTest/runtime").sub === importTest.sub) }}`, "importTest.sub"), runtimesRegistry.StartOptions{Arguments: []interface{}{"kinde"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "hello kinde true", result.GetExitResult())
+
+	result, err = runner.Execute(context.Background(), testWorkflow(runtimesRegistry.ModuleFormatESM, 5*time.Second, `import { hello } from "importTest:sub";
+		import { sub } from "@importTest/runtime";
+		export default async function(name) {
+			const dynamic = await import("importTest:sub");
+			return hello(name) + " " + (sub.hello === dynamic.hello);
+		}`, "importTest.sub"), runtimesRegistry.StartOptions{Arguments: []interface{}{"kinde"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "hello kinde true", result.GetExitResult())
+
+	// bindings which are not requested cannot be imported
+	result, err = runner.Execute(context.Background(), testWorkflow(runtimesRegistry.ModuleFormatCommonJS, 5*time.Second, `module.exports = { default: async function() {
+		try { require("importTest:other") } catch (e) { return e.code }
+	}}`, "importTest.sub"), runtimesRegistry.StartOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, ErrCodeBindingNotRequested, result.GetExitResult())
+
+	result, err = runner.Execute(context.Background(), testWorkflow(runtimesRegistry.ModuleFormatESM, 5*time.Second, `export default async function() {
+		try { await import("importTest:other") } catch (e) { return e.code }
+	}`, "importTest.sub"), runtimesRegistry.StartOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, ErrCodeBindingNotRequested, result.GetExitResult())
+
+	_, err = runner.Execute(context.Background(), testWorkflow(runtimesRegistry.ModuleFormatESM, 5*time.Second, `import { other } from "importTest:other"; export default async function() {}`, "importTest.sub"), runtimesRegistry.StartOptions{})
+	assert.ErrorContains(t, err, "binding importTest.other is not requested by the workflow")
+}
+
+func TestWebAssemblyLimits(t *testing.T) {
+	// spinModule has a memory of 2 pages and exports spin, which loops forever
+	const spinModule = "0061736d01000000010401600000030201000503010002070801047370696e00000a0901070003400c000b0b"
+	source := func(body string) string {
+		return `const bytes = new Uint8Array("` + spinModule + `".match(/../g).map((b) => parseInt(b, 16)));
+			module.exports = { default: async function() {` + body + `}}`
+	}
+
+	runner := newGojaRunner()
+	start := time.Now()
+	_, err := runner.Execute(context.Background(), testWorkflow("", 50*time.Millisecond, source(`const { instance } = await WebAssembly.instantiate(bytes); instance.exports.spin()`), "webassembly"), runtimesRegistry.StartOptions{})
+	assert.ErrorContains(t, err, "execution time exceeded")
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	limited := testWorkflow("", 5*time.Second, source(`try { await WebAssembly.instantiate(bytes) } catch (e) { return e.code }`), "webassembly")
+	limited.Limits.MaxMemoryBytes = 65536
+	result, err := runner.Execute(context.Background(), limited, runtimesRegistry.StartOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, jsErrors.ErrCodeMemoryLimitExceeded, result.GetExitResult())
+}
@@ -0,0 +1,149 @@
+package goja_runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/abort"
+)
+
+var (
+	// ErrExecutionCancelled is the abort cause when the context passed to Execute is cancelled.
+	ErrExecutionCancelled = errors.New("execution cancelled by user")
+	// ErrExecutionTimeExceeded is the abort cause when MaxExecutionDuration is reached, it matches abort.ErrTimeout.
+	ErrExecutionTimeExceeded = abort.NewTimeoutError("execution time exceeded")
+)
+
+// abortGracePeriod bounds how long the abort listeners of a workflow may run before the VM is interrupted.
+const abortGracePeriod = 100 * time.Millisecond
+
+// executionSignal aborts when the host cancels the execution or its time limit is reached.
+// While the workflow is running JS the VM is interrupted straight away, when it is waiting for its
+// promise to settle the abort listeners get to run first, see waitIdle.
+type executionSignal struct {
+	*abort.Signal
+	vm *goja.Runtime
+	// js is the signal exposed to the workflow as kinde.signal, nil when the abort binding is not requested
+	js goja.Value
+	// timers fire the AbortSignal.timeout signals of the workflow while it waits, see settle
+	timers *abort.Timers
+
+	lock     sync.Mutex
+	idle     bool
+	finished chan struct{}
+	once     sync.Once
+	// releases cancel the contexts derived from the signal once the execution finishes
+	releases []context.CancelFunc
+}
+
+func newExecutionSignal(vm *goja.Runtime) *executionSignal {
+	return &executionSignal{
+		Signal:   abort.NewSignal(),
+		vm:       vm,
+		timers:   abort.NewTimers(vm),
+		finished: make(chan struct{}),
+	}
+}
+
+// watch aborts the signal when ctx is done or maxExecutionDuration has elapsed, until the execution finishes.
+func (s *executionSignal) watch(ctx context.Context, maxExecutionDuration time.Duration) {
+	go func() {
+		timer := time.NewTimer(maxExecutionDuration)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			s.abort(ErrExecutionCancelled)
+		case <-timer.C:
+			s.abort(ErrExecutionTimeExceeded)
+		case <-s.finished:
+		}
+	}()
+}
+
+func (s *executionSignal) abort(cause error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.Abort(cause) {
+		return
+	}
+	if !s.idle {
+		s.vm.Interrupt(cause.Error())
+	}
+}
+
+// context returns a copy of parent cancelled when the execution is aborted, or once it finishes.
+func (s *executionSignal) context(parent context.Context) context.Context {
+	ctx, release := abort.WithSignal(parent, s.Signal)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.releases = append(s.releases, release)
+	return ctx
+}
+
+// finish stops watching the execution and its timers, and cancels the contexts derived from the signal.
+func (s *executionSignal) finish() {
+	s.once.Do(func() {
+		close(s.finished)
+		s.timers.Stop()
+		s.lock.Lock()
+		releases := s.releases
+		s.releases = nil
+		s.lock.Unlock()
+		for _, release := range releases {
+			release()
+		}
+	})
+}
+
+// waitIdle sleeps for d with the VM marked idle and returns the abort cause if the signal was aborted.
+func (s *executionSignal) waitIdle(d time.Duration) error {
+	s.lock.Lock()
+	if cause := s.Err(); cause != nil {
+		s.lock.Unlock()
+		return cause
+	}
+	s.idle = true
+	s.lock.Unlock()
+
+	select {
+	case <-time.After(d):
+	case <-s.Done():
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.idle = false
+	return s.Err()
+}
+
+// notify runs the abort listeners of the workflow, interrupting them once abortGracePeriod has elapsed.
+func (s *executionSignal) notify(cause error) error {
+	timer := time.AfterFunc(abortGracePeriod, func() {
+		s.vm.Interrupt(cause.Error())
+	})
+	defer timer.Stop()
+	return abort.Sync(s.js)
+}
+
+// settle waits for promise to settle, firing the elapsed AbortSignal.timeout signals meanwhile. Once the execution is
+// aborted the abort listeners of the workflow get to settle it, the abort cause is returned when they do not.
+func (s *executionSignal) settle(promise *goja.Promise) error {
+	for promise.State() == goja.PromiseStatePending {
+		if cause := s.waitIdle(1 * time.Millisecond); cause != nil {
+			if err := s.notify(cause); err != nil {
+				return fmt.Errorf("%v", err.Error())
+			}
+			if promise.State() == goja.PromiseStatePending {
+				return cause
+			}
+		}
+		if err := s.timers.Run(); err != nil {
+			return fmt.Errorf("%v", err.Error())
+		}
+	}
+	return nil
+}
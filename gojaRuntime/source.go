@@ -0,0 +1,59 @@
+package goja_runtime
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
+	sourceEnvelope "github.com/kinde-oss/workflows-runtime/sourceEnvelope"
+)
+
+// textSource decodes a binary source from its envelope, once per envelope. Its source map is inlined again, so that
+// stack traces map to the original sources, and the module format of the envelope applies unless the descriptor sets
+// one.
+func textSource(cache *gojaCache, source runtimesRegistry.SourceDescriptor) (runtimesRegistry.SourceDescriptor, error) {
+	if source.SourceType != runtimesRegistry.Source_ContentType_Binary {
+		return source, nil
+	}
+	decoded, err := cache.cacheSource(sha256.Sum256(source.Source), func() (runtimesRegistry.SourceDescriptor, error) {
+		envelope, err := sourceEnvelope.Decode(source.Source)
+		if err != nil {
+			return source, err
+		}
+		return runtimesRegistry.SourceDescriptor{
+			Source:       sourceEnvelope.JoinSourceMap(envelope.Source, envelope.SourceMap),
+			SourceType:   runtimesRegistry.Source_ContentType_Text,
+			BuildHash:    envelope.Header.BuildHash,
+			ModuleFormat: envelope.Header.ModuleFormat,
+		}, nil
+	})
+	if err != nil {
+		return source, err
+	}
+	if source.BuildHash != "" {
+		decoded.BuildHash = source.BuildHash
+	}
+	if source.ModuleFormat != "" {
+		decoded.ModuleFormat = source.ModuleFormat
+	}
+	return decoded, nil
+}
+
+// textWorkflow returns workflow with its processed source and its modules as text.
+func textWorkflow(cache *gojaCache, workflow runtimesRegistry.WorkflowDescriptor) (runtimesRegistry.WorkflowDescriptor, error) {
+	var err error
+	if workflow.ProcessedSource, err = textSource(cache, workflow.ProcessedSource); err != nil {
+		return workflow, fmt.Errorf("error decoding source: %w", err)
+	}
+	if len(workflow.Modules) == 0 {
+		return workflow, nil
+	}
+	modules := make(map[string]runtimesRegistry.SourceDescriptor, len(workflow.Modules))
+	for path, module := range workflow.Modules {
+		if modules[path], err = textSource(cache, module); err != nil {
+			return workflow, fmt.Errorf("error decoding module %v: %w", path, err)
+		}
+	}
+	workflow.Modules = modules
+	return workflow, nil
+}
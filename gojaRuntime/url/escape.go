@@ -89,7 +89,8 @@ func escape(s string, table *[128]byte, spaceToPlus bool) string {
 	return sb.String()
 }
 
-func unescapeSearchParam(s string) string {
+// UnescapeSearchParam decodes the percent-encoded bytes and '+' signs of s, malformed escapes are kept as they are.
+func UnescapeSearchParam(s string) string {
 	n := 0
 	hasPlus := false
 	for i := 0; i < len(s); {
@@ -1,7 +1,7 @@
 package url
 
 import (
-	"github.com/dop251/goja"
+	goja "github.com/grafana/sobek"
 	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
 )
 
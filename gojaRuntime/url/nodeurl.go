@@ -14,13 +14,15 @@ func (sp *SearchParam) Encode() string {
 	return sp.string(true)
 }
 
-func escapeSearchParam(s string) string {
+// EscapeSearchParam percent-encodes s the way URLSearchParams serialises names and values,
+// with spaces as '+'. The querystring module shares it so both encode alike.
+func EscapeSearchParam(s string) string {
 	return escape(s, &tblEscapeURLQueryParam, true)
 }
 
 func (sp *SearchParam) string(encode bool) string {
 	if encode {
-		return escapeSearchParam(sp.Name) + "=" + escapeSearchParam(sp.Value)
+		return EscapeSearchParam(sp.Name) + "=" + EscapeSearchParam(sp.Value)
 	} else {
 		return sp.Name + "=" + sp.Value
 	}
@@ -138,9 +140,9 @@ func parseSearchQuery(query string) (ret SearchParams) {
 		pair := strings.SplitN(v, "=", 2)
 		l := len(pair)
 		if l == 1 {
-			ret = append(ret, SearchParam{Name: unescapeSearchParam(pair[0]), Value: ""})
+			ret = append(ret, SearchParam{Name: UnescapeSearchParam(pair[0]), Value: ""})
 		} else if l == 2 {
-			ret = append(ret, SearchParam{Name: unescapeSearchParam(pair[0]), Value: unescapeSearchParam(pair[1])})
+			ret = append(ret, SearchParam{Name: UnescapeSearchParam(pair[0]), Value: UnescapeSearchParam(pair[1])})
 		}
 	}
 
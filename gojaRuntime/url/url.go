@@ -7,7 +7,7 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/dop251/goja"
+	goja "github.com/grafana/sobek"
 	"github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
 
 	"golang.org/x/net/idna"
@@ -385,6 +385,7 @@ func (m *urlModule) createURLConstructor() goja.Value {
 	}).(*goja.Object)
 
 	proto := m.createURLPrototype()
+	f.DefineDataProperty("name", m.r.ToValue("URL"), goja.FLAG_FALSE, goja.FLAG_TRUE, goja.FLAG_FALSE)
 	f.Set("prototype", proto)
 	proto.DefineDataProperty("constructor", f, goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
 	return f
@@ -6,7 +6,7 @@ import (
 
 	"github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
 
-	"github.com/dop251/goja"
+	goja "github.com/grafana/sobek"
 )
 
 var (
@@ -55,6 +55,7 @@ func (m *urlModule) createURLSearchParamsConstructor() goja.Value {
 	}).(*goja.Object)
 
 	m.URLSearchParamsPrototype = m.createURLSearchParamsPrototype()
+	f.DefineDataProperty("name", m.r.ToValue("URLSearchParams"), goja.FLAG_FALSE, goja.FLAG_TRUE, goja.FLAG_FALSE)
 	f.Set("prototype", m.URLSearchParamsPrototype)
 	m.URLSearchParamsPrototype.DefineDataProperty("constructor", f, goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
 
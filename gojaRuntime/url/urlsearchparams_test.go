@@ -4,7 +4,7 @@ import (
 	_ "embed"
 	"testing"
 
-	"github.com/dop251/goja"
+	goja "github.com/grafana/sobek"
 	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
 )
 
@@ -0,0 +1,348 @@
+package util
+
+import (
+	"bytes"
+
+	goja "github.com/grafana/sobek"
+)
+
+// deepEqualMemo tracks the pairs of objects being compared to terminate on cycles.
+type deepEqualMemo struct {
+	val1, val2 map[*goja.Object]int
+	position   int
+}
+
+// IsDeepStrictEqual reports whether a and b are deeply equal using the rules of Node.js' assert.deepStrictEqual:
+// primitives are compared with Object.is, prototypes and own enumerable properties must match and
+// Maps, Sets, Dates, RegExps, errors, boxed primitives and binary data are compared by content.
+func (u *Util) IsDeepStrictEqual(a, b goja.Value) bool {
+	return u.deepEqual(a, b, nil)
+}
+
+func (u *Util) deepEqual(val1, val2 goja.Value, memo *deepEqualMemo) bool {
+	if val1.SameAs(val2) {
+		return true
+	}
+	o1, ok1 := val1.(*goja.Object)
+	o2, ok2 := val2.(*goja.Object)
+	if !ok1 || !ok2 || u.isFunction(o1) || u.isFunction(o2) {
+		return false
+	}
+	if !sameObject(o1.Prototype(), o2.Prototype()) {
+		return false
+	}
+	if u.tag(o1) != u.tag(o2) {
+		return false
+	}
+
+	r := u.runtime
+	iteration := iterateNone
+	switch {
+	case o1.ClassName() == "Array":
+		if o2.ClassName() != "Array" || o1.Get("length").ToInteger() != o2.Get("length").ToInteger() {
+			return false
+		}
+		iteration = iterateArray
+	case o1.ClassName() == "Date":
+		if o2.ClassName() != "Date" || !call(u.intrinsics.dateGetTime, o1).SameAs(call(u.intrinsics.dateGetTime, o2)) {
+			return false
+		}
+	case o1.ClassName() == "RegExp":
+		if o2.ClassName() != "RegExp" {
+			return false
+		}
+		for _, name := range []string{"source", "flags", "lastIndex"} {
+			if !o1.Get(name).SameAs(o2.Get(name)) {
+				return false
+			}
+		}
+	case u.IsNativeError(o1) || r.InstanceOf(o1, u.errorConstructor()):
+		if !u.IsNativeError(o2) && !r.InstanceOf(o2, u.errorConstructor()) {
+			return false
+		}
+		if !o1.Get("message").SameAs(o2.Get("message")) || !o1.Get("name").SameAs(o2.Get("name")) {
+			return false
+		}
+	case u.IsArrayBufferView(o1):
+		if u.TypedArrayName(o1) != u.TypedArrayName(o2) || !u.IsArrayBufferView(o2) {
+			return false
+		}
+		if !bytes.Equal(viewedBytes(o1), viewedBytes(o2)) {
+			return false
+		}
+		iteration = iterateTypedArray
+	case u.IsSet(o1):
+		if !u.IsSet(o2) || !call(u.intrinsics.setSize, o1).SameAs(call(u.intrinsics.setSize, o2)) {
+			return false
+		}
+		iteration = iterateSet
+	case u.IsMap(o1):
+		if !u.IsMap(o2) || !call(u.intrinsics.mapSize, o1).SameAs(call(u.intrinsics.mapSize, o2)) {
+			return false
+		}
+		iteration = iterateMap
+	case u.IsArrayBuffer(o1):
+		if !u.IsArrayBuffer(o2) || !bytes.Equal(viewedBytes(o1), viewedBytes(o2)) {
+			return false
+		}
+	case u.IsBoxedPrimitive(o1):
+		if !u.IsBoxedPrimitive(o2) || !u.BoxedValue(o1).SameAs(u.BoxedValue(o2)) {
+			return false
+		}
+	case o2.ClassName() == "Array" || u.IsArrayBufferView(o2) || u.IsSet(o2) || u.IsMap(o2) ||
+		o2.ClassName() == "Date" || o2.ClassName() == "RegExp" || u.IsArrayBuffer(o2) || u.IsBoxedPrimitive(o2) || u.IsNativeError(o2):
+		return false
+	}
+	return u.keyCheck(o1, o2, iteration, memo)
+}
+
+const (
+	iterateNone = iota
+	iterateArray
+	iterateTypedArray
+	iterateSet
+	iterateMap
+)
+
+func sameObject(a, b *goja.Object) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.SameAs(b)
+}
+
+func (u *Util) errorConstructor() *goja.Object {
+	constructor, _ := u.intrinsics.errorPrototype.Get("constructor").(*goja.Object)
+	if constructor == nil {
+		return u.runtime.NewObject()
+	}
+	return constructor
+}
+
+// BoxedValue returns the primitive wrapped by a Number, String, Boolean, BigInt or Symbol object.
+func (u *Util) BoxedValue(o *goja.Object) goja.Value {
+	switch {
+	case u.isNumberObject(o):
+		return call(u.intrinsics.valueOf["Number"], o)
+	case u.isStringObject(o):
+		return call(u.intrinsics.valueOf["String"], o)
+	case u.isBooleanObject(o):
+		return call(u.intrinsics.valueOf["Boolean"], o)
+	case u.isBigIntObject(o):
+		return call(u.intrinsics.valueOf["BigInt"], o)
+	}
+	return call(u.intrinsics.symbolValueOf, o)
+}
+
+func (u *Util) keyCheck(o1, o2 *goja.Object, iteration int, memo *deepEqualMemo) bool {
+	keys1 := o1.Keys()
+	if iteration == iterateArray || iteration == iterateTypedArray {
+		keys1 = nonIndexKeys(keys1)
+	}
+	keys2 := o2.Keys()
+	if iteration == iterateArray || iteration == iterateTypedArray {
+		keys2 = nonIndexKeys(keys2)
+	}
+	if len(keys1) != len(keys2) {
+		return false
+	}
+	enumerable2 := make(map[string]bool, len(keys2))
+	for _, key := range keys2 {
+		enumerable2[key] = true
+	}
+	for _, key := range keys1 {
+		if !enumerable2[key] {
+			return false
+		}
+	}
+
+	symbols1, symbols2 := o1.Symbols(), o2.Symbols()
+	if len(symbols1) != len(symbols2) {
+		return false
+	}
+	for _, symbol := range symbols1 {
+		found := false
+		for _, other := range symbols2 {
+			if symbol == other {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(keys1) == 0 && len(symbols1) == 0 && (iteration == iterateNone || iteration == iterateTypedArray ||
+		iteration == iterateArray && o1.Get("length").ToInteger() == 0) {
+		return true
+	}
+
+	if memo == nil {
+		memo = &deepEqualMemo{
+			val1: map[*goja.Object]int{},
+			val2: map[*goja.Object]int{},
+		}
+	} else {
+		if position1, ok := memo.val1[o1]; ok {
+			if position2, ok := memo.val2[o2]; ok {
+				return position1 == position2
+			}
+		}
+		memo.position++
+	}
+	memo.val1[o1] = memo.position
+	memo.val2[o2] = memo.position
+	defer func() {
+		delete(memo.val1, o1)
+		delete(memo.val2, o2)
+	}()
+
+	return u.objectEquiv(o1, o2, keys1, symbols1, iteration, memo)
+}
+
+func nonIndexKeys(keys []string) []string {
+	var result []string
+	for _, key := range keys {
+		if !isIndex(key) {
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+func (u *Util) objectEquiv(o1, o2 *goja.Object, keys []string, symbols []*goja.Symbol, iteration int, memo *deepEqualMemo) bool {
+	r := u.runtime
+	switch iteration {
+	case iterateArray:
+		length := o1.Get("length").ToInteger()
+		for i := int64(0); i < length; i++ {
+			key := r.ToValue(i)
+			has1, has2 := call(u.intrinsics.hasOwnProperty, o1, key).ToBoolean(), call(u.intrinsics.hasOwnProperty, o2, key).ToBoolean()
+			if has1 != has2 {
+				return false
+			}
+			if has1 && !u.deepEqual(o1.Get(key.String()), o2.Get(key.String()), memo) {
+				return false
+			}
+		}
+	case iterateSet:
+		if !u.setEquiv(o1, o2, memo) {
+			return false
+		}
+	case iterateMap:
+		if !u.mapEquiv(o1, o2, memo) {
+			return false
+		}
+	}
+
+	for _, key := range keys {
+		if !u.deepEqual(o1.Get(key), o2.Get(key), memo) {
+			return false
+		}
+	}
+	for _, symbol := range symbols {
+		if !u.deepEqual(o1.GetSymbol(symbol), o2.GetSymbol(symbol), memo) {
+			return false
+		}
+	}
+	return true
+}
+
+func isObject(v goja.Value) bool {
+	o, ok := v.(*goja.Object)
+	if !ok {
+		return false
+	}
+	_, isFunction := goja.AssertFunction(o)
+	return !isFunction
+}
+
+// setEquiv compares Sets, primitives must be present in both while objects are matched pairwise by deep equality.
+func (u *Util) setEquiv(a, b *goja.Object, memo *deepEqualMemo) bool {
+	var objects []goja.Value
+	equal := true
+	iterate(u.runtime, u.intrinsics.setValues, a, func(v goja.Value) bool {
+		if isObject(v) {
+			objects = append(objects, v)
+		} else if !call(u.intrinsics.setHas, b, v).ToBoolean() {
+			equal = false
+		}
+		return equal
+	})
+	if !equal || objects == nil {
+		return equal
+	}
+
+	iterate(u.runtime, u.intrinsics.setValues, b, func(v goja.Value) bool {
+		if !isObject(v) {
+			return true
+		}
+		for i, candidate := range objects {
+			if u.deepEqual(v, candidate, memo) {
+				objects = append(objects[:i], objects[i+1:]...)
+				return true
+			}
+		}
+		equal = false
+		return false
+	})
+	return equal && len(objects) == 0
+}
+
+// mapEquiv compares Maps, entries with primitive keys are looked up while object keys are matched pairwise.
+func (u *Util) mapEquiv(a, b *goja.Object, memo *deepEqualMemo) bool {
+	r := u.runtime
+	var objectKeys []goja.Value
+	equal := true
+	iterate(r, u.intrinsics.mapEntries, a, func(v goja.Value) bool {
+		entry := v.ToObject(r)
+		key, item := entry.Get("0"), entry.Get("1")
+		if isObject(key) {
+			objectKeys = append(objectKeys, key)
+			return true
+		}
+		item2 := call(u.intrinsics.mapGet, b, key)
+		if goja.IsUndefined(item2) && !call(u.intrinsics.mapHas, b, key).ToBoolean() || !u.deepEqual(item, item2, memo) {
+			equal = false
+		}
+		return equal
+	})
+	if !equal || objectKeys == nil {
+		return equal
+	}
+
+	iterate(r, u.intrinsics.mapEntries, b, func(v goja.Value) bool {
+		entry := v.ToObject(r)
+		key, item := entry.Get("0"), entry.Get("1")
+		if !isObject(key) {
+			return true
+		}
+		for i, candidate := range objectKeys {
+			if u.deepEqual(key, candidate, memo) && u.deepEqual(item, call(u.intrinsics.mapGet, a, candidate), memo) {
+				objectKeys = append(objectKeys[:i], objectKeys[i+1:]...)
+				return true
+			}
+		}
+		equal = false
+		return false
+	})
+	return equal && len(objectKeys) == 0
+}
+
+// viewedBytes returns the bytes of an ArrayBuffer, or the ones an ArrayBuffer view covers, nil for anything else.
+func viewedBytes(o *goja.Object) []byte {
+	if buffer, ok := o.Export().(goja.ArrayBuffer); ok {
+		return buffer.Bytes()
+	}
+	buffer, ok := o.Get("buffer").Export().(goja.ArrayBuffer)
+	if !ok {
+		return nil
+	}
+	data := buffer.Bytes()
+	offset, length := o.Get("byteOffset").ToInteger(), o.Get("byteLength").ToInteger()
+	if offset < 0 || length < 0 || offset+length > int64(len(data)) {
+		return nil
+	}
+	return data[offset : offset+length]
+}
@@ -0,0 +1,1011 @@
+package util
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	goja "github.com/grafana/sobek"
+)
+
+// InspectOptions control the output of Inspect, it never contains ANSI colors.
+type InspectOptions struct {
+	// Depth is the number of nested levels expanded, negative for no limit.
+	Depth int
+	// BreakLength is the line length above which entries are split onto separate lines.
+	BreakLength int
+	// Compact is the number of innermost levels kept on a single line, 0 always splits.
+	Compact int
+}
+
+// DefaultInspectOptions returns the options util.inspect uses when called without any, they match Node.js.
+func DefaultInspectOptions() InspectOptions {
+	return InspectOptions{
+		Depth:       2,
+		BreakLength: 80,
+		Compact:     3,
+	}
+}
+
+const (
+	maxArrayLength  = 100
+	maxStringLength = 10000
+	minLineWidth    = 16
+)
+
+const (
+	kindObject = iota
+	kindArray
+	kindArrayExtras
+)
+
+var (
+	keyStrRegExp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z_0-9]*$`)
+
+	escapes = func() map[rune]string {
+		m := map[rune]string{'\b': `\b`, '\t': `\t`, '\n': `\n`, '\f': `\f`, '\r': `\r`, '\\': `\\`}
+		for c := rune(0); c < 0xa0; c++ {
+			if _, ok := m[c]; !ok && (c < 0x20 || c >= 0x7f) {
+				m[c] = fmt.Sprintf(`\x%02X`, c)
+			}
+		}
+		return m
+	}()
+)
+
+type inspectContext struct {
+	u              *Util
+	options        InspectOptions
+	seen           []*goja.Object
+	circular       map[*goja.Object]int
+	indentationLvl int
+	currentDepth   int
+}
+
+// Inspect returns a string representation of v intended for debugging, the same way Node.js' util.inspect does.
+// Like goja's own methods it panics with a *goja.Exception if a script throws while v is inspected.
+func (u *Util) Inspect(v goja.Value, options InspectOptions) string {
+	if options.BreakLength <= 0 {
+		options.BreakLength = math.MaxInt32
+	}
+	ctx := &inspectContext{
+		u:        u,
+		options:  options,
+		circular: map[*goja.Object]int{},
+	}
+	return ctx.formatValue(v, 0)
+}
+
+func isString(v goja.Value) bool {
+	if v == nil || v.ExportType() == nil {
+		return false
+	}
+	_, isSymbol := v.(*goja.Symbol)
+	return !isSymbol && v.ExportType().Kind() == reflect.String
+}
+
+func isNumber(v goja.Value) bool {
+	if v == nil || v.ExportType() == nil {
+		return false
+	}
+	kind := v.ExportType().Kind()
+	return kind == reflect.Int64 || kind == reflect.Float64
+}
+
+func isBigInt(v goja.Value) bool {
+	_, isObject := v.(*goja.Object)
+	return !isObject && v != nil && v.ExportType() == typeBigInt
+}
+
+// isIndex reports whether key is a canonical array index.
+func isIndex(key string) bool {
+	n, err := strconv.ParseUint(key, 10, 32)
+	return err == nil && n < math.MaxUint32 && strconv.FormatUint(n, 10) == key
+}
+
+func stringLength(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+func defined(v goja.Value) bool {
+	return v != nil && !goja.IsUndefined(v)
+}
+
+func plural(n int64) string {
+	if n > 1 {
+		return "s"
+	}
+	return ""
+}
+
+func remainingText(remaining int64) string {
+	return fmt.Sprintf("... %d more item%s", remaining, plural(remaining))
+}
+
+// quote escapes s and wraps it in single quotes, or in double quotes or backticks when that avoids escaping.
+func quote(s string) string {
+	q := '\''
+	if strings.ContainsRune(s, '\'') {
+		if !strings.ContainsRune(s, '"') {
+			q = '"'
+		} else if !strings.ContainsRune(s, '`') && !strings.Contains(s, "${") {
+			q = '`'
+		}
+	}
+
+	var b strings.Builder
+	b.WriteRune(q)
+	for _, c := range s {
+		if c == q {
+			b.WriteRune('\\')
+			b.WriteRune(c)
+		} else if escaped, ok := escapes[c]; ok {
+			b.WriteString(escaped)
+		} else {
+			b.WriteRune(c)
+		}
+	}
+	b.WriteRune(q)
+	return b.String()
+}
+
+func formatNumber(v goja.Value) string {
+	if f := v.ToFloat(); f == 0 && math.Signbit(f) {
+		return "-0"
+	}
+	return v.String()
+}
+
+func (ctx *inspectContext) formatPrimitive(v goja.Value) string {
+	switch {
+	case v == nil || goja.IsUndefined(v):
+		return "undefined"
+	case goja.IsNull(v):
+		return "null"
+	}
+	if s, ok := v.(*goja.Symbol); ok {
+		return "Symbol(" + s.String() + ")"
+	}
+	switch {
+	case isString(v):
+		return ctx.formatString(v.String())
+	case isNumber(v):
+		return formatNumber(v)
+	case isBigInt(v):
+		return v.String() + "n"
+	}
+	return v.String()
+}
+
+func (ctx *inspectContext) formatString(s string) string {
+	trailer := ""
+	length := stringLength(s)
+	if length > maxStringLength {
+		remaining := int64(length - maxStringLength)
+		s = string([]rune(s)[:maxStringLength])
+		length = maxStringLength
+		trailer = fmt.Sprintf("... %d more character%s", remaining, plural(remaining))
+	}
+
+	// long multiline strings are split at the line breaks
+	if length > minLineWidth && length > ctx.options.BreakLength-ctx.indentationLvl-4 && strings.Contains(s, "\n") {
+		lines := strings.SplitAfter(s, "\n")
+		if lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		for i := range lines {
+			lines[i] = quote(lines[i])
+		}
+		return strings.Join(lines, " +\n"+strings.Repeat(" ", ctx.indentationLvl+2)) + trailer
+	}
+	return quote(s) + trailer
+}
+
+func (ctx *inspectContext) formatValue(v goja.Value, recurseTimes int) string {
+	o, ok := v.(*goja.Object)
+	if !ok {
+		return ctx.formatPrimitive(v)
+	}
+
+	if ctx.u.IsProxy(o) {
+		target := o.Export().(goja.Proxy).Target()
+		if target == nil {
+			return "<Revoked Proxy>"
+		}
+		o = target
+	}
+
+	if custom, ok := goja.AssertFunction(o.GetSymbol(ctx.u.intrinsics.inspectCustom)); ok && !o.GetSymbol(ctx.u.intrinsics.inspectCustom).SameAs(ctx.u.inspect) {
+		depth := ctx.u.runtime.ToValue(math.Inf(1))
+		if ctx.options.Depth >= 0 {
+			depth = ctx.u.runtime.ToValue(ctx.options.Depth - recurseTimes)
+		}
+		ret := call(custom, o, depth, ctx.u.optionsObject(ctx.options, depth), ctx.u.inspect)
+		if !ret.SameAs(o) {
+			if !isString(ret) {
+				return ctx.formatValue(ret, recurseTimes)
+			}
+			return strings.ReplaceAll(ret.String(), "\n", "\n"+strings.Repeat(" ", ctx.indentationLvl))
+		}
+	}
+
+	for _, seen := range ctx.seen {
+		if seen == o {
+			index, ok := ctx.circular[o]
+			if !ok {
+				index = len(ctx.circular) + 1
+				ctx.circular[o] = index
+			}
+			return fmt.Sprintf("[Circular *%d]", index)
+		}
+	}
+
+	return ctx.formatRaw(o, recurseTimes)
+}
+
+// keys returns the own enumerable string and symbol keys of o.
+func (ctx *inspectContext) keys(o *goja.Object) []goja.Value {
+	var keys []goja.Value
+	for _, key := range o.Keys() {
+		keys = append(keys, ctx.u.runtime.ToValue(key))
+	}
+	for _, symbol := range o.Symbols() {
+		keys = append(keys, symbol)
+	}
+	return keys
+}
+
+func withoutIndices(keys []goja.Value) []goja.Value {
+	result := keys[:0]
+	for _, key := range keys {
+		if _, ok := key.(*goja.Symbol); ok || !isIndex(key.String()) {
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+// IsHostObject reports whether o wraps a Go value, such as the instances of the classes implemented by this runtime.
+func IsHostObject(o *goja.Object) bool {
+	if o.ClassName() != "Object" {
+		return false
+	}
+	switch t := o.ExportType(); {
+	case t == nil || t == typeArrayBuffer || t == typePromise || t == typeProxy || t == typeBigInt:
+		return false
+	default:
+		return t.Kind() == reflect.Ptr || t.Kind() == reflect.Struct
+	}
+}
+
+// hostKeys lists the getters defined by the prototypes of a host object, its own properties reflect Go internals.
+func (ctx *inspectContext) hostKeys(o *goja.Object) []goja.Value {
+	var keys []goja.Value
+	seen := map[string]bool{}
+	for proto := o.Prototype(); proto != nil && !sameObject(proto, ctx.u.intrinsics.objectPrototype); proto = proto.Prototype() {
+		names, _ := call(ctx.u.intrinsics.getOwnPropertyNames, goja.Undefined(), proto).Export().([]interface{})
+		for _, name := range names {
+			key := ctx.u.runtime.ToValue(name)
+			if seen[key.String()] {
+				continue
+			}
+			desc, ok := call(ctx.u.intrinsics.getOwnPropertyDescriptor, goja.Undefined(), proto, key).(*goja.Object)
+			if ok && defined(desc.Get("get")) {
+				seen[key.String()] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+func (ctx *inspectContext) hasOwn(o *goja.Object, key goja.Value) bool {
+	return call(ctx.u.intrinsics.hasOwnProperty, o, key).ToBoolean()
+}
+
+// constructorName finds the name of the constructor o was created with, ok is false for objects without a prototype.
+func (ctx *inspectContext) constructorName(o *goja.Object) (name string, ok bool) {
+	if o.Prototype() == nil {
+		return "", false
+	}
+	r := ctx.u.runtime
+	for proto := o; proto != nil; proto = proto.Prototype() {
+		desc, ok := call(ctx.u.intrinsics.getOwnPropertyDescriptor, goja.Undefined(), proto, r.ToValue("constructor")).(*goja.Object)
+		if !ok {
+			continue
+		}
+		constructor, ok := desc.Get("value").(*goja.Object)
+		if !ok || !ctx.u.isFunction(constructor) {
+			continue
+		}
+		if name := constructor.Get("name"); isString(name) && name.String() != "" {
+			instance := false
+			if ex := r.Try(func() { instance = r.InstanceOf(o, constructor) }); ex == nil && instance {
+				return name.String(), true
+			}
+		}
+	}
+	return o.ClassName(), true
+}
+
+func (ctx *inspectContext) toStringTag(o *goja.Object) string {
+	tag := o.GetSymbol(goja.SymToStringTag)
+	if !isString(tag) {
+		return ""
+	}
+	for _, symbol := range o.Symbols() {
+		if symbol == goja.SymToStringTag {
+			// printed as a property instead
+			return ""
+		}
+	}
+	return tag.String()
+}
+
+func prefix(constructor string, hasConstructor bool, tag, fallback, size string) string {
+	if !hasConstructor {
+		if tag != "" && fallback != tag {
+			return fmt.Sprintf("[%s%s: null prototype] [%s] ", fallback, size, tag)
+		}
+		return fmt.Sprintf("[%s%s: null prototype] ", fallback, size)
+	}
+	if tag != "" && constructor != tag {
+		return fmt.Sprintf("%s%s [%s] ", constructor, size, tag)
+	}
+	return constructor + size + " "
+}
+
+func (ctx *inspectContext) formatRaw(o *goja.Object, recurseTimes int) string {
+	u := ctx.u
+	r := u.runtime
+	keys := ctx.keys(o)
+	constructor, hasConstructor := ctx.constructorName(o)
+	tag := ctx.toStringTag(o)
+
+	base := ""
+	braces := [2]string{"{", "}"}
+	extrasType := kindObject
+	var formatter func(recurseTimes int) []string
+	fallback := "Object"
+
+	switch typedArrayName := u.TypedArrayName(o); {
+	case o.ClassName() == "Array":
+		fallback = "Array"
+		length := o.Get("length").ToInteger()
+		keys = withoutIndices(keys)
+		p := ""
+		if constructor != "Array" || tag != "" {
+			p = prefix(constructor, hasConstructor, tag, "Array", fmt.Sprintf("(%d)", length))
+		}
+		braces = [2]string{p + "[", "]"}
+		if length == 0 && len(keys) == 0 {
+			return braces[0] + "]"
+		}
+		extrasType = kindArrayExtras
+		formatter = func(recurseTimes int) []string {
+			return ctx.formatArray(o, length, recurseTimes)
+		}
+	case u.IsSet(o):
+		fallback = "Set"
+		size := call(u.intrinsics.setSize, o).ToInteger()
+		p := prefix(constructor, hasConstructor, tag, "Set", fmt.Sprintf("(%d)", size))
+		if size == 0 && len(keys) == 0 {
+			return p + "{}"
+		}
+		braces[0] = p + "{"
+		formatter = func(recurseTimes int) []string {
+			return ctx.formatSet(o, recurseTimes)
+		}
+	case u.IsMap(o):
+		fallback = "Map"
+		size := call(u.intrinsics.mapSize, o).ToInteger()
+		p := prefix(constructor, hasConstructor, tag, "Map", fmt.Sprintf("(%d)", size))
+		if size == 0 && len(keys) == 0 {
+			return p + "{}"
+		}
+		braces[0] = p + "{"
+		formatter = func(recurseTimes int) []string {
+			return ctx.formatMap(o, recurseTimes)
+		}
+	case typedArrayName != "":
+		fallback = typedArrayName
+		length := o.Get("length").ToInteger()
+		keys = withoutIndices(keys)
+		braces = [2]string{prefix(constructor, hasConstructor, tag, typedArrayName, fmt.Sprintf("(%d)", length)) + "[", "]"}
+		if length == 0 && len(keys) == 0 {
+			return braces[0] + "]"
+		}
+		extrasType = kindArrayExtras
+		formatter = func(int) []string {
+			return ctx.formatTypedArray(o, length)
+		}
+	case hasConstructor && constructor == "Object" && o.ClassName() == "Object" && !u.IsArrayBuffer(o) && !u.IsArrayBufferView(o) && !u.IsPromise(o) && !IsHostObject(o):
+		if tag != "" {
+			braces[0] = prefix(constructor, hasConstructor, tag, "Object", "") + "{"
+		}
+		if len(keys) == 0 {
+			return braces[0] + "}"
+		}
+	case u.isFunction(o):
+		base = ctx.functionBase(o, constructor, hasConstructor, tag)
+		if len(keys) == 0 {
+			return base
+		}
+	case o.ClassName() == "RegExp":
+		base = call(u.intrinsics.regExpToString, o).String()
+		if p := prefix(constructor, hasConstructor, tag, "RegExp", ""); p != "RegExp " {
+			base = p + base
+		}
+		if len(keys) == 0 || ctx.options.Depth >= 0 && recurseTimes > ctx.options.Depth {
+			return base
+		}
+	case o.ClassName() == "Date":
+		base = "Invalid Date"
+		if !math.IsNaN(call(u.intrinsics.dateGetTime, o).ToFloat()) {
+			base = call(u.intrinsics.dateToISOString, o).String()
+		}
+		if p := prefix(constructor, hasConstructor, tag, "Date", ""); p != "Date " {
+			base = p + base
+		}
+		if len(keys) == 0 {
+			return base
+		}
+	case u.IsNativeError(o):
+		base = ctx.formatError(o)
+		if len(keys) == 0 {
+			return base
+		}
+	case u.IsArrayBuffer(o):
+		fallback = "ArrayBuffer"
+		braces[0] = prefix(constructor, hasConstructor, tag, "ArrayBuffer", "") + "{"
+		keys = append([]goja.Value{r.ToValue("byteLength")}, keys...)
+		formatter = func(int) []string {
+			return ctx.formatArrayBuffer(o)
+		}
+	case u.IsDataView(o):
+		fallback = "DataView"
+		braces[0] = prefix(constructor, hasConstructor, tag, "DataView", "") + "{"
+		keys = append([]goja.Value{r.ToValue("byteLength"), r.ToValue("byteOffset"), r.ToValue("buffer")}, keys...)
+	case u.IsPromise(o):
+		fallback = "Promise"
+		braces[0] = prefix(constructor, hasConstructor, tag, "Promise", "") + "{"
+		formatter = func(recurseTimes int) []string {
+			return ctx.formatPromise(o, recurseTimes)
+		}
+	case u.IsWeakSet(o) || u.IsWeakMap(o):
+		fallback = "WeakSet"
+		if u.IsWeakMap(o) {
+			fallback = "WeakMap"
+		}
+		braces[0] = prefix(constructor, hasConstructor, tag, fallback, "") + "{"
+		formatter = func(int) []string {
+			return []string{"<items unknown>"}
+		}
+	case u.IsBoxedPrimitive(o):
+		var boxed string
+		base, boxed = ctx.boxedBase(o, constructor, hasConstructor, tag)
+		if boxed == "String" {
+			keys = withoutIndices(keys)
+		}
+		if len(keys) == 0 {
+			return base
+		}
+	default:
+		if IsHostObject(o) {
+			keys = ctx.hostKeys(o)
+		}
+		if len(keys) == 0 {
+			return prefix(constructor, hasConstructor, tag, "Object", "") + "{}"
+		}
+		braces[0] = prefix(constructor, hasConstructor, tag, "Object", "") + "{"
+	}
+
+	if ctx.options.Depth >= 0 && recurseTimes > ctx.options.Depth {
+		name := strings.TrimSuffix(prefix(constructor, hasConstructor, tag, fallback, ""), " ")
+		if hasConstructor {
+			name = "[" + name + "]"
+		}
+		return name
+	}
+
+	recurseTimes++
+	ctx.seen = append(ctx.seen, o)
+	ctx.currentDepth = recurseTimes
+	var output []string
+	if formatter != nil {
+		output = formatter(recurseTimes)
+	}
+	for _, key := range keys {
+		output = append(output, ctx.formatProperty(o, key, recurseTimes, extrasType))
+	}
+	ctx.seen = ctx.seen[:len(ctx.seen)-1]
+
+	if index, ok := ctx.circular[o]; ok {
+		reference := fmt.Sprintf("<ref *%d>", index)
+		if base == "" {
+			base = reference
+		} else {
+			base = reference + " " + base
+		}
+	}
+
+	var isNumeric func(i int) bool
+	if extrasType == kindArrayExtras {
+		isNumeric = func(i int) bool {
+			v := o.Get(strconv.Itoa(i))
+			return isNumber(v) || isBigInt(v)
+		}
+	}
+	return ctx.reduceToSingleString(output, base, braces, extrasType, recurseTimes, isNumeric)
+}
+
+func (ctx *inspectContext) formatProperty(o *goja.Object, key goja.Value, recurseTimes int, kind int) string {
+	var str string
+	enumerable := true
+	if desc, ok := call(ctx.u.intrinsics.getOwnPropertyDescriptor, goja.Undefined(), o, key).(*goja.Object); ok {
+		enumerable = desc.Get("enumerable").ToBoolean()
+		value, get, set := desc.Get("value"), desc.Get("get"), desc.Get("set")
+		switch {
+		case defined(value):
+			ctx.indentationLvl += 2
+			str = ctx.formatValue(value, recurseTimes)
+			ctx.indentationLvl -= 2
+		case defined(get) && defined(set):
+			str = "[Getter/Setter]"
+		case defined(get):
+			str = "[Getter]"
+		case defined(set):
+			str = "[Setter]"
+		default:
+			str = "undefined"
+		}
+	} else {
+		var value goja.Value
+		if symbol, ok := key.(*goja.Symbol); ok {
+			value = o.GetSymbol(symbol)
+		} else {
+			value = o.Get(key.String())
+		}
+		ctx.indentationLvl += 2
+		str = ctx.formatValue(value, recurseTimes)
+		ctx.indentationLvl -= 2
+	}
+
+	if kind == kindArray {
+		return str
+	}
+
+	var name string
+	if symbol, ok := key.(*goja.Symbol); ok {
+		name = "[Symbol(" + symbol.String() + ")]"
+	} else if key.String() == "__proto__" {
+		name = "['__proto__']"
+	} else if !enumerable {
+		name = "[" + key.String() + "]"
+	} else if keyStrRegExp.MatchString(key.String()) {
+		name = key.String()
+	} else {
+		name = quote(key.String())
+	}
+	return name + ": " + str
+}
+
+func (ctx *inspectContext) formatArray(o *goja.Object, length int64, recurseTimes int) []string {
+	r := ctx.u.runtime
+	var output []string
+	limit := min(length, maxArrayLength)
+	for i := int64(0); i < limit; i++ {
+		if !ctx.hasOwn(o, r.ToValue(i)) {
+			return ctx.formatSparseArray(o, length, recurseTimes)
+		}
+		output = append(output, ctx.formatProperty(o, r.ToValue(i), recurseTimes, kindArray))
+	}
+	if remaining := length - limit; remaining > 0 {
+		output = append(output, remainingText(remaining))
+	}
+	return output
+}
+
+// formatSparseArray formats arrays with holes, consecutive holes are collapsed into a single entry.
+func (ctx *inspectContext) formatSparseArray(o *goja.Object, length int64, recurseTimes int) []string {
+	r := ctx.u.runtime
+	var indices []int64
+	names := call(ctx.u.intrinsics.getOwnPropertyNames, goja.Undefined(), o).Export()
+	if list, ok := names.([]interface{}); ok {
+		for _, name := range list {
+			if s, ok := name.(string); ok && isIndex(s) {
+				n, _ := strconv.ParseInt(s, 10, 64)
+				indices = append(indices, n)
+			}
+		}
+	}
+	sort.Slice(indices, func(i, j int) bool { return indices[i] < indices[j] })
+
+	var output []string
+	holes := func(n int64) string {
+		return fmt.Sprintf("<%d empty item%s>", n, plural(n))
+	}
+	expected := int64(0)
+	for _, index := range indices {
+		if len(output) >= maxArrayLength {
+			break
+		}
+		if index > expected {
+			output = append(output, holes(index-expected))
+			if len(output) >= maxArrayLength {
+				expected = index
+				break
+			}
+		}
+		output = append(output, ctx.formatProperty(o, r.ToValue(index), recurseTimes, kindArray))
+		expected = index + 1
+	}
+	if expected < length {
+		if len(output) < maxArrayLength {
+			output = append(output, holes(length-expected))
+		} else {
+			output = append(output, remainingText(length-expected))
+		}
+	}
+	return output
+}
+
+func (ctx *inspectContext) formatTypedArray(o *goja.Object, length int64) []string {
+	var output []string
+	limit := min(length, maxArrayLength)
+	for i := int64(0); i < limit; i++ {
+		output = append(output, ctx.formatPrimitive(o.Get(strconv.FormatInt(i, 10))))
+	}
+	if remaining := length - limit; remaining > 0 {
+		output = append(output, remainingText(remaining))
+	}
+	return output
+}
+
+func (ctx *inspectContext) formatSet(o *goja.Object, recurseTimes int) []string {
+	var output []string
+	remaining := int64(0)
+	ctx.indentationLvl += 2
+	iterate(ctx.u.runtime, ctx.u.intrinsics.setValues, o, func(v goja.Value) bool {
+		if len(output) >= maxArrayLength {
+			remaining++
+		} else {
+			output = append(output, ctx.formatValue(v, recurseTimes))
+		}
+		return true
+	})
+	ctx.indentationLvl -= 2
+	if remaining > 0 {
+		output = append(output, remainingText(remaining))
+	}
+	return output
+}
+
+func (ctx *inspectContext) formatMap(o *goja.Object, recurseTimes int) []string {
+	r := ctx.u.runtime
+	var output []string
+	remaining := int64(0)
+	ctx.indentationLvl += 2
+	iterate(r, ctx.u.intrinsics.mapEntries, o, func(v goja.Value) bool {
+		if len(output) >= maxArrayLength {
+			remaining++
+		} else {
+			entry := v.ToObject(r)
+			output = append(output, ctx.formatValue(entry.Get("0"), recurseTimes)+" => "+ctx.formatValue(entry.Get("1"), recurseTimes))
+		}
+		return true
+	})
+	ctx.indentationLvl -= 2
+	if remaining > 0 {
+		output = append(output, remainingText(remaining))
+	}
+	return output
+}
+
+func (ctx *inspectContext) formatArrayBuffer(o *goja.Object) []string {
+	data := o.Export().(goja.ArrayBuffer).Bytes()
+	shown := data[:min(len(data), maxArrayLength)]
+	contents := hex.EncodeToString(shown)
+	var b strings.Builder
+	for i := 0; i < len(contents); i += 2 {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(contents[i : i+2])
+	}
+	if remaining := int64(len(data) - len(shown)); remaining > 0 {
+		fmt.Fprintf(&b, " ... %d more byte%s", remaining, plural(remaining))
+	}
+	return []string{"[Uint8Contents]: <" + b.String() + ">"}
+}
+
+func (ctx *inspectContext) formatPromise(o *goja.Object, recurseTimes int) []string {
+	promise := o.Export().(*goja.Promise)
+	if promise.State() == goja.PromiseStatePending {
+		return []string{"<pending>"}
+	}
+	ctx.indentationLvl += 2
+	str := ctx.formatValue(promise.Result(), recurseTimes)
+	ctx.indentationLvl -= 2
+	if promise.State() == goja.PromiseStateRejected {
+		str = "<rejected> " + str
+	}
+	return []string{str}
+}
+
+func (ctx *inspectContext) functionBase(o *goja.Object, constructor string, hasConstructor bool, tag string) string {
+	r := ctx.u.runtime
+	source := call(ctx.u.intrinsics.functionToString, o).String()
+	name := ""
+	if v := o.Get("name"); isString(v) {
+		name = v.String()
+	}
+
+	if strings.HasPrefix(source, "class") && strings.HasSuffix(source, "}") {
+		if !ctx.hasOwn(o, r.ToValue("name")) || name == "" {
+			name = "(anonymous)"
+		}
+		base := "class " + name
+		if constructor != "Function" && hasConstructor {
+			base += " [" + constructor + "]"
+		}
+		if tag != "" && constructor != tag {
+			base += " [" + tag + "]"
+		}
+		if !hasConstructor {
+			base += " extends [null prototype]"
+		} else if super := o.Prototype().Get("name"); isString(super) && super.String() != "" {
+			base += " extends " + super.String()
+		}
+		return "[" + base + "]"
+	}
+
+	kind := "Function"
+	if ctx.u.isGeneratorFunction(o) {
+		kind = "GeneratorFunction"
+	} else if ctx.u.isAsyncFunction(o) {
+		kind = "AsyncFunction"
+	}
+	base := "[" + kind
+	if !hasConstructor {
+		base += " (null prototype)"
+	}
+	if name == "" {
+		base += " (anonymous)"
+	} else {
+		base += ": " + name
+	}
+	base += "]"
+	if constructor != kind && hasConstructor {
+		base += " " + constructor
+	}
+	if tag != "" && constructor != tag {
+		base += " [" + tag + "]"
+	}
+	return base
+}
+
+func (ctx *inspectContext) formatError(o *goja.Object) string {
+	var stack string
+	if v := o.Get("stack"); isString(v) && v.String() != "" {
+		stack = v.String()
+	} else {
+		stack = call(ctx.u.intrinsics.errorToString, o).String()
+	}
+
+	// goja indents stack frames with tabs
+	stack = strings.TrimRight(strings.ReplaceAll(stack, "\n\tat ", "\n    at "), "\n")
+	if !strings.Contains(stack, "\n    at ") {
+		stack = "[" + stack + "]"
+	}
+	if ctx.indentationLvl != 0 {
+		stack = strings.ReplaceAll(stack, "\n", "\n"+strings.Repeat(" ", ctx.indentationLvl))
+	}
+	return stack
+}
+
+// boxedBase formats a boxed primitive, e.g. [Number: 3], and returns its type.
+func (ctx *inspectContext) boxedBase(o *goja.Object, constructor string, hasConstructor bool, tag string) (string, string) {
+	u := ctx.u
+	var kind string
+	var value goja.Value
+	switch {
+	case u.isNumberObject(o):
+		kind, value = "Number", call(u.intrinsics.valueOf["Number"], o)
+	case u.isStringObject(o):
+		kind, value = "String", call(u.intrinsics.valueOf["String"], o)
+	case u.isBooleanObject(o):
+		kind, value = "Boolean", call(u.intrinsics.valueOf["Boolean"], o)
+	case u.isBigIntObject(o):
+		kind, value = "BigInt", call(u.intrinsics.valueOf["BigInt"], o)
+	default:
+		kind, value = "Symbol", call(u.intrinsics.symbolValueOf, o)
+	}
+
+	base := "[" + kind
+	if kind != constructor {
+		if !hasConstructor {
+			base += " (null prototype)"
+		} else {
+			base += " (" + constructor + ")"
+		}
+	}
+	base += ": " + ctx.formatPrimitive(value) + "]"
+	if tag != "" && tag != constructor {
+		base += " [" + tag + "]"
+	}
+	return base, kind
+}
+
+// groupArrayElements lays out long arrays of short entries in columns.
+func (ctx *inspectContext) groupArrayElements(output []string, isNumeric func(i int) bool) []string {
+	totalLength := 0
+	maxLength := 0
+	outputLength := len(output)
+	if outputLength > maxArrayLength {
+		// the "... n more items" entry is not grouped
+		outputLength--
+	}
+	const separatorSpace = 2
+	dataLen := make([]int, outputLength)
+	for i := 0; i < outputLength; i++ {
+		length := stringLength(output[i])
+		dataLen[i] = length
+		totalLength += length + separatorSpace
+		maxLength = max(maxLength, length)
+	}
+	actualMax := maxLength + separatorSpace
+	if actualMax*3+ctx.indentationLvl >= ctx.options.BreakLength || (float64(totalLength)/float64(actualMax) <= 5 && maxLength > 6) {
+		return output
+	}
+
+	const approxCharHeights = 2.5
+	averageBias := math.Sqrt(float64(actualMax) - float64(totalLength)/float64(len(output)))
+	biasedMax := math.Max(float64(actualMax)-3-averageBias, 1)
+	columns := min(
+		int(math.Round(math.Sqrt(approxCharHeights*biasedMax*float64(outputLength))/biasedMax)),
+		(ctx.options.BreakLength-ctx.indentationLvl)/actualMax,
+		ctx.options.Compact*4,
+		15,
+	)
+	if columns <= 1 {
+		return output
+	}
+
+	var maxLineLength []int
+	for i := 0; i < columns; i++ {
+		lineLength := 0
+		for j := i; j < outputLength; j += columns {
+			lineLength = max(lineLength, dataLen[j])
+		}
+		maxLineLength = append(maxLineLength, lineLength+separatorSpace)
+	}
+
+	padStart := isNumeric != nil
+	for i := 0; padStart && i < len(output); i++ {
+		padStart = isNumeric(i)
+	}
+	pad := func(s string, width int) string {
+		padding := strings.Repeat(" ", max(width-stringLength(s), 0))
+		if padStart {
+			return padding + s
+		}
+		return s + padding
+	}
+
+	var grouped []string
+	for i := 0; i < outputLength; i += columns {
+		end := min(i+columns, outputLength)
+		var line strings.Builder
+		j := i
+		for ; j < end-1; j++ {
+			line.WriteString(pad(output[j]+", ", maxLineLength[j-i]))
+		}
+		if padStart {
+			line.WriteString(pad(output[j], maxLineLength[j-i]-separatorSpace))
+		} else {
+			line.WriteString(output[j])
+		}
+		grouped = append(grouped, line.String())
+	}
+	if outputLength < len(output) {
+		grouped = append(grouped, output[outputLength])
+	}
+	return grouped
+}
+
+func (ctx *inspectContext) isBelowBreakLength(output []string, start int, base string) bool {
+	totalLength := len(output) + start
+	if totalLength+len(output) > ctx.options.BreakLength {
+		return false
+	}
+	for _, entry := range output {
+		totalLength += stringLength(entry)
+		if totalLength > ctx.options.BreakLength {
+			return false
+		}
+	}
+	return base == "" || !strings.Contains(base, "\n")
+}
+
+func (ctx *inspectContext) reduceToSingleString(output []string, base string, braces [2]string, extrasType int, recurseTimes int, isNumeric func(i int) bool) string {
+	if base != "" {
+		base += " "
+	}
+	if ctx.options.Compact >= 1 {
+		entries := len(output)
+		if extrasType == kindArrayExtras && entries > 6 {
+			output = ctx.groupArrayElements(output, isNumeric)
+		}
+		if ctx.currentDepth-recurseTimes < ctx.options.Compact && entries == len(output) {
+			start := len(output) + ctx.indentationLvl + stringLength(braces[0]) + stringLength(base) + 10
+			if ctx.isBelowBreakLength(output, start, base) {
+				joined := strings.Join(output, ", ")
+				if !strings.Contains(joined, "\n") {
+					return base + braces[0] + " " + joined + " " + braces[1]
+				}
+			}
+		}
+	}
+	indentation := "\n" + strings.Repeat(" ", ctx.indentationLvl)
+	return base + braces[0] + indentation + "  " + strings.Join(output, ","+indentation+"  ") + indentation + braces[1]
+}
+
+// optionsObject is the options argument passed to custom inspect functions.
+func (u *Util) optionsObject(options InspectOptions, depth goja.Value) *goja.Object {
+	o := u.runtime.NewObject()
+	o.Set("depth", depth)
+	o.Set("colors", false)
+	o.Set("showHidden", false)
+	o.Set("breakLength", options.BreakLength)
+	o.Set("compact", options.Compact)
+	o.Set("stylize", func(call goja.FunctionCall) goja.Value {
+		return u.runtime.ToValue(call.Argument(0).String())
+	})
+	return o
+}
+
+// inspectOptions reads the options passed to util.inspect, including the legacy
+// inspect(value, showHidden, depth, colors) form.
+func (u *Util) inspectOptions(arg goja.Value, legacyDepth goja.Value) InspectOptions {
+	options := DefaultInspectOptions()
+	var depth, breakLength, compact goja.Value
+	if o, ok := arg.(*goja.Object); ok {
+		depth, breakLength, compact = o.Get("depth"), o.Get("breakLength"), o.Get("compact")
+	} else if arg != nil && !goja.IsUndefined(arg) {
+		depth = legacyDepth
+	}
+
+	if depth != nil && !goja.IsUndefined(depth) {
+		if goja.IsNull(depth) || goja.IsInfinity(depth) {
+			options.Depth = -1
+		} else if isNumber(depth) {
+			options.Depth = int(max(depth.ToInteger(), 0))
+		}
+	}
+	if breakLength != nil && isNumber(breakLength) {
+		if goja.IsInfinity(breakLength) {
+			options.BreakLength = math.MaxInt32
+		} else {
+			options.BreakLength = int(max(breakLength.ToInteger(), 1))
+		}
+	}
+	if compact != nil {
+		if isNumber(compact) {
+			options.Compact = int(max(compact.ToInteger(), 0))
+		} else if compact.ExportType() != nil && compact.ExportType().Kind() == reflect.Bool && !compact.ToBoolean() {
+			options.Compact = 0
+		}
+	}
+	return options
+}
+
+func (u *Util) js_inspect(call goja.FunctionCall) goja.Value {
+	return u.runtime.ToValue(u.Inspect(call.Argument(0), u.inspectOptions(call.Argument(1), call.Argument(2))))
+}
@@ -0,0 +1,191 @@
+package util
+
+import (
+	"math/big"
+	"reflect"
+
+	goja "github.com/grafana/sobek"
+)
+
+var (
+	typeArrayBuffer = reflect.TypeOf(goja.ArrayBuffer{})
+	typePromise     = reflect.TypeOf((*goja.Promise)(nil))
+	typeProxy       = reflect.TypeOf(goja.Proxy{})
+	typeBigInt      = reflect.TypeOf((*big.Int)(nil))
+)
+
+// intrinsics are the built-ins util relies on, captured when the module is created so that
+// scripts replacing globals or prototype methods cannot change how values are inspected.
+type intrinsics struct {
+	getOwnPropertyDescriptor goja.Callable
+	getOwnPropertyNames      goja.Callable
+	hasOwnProperty           goja.Callable
+	objectToString           goja.Callable
+	functionToString         goja.Callable
+	regExpToString           goja.Callable
+	dateGetTime              goja.Callable
+	dateToISOString          goja.Callable
+	errorToString            goja.Callable
+	symbolValueOf            goja.Callable
+	valueOf                  map[string]goja.Callable
+	typedArrayTag            goja.Callable
+	isView                   goja.Callable
+	parseInt                 goja.Callable
+	parseFloat               goja.Callable
+	jsonStringify            goja.Callable
+	mapEntries               goja.Callable
+	mapGet                   goja.Callable
+	mapHas                   goja.Callable
+	setValues                goja.Callable
+	setHas                   goja.Callable
+	weakMapHas               goja.Callable
+	weakSetHas               goja.Callable
+	mapSize                  goja.Callable
+	setSize                  goja.Callable
+
+	objectPrototype *goja.Object
+	errorPrototype  *goja.Object
+	builtIns        map[string]goja.Value
+
+	inspectCustom   *goja.Symbol
+	promisifyCustom *goja.Symbol
+}
+
+// builtInConstructors are the constructors whose toString is not considered user defined by %s.
+var builtInConstructors = []string{
+	"Object", "Function", "Array", "Number", "Boolean", "String", "Symbol", "BigInt", "Date", "RegExp",
+	"Error", "EvalError", "RangeError", "ReferenceError", "SyntaxError", "TypeError", "URIError", "AggregateError",
+	"Map", "Set", "WeakMap", "WeakSet", "Promise", "Proxy", "ArrayBuffer", "DataView", "Int8Array", "Uint8Array",
+	"Uint8ClampedArray", "Int16Array", "Uint16Array", "Int32Array", "Uint32Array", "Float32Array", "Float64Array",
+	"BigInt64Array", "BigUint64Array",
+}
+
+func newIntrinsics(r *goja.Runtime) *intrinsics {
+	object := func(v goja.Value) *goja.Object {
+		if o, ok := v.(*goja.Object); ok {
+			return o
+		}
+		return r.NewObject()
+	}
+	method := func(o *goja.Object, name string) goja.Callable {
+		if fn, ok := goja.AssertFunction(o.Get(name)); ok {
+			return fn
+		}
+		return func(goja.Value, ...goja.Value) (goja.Value, error) {
+			return goja.Undefined(), nil
+		}
+	}
+	global := func(name string) *goja.Object {
+		return object(r.Get(name))
+	}
+	prototype := func(name string) *goja.Object {
+		return object(global(name).Get("prototype"))
+	}
+	symbolFor := method(global("Symbol"), "for")
+	symbol := func(key string) *goja.Symbol {
+		res, err := symbolFor(goja.Undefined(), r.ToValue(key))
+		if err != nil {
+			panic(err)
+		}
+		s, _ := res.(*goja.Symbol)
+		return s
+	}
+
+	i := &intrinsics{
+		getOwnPropertyDescriptor: method(global("Object"), "getOwnPropertyDescriptor"),
+		getOwnPropertyNames:      method(global("Object"), "getOwnPropertyNames"),
+		hasOwnProperty:           method(prototype("Object"), "hasOwnProperty"),
+		objectToString:           method(prototype("Object"), "toString"),
+		functionToString:         method(prototype("Function"), "toString"),
+		regExpToString:           method(prototype("RegExp"), "toString"),
+		dateGetTime:              method(prototype("Date"), "getTime"),
+		dateToISOString:          method(prototype("Date"), "toISOString"),
+		errorToString:            method(prototype("Error"), "toString"),
+		symbolValueOf:            method(prototype("Symbol"), "valueOf"),
+		isView:                   method(global("ArrayBuffer"), "isView"),
+		parseInt:                 method(global("Number"), "parseInt"),
+		parseFloat:               method(global("Number"), "parseFloat"),
+		jsonStringify:            method(global("JSON"), "stringify"),
+		mapEntries:               method(prototype("Map"), "entries"),
+		mapGet:                   method(prototype("Map"), "get"),
+		mapHas:                   method(prototype("Map"), "has"),
+		setValues:                method(prototype("Set"), "values"),
+		setHas:                   method(prototype("Set"), "has"),
+		weakMapHas:               method(prototype("WeakMap"), "has"),
+		weakSetHas:               method(prototype("WeakSet"), "has"),
+		objectPrototype:          prototype("Object"),
+		errorPrototype:           prototype("Error"),
+		valueOf:                  map[string]goja.Callable{},
+		builtIns:                 map[string]goja.Value{},
+		inspectCustom:            symbol("nodejs.util.inspect.custom"),
+		promisifyCustom:          symbol("nodejs.util.promisify.custom"),
+	}
+
+	getter := func(o *goja.Object, key goja.Value) goja.Callable {
+		desc, err := i.getOwnPropertyDescriptor(goja.Undefined(), o, key)
+		if err == nil {
+			if d, ok := desc.(*goja.Object); ok {
+				if fn, ok := goja.AssertFunction(d.Get("get")); ok {
+					return fn
+				}
+			}
+		}
+		return func(goja.Value, ...goja.Value) (goja.Value, error) {
+			return goja.Undefined(), nil
+		}
+	}
+	i.mapSize = getter(prototype("Map"), r.ToValue("size"))
+	i.setSize = getter(prototype("Set"), r.ToValue("size"))
+	if typedArray := prototype("Uint8Array").Prototype(); typedArray != nil {
+		i.typedArrayTag = getter(typedArray, goja.SymToStringTag)
+	} else {
+		i.typedArrayTag = getter(r.NewObject(), goja.SymToStringTag)
+	}
+
+	for _, name := range []string{"Number", "String", "Boolean", "BigInt"} {
+		i.valueOf[name] = method(prototype(name), "valueOf")
+	}
+	for _, name := range builtInConstructors {
+		if v := r.Get(name); v != nil {
+			i.builtIns[name] = v
+		}
+	}
+	return i
+}
+
+// call invokes an intrinsic, JS exceptions are rethrown into the calling script.
+func call(fn goja.Callable, this goja.Value, args ...goja.Value) goja.Value {
+	res, err := fn(this, args...)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// check invokes an intrinsic that throws a TypeError for receivers of the wrong type,
+// reporting whether it succeeded.
+func check(fn goja.Callable, this goja.Value, args ...goja.Value) (goja.Value, bool) {
+	res, err := fn(this, args...)
+	if err != nil {
+		if _, ok := err.(*goja.Exception); ok {
+			return nil, false
+		}
+		panic(err)
+	}
+	return res, true
+}
+
+// iterate calls fn for every value produced by the iterator returned by method called on o.
+func iterate(r *goja.Runtime, method goja.Callable, o goja.Value, fn func(goja.Value) bool) {
+	iterator := call(method, o).ToObject(r)
+	next, ok := goja.AssertFunction(iterator.Get("next"))
+	if !ok {
+		return
+	}
+	for {
+		result := call(next, iterator).ToObject(r)
+		if result.Get("done").ToBoolean() || !fn(result.Get("value")) {
+			return
+		}
+	}
+}
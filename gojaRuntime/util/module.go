@@ -2,33 +2,111 @@ package util
 
 import (
 	"bytes"
+	"strings"
 
-	"github.com/dop251/goja"
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
 	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
 )
 
 const ModuleName = "util"
 
 type Util struct {
-	runtime *goja.Runtime
+	runtime    *goja.Runtime
+	intrinsics *intrinsics
+	// inspect is util.inspect, passed to custom inspect functions
+	inspect *goja.Object
 }
 
-func (u *Util) format(f rune, val goja.Value, w *bytes.Buffer) bool {
+func (u *Util) stringify(val goja.Value) string {
+	res, err := u.intrinsics.jsonStringify(goja.Undefined(), val)
+	if err != nil {
+		if ex, ok := err.(*goja.Exception); ok && strings.Contains(ex.Value().String(), "circular") {
+			return "[Circular]"
+		}
+		panic(err)
+	}
+	return res.String()
+}
+
+// hasBuiltInToString reports whether o is converted to a string by a builtin toString, such objects are inspected by %s.
+func (u *Util) hasBuiltInToString(o *goja.Object) bool {
+	r := u.runtime
+	key := r.ToValue("toString")
+	if !u.isFunction(o.Get("toString")) {
+		return true
+	}
+	if call(u.intrinsics.hasOwnProperty, o, key).ToBoolean() {
+		return false
+	}
+	pointer := o
+	for {
+		if pointer = pointer.Prototype(); pointer == nil {
+			return true
+		}
+		if call(u.intrinsics.hasOwnProperty, pointer, key).ToBoolean() {
+			break
+		}
+	}
+	constructor := pointer.Get("constructor")
+	if constructor == nil || !u.isFunction(constructor) {
+		return false
+	}
+	builtIn, ok := u.intrinsics.builtIns[constructor.ToObject(r).Get("name").String()]
+	return ok && builtIn.SameAs(constructor)
+}
+
+func (u *Util) toNumber(v goja.Value, parse goja.Callable) string {
+	switch {
+	case isBigInt(v):
+		return v.String() + "n"
+	case v == nil:
+		return "NaN"
+	}
+	if _, ok := v.(*goja.Symbol); ok {
+		return "NaN"
+	}
+	if parse != nil {
+		return formatNumber(call(parse, goja.Undefined(), v))
+	}
+	return formatNumber(v.ToNumber())
+}
+
+func (u *Util) format(f rune, val goja.Value, w *bytes.Buffer, options InspectOptions) bool {
 	switch f {
 	case 's':
-		w.WriteString(val.String())
+		if o, ok := val.(*goja.Object); ok && u.hasBuiltInToString(o) {
+			w.WriteString(u.Inspect(val, InspectOptions{Depth: 0, BreakLength: options.BreakLength, Compact: 3}))
+		} else if isNumber(val) || isBigInt(val) {
+			w.WriteString(u.toNumber(val, nil))
+		} else if s, ok := val.(*goja.Symbol); ok {
+			w.WriteString("Symbol(" + s.String() + ")")
+		} else {
+			w.WriteString(val.String())
+		}
 	case 'd':
-		w.WriteString(val.ToNumber().String())
-	case 'j':
-		if json, ok := u.runtime.Get("JSON").(*goja.Object); ok {
-			if stringify, ok := goja.AssertFunction(json.Get("stringify")); ok {
-				res, err := stringify(json, val)
-				if err != nil {
-					panic(err)
-				}
-				w.WriteString(res.String())
-			}
+		w.WriteString(u.toNumber(val, nil))
+	case 'i':
+		if isBigInt(val) {
+			w.WriteString(u.toNumber(val, nil))
+		} else {
+			w.WriteString(u.toNumber(val, u.intrinsics.parseInt))
+		}
+	case 'f':
+		if isBigInt(val) {
+			w.WriteString(val.String())
+		} else {
+			w.WriteString(u.toNumber(val, u.intrinsics.parseFloat))
 		}
+	case 'j':
+		w.WriteString(u.stringify(val))
+	case 'o':
+		options.Depth = 4
+		w.WriteString(u.Inspect(val, options))
+	case 'O':
+		w.WriteString(u.Inspect(val, options))
+	case 'c':
+		// CSS is ignored, the argument is consumed
 	case '%':
 		w.WriteByte('%')
 		return false
@@ -40,13 +118,25 @@ func (u *Util) format(f rune, val goja.Value, w *bytes.Buffer) bool {
 	return true
 }
 
+// Format writes f to b with the printf-like specifiers replaced by args the way Node.js' util.format does.
+// Arguments left over are appended separated by spaces, strings as they are and other values inspected.
 func (u *Util) Format(b *bytes.Buffer, f string, args ...goja.Value) {
+	u.FormatWithOptions(b, DefaultInspectOptions(), f, args...)
+}
+
+// FormatWithOptions is Format with the options used for inspecting values.
+func (u *Util) FormatWithOptions(b *bytes.Buffer, options InspectOptions, f string, args ...goja.Value) {
+	if len(args) == 0 {
+		b.WriteString(f)
+		return
+	}
+
 	pct := false
 	argNum := 0
 	for _, chr := range f {
 		if pct {
 			if argNum < len(args) {
-				if u.format(chr, args[argNum], b) {
+				if u.format(chr, args[argNum], b, options) {
 					argNum++
 				}
 			} else {
@@ -62,42 +152,148 @@ func (u *Util) Format(b *bytes.Buffer, f string, args ...goja.Value) {
 			}
 		}
 	}
+	if pct {
+		b.WriteByte('%')
+	}
 
 	for _, arg := range args[argNum:] {
 		b.WriteByte(' ')
+		u.writeArg(b, arg, options)
+	}
+}
+
+// writeArg writes a value printed without a format specifier, strings as they are and anything else inspected.
+func (u *Util) writeArg(b *bytes.Buffer, arg goja.Value, options InspectOptions) {
+	if isString(arg) {
 		b.WriteString(arg.String())
+	} else {
+		b.WriteString(u.Inspect(arg, options))
 	}
 }
 
-func (u *Util) js_format(call goja.FunctionCall) goja.Value {
+func (u *Util) formatArgs(options InspectOptions, args []goja.Value) goja.Value {
 	var b bytes.Buffer
-	var fmt string
 
-	if arg := call.Argument(0); !goja.IsUndefined(arg) {
-		fmt = arg.String()
+	if len(args) > 0 && isString(args[0]) {
+		u.FormatWithOptions(&b, options, args[0].String(), args[1:]...)
+	} else {
+		for i, arg := range args {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			u.writeArg(&b, arg, options)
+		}
 	}
 
+	return u.runtime.ToValue(b.String())
+}
+
+func (u *Util) js_format(call goja.FunctionCall) goja.Value {
+	return u.formatArgs(DefaultInspectOptions(), call.Arguments)
+}
+
+func (u *Util) js_formatWithOptions(call goja.FunctionCall) goja.Value {
+	if _, ok := call.Argument(0).(*goja.Object); !ok {
+		panic(errors.NewTypeError(u.runtime, errors.ErrCodeInvalidArgType, `The "inspectOptions" argument must be of type object.`))
+	}
 	var args []goja.Value
 	if len(call.Arguments) > 0 {
 		args = call.Arguments[1:]
 	}
-	u.Format(&b, fmt, args...)
+	return u.formatArgs(u.inspectOptions(call.Argument(0), nil), args)
+}
 
-	return u.runtime.ToValue(b.String())
+func (u *Util) js_isDeepStrictEqual(call goja.FunctionCall) goja.Value {
+	return u.runtime.ToValue(u.IsDeepStrictEqual(call.Argument(0), call.Argument(1)))
 }
 
-func Require(runtime *goja.Runtime, module *goja.Object) {
-	u := &Util{
-		runtime: runtime,
+// promisify wraps a function taking a Node.js style callback as its last argument into one returning a promise.
+func (u *Util) promisify(call goja.FunctionCall) goja.Value {
+	r := u.runtime
+	original, ok := call.Argument(0).(*goja.Object)
+	if !ok || !u.isFunction(original) {
+		panic(errors.NewTypeError(r, errors.ErrCodeInvalidArgType, `The "original" argument must be of type function.`))
+	}
+
+	if custom := original.GetSymbol(u.intrinsics.promisifyCustom); custom != nil && !goja.IsUndefined(custom) {
+		if !u.isFunction(custom) {
+			panic(errors.NewTypeError(r, errors.ErrCodeInvalidArgType, `The "util.promisify.custom" argument must be of type function.`))
+		}
+		return custom
 	}
+
+	fn, _ := goja.AssertFunction(original)
+	promisified := r.ToValue(func(call goja.FunctionCall) goja.Value {
+		promise, resolve, reject := r.NewPromise()
+		callback := r.ToValue(func(call goja.FunctionCall) goja.Value {
+			if err := call.Argument(0); err.ToBoolean() {
+				reject(err)
+			} else {
+				resolve(call.Argument(1))
+			}
+			return goja.Undefined()
+		})
+		args := append(append([]goja.Value{}, call.Arguments...), callback)
+		if _, err := fn(call.This, args...); err != nil {
+			ex, ok := err.(*goja.Exception)
+			if !ok {
+				panic(err)
+			}
+			reject(ex.Value())
+		}
+		return r.ToValue(promise)
+	}).(*goja.Object)
+
+	promisified.SetPrototype(original.Prototype())
+	promisified.DefineDataPropertySymbol(u.intrinsics.promisifyCustom, promisified, goja.FLAG_FALSE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+	for _, key := range original.Keys() {
+		promisified.Set(key, original.Get(key))
+	}
+	return promisified
+}
+
+func (u *Util) inherits(call goja.FunctionCall) goja.Value {
+	r := u.runtime
+	constructor, ok := call.Argument(0).(*goja.Object)
+	if !ok || !u.isFunction(constructor) {
+		panic(errors.NewTypeError(r, errors.ErrCodeInvalidArgType, `The "ctor" argument must be of type function.`))
+	}
+	superConstructor, ok := call.Argument(1).(*goja.Object)
+	if !ok || !u.isFunction(superConstructor) {
+		panic(errors.NewTypeError(r, errors.ErrCodeInvalidArgType, `The "superCtor" argument must be of type function.`))
+	}
+	superPrototype, ok := superConstructor.Get("prototype").(*goja.Object)
+	if !ok {
+		panic(errors.NewTypeError(r, errors.ErrCodeInvalidArgType, `The "superCtor.prototype" property must be of type object.`))
+	}
+	constructor.DefineDataProperty("super_", superConstructor, goja.FLAG_TRUE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+	constructor.Get("prototype").ToObject(r).SetPrototype(superPrototype)
+	return goja.Undefined()
+}
+
+func Require(runtime *goja.Runtime, module *goja.Object) {
+	u := New(runtime)
 	obj := module.Get("exports").(*goja.Object)
 	obj.Set("format", u.js_format)
+	obj.Set("formatWithOptions", u.js_formatWithOptions)
+	obj.Set("inspect", u.inspect)
+	obj.Set("isDeepStrictEqual", u.js_isDeepStrictEqual)
+	obj.Set("inherits", u.inherits)
+	obj.Set("types", u.types())
+
+	promisify := runtime.ToValue(u.promisify).(*goja.Object)
+	promisify.Set("custom", u.intrinsics.promisifyCustom)
+	obj.Set("promisify", promisify)
 }
 
 func New(runtime *goja.Runtime) *Util {
-	return &Util{
-		runtime: runtime,
+	u := &Util{
+		runtime:    runtime,
+		intrinsics: newIntrinsics(runtime),
 	}
+	u.inspect = runtime.ToValue(u.js_inspect).(*goja.Object)
+	u.inspect.Set("custom", u.intrinsics.inspectCustom)
+	return u
 }
 
 func init() {
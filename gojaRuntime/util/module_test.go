@@ -4,7 +4,7 @@ import (
 	"bytes"
 	"testing"
 
-	"github.com/dop251/goja"
+	goja "github.com/grafana/sobek"
 	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
 )
 
@@ -72,3 +72,184 @@ func TestJSNoArgs(t *testing.T) {
 		}
 	}
 }
+
+func createVM() *goja.Runtime {
+	vm := goja.New()
+	new(require.Registry).Enable(vm)
+	vm.Set("util", require.Require(vm, ModuleName))
+	return vm
+}
+
+func runTests(t *testing.T, tests []struct {
+	script   string
+	expected string
+}) {
+	t.Helper()
+	for _, tc := range tests {
+		vm := createVM()
+		result, err := vm.RunString(tc.script)
+		if err != nil {
+			t.Fatalf("Failed to run %s: %v", tc.script, err)
+		}
+		if result.String() != tc.expected {
+			t.Fatalf("%s: expected '%s', got '%s'", tc.script, tc.expected, result.String())
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	runTests(t, []struct {
+		script   string
+		expected string
+	}{
+		{`util.inspect("it's")`, `"it's"`},
+		{`util.inspect("a\nb")`, `'a\nb'`},
+		{`util.inspect([-0, 1n, undefined, null, Symbol("s")])`, `[ -0, 1n, undefined, null, Symbol(s) ]`},
+		{`util.inspect({a: 1, "b-c": [1, 2], [Symbol("k")]: true})`, `{ a: 1, 'b-c': [ 1, 2 ], [Symbol(k)]: true }`},
+		{`util.inspect({a: {b: {c: {d: 1}}}})`, `{ a: { b: { c: [Object] } } }`},
+		{`util.inspect({a: {b: {c: {d: 1}}}}, {depth: null})`, `{
+  a: { b: { c: { d: 1 } } }
+}`},
+		{`util.inspect({a: {b: {c: {}}}}, {depth: 0})`, `{ a: [Object] }`},
+		{`const o = {name: "o"}; o.self = o; util.inspect(o)`, `<ref *1> { name: 'o', self: [Circular *1] }`},
+		{`util.inspect(Object.create(null))`, `[Object: null prototype] {}`},
+		{`class Foo { constructor() { this.x = 1 } }; util.inspect([new Foo(), Foo])`, `[ Foo { x: 1 }, [class Foo] ]`},
+		{`util.inspect([function named() {}, () => {}, async function a() {}])`, `[ [Function: named], [Function (anonymous)], [AsyncFunction: a] ]`},
+		{`util.inspect(new Map([["a", 1], [{}, [2]]]))`, `Map(2) { 'a' => 1, {} => [ 2 ] }`},
+		{`util.inspect(new Set([1, "two"]))`, `Set(2) { 1, 'two' }`},
+		{`util.inspect([new Date(0), new Date(NaN), /a+/gi])`, `[ 1970-01-01T00:00:00.000Z, Invalid Date, /a+/gi ]`},
+		{`util.inspect(new Uint8Array([1, 2, 3]))`, `Uint8Array(3) [ 1, 2, 3 ]`},
+		{`util.inspect(new Uint8Array([1, 2]).buffer)`, `ArrayBuffer { [Uint8Contents]: <01 02>, byteLength: 2 }`},
+		{`util.inspect([1, , , 4])`, `[ 1, <2 empty items>, 4 ]`},
+		{`util.inspect(Object.assign([1], {extra: true}))`, `[ 1, extra: true ]`},
+		{`util.inspect([Promise.resolve(4), Promise.reject(3), new Promise(() => {})])`, `[ Promise { 4 }, Promise { <rejected> 3 }, Promise { <pending> } ]`},
+		{`util.inspect([new Number(3), new String("s"), new Boolean(false)])`, `[ [Number: 3], [String: 's'], [Boolean: false] ]`},
+		{`util.inspect({get a() { return 1 }, set b(v) {}, get c() { return 1 }, set c(v) {}})`, `{ a: [Getter], b: [Setter], c: [Getter/Setter] }`},
+		{`util.inspect({[util.inspect.custom]() { return "custom" }})`, `custom`},
+		{`util.inspect({nested: {[util.inspect.custom](depth, options) { return {depth} }}})`, `{ nested: { depth: 1 } }`},
+		{`util.inspect(new WeakSet())`, `WeakSet { <items unknown> }`},
+		{`util.inspect({[Symbol.toStringTag]: "Tagged", a: 1}).startsWith("Object [Tagged]")`, `false`},
+		{`util.inspect(new (class extends Map { get [Symbol.toStringTag]() { return "X" } })())`, `Map(0) [X] {}`},
+		{`util.inspect(Array.from({length: 30}, (_, i) => i))`, `[
+   0,  1,  2,  3,  4,  5,  6,  7,  8,
+   9, 10, 11, 12, 13, 14, 15, 16, 17,
+  18, 19, 20, 21, 22, 23, 24, 25, 26,
+  27, 28, 29
+]`},
+		{`util.inspect(Array.from({length: 101}, () => 0)).endsWith("... 1 more item\n]")`, `true`},
+		{`util.inspect({a: "x".repeat(40), b: "y".repeat(40)})`, `{
+  a: 'xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx',
+  b: 'yyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyy'
+}`},
+		{`util.inspect({a: [1, 2]}, {compact: false})`, `{
+  a: [
+    1,
+    2
+  ]
+}`},
+		{`util.inspect(new Error("boom")).split("\n")[0]`, `Error: boom`},
+		{`const e = new TypeError("bad"); e.code = "E_BAD"; const lines = util.inspect(e).split("\n"); [lines[0], lines[lines.length - 2], lines[lines.length - 1]].join("|")`, "TypeError: bad|  code: 'E_BAD'|}"},
+		{`util.inspect(new Proxy({a: 1}, {get() { return 2 }}))`, `{ a: 1 }`},
+	})
+}
+
+func TestFormat(t *testing.T) {
+	runTests(t, []struct {
+		script   string
+		expected string
+	}{
+		{`util.format("%s:%s", "a")`, `a:%s`},
+		{`util.format("%s", {a: {b: {c: 1}}})`, `{ a: [Object] }`},
+		{`util.format("%s", {toString() { return "custom" }})`, `custom`},
+		{`util.format("%s %s %s", -0, 5n, [1, [2]])`, `-0 5n [ 1, [Array] ]`},
+		{`util.format("%d %d %d", "42", 1.5, {})`, `42 1.5 NaN`},
+		{`util.format("%i %i", "42.9px", 7n)`, `42 7n`},
+		{`util.format("%f", "1.25e1xyz")`, `12.5`},
+		{`util.format("%j", {a: [1]})`, `{"a":[1]}`},
+		{`const o = {}; o.o = o; util.format("%j", o)`, `[Circular]`},
+		{`util.format("%o", {a: {b: {c: {d: {e: 1}}}}})`, `{
+  a: {
+    b: { c: { d: { e: 1 } } }
+  }
+}`},
+		{`util.format("%O", {a: {b: {c: {d: 1}}}})`, `{ a: { b: { c: [Object] } } }`},
+		{`util.format("%cstyled", "color: red")`, `styled`},
+		{`util.format("100%%", 1)`, `100% 1`},
+		{`util.format("%s", "a", {b: 1}, "c")`, `a { b: 1 } c`},
+		{`util.format(1, "a", {b: 1})`, `1 a { b: 1 }`},
+		{`util.formatWithOptions({depth: 0}, "%O", {a: {b: 1}})`, `{ a: [Object] }`},
+	})
+}
+
+func TestTypes(t *testing.T) {
+	runTests(t, []struct {
+		script   string
+		expected string
+	}{
+		{`[util.types.isDate(new Date()), util.types.isDate(Date.now())].join()`, `true,false`},
+		{`[util.types.isRegExp(/a/), util.types.isRegExp({})].join()`, `true,false`},
+		{`[util.types.isPromise(Promise.resolve()), util.types.isPromise({then() {}})].join()`, `true,false`},
+		{`[util.types.isMap(new Map()), util.types.isSet(new Set()), util.types.isWeakMap(new WeakMap()), util.types.isWeakSet(new WeakSet())].join()`, `true,true,true,true`},
+		{`[util.types.isNativeError(new RangeError()), util.types.isNativeError({name: "Error"})].join()`, `true,false`},
+		{`[util.types.isAsyncFunction(async () => {}), util.types.isAsyncFunction(() => {})].join()`, `true,false`},
+		{`[util.types.isGeneratorFunction(function* () {}), util.types.isGeneratorObject((function* () {})())].join()`, `true,true`},
+		{`[util.types.isMapIterator(new Map().keys()), util.types.isSetIterator(new Set().values())].join()`, `true,true`},
+		{`[util.types.isArrayBuffer(new ArrayBuffer(1)), util.types.isArrayBufferView(new DataView(new ArrayBuffer(1))), util.types.isDataView(new Uint8Array())].join()`, `true,true,false`},
+		{`[util.types.isTypedArray(new Float64Array()), util.types.isUint8Array(new Uint8Array()), util.types.isUint8Array(new Int8Array())].join()`, `true,true,false`},
+		{`[util.types.isBoxedPrimitive(Object(1n)), util.types.isSymbolObject(Object(Symbol())), util.types.isBoxedPrimitive(1)].join()`, `true,true,false`},
+		{`util.types.isProxy(new Proxy({}, {}))`, `true`},
+	})
+}
+
+func TestIsDeepStrictEqual(t *testing.T) {
+	runTests(t, []struct {
+		script   string
+		expected string
+	}{
+		{`util.isDeepStrictEqual({a: [1, {b: 2}]}, {a: [1, {b: 2}]})`, `true`},
+		{`util.isDeepStrictEqual({a: 1}, {a: "1"})`, `false`},
+		{`util.isDeepStrictEqual([NaN], [NaN])`, `true`},
+		{`util.isDeepStrictEqual(0, -0)`, `false`},
+		{`util.isDeepStrictEqual([1, , 3], [1, undefined, 3])`, `false`},
+		{`util.isDeepStrictEqual({}, Object.create(null))`, `false`},
+		{`util.isDeepStrictEqual(new Date(1), new Date(1))`, `true`},
+		{`util.isDeepStrictEqual(/a/g, /a/i)`, `false`},
+		{`util.isDeepStrictEqual(new Map([[{k: 1}, "v"]]), new Map([[{k: 1}, "v"]]))`, `true`},
+		{`util.isDeepStrictEqual(new Map([["k", 1]]), new Map([["k", 2]]))`, `false`},
+		{`util.isDeepStrictEqual(new Set([1, {a: 1}]), new Set([{a: 1}, 1]))`, `true`},
+		{`util.isDeepStrictEqual(new Set([{a: 1}]), new Set([{a: 2}]))`, `false`},
+		{`util.isDeepStrictEqual(new Uint8Array([1, 2]), new Uint8Array([1, 2]))`, `true`},
+		{`util.isDeepStrictEqual(new Uint8Array([1, 2]), new Int8Array([1, 2]))`, `false`},
+		{`util.isDeepStrictEqual(new Error("a"), new Error("b"))`, `false`},
+		{`util.isDeepStrictEqual(new Number(1), new Number(1))`, `true`},
+		{`const a = {}; a.a = a; const b = {}; b.a = b; util.isDeepStrictEqual(a, b)`, `true`},
+		{`const a = {}; a.a = a; const b = {a: {}}; util.isDeepStrictEqual(a, b)`, `false`},
+		{`util.isDeepStrictEqual({[Symbol.for("s")]: 1}, {[Symbol.for("s")]: 2})`, `false`},
+	})
+}
+
+func TestPromisify(t *testing.T) {
+	vm := createVM()
+	_, err := vm.RunString(`
+		function callbackStyle(value, cb) {
+			if (value < 0) { cb(new Error("negative")); } else { cb(null, value * 2); }
+		}
+		function throws() { throw new Error("sync"); }
+		function withCustom() {}
+		withCustom[util.promisify.custom] = () => Promise.resolve("custom");
+
+		const doubled = util.promisify(callbackStyle);
+		globalThis.out = [];
+		doubled(21).then(v => out.push(v));
+		doubled(-1).catch(e => out.push(e.message));
+		util.promisify(throws)().catch(e => out.push(e.message));
+		util.promisify(withCustom)().then(v => out.push(v));
+		try { util.promisify(1) } catch (e) { out.push(e.code) }
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := vm.Get("out").String(); res != "ERR_INVALID_ARG_TYPE,42,negative,sync,custom" {
+		t.Fatalf("Unexpected result: '%s'", res)
+	}
+}
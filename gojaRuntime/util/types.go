@@ -0,0 +1,190 @@
+package util
+
+import (
+	goja "github.com/grafana/sobek"
+)
+
+var typedArrayNames = []string{
+	"Int8Array", "Uint8Array", "Uint8ClampedArray", "Int16Array", "Uint16Array", "Int32Array", "Uint32Array",
+	"Float32Array", "Float64Array", "BigInt64Array", "BigUint64Array",
+}
+
+func asObject(v goja.Value) (*goja.Object, bool) {
+	o, ok := v.(*goja.Object)
+	return o, ok
+}
+
+// tag returns the builtin tag of v, as in "[object Tag]" produced by Object.prototype.toString.
+func (u *Util) tag(v goja.Value) string {
+	s := call(u.intrinsics.objectToString, v).String()
+	if len(s) > len("[object ]") {
+		return s[len("[object ") : len(s)-1]
+	}
+	return ""
+}
+
+func (u *Util) isClass(v goja.Value, class string) bool {
+	o, ok := asObject(v)
+	return ok && o.ClassName() == class
+}
+
+// hasBrand reports whether v is accepted as receiver by an intrinsic that checks the internal slots of its receiver.
+func (u *Util) hasBrand(v goja.Value, fn goja.Callable, args ...goja.Value) bool {
+	if _, ok := asObject(v); !ok {
+		return false
+	}
+	_, ok := check(fn, v, args...)
+	return ok
+}
+
+// IsMap and the other exported predicates back util.types, they are shared with the built-ins
+// that need to recognise the same values, such as structuredClone.
+func (u *Util) IsMap(v goja.Value) bool {
+	return u.hasBrand(v, u.intrinsics.mapSize)
+}
+
+func (u *Util) IsSet(v goja.Value) bool {
+	return u.hasBrand(v, u.intrinsics.setSize)
+}
+
+func (u *Util) IsWeakMap(v goja.Value) bool {
+	return u.hasBrand(v, u.intrinsics.weakMapHas, u.runtime.NewObject())
+}
+
+func (u *Util) IsWeakSet(v goja.Value) bool {
+	return u.hasBrand(v, u.intrinsics.weakSetHas, u.runtime.NewObject())
+}
+
+func (u *Util) IsArrayBuffer(v goja.Value) bool {
+	o, ok := asObject(v)
+	return ok && o.ExportType() == typeArrayBuffer
+}
+
+func (u *Util) IsArrayBufferView(v goja.Value) bool {
+	if _, ok := asObject(v); !ok {
+		return false
+	}
+	return call(u.intrinsics.isView, goja.Undefined(), v).ToBoolean()
+}
+
+// TypedArrayName returns the constructor name of a typed array, or "" if v is not one.
+func (u *Util) TypedArrayName(v goja.Value) string {
+	if _, ok := asObject(v); !ok {
+		return ""
+	}
+	if name := call(u.intrinsics.typedArrayTag, v); !goja.IsUndefined(name) {
+		return name.String()
+	}
+	return ""
+}
+
+func (u *Util) IsTypedArray(v goja.Value) bool {
+	return u.TypedArrayName(v) != ""
+}
+
+func (u *Util) IsDataView(v goja.Value) bool {
+	return u.IsArrayBufferView(v) && !u.IsTypedArray(v)
+}
+
+func (u *Util) IsPromise(v goja.Value) bool {
+	o, ok := asObject(v)
+	return ok && o.ExportType() == typePromise
+}
+
+func (u *Util) IsProxy(v goja.Value) bool {
+	o, ok := asObject(v)
+	return ok && o.ExportType() == typeProxy
+}
+
+func (u *Util) isFunction(v goja.Value) bool {
+	_, ok := goja.AssertFunction(v)
+	return ok
+}
+
+// isAsyncFunction and isGeneratorFunction rely on the Symbol.toStringTag inherited from the function's prototype.
+func (u *Util) isAsyncFunction(v goja.Value) bool {
+	return u.isFunction(v) && u.tag(v) == "AsyncFunction"
+}
+
+func (u *Util) isGeneratorFunction(v goja.Value) bool {
+	return u.isFunction(v) && u.tag(v) == "GeneratorFunction"
+}
+
+// isIteratorKind reports whether v is a builtin iterator or generator object of the given kind.
+// These have no dedicated class, the inherited Symbol.toStringTag is checked instead.
+func (u *Util) isIteratorKind(v goja.Value, kind string) bool {
+	if _, ok := asObject(v); !ok || u.isFunction(v) {
+		return false
+	}
+	return u.tag(v) == kind
+}
+
+func (u *Util) IsNativeError(v goja.Value) bool {
+	return u.isClass(v, "Error")
+}
+
+func (u *Util) isNumberObject(v goja.Value) bool {
+	return u.isClass(v, "Number")
+}
+
+func (u *Util) isStringObject(v goja.Value) bool {
+	return u.isClass(v, "String")
+}
+
+func (u *Util) isBooleanObject(v goja.Value) bool {
+	return u.isClass(v, "Boolean")
+}
+
+func (u *Util) isBigIntObject(v goja.Value) bool {
+	o, ok := asObject(v)
+	return ok && o.ExportType() == typeBigInt
+}
+
+func (u *Util) isSymbolObject(v goja.Value) bool {
+	return u.hasBrand(v, u.intrinsics.symbolValueOf)
+}
+
+func (u *Util) IsBoxedPrimitive(v goja.Value) bool {
+	return u.isNumberObject(v) || u.isStringObject(v) || u.isBooleanObject(v) || u.isBigIntObject(v) || u.isSymbolObject(v)
+}
+
+func (u *Util) types() *goja.Object {
+	types := u.runtime.NewObject()
+	predicate := func(name string, fn func(goja.Value) bool) {
+		types.Set(name, func(call goja.FunctionCall) goja.Value {
+			return u.runtime.ToValue(fn(call.Argument(0)))
+		})
+	}
+
+	predicate("isDate", func(v goja.Value) bool { return u.isClass(v, "Date") })
+	predicate("isRegExp", func(v goja.Value) bool { return u.isClass(v, "RegExp") })
+	predicate("isMap", u.IsMap)
+	predicate("isSet", u.IsSet)
+	predicate("isWeakMap", u.IsWeakMap)
+	predicate("isWeakSet", u.IsWeakSet)
+	predicate("isMapIterator", func(v goja.Value) bool { return u.isIteratorKind(v, "Map Iterator") })
+	predicate("isSetIterator", func(v goja.Value) bool { return u.isIteratorKind(v, "Set Iterator") })
+	predicate("isGeneratorObject", func(v goja.Value) bool { return u.isIteratorKind(v, "Generator") })
+	predicate("isPromise", u.IsPromise)
+	predicate("isProxy", u.IsProxy)
+	predicate("isNativeError", u.IsNativeError)
+	predicate("isAsyncFunction", u.isAsyncFunction)
+	predicate("isGeneratorFunction", u.isGeneratorFunction)
+	predicate("isArrayBuffer", u.IsArrayBuffer)
+	predicate("isAnyArrayBuffer", u.IsArrayBuffer)
+	predicate("isSharedArrayBuffer", func(goja.Value) bool { return false })
+	predicate("isArrayBufferView", u.IsArrayBufferView)
+	predicate("isTypedArray", u.IsTypedArray)
+	predicate("isDataView", u.IsDataView)
+	for _, name := range typedArrayNames {
+		name := name
+		predicate("is"+name, func(v goja.Value) bool { return u.TypedArrayName(v) == name })
+	}
+	predicate("isNumberObject", u.isNumberObject)
+	predicate("isStringObject", u.isStringObject)
+	predicate("isBooleanObject", u.isBooleanObject)
+	predicate("isBigIntObject", u.isBigIntObject)
+	predicate("isSymbolObject", u.isSymbolObject)
+	predicate("isBoxedPrimitive", u.IsBoxedPrimitive)
+	return types
+}
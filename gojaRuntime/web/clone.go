@@ -0,0 +1,226 @@
+package web
+
+import (
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/util"
+)
+
+// serializableErrors are the error names kept by structuredClone, other errors are cloned as Error.
+var serializableErrors = map[string]bool{
+	"Error":          true,
+	"EvalError":      true,
+	"RangeError":     true,
+	"ReferenceError": true,
+	"SyntaxError":    true,
+	"TypeError":      true,
+	"URIError":       true,
+}
+
+// cloner implements the structured clone algorithm of the HTML standard for a single structuredClone call,
+// memory maps the objects already cloned to their copies so that shared references and cycles are preserved.
+type cloner struct {
+	m      *webModule
+	u      *util.Util
+	memory map[*goja.Object]*goja.Object
+}
+
+func (m *webModule) newDataCloneError(msg string) *goja.Object {
+	return m.newDOMException(m.domExceptionPrototype, msg, "DataCloneError")
+}
+
+func (m *webModule) structuredClone(call goja.FunctionCall) goja.Value {
+	if len(call.Arguments) == 0 {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeMissingArgs, `The "value" argument must be specified`))
+	}
+	c := &cloner{
+		m:      m,
+		u:      m.util,
+		memory: map[*goja.Object]*goja.Object{},
+	}
+
+	var transfer []goja.ArrayBuffer
+	if options, ok := call.Argument(1).(*goja.Object); ok {
+		if list := options.Get("transfer"); list != nil && !goja.IsUndefined(list) {
+			var values []goja.Value
+			if err := m.r.ExportTo(list, &values); err != nil {
+				panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "options.transfer" property must be an instance of Array.`))
+			}
+			for _, v := range values {
+				o, ok := v.(*goja.Object)
+				if !ok || !c.u.IsArrayBuffer(o) {
+					panic(m.newDataCloneError("Value not transferable"))
+				}
+				if _, duplicate := c.memory[o]; duplicate {
+					panic(m.newDataCloneError("ArrayBuffer at index " + itoa(len(transfer)) + " is a duplicate of an earlier ArrayBuffer. Duplicate ArrayBuffers cannot be transferred."))
+				}
+				buffer := o.Export().(goja.ArrayBuffer)
+				if buffer.Detached() {
+					panic(m.newDataCloneError("An ArrayBuffer is detached and could not be cloned."))
+				}
+				c.memory[o] = m.r.ToValue(m.r.NewArrayBuffer(append([]byte(nil), buffer.Bytes()...))).(*goja.Object)
+				transfer = append(transfer, buffer)
+			}
+		}
+	} else if v := call.Argument(1); !goja.IsUndefined(v) && !goja.IsNull(v) {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "options" argument must be of type object.`))
+	}
+
+	result := c.clone(call.Argument(0))
+	for _, buffer := range transfer {
+		buffer.Detach()
+	}
+	return result
+}
+
+func (c *cloner) clone(v goja.Value) goja.Value {
+	r := c.m.r
+	if s, ok := v.(*goja.Symbol); ok {
+		panic(c.m.newDataCloneError("Symbol(" + s.String() + ") could not be cloned."))
+	}
+	o, ok := v.(*goja.Object)
+	if !ok {
+		return v
+	}
+	if copied, ok := c.memory[o]; ok {
+		return copied
+	}
+	if _, ok := goja.AssertFunction(o); ok {
+		panic(c.m.newDataCloneError(o.String() + " could not be cloned."))
+	}
+
+	switch {
+	case c.u.IsProxy(o) || c.u.IsPromise(o) || c.u.IsWeakMap(o) || c.u.IsWeakSet(o):
+		panic(c.m.newDataCloneError("#<" + c.constructorName(o) + "> could not be cloned."))
+	case c.u.IsBoxedPrimitive(o):
+		value := c.u.BoxedValue(o)
+		if _, ok := value.(*goja.Symbol); ok {
+			panic(c.m.newDataCloneError("Symbol(" + value.String() + ") could not be cloned."))
+		}
+		return c.remember(o, value.ToObject(r))
+	case o.ClassName() == "Date":
+		return c.remember(o, c.construct("Date", call(c.m.intrinsics.dateGetTime, o)))
+	case o.ClassName() == "RegExp":
+		return c.remember(o, c.construct("RegExp", o.Get("source"), o.Get("flags")))
+	case c.u.IsArrayBuffer(o):
+		buffer := o.Export().(goja.ArrayBuffer)
+		if buffer.Detached() {
+			panic(c.m.newDataCloneError("An ArrayBuffer is detached and could not be cloned."))
+		}
+		return c.remember(o, r.ToValue(r.NewArrayBuffer(append([]byte(nil), buffer.Bytes()...))).(*goja.Object))
+	case c.u.IsArrayBufferView(o):
+		buffer := c.clone(o.Get("buffer"))
+		if name := c.u.TypedArrayName(o); name != "" {
+			return c.remember(o, c.construct(name, buffer, o.Get("byteOffset"), o.Get("length")))
+		}
+		return c.remember(o, c.construct("DataView", buffer, o.Get("byteOffset"), o.Get("byteLength")))
+	case c.u.IsMap(o):
+		copied := c.remember(o, c.construct("Map"))
+		var entries []goja.Value
+		iterate(r, c.m.intrinsics.mapEntries, o, func(entry goja.Value) {
+			pair := entry.ToObject(r)
+			entries = append(entries, pair.Get("0"), pair.Get("1"))
+		})
+		for i := 0; i < len(entries); i += 2 {
+			call(c.m.intrinsics.mapSet, copied, c.clone(entries[i]), c.clone(entries[i+1]))
+		}
+		return copied
+	case c.u.IsSet(o):
+		copied := c.remember(o, c.construct("Set"))
+		var values []goja.Value
+		iterate(r, c.m.intrinsics.setValues, o, func(value goja.Value) {
+			values = append(values, value)
+		})
+		for _, value := range values {
+			call(c.m.intrinsics.setAdd, copied, c.clone(value))
+		}
+		return copied
+	case c.isDOMException(o):
+		e, _ := slot[*domException](o, domExceptionSlot)
+		return c.remember(o, c.m.newDOMException(c.m.domExceptionPrototype, e.message, e.name))
+	case c.u.IsNativeError(o):
+		return c.cloneError(o)
+	case o.ClassName() == "Array":
+		copied := c.remember(o, r.NewArray())
+		copied.Set("length", o.Get("length"))
+		c.copyProperties(o, copied)
+		return copied
+	case util.IsHostObject(o) || c.isPlatformObject(o):
+		panic(c.m.newDataCloneError("#<" + c.constructorName(o) + "> could not be cloned."))
+	}
+
+	copied := c.remember(o, r.NewObject())
+	c.copyProperties(o, copied)
+	return copied
+}
+
+func (c *cloner) remember(o, copied *goja.Object) *goja.Object {
+	c.memory[o] = copied
+	return copied
+}
+
+func (c *cloner) construct(name string, args ...goja.Value) *goja.Object {
+	o, err := c.m.r.New(c.m.intrinsics.constructors[name], args...)
+	if err != nil {
+		panic(err)
+	}
+	return o
+}
+
+// copyProperties clones the own enumerable string keyed properties of o onto copied, invoking getters.
+func (c *cloner) copyProperties(o, copied *goja.Object) {
+	for _, key := range o.Keys() {
+		copied.Set(key, c.clone(o.Get(key)))
+	}
+}
+
+func (c *cloner) cloneError(o *goja.Object) *goja.Object {
+	r := c.m.r
+	name := "Error"
+	if v := o.Get("name"); v != nil && serializableErrors[v.String()] {
+		name = v.String()
+	}
+	var args []goja.Value
+	if c.hasOwn(o, "message") {
+		args = append(args, r.ToValue(o.Get("message").String()))
+	}
+	copied := c.remember(o, c.construct(name, args...))
+	if c.hasOwn(o, "stack") {
+		if stack := o.Get("stack"); stack != nil && !goja.IsUndefined(stack) {
+			copied.DefineDataProperty("stack", r.ToValue(stack.String()), goja.FLAG_TRUE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+		}
+	}
+	if c.hasOwn(o, "cause") {
+		copied.DefineDataProperty("cause", c.clone(o.Get("cause")), goja.FLAG_TRUE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+	}
+	return copied
+}
+
+func (c *cloner) hasOwn(o *goja.Object, key string) bool {
+	return call(c.m.intrinsics.hasOwnProperty, o, c.m.r.ToValue(key)).ToBoolean()
+}
+
+func (c *cloner) isDOMException(o *goja.Object) bool {
+	e, ok := slot[*domException](o, domExceptionSlot)
+	return ok && e.object.SameAs(o)
+}
+
+// isPlatformObject reports whether o is an Event or EventTarget, which are not serializable.
+func (c *cloner) isPlatformObject(o *goja.Object) bool {
+	if _, ok := targetOf(o); ok {
+		return true
+	}
+	e, ok := slot[*event](o, eventSlot)
+	return ok && e.object.SameAs(o)
+}
+
+func (c *cloner) constructorName(o *goja.Object) string {
+	for p := o.Prototype(); p != nil; p = p.Prototype() {
+		if constructor, ok := p.Get("constructor").(*goja.Object); ok {
+			if name := constructor.Get("name"); name != nil && name.String() != "" {
+				return name.String()
+			}
+		}
+	}
+	return "Object"
+}
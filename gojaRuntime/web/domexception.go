@@ -0,0 +1,147 @@
+package web
+
+import (
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+)
+
+// domExceptionCodes are the legacy codes of the DOMException names which have one, other names have code 0.
+var domExceptionCodes = map[string]int{
+	"IndexSizeError":             1,
+	"HierarchyRequestError":      3,
+	"WrongDocumentError":         4,
+	"InvalidCharacterError":      5,
+	"NoModificationAllowedError": 7,
+	"NotFoundError":              8,
+	"NotSupportedError":          9,
+	"InUseAttributeError":        10,
+	"InvalidStateError":          11,
+	"SyntaxError":                12,
+	"InvalidModificationError":   13,
+	"NamespaceError":             14,
+	"InvalidAccessError":         15,
+	"TypeMismatchError":          17,
+	"SecurityError":              18,
+	"NetworkError":               19,
+	"AbortError":                 20,
+	"URLMismatchError":           21,
+	"QuotaExceededError":         22,
+	"TimeoutError":               23,
+	"InvalidNodeTypeError":       24,
+	"DataCloneError":             25,
+}
+
+// domExceptionConstants are exposed on both the DOMException constructor and its prototype.
+var domExceptionConstants = []struct {
+	name string
+	code int
+}{
+	{"INDEX_SIZE_ERR", 1},
+	{"DOMSTRING_SIZE_ERR", 2},
+	{"HIERARCHY_REQUEST_ERR", 3},
+	{"WRONG_DOCUMENT_ERR", 4},
+	{"INVALID_CHARACTER_ERR", 5},
+	{"NO_DATA_ALLOWED_ERR", 6},
+	{"NO_MODIFICATION_ALLOWED_ERR", 7},
+	{"NOT_FOUND_ERR", 8},
+	{"NOT_SUPPORTED_ERR", 9},
+	{"INUSE_ATTRIBUTE_ERR", 10},
+	{"INVALID_STATE_ERR", 11},
+	{"SYNTAX_ERR", 12},
+	{"INVALID_MODIFICATION_ERR", 13},
+	{"NAMESPACE_ERR", 14},
+	{"INVALID_ACCESS_ERR", 15},
+	{"VALIDATION_ERR", 16},
+	{"TYPE_MISMATCH_ERR", 17},
+	{"SECURITY_ERR", 18},
+	{"NETWORK_ERR", 19},
+	{"ABORT_ERR", 20},
+	{"URL_MISMATCH_ERR", 21},
+	{"QUOTA_EXCEEDED_ERR", 22},
+	{"TIMEOUT_ERR", 23},
+	{"INVALID_NODE_TYPE_ERR", 24},
+	{"DATA_CLONE_ERR", 25},
+}
+
+var domExceptionSlot = goja.NewSymbol("kDOMException")
+
+type domException struct {
+	object  *goja.Object
+	name    string
+	message string
+}
+
+func (m *webModule) toDOMException(v goja.Value) *domException {
+	if e, ok := slot[*domException](v, domExceptionSlot); ok && e.object.SameAs(v) {
+		return e
+	}
+	panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidThis, `Value of "this" must be of type DOMException`))
+}
+
+// newDOMException creates a DOMException, instances are errors so that they carry a stack.
+func (m *webModule) newDOMException(prototype *goja.Object, message, name string) *goja.Object {
+	o, err := m.r.New(m.errorConstructor, m.r.ToValue(message))
+	if err != nil {
+		panic(err)
+	}
+	o.SetPrototype(prototype)
+	// message is an accessor of the prototype
+	o.Delete("message")
+	o.DefineDataPropertySymbol(domExceptionSlot, m.r.ToValue(&domException{object: o, name: name, message: message}), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+	return o
+}
+
+func (m *webModule) createDOMExceptionConstructor() goja.Value {
+	f := m.r.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		message := ""
+		if v := call.Argument(0); !goja.IsUndefined(v) {
+			message = v.String()
+		}
+		name := "Error"
+		var cause goja.Value
+		if options, ok := call.Argument(1).(*goja.Object); ok {
+			if v := options.Get("name"); v != nil && !goja.IsUndefined(v) {
+				name = v.String()
+			}
+			cause = options.Get("cause")
+		} else if v := call.Argument(1); !goja.IsUndefined(v) {
+			name = v.String()
+		}
+
+		o := m.newDOMException(call.This.Prototype(), message, name)
+		if cause != nil {
+			o.DefineDataProperty("cause", cause, goja.FLAG_TRUE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+		}
+		return o
+	}).(*goja.Object)
+
+	m.domExceptionPrototype = m.createDOMExceptionPrototype()
+	f.DefineDataProperty("name", m.r.ToValue("DOMException"), goja.FLAG_FALSE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+	f.Set("prototype", m.domExceptionPrototype)
+	m.domExceptionPrototype.DefineDataProperty("constructor", f, goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+	for _, constant := range domExceptionConstants {
+		f.DefineDataProperty(constant.name, m.r.ToValue(constant.code), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	}
+	return f
+}
+
+func (m *webModule) createDOMExceptionPrototype() *goja.Object {
+	p := m.r.NewObject()
+	p.SetPrototype(m.errorConstructor.Get("prototype").ToObject(m.r))
+
+	m.defineGetter(p, "name", func(call goja.FunctionCall) goja.Value {
+		return m.r.ToValue(m.toDOMException(call.This).name)
+	})
+	m.defineGetter(p, "message", func(call goja.FunctionCall) goja.Value {
+		return m.r.ToValue(m.toDOMException(call.This).message)
+	})
+	m.defineGetter(p, "code", func(call goja.FunctionCall) goja.Value {
+		return m.r.ToValue(domExceptionCodes[m.toDOMException(call.This).name])
+	})
+	for _, constant := range domExceptionConstants {
+		p.DefineDataProperty(constant.name, m.r.ToValue(constant.code), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	}
+
+	p.DefineDataPropertySymbol(goja.SymToStringTag, m.r.ToValue("DOMException"), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	return p
+}
@@ -0,0 +1,198 @@
+package web
+
+import (
+	"time"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+)
+
+const (
+	phaseNone = iota
+	phaseCapturing
+	phaseAtTarget
+	phaseBubbling
+)
+
+var eventSlot = goja.NewSymbol("kEvent")
+
+type event struct {
+	object    *goja.Object
+	eventType string
+	timeStamp float64
+
+	bubbles    bool
+	cancelable bool
+	composed   bool
+	trusted    bool
+	// detail is the detail of a CustomEvent
+	detail goja.Value
+
+	target        goja.Value
+	currentTarget goja.Value
+	phase         int
+
+	canceled          bool
+	stopPropagation   bool
+	stopImmediate     bool
+	inPassiveListener bool
+	dispatching       bool
+}
+
+func (m *webModule) toEvent(v goja.Value) *event {
+	if e, ok := slot[*event](v, eventSlot); ok && e.object.SameAs(v) {
+		return e
+	}
+	panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidThis, `Value of "this" must be of type Event`))
+}
+
+// initEvent attaches the state of an Event to o, which has been created by the Event or CustomEvent constructor.
+func (m *webModule) initEvent(o *goja.Object, call goja.ConstructorCall) *event {
+	if len(call.Arguments) == 0 {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeMissingArgs, `The "type" argument must be specified`))
+	}
+	e := &event{
+		object:        o,
+		eventType:     call.Argument(0).String(),
+		timeStamp:     float64(time.Since(m.timeOrigin).Microseconds()) / 1000,
+		target:        goja.Null(),
+		currentTarget: goja.Null(),
+		detail:        goja.Null(),
+	}
+	if init, ok := call.Argument(1).(*goja.Object); ok {
+		e.bubbles = m.boolOption(init, "bubbles")
+		e.cancelable = m.boolOption(init, "cancelable")
+		e.composed = m.boolOption(init, "composed")
+	} else if v := call.Argument(1); !goja.IsUndefined(v) && !goja.IsNull(v) {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "options" argument must be of type object.`))
+	}
+	o.DefineDataPropertySymbol(eventSlot, m.r.ToValue(e), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+	return e
+}
+
+func (m *webModule) boolOption(o *goja.Object, name string) bool {
+	v := o.Get(name)
+	return v != nil && v.ToBoolean()
+}
+
+func (m *webModule) createEventConstructor() goja.Value {
+	f := m.r.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		m.initEvent(call.This, call)
+		return call.This
+	}).(*goja.Object)
+
+	m.eventPrototype = m.createEventPrototype()
+	f.DefineDataProperty("name", m.r.ToValue("Event"), goja.FLAG_FALSE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+	f.Set("prototype", m.eventPrototype)
+	m.eventPrototype.DefineDataProperty("constructor", f, goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+	m.defineEventPhases(f)
+	return f
+}
+
+func (m *webModule) defineEventPhases(o *goja.Object) {
+	for name, phase := range map[string]int{
+		"NONE":            phaseNone,
+		"CAPTURING_PHASE": phaseCapturing,
+		"AT_TARGET":       phaseAtTarget,
+		"BUBBLING_PHASE":  phaseBubbling,
+	} {
+		o.DefineDataProperty(name, m.r.ToValue(phase), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	}
+}
+
+func (m *webModule) createEventPrototype() *goja.Object {
+	p := m.r.NewObject()
+
+	getter := func(name string, get func(e *event) interface{}) {
+		m.defineGetter(p, name, func(call goja.FunctionCall) goja.Value {
+			return m.r.ToValue(get(m.toEvent(call.This)))
+		})
+	}
+	getter("type", func(e *event) interface{} { return e.eventType })
+	getter("target", func(e *event) interface{} { return e.target })
+	getter("srcElement", func(e *event) interface{} { return e.target })
+	getter("currentTarget", func(e *event) interface{} { return e.currentTarget })
+	getter("eventPhase", func(e *event) interface{} { return e.phase })
+	getter("bubbles", func(e *event) interface{} { return e.bubbles })
+	getter("cancelable", func(e *event) interface{} { return e.cancelable })
+	getter("composed", func(e *event) interface{} { return e.composed })
+	getter("defaultPrevented", func(e *event) interface{} { return e.canceled })
+	getter("isTrusted", func(e *event) interface{} { return e.trusted })
+	getter("timeStamp", func(e *event) interface{} { return e.timeStamp })
+
+	p.DefineAccessorProperty("returnValue", m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		return m.r.ToValue(!m.toEvent(call.This).canceled)
+	}), m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		if e := m.toEvent(call.This); !call.Argument(0).ToBoolean() {
+			e.preventDefault()
+		}
+		return goja.Undefined()
+	}), goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	p.DefineAccessorProperty("cancelBubble", m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		return m.r.ToValue(m.toEvent(call.This).stopPropagation)
+	}), m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		if e := m.toEvent(call.This); call.Argument(0).ToBoolean() {
+			e.stopPropagation = true
+		}
+		return goja.Undefined()
+	}), goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	p.Set("preventDefault", m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		m.toEvent(call.This).preventDefault()
+		return goja.Undefined()
+	}))
+	p.Set("stopPropagation", m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		m.toEvent(call.This).stopPropagation = true
+		return goja.Undefined()
+	}))
+	p.Set("stopImmediatePropagation", m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		e := m.toEvent(call.This)
+		e.stopPropagation = true
+		e.stopImmediate = true
+		return goja.Undefined()
+	}))
+	p.Set("composedPath", m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		e := m.toEvent(call.This)
+		if !e.dispatching {
+			return m.r.NewArray()
+		}
+		return m.r.NewArray(e.currentTarget)
+	}))
+
+	m.defineEventPhases(p)
+	p.DefineDataPropertySymbol(goja.SymToStringTag, m.r.ToValue("Event"), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	return p
+}
+
+// preventDefault cancels the event unless it is not cancelable or a passive listener is running.
+func (e *event) preventDefault() {
+	if e.cancelable && !e.inPassiveListener {
+		e.canceled = true
+	}
+}
+
+func (m *webModule) createCustomEventConstructor() goja.Value {
+	f := m.r.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		e := m.initEvent(call.This, call)
+		if init, ok := call.Argument(1).(*goja.Object); ok {
+			if detail := init.Get("detail"); detail != nil && !goja.IsUndefined(detail) {
+				e.detail = detail
+			}
+		}
+		return call.This
+	}).(*goja.Object)
+	f.SetPrototype(m.exports.Get("Event").ToObject(m.r))
+
+	p := m.r.NewObject()
+	p.SetPrototype(m.eventPrototype)
+	m.defineGetter(p, "detail", func(call goja.FunctionCall) goja.Value {
+		return m.toEvent(call.This).detail
+	})
+	p.DefineDataPropertySymbol(goja.SymToStringTag, m.r.ToValue("CustomEvent"), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	f.DefineDataProperty("name", m.r.ToValue("CustomEvent"), goja.FLAG_FALSE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+	f.Set("prototype", p)
+	p.DefineDataProperty("constructor", f, goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+	return f
+}
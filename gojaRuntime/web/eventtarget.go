@@ -0,0 +1,246 @@
+package web
+
+import (
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+)
+
+var eventTargetSlot = goja.NewSymbol("kEvents")
+
+type eventTarget struct {
+	object    *goja.Object
+	listeners []*listener
+}
+
+type listener struct {
+	eventType string
+	// callback is a function or an object with a handleEvent method, fn is set instead for listeners added from Go
+	callback goja.Value
+	fn       func(event *goja.Object)
+	capture  bool
+	once     bool
+	passive  bool
+	// handler marks the listener installed by an event handler attribute such as onabort
+	handler bool
+	removed bool
+}
+
+func targetOf(v goja.Value) (*eventTarget, bool) {
+	if t, ok := slot[*eventTarget](v, eventTargetSlot); ok && t.object.SameAs(v) {
+		return t, true
+	}
+	return nil, false
+}
+
+func (m *webModule) toEventTarget(v goja.Value) *eventTarget {
+	if t, ok := targetOf(v); ok {
+		return t
+	}
+	panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidThis, `Value of "this" must be of type EventTarget`))
+}
+
+func (m *webModule) initEventTarget(o *goja.Object) *eventTarget {
+	t := &eventTarget{object: o}
+	o.DefineDataPropertySymbol(eventTargetSlot, m.r.ToValue(t), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+	return t
+}
+
+func (m *webModule) createEventTargetConstructor() goja.Value {
+	f := m.r.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		m.initEventTarget(call.This)
+		return call.This
+	}).(*goja.Object)
+
+	m.eventTargetPrototype = m.createEventTargetPrototype()
+	f.DefineDataProperty("name", m.r.ToValue("EventTarget"), goja.FLAG_FALSE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+	f.Set("prototype", m.eventTargetPrototype)
+	m.eventTargetPrototype.DefineDataProperty("constructor", f, goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+	return f
+}
+
+func (m *webModule) createEventTargetPrototype() *goja.Object {
+	p := m.r.NewObject()
+
+	p.Set("addEventListener", m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		t := m.toEventTarget(call.This)
+		if len(call.Arguments) < 2 {
+			panic(errors.NewTypeError(m.r, errors.ErrCodeMissingArgs, `The "type" and "listener" arguments must be specified`))
+		}
+		m.addEventListener(t, call.Argument(0).String(), call.Argument(1), call.Argument(2))
+		return goja.Undefined()
+	}))
+
+	p.Set("removeEventListener", m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		t := m.toEventTarget(call.This)
+		if len(call.Arguments) < 2 {
+			panic(errors.NewTypeError(m.r, errors.ErrCodeMissingArgs, `The "type" and "listener" arguments must be specified`))
+		}
+		capture := false
+		if options, ok := call.Argument(2).(*goja.Object); ok {
+			capture = m.boolOption(options, "capture")
+		} else {
+			capture = call.Argument(2).ToBoolean()
+		}
+		if l := t.find(call.Argument(0).String(), call.Argument(1), capture); l != nil {
+			t.remove(l)
+		}
+		return goja.Undefined()
+	}))
+
+	p.Set("dispatchEvent", m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		t := m.toEventTarget(call.This)
+		e, ok := slot[*event](call.Argument(0), eventSlot)
+		if !ok {
+			panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "event" argument must be an instance of Event.`))
+		}
+		return m.r.ToValue(m.dispatch(t, e))
+	}))
+
+	p.DefineDataPropertySymbol(goja.SymToStringTag, m.r.ToValue("EventTarget"), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	return p
+}
+
+func (m *webModule) addEventListener(t *eventTarget, eventType string, callback, options goja.Value) {
+	if goja.IsUndefined(callback) || goja.IsNull(callback) {
+		return
+	}
+	if _, ok := callback.(*goja.Object); !ok {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "listener" argument must be an instance of EventListener.`))
+	}
+
+	l := &listener{eventType: eventType, callback: callback}
+	var signal *eventTarget
+	if o, ok := options.(*goja.Object); ok {
+		l.capture = m.boolOption(o, "capture")
+		l.once = m.boolOption(o, "once")
+		l.passive = m.boolOption(o, "passive")
+		if v := o.Get("signal"); v != nil && !goja.IsUndefined(v) {
+			if signal, ok = targetOf(v); !ok || v.ToObject(m.r).Get("aborted") == nil {
+				panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "options.signal" property must be an instance of AbortSignal.`))
+			}
+			if v.ToObject(m.r).Get("aborted").ToBoolean() {
+				return
+			}
+		}
+	} else {
+		l.capture = options.ToBoolean()
+	}
+
+	if t.find(eventType, callback, l.capture) != nil {
+		return
+	}
+	t.listeners = append(t.listeners, l)
+	if signal != nil {
+		signal.listeners = append(signal.listeners, &listener{eventType: "abort", once: true, fn: func(*goja.Object) {
+			t.remove(l)
+		}})
+	}
+}
+
+func (t *eventTarget) find(eventType string, callback goja.Value, capture bool) *listener {
+	for _, l := range t.listeners {
+		if !l.handler && l.fn == nil && l.eventType == eventType && l.capture == capture && l.callback.SameAs(callback) {
+			return l
+		}
+	}
+	return nil
+}
+
+func (t *eventTarget) remove(l *listener) {
+	l.removed = true
+	for i, existing := range t.listeners {
+		if existing == l {
+			t.listeners = append(t.listeners[:i:i], t.listeners[i+1:]...)
+			return
+		}
+	}
+}
+
+// dispatch runs the listeners of t for e, capturing listeners first as the target is the only object on the event path.
+// As in browsers an exception thrown by a listener does not prevent the others from running,
+// only interrupting the VM stops the dispatch.
+func (m *webModule) dispatch(t *eventTarget, e *event) bool {
+	if e.dispatching {
+		panic(m.newDOMException(m.domExceptionPrototype, "The event is already being dispatched", "InvalidStateError"))
+	}
+	e.dispatching = true
+	e.target = t.object
+	e.currentTarget = t.object
+	e.phase = phaseAtTarget
+	defer func() {
+		e.dispatching = false
+		e.currentTarget = goja.Null()
+		e.phase = phaseNone
+		e.stopPropagation = false
+		e.stopImmediate = false
+	}()
+
+	var listeners []*listener
+	for _, capture := range []bool{true, false} {
+		for _, l := range t.listeners {
+			if l.eventType == e.eventType && l.capture == capture {
+				listeners = append(listeners, l)
+			}
+		}
+	}
+
+	for _, l := range listeners {
+		if e.stopImmediate {
+			break
+		}
+		if l.removed {
+			continue
+		}
+		if l.once {
+			t.remove(l)
+		}
+		if l.fn != nil {
+			l.fn(e.object)
+			continue
+		}
+
+		e.inPassiveListener = l.passive
+		var err error
+		if fn, ok := goja.AssertFunction(l.callback); ok {
+			_, err = fn(t.object, e.object)
+		} else if fn, ok := goja.AssertFunction(l.callback.ToObject(m.r).Get("handleEvent")); ok {
+			_, err = fn(l.callback, e.object)
+		}
+		e.inPassiveListener = false
+		if interrupted, ok := err.(*goja.InterruptedError); ok {
+			panic(interrupted)
+		}
+	}
+	return !e.canceled
+}
+
+// defineEventHandler defines the on<type> event handler attribute on the prototype p, the handler keeps
+// the position among the listeners it was first registered at.
+func (m *webModule) defineEventHandler(p *goja.Object, eventType string) {
+	p.DefineAccessorProperty("on"+eventType, m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		for _, l := range m.toEventTarget(call.This).listeners {
+			if l.handler && l.eventType == eventType {
+				return l.callback
+			}
+		}
+		return goja.Null()
+	}), m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		t := m.toEventTarget(call.This)
+		callback := call.Argument(0)
+		_, callable := goja.AssertFunction(callback)
+		for _, l := range t.listeners {
+			if l.handler && l.eventType == eventType {
+				if callable {
+					l.callback = callback
+				} else {
+					t.remove(l)
+				}
+				return goja.Undefined()
+			}
+		}
+		if callable {
+			t.listeners = append(t.listeners, &listener{eventType: eventType, callback: callback, handler: true})
+		}
+		return goja.Undefined()
+	}), goja.FLAG_FALSE, goja.FLAG_TRUE)
+}
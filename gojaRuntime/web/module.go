@@ -0,0 +1,227 @@
+package web
+
+import (
+	"strconv"
+	"time"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/util"
+)
+
+const ModuleName = "web"
+
+// globals are the exports of the module Enable installs as globals.
+var globals = []string{"DOMException", "Event", "CustomEvent", "EventTarget", "structuredClone", "queueMicrotask"}
+
+type webModule struct {
+	r          *goja.Runtime
+	util       *util.Util
+	intrinsics *intrinsics
+	exports    *goja.Object
+	timeOrigin time.Time
+
+	errorConstructor      *goja.Object
+	domExceptionPrototype *goja.Object
+	eventPrototype        *goja.Object
+	eventTargetPrototype  *goja.Object
+}
+
+// intrinsics are the built-ins captured when the module is created, so that scripts replacing them
+// cannot change how values are cloned or microtasks are queued.
+type intrinsics struct {
+	constructors   map[string]*goja.Object
+	hasOwnProperty goja.Callable
+	dateGetTime    goja.Callable
+	mapEntries     goja.Callable
+	mapSet         goja.Callable
+	setValues      goja.Callable
+	setAdd         goja.Callable
+	promiseThen    goja.Callable
+}
+
+func newIntrinsics(r *goja.Runtime) *intrinsics {
+	method := func(constructor, name string) goja.Callable {
+		fn, _ := goja.AssertFunction(r.Get(constructor).ToObject(r).Get("prototype").ToObject(r).Get(name))
+		return fn
+	}
+	i := &intrinsics{
+		constructors:   map[string]*goja.Object{},
+		hasOwnProperty: method("Object", "hasOwnProperty"),
+		dateGetTime:    method("Date", "getTime"),
+		mapEntries:     method("Map", "entries"),
+		mapSet:         method("Map", "set"),
+		setValues:      method("Set", "values"),
+		setAdd:         method("Set", "add"),
+		promiseThen:    method("Promise", "then"),
+	}
+	for _, name := range []string{
+		"Date", "RegExp", "Map", "Set", "DataView", "Error", "EvalError", "RangeError", "ReferenceError", "SyntaxError",
+		"TypeError", "URIError", "Int8Array", "Uint8Array", "Uint8ClampedArray", "Int16Array", "Uint16Array", "Int32Array",
+		"Uint32Array", "Float32Array", "Float64Array", "BigInt64Array", "BigUint64Array",
+	} {
+		i.constructors[name] = r.Get(name).ToObject(r)
+	}
+	return i
+}
+
+func call(fn goja.Callable, this goja.Value, args ...goja.Value) goja.Value {
+	res, err := fn(this, args...)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// iterate calls fn for every value produced by the iterator returned by method called on o.
+func iterate(r *goja.Runtime, method goja.Callable, o goja.Value, fn func(goja.Value)) {
+	iterator := call(method, o).ToObject(r)
+	next, _ := goja.AssertFunction(iterator.Get("next"))
+	for {
+		result := call(next, iterator).ToObject(r)
+		if result.Get("done").ToBoolean() {
+			return
+		}
+		fn(result.Get("value"))
+	}
+}
+
+// slot returns the Go state the built-ins keep under an internal symbol of the objects they create.
+// Callers check that the state belongs to v itself, as symbols are inherited through the prototype chain.
+func slot[T any](v goja.Value, symbol *goja.Symbol) (T, bool) {
+	var zero T
+	o, ok := v.(*goja.Object)
+	if !ok {
+		return zero, false
+	}
+	value := o.GetSymbol(symbol)
+	if value == nil {
+		return zero, false
+	}
+	state, ok := value.Export().(T)
+	return state, ok
+}
+
+func itoa(i int) string {
+	return strconv.Itoa(i)
+}
+
+func (m *webModule) defineGetter(p *goja.Object, name string, getter func(call goja.FunctionCall) goja.Value) {
+	p.DefineAccessorProperty(name, m.r.ToValue(getter), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+}
+
+// queueMicrotask runs callback from the promise job queue, after the current script and before the next task.
+func (m *webModule) queueMicrotask(call goja.FunctionCall) goja.Value {
+	callback, ok := goja.AssertFunction(call.Argument(0))
+	if !ok {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "callback" argument must be of type function.`))
+	}
+	promise, resolve, _ := m.r.NewPromise()
+	resolve(goja.Undefined())
+	job := m.r.ToValue(func(goja.FunctionCall) goja.Value {
+		if _, err := callback(goja.Undefined()); err != nil {
+			panic(err)
+		}
+		return goja.Undefined()
+	})
+	m.intrinsics.promiseThen(m.r.ToValue(promise), job)
+	return goja.Undefined()
+}
+
+func Require(runtime *goja.Runtime, module *goja.Object) {
+	exports := module.Get("exports").(*goja.Object)
+	m := &webModule{
+		r:                runtime,
+		util:             util.New(runtime),
+		intrinsics:       newIntrinsics(runtime),
+		exports:          exports,
+		timeOrigin:       time.Now(),
+		errorConstructor: runtime.Get("Error").ToObject(runtime),
+	}
+	exports.Set("DOMException", m.createDOMExceptionConstructor())
+	exports.Set("EventTarget", m.createEventTargetConstructor())
+	exports.Set("Event", m.createEventConstructor())
+	exports.Set("CustomEvent", m.createCustomEventConstructor())
+	exports.Set("structuredClone", m.structuredClone)
+	exports.Set("queueMicrotask", m.queueMicrotask)
+	exports.DefineDataPropertySymbol(moduleSlot, runtime.ToValue(m), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+}
+
+var moduleSlot = goja.NewSymbol("kWeb")
+
+// module returns the state of the web module of runtime, requiring it if it was not yet.
+func module(runtime *goja.Runtime) *webModule {
+	m, _ := slot[*webModule](require.Require(runtime, ModuleName), moduleSlot)
+	return m
+}
+
+// EventTargetPrototype returns EventTarget.prototype, for built-ins implementing classes that extend EventTarget.
+func EventTargetPrototype(runtime *goja.Runtime) *goja.Object {
+	return module(runtime).eventTargetPrototype
+}
+
+// InitEventTarget makes o an EventTarget, it must be called on the instances of classes extending EventTarget.
+func InitEventTarget(runtime *goja.Runtime, o *goja.Object) {
+	module(runtime).initEventTarget(o)
+}
+
+// DefineEventHandler defines the on<eventType> event handler attribute on p, the prototype of an EventTarget class.
+func DefineEventHandler(runtime *goja.Runtime, p *goja.Object, eventType string) {
+	module(runtime).defineEventHandler(p, eventType)
+}
+
+// NewEvent creates a trusted Event of the given type, as dispatched by the runtime rather than by a script.
+func NewEvent(runtime *goja.Runtime, eventType string) *goja.Object {
+	m := module(runtime)
+	o := runtime.NewObject()
+	o.SetPrototype(m.eventPrototype)
+	m.initEvent(o, goja.ConstructorCall{Arguments: []goja.Value{runtime.ToValue(eventType)}}).trusted = true
+	return o
+}
+
+// DispatchEvent dispatches event, created with NewEvent or by a script, to target and reports whether it was not canceled.
+// A *goja.InterruptedError interrupting a listener is rethrown as a panic.
+func DispatchEvent(runtime *goja.Runtime, target, eventObject *goja.Object) bool {
+	m := module(runtime)
+	e, ok := slot[*event](eventObject, eventSlot)
+	if !ok {
+		panic(errors.NewTypeError(runtime, errors.ErrCodeInvalidArgType, `The "event" argument must be an instance of Event.`))
+	}
+	return m.dispatch(m.toEventTarget(target), e)
+}
+
+// EventListeners returns the callbacks of the listeners scripts added to target for eventType, it reports false
+// when target is not an EventTarget. Listeners added from Go have no callback and are left out.
+func EventListeners(target goja.Value, eventType string) ([]goja.Value, bool) {
+	t, ok := targetOf(target)
+	if !ok {
+		return nil, false
+	}
+	callbacks := []goja.Value{}
+	for _, l := range t.listeners {
+		if !l.removed && l.fn == nil && l.eventType == eventType {
+			callbacks = append(callbacks, l.callback)
+		}
+	}
+	return callbacks, true
+}
+
+// NewDOMException creates a DOMException with the given message and name.
+func NewDOMException(runtime *goja.Runtime, message, name string) *goja.Object {
+	m := module(runtime)
+	return m.newDOMException(m.domExceptionPrototype, message, name)
+}
+
+// Enable installs DOMException, Event, CustomEvent, EventTarget, structuredClone and queueMicrotask as globals.
+func Enable(runtime *goja.Runtime) *goja.Object {
+	m := require.Require(runtime, ModuleName).ToObject(runtime)
+	for _, name := range globals {
+		runtime.Set(name, m.Get(name))
+	}
+	return m
+}
+
+func init() {
+	require.RegisterNativeModule(ModuleName, Require)
+}
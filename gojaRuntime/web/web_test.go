@@ -0,0 +1,180 @@
+package web
+
+import (
+	"testing"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/require"
+)
+
+func createVM() *goja.Runtime {
+	vm := goja.New()
+	new(require.Registry).Enable(vm)
+	Enable(vm)
+	return vm
+}
+
+func runTests(t *testing.T, vm *goja.Runtime, tests []struct {
+	script   string
+	expected string
+}) {
+	t.Helper()
+	for _, tc := range tests {
+		result, err := vm.RunString(tc.script)
+		if err != nil {
+			t.Fatalf("Failed to run %s: %v", tc.script, err)
+		}
+		if result.String() != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.script, tc.expected, result.String())
+		}
+	}
+}
+
+func TestWebGlobals(t *testing.T) {
+	vm := createVM()
+	for _, name := range globals {
+		if v := vm.Get(name); v == nil {
+			t.Fatalf("%s not found", name)
+		}
+	}
+}
+
+func TestDOMException(t *testing.T) {
+	runTests(t, createVM(), []struct {
+		script   string
+		expected string
+	}{
+		{`const e = new DOMException("boom", "AbortError"); [e.name, e.message, e.code].join()`, "AbortError,boom,20"},
+		{`e instanceof DOMException && e instanceof Error`, "true"},
+		{`String(e)`, "AbortError: boom"},
+		{`e.stack.split("\n")[0]`, "AbortError: boom"},
+		{`Object.prototype.toString.call(e)`, "[object DOMException]"},
+		{`const d = new DOMException(); [d.name, d.message, d.code].join()`, "Error,,0"},
+		{`new DOMException("m", "CustomName").code`, "0"},
+		{`const withCause = new DOMException("m", { name: "DataCloneError", cause: 1 }); withCause.name + withCause.code + withCause.cause`, "DataCloneError251"},
+		{`DOMException.ABORT_ERR + " " + e.TIMEOUT_ERR + " " + DOMException.DATA_CLONE_ERR`, "20 23 25"},
+		{`class MyException extends DOMException {}; const mine = new MyException("x", "NotFoundError"); (mine instanceof MyException) + " " + mine.code`, "true 8"},
+		{`try { DOMException.prototype.name } catch (err) { err.code }`, "ERR_INVALID_THIS"},
+	})
+}
+
+func TestNewDOMException(t *testing.T) {
+	vm := goja.New()
+	new(require.Registry).Enable(vm)
+	e := NewDOMException(vm, "too slow", "TimeoutError")
+	vm.Set("e", e)
+	runTests(t, vm, []struct {
+		script   string
+		expected string
+	}{
+		{`e.name + " " + e.code + " " + (e instanceof require("web").DOMException)`, "TimeoutError 23 true"},
+	})
+}
+
+func TestEvent(t *testing.T) {
+	runTests(t, createVM(), []struct {
+		script   string
+		expected string
+	}{
+		{`const ev = new Event("ping"); [ev.type, ev.bubbles, ev.cancelable, ev.defaultPrevented, ev.isTrusted, ev.eventPhase, ev.target].join()`, "ping,false,false,false,false,0,"},
+		{`ev.preventDefault(); ev.defaultPrevented`, "false"},
+		{`const cancelable = new Event("ping", { cancelable: true }); cancelable.preventDefault(); cancelable.defaultPrevented + " " + cancelable.returnValue`, "true false"},
+		{`try { new Event() } catch (err) { err.code }`, "ERR_MISSING_ARGS"},
+		{`const custom = new CustomEvent("data", { detail: { id: 1 } }); custom.detail.id + " " + (custom instanceof Event) + " " + new CustomEvent("x").detail`, "1 true null"},
+		{`Event.AT_TARGET + " " + ev.BUBBLING_PHASE`, "2 3"},
+		{`typeof ev.timeStamp`, "number"},
+		{`Object.prototype.toString.call(custom)`, "[object CustomEvent]"},
+	})
+}
+
+func TestEventTarget(t *testing.T) {
+	runTests(t, createVM(), []struct {
+		script   string
+		expected string
+	}{
+		{`const target = new EventTarget(); const calls = [];
+		  target.addEventListener("ping", (e) => calls.push("bubble:" + e.eventPhase + ":" + (e.currentTarget === target)));
+		  target.addEventListener("ping", () => calls.push("capture"), true);
+		  target.addEventListener("ping", { handleEvent(e) { calls.push("object:" + (this !== target)) } });
+		  target.dispatchEvent(new Event("ping")); calls.join()`, "capture,bubble:2:true,object:true"},
+		{`const e = new Event("ping"); target.dispatchEvent(e); [e.eventPhase, e.currentTarget, e.target === target].join()`, "0,,true"},
+		{`let n = 0; const inc = () => n++; target.addEventListener("count", inc); target.addEventListener("count", inc);
+		  target.addEventListener("count", inc, { capture: true }); target.dispatchEvent(new Event("count")); n`, "2"},
+		{`target.removeEventListener("count", inc); target.dispatchEvent(new Event("count")); n`, "3"},
+		{`let once = 0; target.addEventListener("once", () => once++, { once: true });
+		  target.dispatchEvent(new Event("once")); target.dispatchEvent(new Event("once")); once`, "1"},
+		{`const order = []; target.addEventListener("stop", (e) => { order.push(1); e.stopImmediatePropagation() }); target.addEventListener("stop", () => order.push(2));
+		  target.dispatchEvent(new Event("stop")); order.join()`, "1"},
+		{`target.addEventListener("cancel", (e) => e.preventDefault());
+		  [target.dispatchEvent(new Event("cancel", { cancelable: true })), target.dispatchEvent(new Event("cancel"))].join()`, "false,true"},
+		{`target.addEventListener("passive", (e) => e.preventDefault(), { passive: true }); target.dispatchEvent(new Event("passive", { cancelable: true }))`, "true"},
+		{`let after = false; target.addEventListener("throws", () => { throw new Error("ignored") }); target.addEventListener("throws", () => after = true);
+		  target.dispatchEvent(new Event("throws")); after`, "true"},
+		{`let nested; target.addEventListener("nested", (e) => { try { target.dispatchEvent(e) } catch (err) { nested = err.name } });
+		  target.dispatchEvent(new Event("nested")); nested`, "InvalidStateError"},
+		{`try { target.dispatchEvent({ type: "ping" }) } catch (err) { err.code }`, "ERR_INVALID_ARG_TYPE"},
+		{`try { target.addEventListener("ping") } catch (err) { err.code }`, "ERR_MISSING_ARGS"},
+		{`class Emitter extends EventTarget { constructor() { super(); this.name = "emitter" } };
+		  const emitter = new Emitter(); let received; emitter.addEventListener("hello", (e) => received = e.detail + " " + e.target.name);
+		  emitter.dispatchEvent(new CustomEvent("hello", { detail: "hi" })); received`, "hi emitter"},
+		{`try { EventTarget.prototype.addEventListener.call({}, "x", () => {}) } catch (err) { err.code }`, "ERR_INVALID_THIS"},
+	})
+}
+
+func TestStructuredClone(t *testing.T) {
+	runTests(t, createVM(), []struct {
+		script   string
+		expected string
+	}{
+		{`structuredClone(1) + structuredClone("a") + structuredClone(null) + structuredClone(undefined) + structuredClone(10n)`, "1anullundefined10"},
+		{`const source = { date: new Date(0), re: /a+/gi, nested: { list: [1, , 3] } }; source.self = source;
+		  const copy = structuredClone(source);
+		  [copy !== source, copy.self === copy, copy.date instanceof Date, copy.date.getTime(), copy.date !== source.date,
+		   String(copy.re), copy.nested.list.length, 1 in copy.nested.list].join()`, "true,true,true,0,true,/a+/gi,3,false"},
+		{`const shared = { v: 1 }; const pair = structuredClone([shared, shared]); pair[0] === pair[1]`, "true"},
+		{`const map = new Map([[{ k: 1 }, new Set([1, 2])]]); map.set("self", map); const m2 = structuredClone(map);
+		  const [key, set] = [...m2.entries()][0];
+		  [m2 instanceof Map, m2.size, key.k, set instanceof Set, [...set].join("-"), m2.get("self") === m2].join()`, "true,2,1,true,1-2,true"},
+		{`const bytes = new Uint8Array([1, 2, 3, 4]); const view = new Uint16Array(bytes.buffer, 2, 1);
+		  const [b2, v2] = structuredClone([bytes, view]);
+		  [b2 instanceof Uint8Array, b2.join(""), v2 instanceof Uint16Array, v2.byteOffset, b2.buffer === v2.buffer, b2.buffer !== bytes.buffer].join()`, "true,1234,true,2,true,true"},
+		{`const dv = structuredClone(new DataView(new ArrayBuffer(4), 1)); (dv instanceof DataView) + " " + dv.byteLength`, "true 3"},
+		{`const boxed = structuredClone([new Number(1), new String("s"), Object(2n)]); boxed.map((b) => typeof b + ":" + b.valueOf()).join()`, "object:1,object:s,object:2"},
+		{`class Point { constructor() { this.x = 1 } get y() { return 2 } }; const p = structuredClone(new Point());
+		  (p instanceof Point) + " " + (Object.getPrototypeOf(p) === Object.prototype) + " " + p.x + " " + p.y`, "false true 1 undefined"},
+		{`const err = structuredClone(new RangeError("bad", { cause: { c: 1 } })); [err instanceof RangeError, err.message, err.cause.c].join()`, "true,bad,1"},
+		{`class MyError extends Error { constructor() { super("mine"); this.name = "MyError" } }; const mine = structuredClone(new MyError()); mine.name + " " + mine.message`, "Error mine"},
+		{`const ex = structuredClone(new DOMException("x", "AbortError")); (ex instanceof DOMException) + " " + ex.name + " " + ex.code`, "true AbortError 20"},
+		{`const cloneError = (v) => { try { structuredClone(v); return "cloned" } catch (e) { return e.name + ":" + e.code + ":" + e.message } };
+		  cloneError(() => 1)`, "DataCloneError:25:() => 1 could not be cloned."},
+		{`cloneError(Symbol("s"))`, "DataCloneError:25:Symbol(s) could not be cloned."},
+		{`cloneError({ p: Promise.resolve() })`, "DataCloneError:25:#<Promise> could not be cloned."},
+		{`cloneError(new WeakMap())`, "DataCloneError:25:#<WeakMap> could not be cloned."},
+		{`cloneError(new EventTarget())`, "DataCloneError:25:#<EventTarget> could not be cloned."},
+		{`try { structuredClone() } catch (e) { e.code }`, "ERR_MISSING_ARGS"},
+	})
+}
+
+func TestStructuredCloneTransfer(t *testing.T) {
+	runTests(t, createVM(), []struct {
+		script   string
+		expected string
+	}{
+		{`const buffer = new Uint8Array([1, 2]).buffer; const moved = structuredClone({ buffer }, { transfer: [buffer] });
+		  [buffer.byteLength, moved.buffer.byteLength, new Uint8Array(moved.buffer).join("")].join()`, "0,2,12"},
+		{`try { structuredClone(buffer) } catch (e) { e.name }`, "DataCloneError"},
+		{`const twice = new ArrayBuffer(1); try { structuredClone(twice, { transfer: [twice, twice] }) } catch (e) { e.name }`, "DataCloneError"},
+		{`try { structuredClone(1, { transfer: [{}] }) } catch (e) { e.message }`, "Value not transferable"},
+	})
+}
+
+func TestQueueMicrotask(t *testing.T) {
+	runTests(t, createVM(), []struct {
+		script   string
+		expected string
+	}{
+		{`var order = []; queueMicrotask(() => order.push("micro")); Promise.resolve().then(() => order.push("promise")); order.push("sync"); order.length`, "1"},
+		{`order.join()`, "sync,micro,promise"},
+		{`try { queueMicrotask("x") } catch (e) { e.code }`, "ERR_INVALID_ARG_TYPE"},
+	})
+}
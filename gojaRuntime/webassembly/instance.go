@@ -0,0 +1,356 @@
+package webassembly
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+var (
+	moduleSlot   = goja.NewSymbol("kWebAssemblyModule")
+	instanceSlot = goja.NewSymbol("kWebAssemblyInstance")
+)
+
+// compiledModule is a validated module. Every instance compiles it again in a wazero runtime of its own, so that
+// the imports of instances of the same module do not clash, which reuses the code compiled when validating it
+// unless the memory limit changed the binary.
+type compiledModule struct {
+	binary []byte
+	iface  *moduleInterface
+}
+
+type instance struct {
+	exports *goja.Object
+}
+
+// thrownValue carries a value thrown by a JS import through wazero, which only passes errors on.
+type thrownValue struct {
+	value goja.Value
+}
+
+func (e *thrownValue) Error() string {
+	return e.value.String()
+}
+
+func (m *webAssemblyModule) compileBytes(binary []byte) (*compiledModule, error) {
+	runtime := m.newRuntime(maxPages)
+	defer runtime.Close(m.ctx)
+	if _, err := runtime.CompileModule(m.ctx, binary); err != nil {
+		return nil, err
+	}
+	moduleInterface, err := parseModule(binary)
+	if err != nil {
+		return nil, err
+	}
+	return &compiledModule{binary: binary, iface: moduleInterface}, nil
+}
+
+func (m *webAssemblyModule) newModule(binary []byte) *goja.Object {
+	module, err := m.compileBytes(binary)
+	if err != nil {
+		m.throwCallError(err, m.compileError)
+	}
+	o := m.r.NewObject()
+	o.SetPrototype(m.modulePrototype)
+	o.DefineDataPropertySymbol(moduleSlot, m.r.ToValue(module), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+	return o
+}
+
+func (m *webAssemblyModule) toModule(v goja.Value) *compiledModule {
+	module, ok := slot[*compiledModule](v, moduleSlot)
+	if !ok {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "module" argument must be an instance of WebAssembly.Module.`))
+	}
+	return module
+}
+
+func (m *webAssemblyModule) createModuleConstructor() goja.Value {
+	f := m.r.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		o := m.newModule(m.sourceBytes(call.Argument(0)))
+		o.SetPrototype(call.This.Prototype())
+		return o
+	}).(*goja.Object)
+
+	m.modulePrototype = m.r.NewObject()
+	m.modulePrototype.DefineDataPropertySymbol(goja.SymToStringTag, m.r.ToValue("WebAssembly.Module"), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	f.Set("prototype", m.modulePrototype)
+	m.modulePrototype.DefineDataProperty("constructor", f, goja.FLAG_TRUE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	f.Set("imports", func(call goja.FunctionCall) goja.Value {
+		descriptors := []interface{}{}
+		for _, imported := range m.toModule(call.Argument(0)).iface.imports {
+			descriptors = append(descriptors, map[string]interface{}{"module": imported.module, "name": imported.name, "kind": imported.kind})
+		}
+		return m.r.ToValue(descriptors)
+	})
+	f.Set("exports", func(call goja.FunctionCall) goja.Value {
+		descriptors := []interface{}{}
+		for _, export := range m.toModule(call.Argument(0)).iface.exports {
+			descriptors = append(descriptors, map[string]interface{}{"name": export.name, "kind": export.kind})
+		}
+		return m.r.ToValue(descriptors)
+	})
+	f.Set("customSections", func(call goja.FunctionCall) goja.Value {
+		module := m.toModule(call.Argument(0))
+		name := call.Argument(1).String()
+		sections := []interface{}{}
+		for _, section := range module.iface.custom {
+			if section.name == name {
+				sections = append(sections, m.r.NewArrayBuffer(append([]byte(nil), section.data...)))
+			}
+		}
+		return m.r.ToValue(sections)
+	})
+	return f
+}
+
+func (m *webAssemblyModule) createInstanceConstructor() goja.Value {
+	f := m.r.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		o := m.newInstance(m.toModule(call.Argument(0)), call.Argument(1))
+		o.SetPrototype(call.This.Prototype())
+		return o
+	}).(*goja.Object)
+
+	m.instancePrototype = m.r.NewObject()
+	m.instancePrototype.DefineDataPropertySymbol(goja.SymToStringTag, m.r.ToValue("WebAssembly.Instance"), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	m.defineGetter(m.instancePrototype, "exports", func(call goja.FunctionCall) goja.Value {
+		i, ok := slot[*instance](call.This, instanceSlot)
+		if !ok {
+			panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidThis, `Value of "this" must be of type WebAssembly.Instance`))
+		}
+		return i.exports
+	})
+	f.Set("prototype", m.instancePrototype)
+	m.instancePrototype.DefineDataProperty("constructor", f, goja.FLAG_TRUE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	return f
+}
+
+// newInstance instantiates module with the functions of importObject. Its memory counts against the memory limit:
+// the instance may start with and grow to what the other instances leave of it, see memoryBudget.
+func (m *webAssemblyModule) newInstance(module *compiledModule, importObject goja.Value) *goja.Object {
+	if len(module.iface.imports) > 0 {
+		if _, ok := importObject.(*goja.Object); !ok {
+			panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "importObject" argument must be of type object.`))
+		}
+	}
+
+	binary := module.binary
+	memoryLimitPages := uint32(maxPages)
+	if m.maxPages > 0 {
+		if m.usedPages()+module.iface.minPages() > m.maxPages {
+			panic(m.memoryLimitError())
+		}
+		memoryLimitPages = m.maxPages
+		binary = limitMemory(binary, memoryLimitPages)
+	}
+
+	runtime := m.newRuntime(memoryLimitPages)
+	kept := false
+	defer func() {
+		// the runtime of an instance which failed to link or start is of no further use
+		if !kept {
+			runtime.Close(m.ctx)
+		}
+	}()
+	compiled, err := runtime.CompileModule(m.ctx, binary)
+	if err != nil {
+		m.throwCallError(err, m.compileError)
+	}
+	m.linkImports(runtime, module, compiled, importObject)
+
+	// the imports are linked, what fails now is the start function or the initialisation of the memory
+	ctx := experimental.WithMemoryAllocator(m.ctx, m.memory)
+	instantiated, err := runtime.InstantiateModule(ctx, compiled, wazero.NewModuleConfig().WithStartFunctions())
+	if err != nil {
+		m.throwCallError(err, m.runtimeError)
+	}
+	m.keep(runtime)
+	kept = true
+	m.instances = append(m.instances, instantiated)
+
+	exports := m.r.NewObject()
+	exports.SetPrototype(nil)
+	for _, export := range module.iface.exports {
+		switch export.kind {
+		case "function":
+			exports.Set(export.name, m.exportedFunction(instantiated.ExportedFunction(export.name)))
+		case "memory":
+			exports.Set(export.name, m.newMemory(instantiated.ExportedMemory(export.name)))
+		case "global":
+			exports.Set(export.name, m.newGlobal(instantiated.ExportedGlobal(export.name)))
+		}
+	}
+	freeze, _ := goja.AssertFunction(m.r.Get("Object").ToObject(m.r).Get("freeze"))
+	if _, err := freeze(goja.Undefined(), exports); err != nil {
+		panic(err)
+	}
+
+	o := m.r.NewObject()
+	o.SetPrototype(m.instancePrototype)
+	o.DefineDataPropertySymbol(instanceSlot, m.r.ToValue(&instance{exports: exports}), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+	return o
+}
+
+// linkImports instantiates the host modules providing the imported functions from importObject. Only functions
+// can be imported, wazero cannot share memories, tables and globals across runtimes.
+func (m *webAssemblyModule) linkImports(runtime wazero.Runtime, module *compiledModule, compiled wazero.CompiledModule, importObject goja.Value) {
+	for i, imported := range module.iface.imports {
+		if imported.kind != "function" {
+			panic(m.newError(m.linkError, fmt.Sprintf(`Import #%d "%s" "%s": importing a %s is not supported`, i, imported.module, imported.name, imported.kind)))
+		}
+	}
+
+	builders := map[string]wazero.HostModuleBuilder{}
+	names := []string{}
+	for i, definition := range compiled.ImportedFunctions() {
+		moduleName, name, _ := definition.Import()
+		namespace, ok := importObject.(*goja.Object).Get(moduleName).(*goja.Object)
+		if !ok {
+			panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `Import #%d "%s": module is not an object`, i, moduleName))
+		}
+		fn, ok := goja.AssertFunction(namespace.Get(name))
+		if !ok {
+			panic(m.newError(m.linkError, fmt.Sprintf(`Import #%d "%s" "%s": function import requires a callable`, i, moduleName, name)))
+		}
+		builder, ok := builders[moduleName]
+		if !ok {
+			builder = runtime.NewHostModuleBuilder(moduleName)
+			builders[moduleName] = builder
+			names = append(names, moduleName)
+		}
+		builder.NewFunctionBuilder().
+			WithGoModuleFunction(m.importedFunction(fn, definition.ParamTypes(), definition.ResultTypes()), definition.ParamTypes(), definition.ResultTypes()).
+			Export(name)
+	}
+	for _, name := range names {
+		if _, err := builders[name].Instantiate(m.ctx); err != nil {
+			m.throwCallError(err, m.linkError)
+		}
+	}
+}
+
+// importedFunction calls fn from WebAssembly.
+func (m *webAssemblyModule) importedFunction(fn goja.Callable, params, results []api.ValueType) api.GoModuleFunction {
+	return api.GoModuleFunc(func(_ context.Context, _ api.Module, stack []uint64) {
+		defer func() {
+			if r := recover(); r != nil {
+				if v, ok := r.(goja.Value); ok {
+					panic(&thrownValue{value: v})
+				}
+				panic(r)
+			}
+		}()
+		args := make([]goja.Value, len(params))
+		for i, t := range params {
+			args[i] = m.fromWasm(stack[i], t)
+		}
+		result, err := fn(goja.Undefined(), args...)
+		if err != nil {
+			panic(err)
+		}
+		switch len(results) {
+		case 0:
+		case 1:
+			stack[0] = m.toWasm(result, results[0])
+		default:
+			values := result.ToObject(m.r)
+			for i, t := range results {
+				stack[i] = m.toWasm(values.Get(strconv.Itoa(i)), t)
+			}
+		}
+	})
+}
+
+// exportedFunction calls fn from JS, results are returned as an array when there are several.
+func (m *webAssemblyModule) exportedFunction(fn api.Function) goja.Value {
+	definition := fn.Definition()
+	params, results := definition.ParamTypes(), definition.ResultTypes()
+	f := m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		args := make([]uint64, len(params))
+		for i, t := range params {
+			args[i] = m.toWasm(call.Argument(i), t)
+		}
+		stack, err := fn.Call(m.ctx, args...)
+		if err != nil {
+			m.throwCallError(err, m.runtimeError)
+		}
+		switch len(results) {
+		case 0:
+			return goja.Undefined()
+		case 1:
+			return m.fromWasm(stack[0], results[0])
+		}
+		values := make([]interface{}, len(results))
+		for i, t := range results {
+			values[i] = m.fromWasm(stack[i], t)
+		}
+		return m.r.ToValue(values)
+	}).(*goja.Object)
+	f.DefineDataProperty("length", m.r.ToValue(len(params)), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	return f
+}
+
+// throwCallError throws the JS error matching err: values thrown by imports are rethrown, the execution being
+// aborted throws its cause and other errors are thrown as errors of constructor.
+func (m *webAssemblyModule) throwCallError(err error, constructor *goja.Object) {
+	var thrown *thrownValue
+	if goerrors.As(err, &thrown) {
+		panic(thrown.value)
+	}
+	var exception *goja.Exception
+	if goerrors.As(err, &exception) {
+		panic(exception)
+	}
+	var interrupted *goja.InterruptedError
+	if goerrors.As(err, &interrupted) {
+		panic(interrupted)
+	}
+	var exit *sys.ExitError
+	if goerrors.As(err, &exit) && m.ctx.Err() != nil {
+		panic(errors.FromGoError(m.r, context.Cause(m.ctx)))
+	}
+	panic(m.newError(constructor, err.Error()))
+}
+
+func (m *webAssemblyModule) toWasm(v goja.Value, t api.ValueType) uint64 {
+	switch t {
+	case api.ValueTypeI32:
+		return api.EncodeI32(int32(v.ToInteger()))
+	case api.ValueTypeI64:
+		toBigInt, _ := goja.AssertFunction(m.r.Get("BigInt"))
+		n, err := toBigInt(goja.Undefined(), v)
+		if err != nil {
+			panic(err)
+		}
+		i, _ := n.Export().(*big.Int)
+		return new(big.Int).And(i, new(big.Int).SetUint64(^uint64(0))).Uint64()
+	case api.ValueTypeF32:
+		return api.EncodeF32(float32(v.ToFloat()))
+	case api.ValueTypeF64:
+		return api.EncodeF64(v.ToFloat())
+	}
+	panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, "%s values cannot be passed from JS", api.ValueTypeName(t)))
+}
+
+func (m *webAssemblyModule) fromWasm(v uint64, t api.ValueType) goja.Value {
+	switch t {
+	case api.ValueTypeI32:
+		return m.r.ToValue(api.DecodeI32(v))
+	case api.ValueTypeI64:
+		return m.r.ToValue(big.NewInt(int64(v)))
+	case api.ValueTypeF32:
+		return m.r.ToValue(float64(api.DecodeF32(v)))
+	case api.ValueTypeF64:
+		return m.r.ToValue(api.DecodeF64(v))
+	}
+	panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, "%s values cannot be passed to JS", api.ValueTypeName(t)))
+}
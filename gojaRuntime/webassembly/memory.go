@@ -0,0 +1,205 @@
+package webassembly
+
+import (
+	"sync"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+var (
+	memorySlot = goja.NewSymbol("kWebAssemblyMemory")
+	globalSlot = goja.NewSymbol("kWebAssemblyGlobal")
+)
+
+type memory struct {
+	memory api.Memory
+	// buffer views the memory until it grows, it is then detached
+	buffer *goja.Object
+	pages  uint32
+}
+
+type global struct {
+	global api.Global
+}
+
+// memoryBudget allocates the memories of all the instances of a runtime, reserving their pages against the limit as
+// they grow. A memory growing past what the others leave fails to grow, as it would past its maximum.
+type memoryBudget struct {
+	lock sync.Mutex
+	// maxBytes is the memory limit, 0 when there is none
+	maxBytes uint64
+	reserved uint64
+}
+
+// Allocate implements experimental.MemoryAllocator.
+func (b *memoryBudget) Allocate(cap, _ uint64) experimental.LinearMemory {
+	return &linearMemory{budget: b, buffer: make([]byte, 0, cap)}
+}
+
+// reserve reserves size more bytes, it returns false when they would exceed the limit.
+func (b *memoryBudget) reserve(size uint64) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.maxBytes > 0 && b.reserved+size > b.maxBytes {
+		return false
+	}
+	b.reserved += size
+	return true
+}
+
+func (b *memoryBudget) release(size uint64) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.reserved -= size
+}
+
+// pages returns the pages the memories of all instances have together.
+func (b *memoryBudget) pages() uint32 {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return uint32(b.reserved / pageSize)
+}
+
+// linearMemory is a memory whose size is reserved from its budget.
+type linearMemory struct {
+	budget *memoryBudget
+	buffer []byte
+}
+
+// Reallocate implements experimental.LinearMemory, it returns nil when the budget does not have size bytes left.
+func (l *linearMemory) Reallocate(size uint64) []byte {
+	used := uint64(len(l.buffer))
+	if size <= used {
+		return l.buffer
+	}
+	if !l.budget.reserve(size - used) {
+		return nil
+	}
+	l.buffer = append(l.buffer, make([]byte, size-used)...)
+	return l.buffer
+}
+
+// Free implements experimental.LinearMemory.
+func (l *linearMemory) Free() {
+	l.budget.release(uint64(len(l.buffer)))
+	l.buffer = nil
+}
+
+// usedPages returns the pages the memories of all instances have together.
+func (m *webAssemblyModule) usedPages() uint32 {
+	return m.memory.pages()
+}
+
+func (m *webAssemblyModule) memoryLimitError() *goja.Object {
+	return errors.NewError(m.r, m.rangeErrorConstructor, errors.ErrCodeMemoryLimitExceeded, "WebAssembly memory may not exceed %d bytes", uint64(m.maxPages)*pageSize)
+}
+
+func (m *webAssemblyModule) newMemory(mem api.Memory) *goja.Object {
+	o := m.r.NewObject()
+	o.SetPrototype(m.memoryPrototype)
+	o.DefineDataPropertySymbol(memorySlot, m.r.ToValue(&memory{memory: mem}), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+	return o
+}
+
+func (m *webAssemblyModule) toMemory(v goja.Value) *memory {
+	mem, ok := slot[*memory](v, memorySlot)
+	if !ok {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidThis, `Value of "this" must be of type WebAssembly.Memory`))
+	}
+	return mem
+}
+
+// detach detaches the buffer of mem, once the memory grew it no longer views it.
+func (mem *memory) detach() {
+	if mem.buffer != nil {
+		if buffer, ok := mem.buffer.Export().(goja.ArrayBuffer); ok {
+			buffer.Detach()
+		}
+		mem.buffer = nil
+	}
+}
+
+// createMemoryConstructor creates WebAssembly.Memory. Memories cannot be imported, so only instances create them.
+func (m *webAssemblyModule) createMemoryConstructor() goja.Value {
+	f := m.r.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeIllegalConstructor, "Illegal constructor"))
+	}).(*goja.Object)
+
+	m.memoryPrototype = m.r.NewObject()
+	m.memoryPrototype.DefineDataPropertySymbol(goja.SymToStringTag, m.r.ToValue("WebAssembly.Memory"), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	m.defineGetter(m.memoryPrototype, "buffer", func(call goja.FunctionCall) goja.Value {
+		mem := m.toMemory(call.This)
+		// the memory may have grown from WebAssembly
+		if pages := mem.memory.Size() / pageSize; mem.buffer == nil || pages != mem.pages {
+			mem.detach()
+			data, _ := mem.memory.Read(0, mem.memory.Size())
+			mem.buffer = m.r.ToValue(m.r.NewArrayBuffer(data)).(*goja.Object)
+			mem.pages = pages
+		}
+		return mem.buffer
+	})
+	m.memoryPrototype.Set("grow", func(call goja.FunctionCall) goja.Value {
+		mem := m.toMemory(call.This)
+		delta := call.Argument(0).ToInteger()
+		if delta < 0 || delta > maxPages {
+			panic(errors.NewError(m.r, m.rangeErrorConstructor, errors.ErrCodeOutOfRange, "WebAssembly.Memory.grow(): delta must be between 0 and %d", maxPages))
+		}
+		if m.maxPages > 0 && int64(m.usedPages())+delta > int64(m.maxPages) {
+			panic(m.memoryLimitError())
+		}
+		previous, ok := mem.memory.Grow(uint32(delta))
+		if !ok {
+			panic(errors.NewError(m.r, m.rangeErrorConstructor, errors.ErrCodeOutOfRange, "WebAssembly.Memory.grow(): Maximum memory size exceeded"))
+		}
+		mem.detach()
+		return m.r.ToValue(previous)
+	})
+	f.Set("prototype", m.memoryPrototype)
+	m.memoryPrototype.DefineDataProperty("constructor", f, goja.FLAG_TRUE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	return f
+}
+
+func (m *webAssemblyModule) newGlobal(g api.Global) *goja.Object {
+	o := m.r.NewObject()
+	o.SetPrototype(m.globalPrototype)
+	o.DefineDataPropertySymbol(globalSlot, m.r.ToValue(&global{global: g}), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+	return o
+}
+
+func (m *webAssemblyModule) toGlobal(v goja.Value) *global {
+	g, ok := slot[*global](v, globalSlot)
+	if !ok {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidThis, `Value of "this" must be of type WebAssembly.Global`))
+	}
+	return g
+}
+
+// createGlobalConstructor creates WebAssembly.Global. Globals cannot be imported, so only instances create them.
+func (m *webAssemblyModule) createGlobalConstructor() goja.Value {
+	f := m.r.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeIllegalConstructor, "Illegal constructor"))
+	}).(*goja.Object)
+
+	m.globalPrototype = m.r.NewObject()
+	m.globalPrototype.DefineDataPropertySymbol(goja.SymToStringTag, m.r.ToValue("WebAssembly.Global"), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	value := func(call goja.FunctionCall) goja.Value {
+		g := m.toGlobal(call.This)
+		return m.fromWasm(g.global.Get(), g.global.Type())
+	}
+	m.globalPrototype.DefineAccessorProperty("value", m.r.ToValue(value), m.r.ToValue(func(call goja.FunctionCall) goja.Value {
+		g := m.toGlobal(call.This)
+		mutable, ok := g.global.(api.MutableGlobal)
+		if !ok {
+			panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidThis, "Can't set the value of an immutable global."))
+		}
+		mutable.Set(m.toWasm(call.Argument(0), g.global.Type()))
+		return goja.Undefined()
+	}), goja.FLAG_FALSE, goja.FLAG_TRUE)
+	m.globalPrototype.Set("valueOf", value)
+	f.Set("prototype", m.globalPrototype)
+	m.globalPrototype.DefineDataProperty("constructor", f, goja.FLAG_TRUE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	return f
+}
@@ -0,0 +1,256 @@
+package webassembly
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	goja "github.com/grafana/sobek"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/encoding"
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/errors"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+const (
+	// pageSize is the size of a page of WebAssembly memory
+	pageSize = 65536
+	// maxPages is the most pages a memory can have
+	maxPages = 65536
+)
+
+// Options configures the WebAssembly global of a runtime.
+type Options struct {
+	// Context bounds compiling, instantiating and calling modules, they are aborted and their runtimes closed once it
+	// is done
+	Context context.Context
+	// MaxMemoryBytes caps the memory of all the instances of the runtime together, 0 means no limit. It is rounded up
+	// to whole pages.
+	MaxMemoryBytes int64
+}
+
+type webAssemblyModule struct {
+	r   *goja.Runtime
+	ctx context.Context
+	// maxPages is the memory limit in pages, 0 when there is none
+	maxPages  uint32
+	instances []api.Module
+	// memory allocates the memories of the instances, keeping them within maxPages together
+	memory *memoryBudget
+	// cache shares the compiled code of a module between the runtimes of its instances
+	cache wazero.CompilationCache
+
+	mu       sync.Mutex
+	runtimes []wazero.Runtime
+	closed   bool
+
+	errorConstructor      *goja.Object
+	rangeErrorConstructor *goja.Object
+	compileError          *goja.Object
+	linkError             *goja.Object
+	runtimeError          *goja.Object
+	modulePrototype       *goja.Object
+	instancePrototype     *goja.Object
+	memoryPrototype       *goja.Object
+	globalPrototype       *goja.Object
+}
+
+// slot returns the Go state kept under an internal symbol of the objects the API creates.
+func slot[T any](v goja.Value, symbol *goja.Symbol) (T, bool) {
+	var zero T
+	o, ok := v.(*goja.Object)
+	if !ok {
+		return zero, false
+	}
+	value := o.GetSymbol(symbol)
+	if value == nil {
+		return zero, false
+	}
+	state, ok := value.Export().(T)
+	return state, ok
+}
+
+func (m *webAssemblyModule) defineGetter(p *goja.Object, name string, getter func(call goja.FunctionCall) goja.Value) {
+	p.DefineAccessorProperty(name, m.r.ToValue(getter), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+}
+
+// NewRuntimeConfig returns the configuration of the wazero runtimes running untrusted modules, shared with the wasi
+// runtime. The interpreter keeps the engine pure Go, without generating native code, and modules are closed once
+// the context of their runtime is done.
+func NewRuntimeConfig() wazero.RuntimeConfig {
+	return wazero.NewRuntimeConfigInterpreter().WithCloseOnContextDone(true)
+}
+
+// newRuntime creates a wazero runtime whose memories may not exceed memoryLimitPages. Callers close the runtime or
+// keep it with keep.
+func (m *webAssemblyModule) newRuntime(memoryLimitPages uint32) wazero.Runtime {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		panic(errors.FromGoError(m.r, context.Cause(m.ctx)))
+	}
+	config := NewRuntimeConfig().
+		WithMemoryLimitPages(memoryLimitPages).
+		WithCompilationCache(m.cache)
+	return wazero.NewRuntimeWithConfig(m.ctx, config)
+}
+
+// keep closes runtime along with the others once the context is done.
+func (m *webAssemblyModule) keep(runtime wazero.Runtime) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		runtime.Close(context.Background())
+		return
+	}
+	m.runtimes = append(m.runtimes, runtime)
+}
+
+// close closes the runtimes of the instances and releases the compiled code.
+func (m *webAssemblyModule) close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	for _, runtime := range m.runtimes {
+		runtime.Close(context.Background())
+	}
+	m.runtimes = nil
+	m.cache.Close(context.Background())
+}
+
+// sourceBytes copies the bytes of a module, so that scripts changing their buffer do not change the module.
+func (m *webAssemblyModule) sourceBytes(v goja.Value) []byte {
+	data, ok := encoding.BufferSourceBytes(m.r, v)
+	if !ok {
+		panic(errors.NewTypeError(m.r, errors.ErrCodeInvalidArgType, `The "bytes" argument must be an instance of ArrayBuffer, TypedArray, or DataView.`))
+	}
+	return append([]byte(nil), data...)
+}
+
+func (m *webAssemblyModule) newError(constructor *goja.Object, message string) *goja.Object {
+	// wazero errors end with the wasm stack trace
+	message, _, _ = strings.Cut(message, "\n")
+	o, err := m.r.New(constructor, m.r.ToValue(message))
+	if err != nil {
+		panic(err)
+	}
+	return o
+}
+
+// createErrorConstructor creates CompileError, LinkError or RuntimeError, which extend Error.
+func (m *webAssemblyModule) createErrorConstructor(name string) *goja.Object {
+	f := m.r.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		o, err := m.r.New(m.errorConstructor, call.Arguments...)
+		if err != nil {
+			panic(err)
+		}
+		o.SetPrototype(call.This.Prototype())
+		return o
+	}).(*goja.Object)
+
+	p := m.r.NewObject()
+	p.SetPrototype(m.errorConstructor.Get("prototype").ToObject(m.r))
+	p.DefineDataProperty("name", m.r.ToValue(name), goja.FLAG_TRUE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	p.DefineDataProperty("message", m.r.ToValue(""), goja.FLAG_TRUE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	p.DefineDataProperty("constructor", f, goja.FLAG_TRUE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	f.SetPrototype(m.errorConstructor)
+	f.DefineDataProperty("name", m.r.ToValue(name), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	f.Set("prototype", p)
+	return f
+}
+
+// promise runs fn synchronously and settles the returned promise with its result, as compile and instantiate
+// report errors by rejecting.
+func (m *webAssemblyModule) promise(fn func() goja.Value) goja.Value {
+	promise, resolve, reject := m.r.NewPromise()
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				switch e := r.(type) {
+				case *goja.Exception:
+					reject(e.Value())
+				case goja.Value:
+					reject(e)
+				default:
+					panic(r)
+				}
+			}
+		}()
+		resolve(fn())
+	}()
+	return m.r.ToValue(promise)
+}
+
+func (m *webAssemblyModule) validate(call goja.FunctionCall) goja.Value {
+	_, err := m.compileBytes(m.sourceBytes(call.Argument(0)))
+	return m.r.ToValue(err == nil)
+}
+
+func (m *webAssemblyModule) compile(call goja.FunctionCall) goja.Value {
+	return m.promise(func() goja.Value {
+		return m.newModule(m.sourceBytes(call.Argument(0)))
+	})
+}
+
+// instantiate resolves to an Instance when given a Module, and to the Module and its Instance when given bytes.
+func (m *webAssemblyModule) instantiate(call goja.FunctionCall) goja.Value {
+	return m.promise(func() goja.Value {
+		if module, ok := slot[*compiledModule](call.Argument(0), moduleSlot); ok {
+			return m.newInstance(module, call.Argument(1))
+		}
+		moduleObject := m.newModule(m.sourceBytes(call.Argument(0)))
+		module, _ := slot[*compiledModule](moduleObject, moduleSlot)
+		result := m.r.NewObject()
+		result.Set("module", moduleObject)
+		result.Set("instance", m.newInstance(module, call.Argument(1)))
+		return result
+	})
+}
+
+// Enable installs the WebAssembly global into the runtime and returns it.
+func Enable(runtime *goja.Runtime, options Options) *goja.Object {
+	_, webAssembly := enable(runtime, options)
+	return webAssembly
+}
+
+func enable(runtime *goja.Runtime, options Options) (*webAssemblyModule, *goja.Object) {
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	m := &webAssemblyModule{
+		r:                     runtime,
+		ctx:                   ctx,
+		cache:                 wazero.NewCompilationCache(),
+		errorConstructor:      runtime.Get("Error").ToObject(runtime),
+		rangeErrorConstructor: runtime.Get("RangeError").ToObject(runtime),
+	}
+	if options.MaxMemoryBytes > 0 {
+		// a limit below a page still allows a page, 0 would mean no limit
+		pages := options.MaxMemoryBytes / pageSize
+		if options.MaxMemoryBytes%pageSize != 0 {
+			pages++
+		}
+		m.maxPages = uint32(min(pages, maxPages))
+	}
+	m.memory = &memoryBudget{maxBytes: uint64(m.maxPages) * pageSize}
+	context.AfterFunc(ctx, m.close)
+	m.compileError = m.createErrorConstructor("CompileError")
+	m.linkError = m.createErrorConstructor("LinkError")
+	m.runtimeError = m.createErrorConstructor("RuntimeError")
+
+	webAssembly := runtime.NewObject()
+	webAssembly.Set("validate", m.validate)
+	webAssembly.Set("compile", m.compile)
+	webAssembly.Set("instantiate", m.instantiate)
+	webAssembly.Set("Module", m.createModuleConstructor())
+	webAssembly.Set("Instance", m.createInstanceConstructor())
+	webAssembly.Set("Memory", m.createMemoryConstructor())
+	webAssembly.Set("Global", m.createGlobalConstructor())
+	webAssembly.Set("CompileError", m.compileError)
+	webAssembly.Set("LinkError", m.linkError)
+	webAssembly.Set("RuntimeError", m.runtimeError)
+	webAssembly.DefineDataPropertySymbol(goja.SymToStringTag, runtime.ToValue("WebAssembly"), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	runtime.Set("WebAssembly", webAssembly)
+	return m, webAssembly
+}
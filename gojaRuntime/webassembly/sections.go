@@ -0,0 +1,249 @@
+package webassembly
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// The ids of the sections read by parseModule.
+const (
+	customSectionID = 0
+	importSectionID = 2
+	memorySectionID = 5
+	exportSectionID = 7
+)
+
+// externalKinds are the names WebAssembly.Module.imports and exports give the kinds of imports and exports.
+var externalKinds = []string{"function", "table", "memory", "global"}
+
+var errMalformed = errors.New("malformed module")
+
+type importDescriptor struct {
+	module string
+	name   string
+	kind   string
+}
+
+type exportDescriptor struct {
+	name string
+	kind string
+}
+
+type customSection struct {
+	name string
+	data []byte
+}
+
+type memoryLimits struct {
+	min    uint32
+	max    uint32
+	hasMax bool
+}
+
+// moduleInterface is what a module imports and exports, read from a binary wazero already validated. wazero only
+// lists functions and memories, the JS API describes tables and globals as well.
+type moduleInterface struct {
+	imports  []importDescriptor
+	exports  []exportDescriptor
+	memories []memoryLimits
+	custom   []customSection
+}
+
+// minPages returns the number of pages the memory defined by the module starts with.
+func (i *moduleInterface) minPages() uint32 {
+	var pages uint32
+	for _, memory := range i.memories {
+		pages += memory.min
+	}
+	return pages
+}
+
+type reader struct {
+	*bytes.Reader
+}
+
+func (r reader) u32() (uint32, error) {
+	v, err := binary.ReadUvarint(r)
+	if err != nil || v > 0xFFFFFFFF {
+		return 0, errMalformed
+	}
+	return uint32(v), nil
+}
+
+func (r reader) name() (string, error) {
+	size, err := r.u32()
+	if err != nil || int(size) > r.Len() {
+		return "", errMalformed
+	}
+	name := make([]byte, size)
+	_, err = r.Read(name)
+	return string(name), err
+}
+
+func (r reader) limits() (memoryLimits, error) {
+	flags, err := r.ReadByte()
+	if err != nil {
+		return memoryLimits{}, err
+	}
+	var limits memoryLimits
+	if limits.min, err = r.u32(); err != nil {
+		return limits, err
+	}
+	if flags&1 != 0 {
+		limits.hasMax = true
+		limits.max, err = r.u32()
+	}
+	return limits, err
+}
+
+// sections calls fn with the id and the contents of each section of binary, and where the section starts and ends.
+func sections(binary []byte, fn func(id byte, start, end int, contents []byte) error) error {
+	if len(binary) < 8 {
+		return errMalformed
+	}
+	r := reader{bytes.NewReader(binary[8:])}
+	for r.Len() > 0 {
+		start := len(binary) - r.Len()
+		id, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		size, err := r.u32()
+		if err != nil || int(size) > r.Len() {
+			return errMalformed
+		}
+		offset := len(binary) - r.Len()
+		if err := fn(id, start, offset+int(size), binary[offset:offset+int(size)]); err != nil {
+			return err
+		}
+		r.Seek(int64(size), 1)
+	}
+	return nil
+}
+
+func parseModule(binary []byte) (*moduleInterface, error) {
+	i := &moduleInterface{}
+	err := sections(binary, func(id byte, _, _ int, contents []byte) error {
+		r := reader{bytes.NewReader(contents)}
+		switch id {
+		case customSectionID:
+			name, err := r.name()
+			if err != nil {
+				return err
+			}
+			i.custom = append(i.custom, customSection{name: name, data: contents[len(contents)-r.Len():]})
+			return nil
+		case importSectionID:
+			return parseImports(r, i)
+		case memorySectionID:
+			count, err := r.u32()
+			for n := uint32(0); err == nil && n < count; n++ {
+				var limits memoryLimits
+				if limits, err = r.limits(); err == nil {
+					i.memories = append(i.memories, limits)
+				}
+			}
+			return err
+		case exportSectionID:
+			count, err := r.u32()
+			for n := uint32(0); err == nil && n < count; n++ {
+				var export exportDescriptor
+				if export.name, err = r.name(); err != nil {
+					return err
+				}
+				kind, err := r.ReadByte()
+				if err != nil || int(kind) >= len(externalKinds) {
+					return errMalformed
+				}
+				export.kind = externalKinds[kind]
+				i.exports = append(i.exports, export)
+				_, err = r.u32()
+			}
+			return err
+		}
+		return nil
+	})
+	return i, err
+}
+
+func parseImports(r reader, i *moduleInterface) error {
+	count, err := r.u32()
+	for n := uint32(0); err == nil && n < count; n++ {
+		var imported importDescriptor
+		if imported.module, err = r.name(); err != nil {
+			return err
+		}
+		if imported.name, err = r.name(); err != nil {
+			return err
+		}
+		kind, err := r.ReadByte()
+		if err != nil || int(kind) >= len(externalKinds) {
+			return errMalformed
+		}
+		imported.kind = externalKinds[kind]
+		i.imports = append(i.imports, imported)
+		switch kind {
+		case 0:
+			_, err = r.u32()
+		case 1:
+			if _, err = r.ReadByte(); err == nil {
+				_, err = r.limits()
+			}
+		case 2:
+			_, err = r.limits()
+		case 3:
+			_, err = r.Seek(2, 1)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// limitMemory lowers the maximum the memories of binary declare to maxPages. Memories growing past it then fail
+// to grow, as they would past the maximum they declare, rather than failing to compile with a lower memory limit.
+func limitMemory(binary []byte, maxPages uint32) []byte {
+	var result []byte
+	sections(binary, func(id byte, start, end int, contents []byte) error {
+		if id != memorySectionID {
+			return nil
+		}
+		r := reader{bytes.NewReader(contents)}
+		count, err := r.u32()
+		section := binaryAppendU32(nil, count)
+		for n := uint32(0); err == nil && n < count; n++ {
+			var limits memoryLimits
+			if limits, err = r.limits(); err != nil {
+				return err
+			}
+			if limits.hasMax && limits.max > maxPages {
+				limits.max = maxPages
+			}
+			if limits.hasMax {
+				section = append(section, 1)
+				section = binaryAppendU32(binaryAppendU32(section, limits.min), limits.max)
+			} else {
+				section = binaryAppendU32(append(section, 0), limits.min)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		result = append(result, binary[:start]...)
+		result = append(result, memorySectionID)
+		result = binaryAppendU32(result, uint32(len(section)))
+		result = append(result, section...)
+		result = append(result, binary[end:]...)
+		return nil
+	})
+	if result == nil {
+		return binary
+	}
+	return result
+}
+
+func binaryAppendU32(b []byte, v uint32) []byte {
+	return binary.AppendUvarint(b, uint64(v))
+}
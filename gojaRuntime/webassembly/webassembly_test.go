@@ -0,0 +1,158 @@
+package webassembly
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	goja "github.com/grafana/sobek"
+	"github.com/stretchr/testify/assert"
+)
+
+// testModule imports env.log (i32) -> i32 and exports:
+//
+//	memory   1 to 4 pages, starting with "hi"
+//	counter  a mutable i32 global set to 7
+//	add      (i32, i32) -> i32
+//	callLog  (x i32) -> i32, returns log(x) + x
+//	trap     () -> (), unreachable
+//	grow     (i32) -> i32, memory.grow
+//	wide     (i64) -> i64, adds 1
+//	spin     () -> (), loops forever
+//
+// It also has a custom section "note" holding "kinde".
+const testModule = "0061736d0100000001140460017f017f60027f7f017f60000060017e017e020b0103656e76036c6f6700000307060100020003020504010101040606017f0141070b074008066d656d6f7279020007636f756e74657203000361646400010763616c6c4c6f670002047472617000030467726f77000404776964650005047370696e00060a2e060700200020016a0b09002000100020006a0b0300000b0600200040000b0700200042017c0b070003400c000b0b0b08010041000b026869000a046e6f74656b696e6465"
+
+func createVM(t *testing.T, options Options) *goja.Runtime {
+	vm, _ := createModule(t, options)
+	return vm
+}
+
+func createModule(t *testing.T, options Options) (*goja.Runtime, *webAssemblyModule) {
+	vm := goja.New()
+	m, _ := enable(vm, options)
+	binary, _ := hex.DecodeString(testModule)
+	vm.Set("bytes", vm.NewArrayBuffer(binary))
+	_, err := vm.RunString(`const imports = { env: { log: (x) => x * 10 } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vm, m
+}
+
+// runAsync runs script as the body of an async function and returns what it resolved to,
+// or the name and message of the rejection.
+func runAsync(t *testing.T, vm *goja.Runtime, script string) string {
+	t.Helper()
+	_, err := vm.RunString(`(async () => {` + script + `})().then(v => globalThis.out = String(v), e => globalThis.out = "rejected: " + e.name + ": " + e.message)`)
+	if err != nil {
+		t.Fatalf("Failed to run %s: %v", script, err)
+	}
+	return vm.Get("out").String()
+}
+
+func TestWebAssembly(t *testing.T) {
+	tests := []struct {
+		script   string
+		expected string
+	}{
+		{`const { instance } = await WebAssembly.instantiate(bytes, imports); return instance.exports.add(2, 3)`, "5"},
+		{`const { instance } = await WebAssembly.instantiate(bytes, imports); return instance.exports.callLog(4)`, "44"},
+		{`const { module, instance } = await WebAssembly.instantiate(bytes, imports); return [module instanceof WebAssembly.Module, instance instanceof WebAssembly.Instance]`, "true,true"},
+		{`const module = await WebAssembly.compile(bytes); const instance = await WebAssembly.instantiate(module, imports); return instance.exports.add(1, 1)`, "2"},
+		{`const instance = new WebAssembly.Instance(new WebAssembly.Module(bytes), imports); return Object.keys(instance.exports)`, "memory,counter,add,callLog,trap,grow,wide,spin"},
+		{`const instance = new WebAssembly.Instance(new WebAssembly.Module(bytes), imports); return Object.isFrozen(instance.exports)`, "true"},
+		{`return WebAssembly.Module.imports(new WebAssembly.Module(bytes)).map(i => i.module + "." + i.name + ":" + i.kind)`, "env.log:function"},
+		{`return WebAssembly.Module.exports(new WebAssembly.Module(bytes)).slice(0, 3).map(e => e.name + ":" + e.kind)`, "memory:memory,counter:global,add:function"},
+		{`return String.fromCharCode(...new Uint8Array(WebAssembly.Module.customSections(new WebAssembly.Module(bytes), "note")[0]))`, "kinde"},
+		{`return [WebAssembly.validate(bytes), WebAssembly.validate(new Uint8Array([0, 97, 115, 109]))]`, "true,false"},
+		{`const { instance } = await WebAssembly.instantiate(bytes, imports); return instance.exports.wide(41n) === 42n`, "true"},
+		{`const { instance } = await WebAssembly.instantiate(bytes, imports); return instance.exports.add.length`, "2"},
+
+		// memory and globals
+		{`const { memory } = (await WebAssembly.instantiate(bytes, imports)).instance.exports; return [memory instanceof WebAssembly.Memory, memory.buffer.byteLength, new Uint8Array(memory.buffer, 0, 2)]`, "true,65536,104,105"},
+		{`const { memory } = (await WebAssembly.instantiate(bytes, imports)).instance.exports; const buffer = memory.buffer; return [memory.grow(1), buffer.byteLength, memory.buffer.byteLength]`, "1,0,131072"},
+		{`const { memory, grow } = (await WebAssembly.instantiate(bytes, imports)).instance.exports; return [grow(2), memory.buffer.byteLength, grow(2)]`, "1,196608,-1"},
+		{`const { memory } = (await WebAssembly.instantiate(bytes, imports)).instance.exports; try { memory.grow(4) } catch (e) { return e.name }`, "RangeError"},
+		{`const { counter } = (await WebAssembly.instantiate(bytes, imports)).instance.exports; counter.value = 8; return [counter.value, counter.valueOf()]`, "8,8"},
+
+		// errors
+		{`await WebAssembly.compile(new Uint8Array([0, 97, 115, 109]))`, "rejected: CompileError: invalid version header"},
+		{`await WebAssembly.instantiate(bytes, { env: {} })`, `rejected: LinkError: Import #0 "env" "log": function import requires a callable`},
+		{`await WebAssembly.instantiate(bytes, {})`, `rejected: TypeError: Import #0 "env": module is not an object`},
+		{`const { instance } = await WebAssembly.instantiate(bytes, imports); instance.exports.trap()`, "rejected: RuntimeError: wasm error: unreachable"},
+		{`const { instance } = await WebAssembly.instantiate(bytes, { env: { log: () => { throw new Error("from js") } } }); instance.exports.callLog(1)`, "rejected: Error: from js"},
+		{`try { new WebAssembly.Memory({ initial: 1 }) } catch (e) { return e.code }`, "ERR_ILLEGAL_CONSTRUCTOR"},
+		{`return [new WebAssembly.RuntimeError("x") instanceof Error, WebAssembly.LinkError.name, Object.prototype.toString.call(WebAssembly)]`, "true,LinkError,[object WebAssembly]"},
+	}
+
+	for _, tc := range tests {
+		vm := createVM(t, Options{})
+		assert.Equal(t, tc.expected, runAsync(t, vm, tc.script), tc.script)
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	tests := []struct {
+		script   string
+		expected string
+	}{
+		// the module starts with a page and may grow to 4, the limit lowers its maximum
+		{`const { grow } = (await WebAssembly.instantiate(bytes, imports)).instance.exports; return [grow(1), grow(1)]`, "1,-1"},
+		{`const { memory } = (await WebAssembly.instantiate(bytes, imports)).instance.exports; memory.grow(1); try { memory.grow(1) } catch (e) { return e.code }`, "ERR_MEMORY_LIMIT_EXCEEDED"},
+		// instances share the limit
+		{`await WebAssembly.instantiate(bytes, imports); await WebAssembly.instantiate(bytes, imports); await WebAssembly.instantiate(bytes, imports)`, "rejected: RangeError: WebAssembly memory may not exceed 131072 bytes"},
+		// pages are reserved as memories grow, an instance may not grow into what another one uses
+		{`const first = (await WebAssembly.instantiate(bytes, imports)).instance.exports; await WebAssembly.instantiate(bytes, imports); return first.grow(1)`, "-1"},
+		{`const first = (await WebAssembly.instantiate(bytes, imports)).instance.exports; first.grow(1); await WebAssembly.instantiate(bytes, imports)`, "rejected: RangeError: WebAssembly memory may not exceed 131072 bytes"},
+	}
+
+	for _, tc := range tests {
+		vm := createVM(t, Options{MaxMemoryBytes: 2 * pageSize})
+		assert.Equal(t, tc.expected, runAsync(t, vm, tc.script), tc.script)
+	}
+
+	// limits are rounded up to whole pages rather than down to no limit
+	vm := createVM(t, Options{MaxMemoryBytes: 1000})
+	assert.Equal(t, "1,-1", runAsync(t, vm, `const { grow } = (await WebAssembly.instantiate(bytes, imports)).instance.exports; return [grow(0), grow(1)]`))
+	assert.Equal(t, "rejected: RangeError: WebAssembly memory may not exceed 65536 bytes", runAsync(t, vm, `await WebAssembly.instantiate(bytes, imports)`))
+}
+
+func TestContextAbortsCalls(t *testing.T) {
+	ctx, cancel := context.WithTimeoutCause(context.Background(), 50*time.Millisecond, context.DeadlineExceeded)
+	defer cancel()
+	vm := createVM(t, Options{Context: ctx})
+
+	start := time.Now()
+	out := runAsync(t, vm, `const { instance } = await WebAssembly.instantiate(bytes, imports); instance.exports.spin()`)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Contains(t, out, "rejected: ")
+	assert.Contains(t, out, "deadline exceeded")
+}
+
+func TestRuntimesAreClosed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	vm, m := createModule(t, Options{Context: ctx})
+
+	// instances failing to link or start do not keep their runtime
+	assert.Equal(t, `rejected: LinkError: Import #0 "env" "log": function import requires a callable`, runAsync(t, vm, `await WebAssembly.instantiate(bytes, { env: {} })`))
+	assert.Equal(t, "5", runAsync(t, vm, `const { instance } = await WebAssembly.instantiate(bytes, imports); return instance.exports.add(2, 3)`))
+	assert.Len(t, m.runtimes, 1)
+	assert.Len(t, m.instances, 1)
+
+	cancel()
+	assert.Eventually(t, func() bool { return m.instances[0].IsClosed() }, time.Second, time.Millisecond)
+	assert.Contains(t, runAsync(t, vm, `await WebAssembly.compile(bytes)`), "rejected: ")
+}
+
+func TestLimitMemory(t *testing.T) {
+	binary, _ := hex.DecodeString(testModule)
+	limited, err := parseModule(limitMemory(binary, 2))
+	assert.Nil(t, err)
+	assert.Equal(t, []memoryLimits{{min: 1, max: 2, hasMax: true}}, limited.memories)
+
+	unchanged, _ := parseModule(limitMemory(binary, 8))
+	assert.Equal(t, []memoryLimits{{min: 1, max: 4, hasMax: true}}, unchanged.memories)
+	assert.Equal(t, []customSection{{name: "note", data: []byte("kinde")}}, unchanged.custom)
+}
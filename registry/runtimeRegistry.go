@@ -6,9 +6,22 @@ import (
 	"encoding/base32"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 )
 
+const (
+	// BindingLimitsKey is the binding settings key holding a BindingLimit for that binding
+	BindingLimitsKey = "limits"
+)
+
+const (
+	// ModuleFormatCommonJS sources assign their exports to module.exports, the default
+	ModuleFormatCommonJS ModuleFormat = "commonjs"
+	// ModuleFormatESM sources are ES modules with an export default
+	ModuleFormatESM ModuleFormat = "esm"
+)
+
 const (
 	Source_ContentType_Text   SourceContentType = iota
 	Source_ContentType_Binary                   = iota
@@ -23,6 +36,9 @@ const (
 type (
 	SourceContentType int
 
+	// ModuleFormat tells how a source is evaluated, an empty format is ModuleFormatCommonJS.
+	ModuleFormat string
+
 	LogLevel int
 
 	Logger interface {
@@ -33,42 +49,119 @@ type (
 		EntryPoint string
 		Arguments  []interface{}
 		Loggger    Logger
+		// InitialContext seeds writable context values before the workflow starts
+		InitialContext map[string]interface{}
+		// ReadOnlyContext seeds context values which neither the workflow nor bindings may overwrite
+		ReadOnlyContext map[string]interface{}
+		// BindingOverrides replace binding implementations for this execution only, keyed by binding path, e.g. kinde.fetch.
+		// The accepted values are runtime specific.
+		BindingOverrides map[string]interface{}
 	}
 
 	SourceDescriptor struct {
-		Source     []byte            `json:"source"`
-		SourceType SourceContentType `json:"source_type"`
-		BuildHash  string            `json:"build_hash"`
+		Source       []byte            `json:"source"`
+		SourceType   SourceContentType `json:"source_type"`
+		BuildHash    string            `json:"build_hash"`
+		ModuleFormat ModuleFormat      `json:"module_format,omitempty"`
 	}
 
 	BindingSettings struct {
 		Settings map[string]interface{} `json:"settings"`
 	}
 
+	// BindingLimit restricts how often a native binding may be used within a single execution, zero means unlimited.
+	BindingLimit struct {
+		MaxCalls      int `json:"max_calls,omitempty"`
+		MaxConcurrent int `json:"max_concurrent,omitempty"`
+	}
+
 	RuntimeLimits struct {
 		MaxExecutionDuration time.Duration `json:"max_execution_duration"`
+		// MaxMemoryBytes caps the WebAssembly memory of an execution, of all its instances together, 0 means no limit
+		MaxMemoryBytes int64 `json:"max_memory_bytes,omitempty"`
+		// BindingLimits are keyed by binding path, e.g. kinde.fetch, and apply to all functions below that path
+		BindingLimits map[string]BindingLimit `json:"binding_limits,omitempty"`
 	}
 
 	WorkflowDescriptor struct {
 		ProcessedSource   SourceDescriptor           `json:"processed_source"`
 		RequestedBindings map[string]BindingSettings `json:"bindings"`
 		Limits            RuntimeLimits              `json:"runtime_limits"`
+		// Modules are extra sources the processed source can require, keyed by their path relative to it,
+		// e.g. lib/shared.js for require("./lib/shared")
+		Modules map[string]SourceDescriptor `json:"modules,omitempty"`
+		// Conditions are matched by the conditional "exports" and "imports" of the package.json files among Modules,
+		// in addition to the default conditions of the runtime, e.g. "worker"
+		Conditions []string `json:"conditions,omitempty"`
+		// Signature signs the SigningPayload of the descriptor, runners verifying descriptors refuse it when it is nil
+		Signature *WorkflowSignature `json:"signature,omitempty"`
+	}
+
+	// WorkflowSignature is an Ed25519 signature of a workflow descriptor by the key with KeyID.
+	WorkflowSignature struct {
+		KeyID     string `json:"key_id"`
+		Signature []byte `json:"signature"`
+	}
+
+	// signedWorkflow is what SigningPayload encodes, the parts of a descriptor its signature covers
+	signedWorkflow struct {
+		Version    int                               `json:"version"`
+		Source     SourceDescriptor                  `json:"source"`
+		Modules    map[string]SourceDescriptor       `json:"modules,omitempty"`
+		Conditions []string                          `json:"conditions,omitempty"`
+		Bindings   map[string]map[string]interface{} `json:"bindings,omitempty"`
+		Limits     RuntimeLimits                     `json:"limits"`
+	}
+
+	// ContextDiff describes how the top level context values changed during an execution.
+	ContextDiff struct {
+		Added   map[string]interface{} `json:"added,omitempty"`
+		Changed map[string]interface{} `json:"changed,omitempty"`
+		// Removed lists the keys the execution was started with and which were deleted
+		Removed []string `json:"removed,omitempty"`
+		// WrittenBy maps each added, changed or removed key to the binding path which wrote it last
+		WrittenBy map[string]string `json:"written_by,omitempty"`
 	}
 
 	RuntimeContext interface {
 		GetValues() map[string]interface{}
 		GetValueAsMap(key string) (map[string]interface{}, error)
+		// Snapshot returns a deep copy of the current context values
+		Snapshot() map[string]interface{}
+		// Diff returns the changes made against the values the execution was started with
+		Diff() ContextDiff
+	}
+
+	// LimitBreach records a native call rejected because a binding limit was reached.
+	LimitBreach struct {
+		ModulePath string `json:"module_path"`
+		Binding    string `json:"binding"`
+		Code       string `json:"code"`
+		Limit      int    `json:"limit"`
 	}
 
 	ExecutionMetadata struct {
 		StartedAt          time.Time     `json:"started_at"`
 		ExecutionDuration  time.Duration `json:"execution_duration"`
 		HasRunToCompletion bool          `json:"has_run_to_completion"`
+		LimitBreaches      []LimitBreach `json:"limit_breaches,omitempty"`
+	}
+	// AuditRecord describes a single native call made by a workflow during an execution.
+	AuditRecord struct {
+		ModulePath string        `json:"module_path"`
+		Arguments  []interface{} `json:"arguments,omitempty"`
+		Result     interface{}   `json:"result,omitempty"`
+		Error      string        `json:"error,omitempty"`
+		StartedAt  time.Time     `json:"started_at"`
+		Duration   time.Duration `json:"duration"`
 	}
+
 	ExecutionResult interface {
 		ExecutionMetadata() ExecutionMetadata
 		GetExitResult() interface{}
 		GetContext() RuntimeContext
+		// GetAuditTrail returns the audit records collected by native call interceptors, in call order
+		GetAuditTrail() []AuditRecord
 	}
 
 	IntrospectedExport interface {
@@ -106,6 +199,26 @@ func (settings BindingSettings) MarshalJSON() ([]byte, error) {
 	return json.Marshal(settings.Settings)
 }
 
+// Limits returns the BindingLimit declared under the "limits" key of the binding settings, if any.
+func (settings BindingSettings) Limits() (BindingLimit, bool) {
+	declared, ok := settings.Settings[BindingLimitsKey]
+	if !ok || declared == nil {
+		return BindingLimit{}, false
+	}
+	if limit, ok := declared.(BindingLimit); ok {
+		return limit, true
+	}
+	marshalled, err := json.Marshal(declared)
+	if err != nil {
+		return BindingLimit{}, false
+	}
+	limit := BindingLimit{}
+	if err := json.Unmarshal(marshalled, &limit); err != nil {
+		return BindingLimit{}, false
+	}
+	return limit, true
+}
+
 var runtimes map[string]func() Runner = map[string]func() Runner{}
 
 // not thread safe, should be called as part of init
@@ -125,6 +238,45 @@ func ResolveRuntime(name string) (Runner, error) {
 func (wd *WorkflowDescriptor) GetHash() string {
 	sha := sha256.New()
 	sha.Write([]byte(wd.ProcessedSource.Source))
+	paths := make([]string, 0, len(wd.Modules))
+	for p := range wd.Modules {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		sha.Write([]byte{0})
+		sha.Write([]byte(p))
+		sha.Write([]byte{0})
+		sha.Write(wd.Modules[p].Source)
+	}
 	result := base32.StdEncoding.EncodeToString(sha.Sum(nil))
 	return fmt.Sprintf("%v", result)
 }
+
+// SigningPayload returns the canonical encoding of the source, modules, bindings and limits of the descriptor, which
+// its Signature signs. Object keys are sorted, and bindings without settings encode the same whether their settings
+// are nil or empty, so descriptors encode the same after a JSON round trip.
+func (wd *WorkflowDescriptor) SigningPayload() ([]byte, error) {
+	payload := signedWorkflow{
+		Version:    1,
+		Source:     wd.ProcessedSource,
+		Modules:    wd.Modules,
+		Conditions: wd.Conditions,
+		Limits:     wd.Limits,
+	}
+	if len(wd.RequestedBindings) > 0 {
+		payload.Bindings = make(map[string]map[string]interface{}, len(wd.RequestedBindings))
+		for name, binding := range wd.RequestedBindings {
+			settings := binding.Settings
+			if settings == nil {
+				settings = map[string]interface{}{}
+			}
+			payload.Bindings[name] = settings
+		}
+	}
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding workflow for signing: %w", err)
+	}
+	return append([]byte("kinde-workflow-signature\n"), encoded...), nil
+}
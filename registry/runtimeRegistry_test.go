@@ -20,3 +20,16 @@ func TestBindingMarshalling(t *testing.T) {
 	json.Unmarshal(marshalled, &settings)
 	assert.Equal("value", settings.Settings["key"])
 }
+
+func TestBindingLimitsFromSettings(t *testing.T) {
+	settings := BindingSettings{}
+	assert := assert.New(t)
+	assert.Nil(json.Unmarshal([]byte(`{"limits": {"max_calls": 10, "max_concurrent": 2}}`), &settings))
+
+	limit, ok := settings.Limits()
+	assert.True(ok)
+	assert.Equal(BindingLimit{MaxCalls: 10, MaxConcurrent: 2}, limit)
+
+	_, ok = BindingSettings{}.Limits()
+	assert.False(ok)
+}
@@ -0,0 +1,274 @@
+// Package runnertest is a conformance suite for registry.Runner implementations. Run checks the behaviour of
+// JavaScript workflows, the one of the goja runner, which wrappers and other JavaScript runtimes are expected to
+// match. RunWithFixtures checks what every runner is expected to do, with workflows the runtime under test supplies
+// through Fixtures.
+package runnertest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+// Factory creates the runner under test, every test gets a runner of its own.
+type Factory func() runtimesRegistry.Runner
+
+type (
+	// Fixture is a workflow and the entry point to execute it through.
+	Fixture struct {
+		Workflow   runtimesRegistry.WorkflowDescriptor
+		EntryPoint string
+	}
+
+	// Rejection is a workflow whose execution fails with an error containing Error.
+	Rejection struct {
+		Fixture
+		Error string
+	}
+
+	// Fixtures supply the workflows of the suite, written for the runtime under test.
+	Fixtures interface {
+		// Adds returns the sum of the two numbers it is called with
+		Adds() Fixture
+		// Rejects lists workflows failing in each of the ways the runtime reports errors
+		Rejects() []Rejection
+		// NeverFinishes runs until it is stopped, the suite sets its MaxExecutionDuration
+		NeverFinishes() Fixture
+		// ExportsSettings exports workflowSettings as {"id": "introspected", "bindings": {"kinde.fetch": {}}}
+		ExportsSettings() runtimesRegistry.WorkflowDescriptor
+	}
+
+	javaScript struct{}
+)
+
+// JavaScript are the fixtures of runtimes running CommonJS workflows.
+var JavaScript Fixtures = javaScript{}
+
+// timeout is the MaxExecutionDuration of the workflows which never finish.
+const timeout = 200 * time.Millisecond
+
+// RunWithFixtures runs the conformance suite against the runners factory creates, with the workflows of fixtures,
+// each test as a subtest of t.
+func RunWithFixtures(t *testing.T, factory Factory, fixtures Fixtures) {
+	t.Run("Adds", func(t *testing.T) { testAdds(t, factory, fixtures) })
+	t.Run("Rejection", func(t *testing.T) { testRejection(t, factory, fixtures) })
+	t.Run("Timeout", func(t *testing.T) { testTimeout(t, factory, fixtures) })
+	t.Run("Context", func(t *testing.T) { testContext(t, factory, fixtures) })
+	t.Run("Introspect", func(t *testing.T) { testIntrospect(t, factory, fixtures) })
+}
+
+// Run runs the conformance suite against the runners factory creates with the JavaScript fixtures, and the tests of
+// how JavaScript workflows export their entry points.
+func Run(t *testing.T, factory Factory) {
+	RunWithFixtures(t, factory, JavaScript)
+	t.Run("DefaultFunction", func(t *testing.T) { testDefaultFunction(t, factory) })
+	t.Run("EntryPoint", func(t *testing.T) { testEntryPoint(t, factory) })
+	t.Run("ESModule", func(t *testing.T) { testESModule(t, factory) })
+	t.Run("MissingDefaultExport", func(t *testing.T) { testMissingDefaultExport(t, factory) })
+}
+
+// Workflow describes a CommonJS workflow with source, which has a second to run.
+func Workflow(source string) runtimesRegistry.WorkflowDescriptor {
+	return runtimesRegistry.WorkflowDescriptor{
+		ProcessedSource: runtimesRegistry.SourceDescriptor{
+			Source:     []byte(source),
+			SourceType: runtimesRegistry.Source_ContentType_Text,
+		},
+		Limits: runtimesRegistry.RuntimeLimits{
+			MaxExecutionDuration: time.Second,
+		},
+	}
+}
+
+func (javaScript) Adds() Fixture {
+	return Fixture{Workflow: Workflow(`module.exports = { default: async (a, b) => a + b }`)}
+}
+
+// Rejects rejects promises and throws from async entry points.
+func (javaScript) Rejects() []Rejection {
+	rejection := func(source, expected string) Rejection {
+		return Rejection{Fixture: Fixture{Workflow: Workflow(source)}, Error: expected}
+	}
+	return []Rejection{
+		rejection(`module.exports = { default: async () => { throw new Error("boom") } }`, "Error: boom"),
+		rejection(`module.exports = { default: () => Promise.reject(new TypeError("bad type")) }`, "TypeError: bad type"),
+		rejection(`module.exports = { default: () => Promise.reject("plain reason") }`, "plain reason"),
+	}
+}
+
+func (javaScript) NeverFinishes() Fixture {
+	return Fixture{Workflow: Workflow(`module.exports = { default: async () => { while (true) {} } }`)}
+}
+
+// ExportsSettings throws when executed, introspection must not execute it.
+func (javaScript) ExportsSettings() runtimesRegistry.WorkflowDescriptor {
+	return Workflow(`
+		module.exports = {
+			workflowSettings: { id: "introspected", bindings: { "kinde.fetch": {} } },
+			default: async () => { throw new Error("introspection must not execute the workflow") },
+		}`)
+}
+
+// Adds is called with the arguments of the execution.
+func testAdds(t *testing.T, factory Factory, fixtures Fixtures) {
+	adds := fixtures.Adds()
+	result, err := factory().Execute(context.Background(), adds.Workflow, runtimesRegistry.StartOptions{
+		EntryPoint: adds.EntryPoint,
+		Arguments:  []interface{}{2, 3},
+	})
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, 5, result.GetExitResult())
+		assert.True(t, result.ExecutionMetadata().HasRunToCompletion)
+		assert.False(t, result.ExecutionMetadata().StartedAt.IsZero())
+	}
+}
+
+// A default exported function is called whatever the entry point.
+func testDefaultFunction(t *testing.T, factory Factory) {
+	result, err := factory().Execute(context.Background(), JavaScript.Adds().Workflow, runtimesRegistry.StartOptions{
+		EntryPoint: "handle",
+		Arguments:  []interface{}{2, 3},
+	})
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, 5, result.GetExitResult())
+	}
+}
+
+// A default exported object has its entry point called, an entry point it lacks is an error.
+func testEntryPoint(t *testing.T, factory Factory) {
+	workflow := Workflow(`module.exports = { default: {
+		async handle(event) { return { greeting: "hello " + event.name } },
+		async other() { return "other" },
+	} }`)
+
+	result, err := factory().Execute(context.Background(), workflow, runtimesRegistry.StartOptions{
+		EntryPoint: "handle",
+		Arguments:  []interface{}{map[string]interface{}{"name": "kinde"}},
+	})
+	if assert.Nil(t, err) {
+		assert.Equal(t, map[string]interface{}{"greeting": "hello kinde"}, result.GetExitResult())
+	}
+
+	result, err = factory().Execute(context.Background(), workflow, runtimesRegistry.StartOptions{EntryPoint: "other"})
+	if assert.Nil(t, err) {
+		assert.Equal(t, "other", result.GetExitResult())
+	}
+
+	_, err = factory().Execute(context.Background(), workflow, runtimesRegistry.StartOptions{EntryPoint: "missing"})
+	assert.ErrorContains(t, err, "missing")
+}
+
+// ES modules resolve the entry point from their export default.
+func testESModule(t *testing.T, factory Factory) {
+	workflow := Workflow(`export const workflowSettings = { id: "esm" };
+		export default { async handle(event) { return event.id + ":" + workflowSettings.id } }`)
+	workflow.ProcessedSource.ModuleFormat = runtimesRegistry.ModuleFormatESM
+
+	result, err := factory().Execute(context.Background(), workflow, runtimesRegistry.StartOptions{
+		EntryPoint: "handle",
+		Arguments:  []interface{}{map[string]interface{}{"id": "event"}},
+	})
+	if assert.Nil(t, err) {
+		assert.Equal(t, "event:esm", result.GetExitResult())
+	}
+}
+
+// Workflows without a default export cannot be executed nor introspected.
+func testMissingDefaultExport(t *testing.T, factory Factory) {
+	workflow := Workflow(`module.exports = { handle: async () => "not the default" }`)
+	_, err := factory().Execute(context.Background(), workflow, runtimesRegistry.StartOptions{EntryPoint: "handle"})
+	assert.ErrorContains(t, err, "no default export")
+
+	_, err = factory().Introspect(context.Background(), Workflow(`module.exports = { workflowSettings: {} }`), runtimesRegistry.IntrospectionOptions{})
+	assert.ErrorContains(t, err, "default")
+}
+
+// A failing workflow, e.g. one rejecting a promise, fails the execution with its error.
+func testRejection(t *testing.T, factory Factory, fixtures Fixtures) {
+	for i, rejection := range fixtures.Rejects() {
+		result, err := factory().Execute(context.Background(), rejection.Workflow, runtimesRegistry.StartOptions{EntryPoint: rejection.EntryPoint})
+		assert.ErrorContains(t, err, rejection.Error, "rejection %d", i)
+		if result != nil {
+			assert.False(t, result.ExecutionMetadata().HasRunToCompletion, "rejection %d", i)
+			assert.Nil(t, result.GetExitResult(), "rejection %d", i)
+		}
+	}
+}
+
+// Workflows running for longer than MaxExecutionDuration are stopped with an error.
+func testTimeout(t *testing.T, factory Factory, fixtures Fixtures) {
+	neverFinishes := fixtures.NeverFinishes()
+	neverFinishes.Workflow.Limits.MaxExecutionDuration = timeout
+
+	start := time.Now()
+	result, err := factory().Execute(context.Background(), neverFinishes.Workflow, runtimesRegistry.StartOptions{EntryPoint: neverFinishes.EntryPoint})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 10*timeout)
+	if result != nil {
+		assert.False(t, result.ExecutionMetadata().HasRunToCompletion)
+		assert.False(t, result.ExecutionMetadata().StartedAt.IsZero())
+	}
+}
+
+// The context of the result holds the values the execution was started with, and reports no changes when the
+// workflow made none.
+func testContext(t *testing.T, factory Factory, fixtures Fixtures) {
+	adds := fixtures.Adds()
+	result, err := factory().Execute(context.Background(), adds.Workflow, runtimesRegistry.StartOptions{
+		EntryPoint:      adds.EntryPoint,
+		Arguments:       []interface{}{2, 3},
+		InitialContext:  map[string]interface{}{"request": map[string]interface{}{"ip": "127.0.0.1"}},
+		ReadOnlyContext: map[string]interface{}{"tenant": "acme"},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	runtimeContext := result.GetContext()
+	if !assert.NotNil(t, runtimeContext) {
+		return
+	}
+	assert.Equal(t, "acme", runtimeContext.GetValues()["tenant"])
+	request, err := runtimeContext.GetValueAsMap("request")
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1", request["ip"])
+
+	snapshot := runtimeContext.Snapshot()
+	assert.Equal(t, "acme", snapshot["tenant"])
+	// snapshots are copies
+	snapshot["tenant"] = "changed"
+	assert.Equal(t, "acme", runtimeContext.GetValues()["tenant"])
+
+	diff := runtimeContext.Diff()
+	assert.Empty(t, diff.Added)
+	assert.Empty(t, diff.Changed)
+	assert.Empty(t, diff.Removed)
+}
+
+// Introspection reports the requested exports without running the workflow, missing ones have no export.
+func testIntrospect(t *testing.T, factory Factory, fixtures Fixtures) {
+	result, err := factory().Introspect(context.Background(), fixtures.ExportsSettings(), runtimesRegistry.IntrospectionOptions{
+		Exports: []string{"workflowSettings", "missing"},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	settings := result.GetExport("workflowSettings")
+	if assert.NotNil(t, settings) && assert.True(t, settings.HasExport()) {
+		assert.Equal(t, "introspected", settings.ValueAsMap()["id"])
+		assert.Contains(t, settings.BindingsFrom("workflowSettings"), "kinde.fetch")
+	}
+
+	for _, name := range []string{"missing", "notRequested"} {
+		missing := result.GetExport(name)
+		if assert.NotNil(t, missing, name) {
+			assert.False(t, missing.HasExport(), name)
+			assert.Nil(t, missing.Value(), name)
+			assert.Empty(t, missing.ValueAsMap(), name)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package workflows_runtime
 import (
 	_ "github.com/kinde-oss/workflows-runtime/gojaRuntime"
 	registry "github.com/kinde-oss/workflows-runtime/registry"
+	_ "github.com/kinde-oss/workflows-runtime/wasiRuntime"
 )
 
 func GetRuntime(name string) (registry.Runner, error) {
@@ -7,7 +7,7 @@ import (
 	"testing"
 	"time"
 
-	"github.com/dop251/goja"
+	goja "github.com/grafana/sobek"
 	"github.com/stretchr/testify/assert"
 
 	gojaRuntime "github.com/kinde-oss/workflows-runtime/gojaRuntime"
@@ -268,8 +268,7 @@ func getGojaRunner() registry.Runner {
 		if !ok1 {
 			return nil, fmt.Errorf("first argument must be string")
 		}
-		jsContext.SetValue("idToken", map[string]interface{}{name: args[1]})
-		return nil, nil
+		return nil, jsContext.SetValue("idToken", map[string]interface{}{name: args[1]})
 	})
 
 	kindeAPI.RegisterNativeAPI("accessToken").RegisterNativeFunction("setCustomClaim", func(ctx context.Context, binding registry.BindingSettings, jsContext gojaRuntime.JsContext, args ...interface{}) (interface{}, error) {
@@ -281,13 +280,15 @@ func getGojaRunner() registry.Runner {
 		if !ok1 {
 			return nil, fmt.Errorf("first argument must be string")
 		}
-		at := jsContext.GetValue("accessToken")
-		if at == nil {
+		at, ok := jsContext.CopyValue("accessToken").(map[string]interface{})
+		if !ok {
 			at = make(map[string]interface{})
 		}
-		at.(map[string]interface{})[name] = args[1]
+		at[name] = args[1]
 
-		jsContext.SetValue("accessToken", at)
+		if err := jsContext.SetValue("accessToken", at); err != nil {
+			return nil, err
+		}
 		return nil, nil
 	})
 	return runtime
@@ -0,0 +1,255 @@
+// Package source_envelope defines the binary format of workflow sources with runtimesRegistry.Source_ContentType_Binary.
+//
+// An envelope starts with the magic bytes "\x00kwf", a big endian uint16 version and the big endian uint32 length of
+// a JSON Header. The compressed source follows the header, then the compressed source map, if there is one.
+package source_envelope
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// Version is the envelope version Encode writes and the latest Decode reads
+	Version uint16 = 1
+
+	// MaxLength caps the decompressed length of the source and of the source map of an envelope
+	MaxLength = 64 << 20
+
+	// maxHeaderLength caps the header Decode reads, headers hold metadata only
+	maxHeaderLength = 1 << 20
+)
+
+const (
+	CompressionNone Compression = "none"
+	CompressionGzip Compression = "gzip"
+	CompressionZstd Compression = "zstd"
+)
+
+var (
+	magic = []byte{0x00, 'k', 'w', 'f'}
+
+	// ErrNotAnEnvelope is returned when decoding data which does not start with the envelope magic bytes.
+	ErrNotAnEnvelope = errors.New("source is not a workflow envelope")
+	// ErrUnsupportedVersion is returned when decoding an envelope written by a later version.
+	ErrUnsupportedVersion = errors.New("unsupported workflow envelope version")
+
+	// inlineSourceMap matches the source map comment esbuild appends to bundles built with api.SourceMapInline
+	inlineSourceMap = regexp.MustCompile(`\n?//# sourceMappingURL=data:application/json;base64,([A-Za-z0-9+/=]*)\s*$`)
+)
+
+type (
+	// Compression is how the source and the source map of an envelope are compressed.
+	Compression string
+
+	// Header describes the content of an envelope. The lengths are computed by Encode.
+	Header struct {
+		Compression  Compression                   `json:"compression"`
+		ModuleFormat runtimesRegistry.ModuleFormat `json:"module_format,omitempty"`
+		BuildHash    string                        `json:"build_hash,omitempty"`
+		// Metadata is free form, e.g. the version of the bundler or the entry point the source was built from
+		Metadata map[string]string `json:"metadata,omitempty"`
+
+		// SourceSize and SourceMapSize are the compressed lengths in the envelope
+		SourceSize    int `json:"source_size"`
+		SourceMapSize int `json:"source_map_size,omitempty"`
+		// SourceLength and SourceMapLength are the decompressed lengths
+		SourceLength    int `json:"source_length"`
+		SourceMapLength int `json:"source_map_length,omitempty"`
+	}
+
+	// Envelope is a decoded envelope.
+	Envelope struct {
+		Header    Header
+		Source    []byte
+		SourceMap []byte
+	}
+)
+
+// IsEnvelope tells whether data starts with the envelope magic bytes.
+func IsEnvelope(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// Encode writes envelope, compressed the way its header tells. An empty compression is CompressionGzip.
+func Encode(envelope Envelope) ([]byte, error) {
+	if len(envelope.Source) > MaxLength || len(envelope.SourceMap) > MaxLength {
+		return nil, fmt.Errorf("workflow sources and source maps may not exceed %d bytes", MaxLength)
+	}
+	header := envelope.Header
+	if header.Compression == "" {
+		header.Compression = CompressionGzip
+	}
+
+	source, err := compress(header.Compression, envelope.Source)
+	if err != nil {
+		return nil, err
+	}
+	var sourceMap []byte
+	if len(envelope.SourceMap) > 0 {
+		if sourceMap, err = compress(header.Compression, envelope.SourceMap); err != nil {
+			return nil, err
+		}
+	}
+	header.SourceSize, header.SourceLength = len(source), len(envelope.Source)
+	header.SourceMapSize, header.SourceMapLength = len(sourceMap), len(envelope.SourceMap)
+
+	encodedHeader, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(magic)+6+len(encodedHeader)+len(source)+len(sourceMap)))
+	out.Write(magic)
+	binary.Write(out, binary.BigEndian, Version)
+	binary.Write(out, binary.BigEndian, uint32(len(encodedHeader)))
+	out.Write(encodedHeader)
+	out.Write(source)
+	out.Write(sourceMap)
+	return out.Bytes(), nil
+}
+
+// Decode reads an envelope and decompresses its source and source map, which may not exceed MaxLength.
+func Decode(data []byte) (Envelope, error) {
+	if !IsEnvelope(data) {
+		return Envelope{}, ErrNotAnEnvelope
+	}
+	data = data[len(magic):]
+	if len(data) < 6 {
+		return Envelope{}, fmt.Errorf("workflow envelope is truncated")
+	}
+	version := binary.BigEndian.Uint16(data)
+	if version == 0 || version > Version {
+		return Envelope{}, fmt.Errorf("%w %d", ErrUnsupportedVersion, version)
+	}
+	headerLength := binary.BigEndian.Uint32(data[2:])
+	data = data[6:]
+	if headerLength > maxHeaderLength || int(headerLength) > len(data) {
+		return Envelope{}, fmt.Errorf("workflow envelope header is truncated")
+	}
+
+	envelope := Envelope{}
+	if err := json.Unmarshal(data[:headerLength], &envelope.Header); err != nil {
+		return Envelope{}, fmt.Errorf("error reading workflow envelope header: %w", err)
+	}
+	header := envelope.Header
+	data = data[headerLength:]
+	if header.SourceSize < 0 || header.SourceMapSize < 0 || header.SourceSize+header.SourceMapSize != len(data) {
+		return Envelope{}, fmt.Errorf("workflow envelope has %d bytes of content, its header describes %d", len(data), header.SourceSize+header.SourceMapSize)
+	}
+	if header.SourceLength > MaxLength || header.SourceMapLength > MaxLength {
+		return Envelope{}, fmt.Errorf("workflow envelope header declares more than %d bytes", MaxLength)
+	}
+
+	var err error
+	if envelope.Source, err = decompress(header.Compression, data[:header.SourceSize], header.SourceLength); err != nil {
+		return Envelope{}, fmt.Errorf("error decompressing workflow source: %w", err)
+	}
+	if header.SourceMapSize > 0 {
+		if envelope.SourceMap, err = decompress(header.Compression, data[header.SourceSize:], header.SourceMapLength); err != nil {
+			return Envelope{}, fmt.Errorf("error decompressing workflow source map: %w", err)
+		}
+	}
+	return envelope, nil
+}
+
+// SplitSourceMap separates the inline source map of source, returning the source without it and the decoded map.
+// Sources without an inline source map are returned as they are.
+func SplitSourceMap(source []byte) ([]byte, []byte) {
+	match := inlineSourceMap.FindSubmatchIndex(source)
+	if match == nil {
+		return source, nil
+	}
+	sourceMap, err := base64.StdEncoding.DecodeString(string(source[match[2]:match[3]]))
+	if err != nil {
+		return source, nil
+	}
+	return source[:match[0]], sourceMap
+}
+
+// JoinSourceMap appends sourceMap to source as an inline source map, the way SplitSourceMap found it.
+func JoinSourceMap(source, sourceMap []byte) []byte {
+	if len(sourceMap) == 0 {
+		return source
+	}
+	joined := bytes.NewBuffer(make([]byte, 0, len(source)+base64.StdEncoding.EncodedLen(len(sourceMap))+64))
+	joined.Write(source)
+	joined.WriteString("\n//# sourceMappingURL=data:application/json;base64,")
+	joined.WriteString(base64.StdEncoding.EncodeToString(sourceMap))
+	joined.WriteString("\n")
+	return joined.Bytes()
+}
+
+func compress(compression Compression, data []byte) ([]byte, error) {
+	switch compression {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		out := &bytes.Buffer{}
+		writer, _ := gzip.NewWriterLevel(out, gzip.BestCompression)
+		if _, err := writer.Write(data); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return out.Bytes(), nil
+	case CompressionZstd:
+		encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+		if err != nil {
+			return nil, err
+		}
+		defer encoder.Close()
+		return encoder.EncodeAll(data, nil), nil
+	}
+	return nil, fmt.Errorf("unsupported workflow envelope compression %q", compression)
+}
+
+// decompress decompresses data, which the header says is length bytes long once decompressed. Reading stops past
+// length, so that a crafted envelope cannot inflate into more memory than it declares.
+func decompress(compression Compression, data []byte, length int) ([]byte, error) {
+	var reader io.Reader
+	switch compression {
+	case CompressionNone:
+		reader = bytes.NewReader(data)
+	case CompressionGzip:
+		gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	case CompressionZstd:
+		decoder, err := zstd.NewReader(bytes.NewReader(data), zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		defer decoder.Close()
+		reader = decoder
+	default:
+		return nil, fmt.Errorf("unsupported workflow envelope compression %q", compression)
+	}
+
+	if length < 0 {
+		return nil, fmt.Errorf("invalid length %d", length)
+	}
+	// the declared length is not trusted for allocating
+	buffer := bytes.NewBuffer(make([]byte, 0, min(length, 4*len(data)+512)))
+	if _, err := io.Copy(buffer, io.LimitReader(reader, int64(length)+1)); err != nil {
+		return nil, err
+	}
+	if buffer.Len() != length {
+		return nil, fmt.Errorf("decompressed %d bytes, the header declares %d", buffer.Len(), length)
+	}
+	return buffer.Bytes(), nil
+}
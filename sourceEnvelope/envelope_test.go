@@ -0,0 +1,101 @@
+package source_envelope
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundTrip(t *testing.T) {
+	source := bytes.Repeat([]byte(`module.exports = { default: async () => "hello" };`), 100)
+	sourceMap := []byte(`{"version":3,"sources":["workflow.ts"],"mappings":"AAAA"}`)
+
+	for _, compression := range []Compression{"", CompressionNone, CompressionGzip, CompressionZstd} {
+		encoded, err := Encode(Envelope{
+			Header: Header{
+				Compression:  compression,
+				ModuleFormat: runtimesRegistry.ModuleFormatESM,
+				BuildHash:    "hash",
+				Metadata:     map[string]string{"bundler": "test"},
+			},
+			Source:    source,
+			SourceMap: sourceMap,
+		})
+		assert.Nil(t, err, compression)
+		assert.True(t, IsEnvelope(encoded))
+		if compression != CompressionNone {
+			assert.Less(t, len(encoded), len(source), compression)
+		}
+
+		decoded, err := Decode(encoded)
+		assert.Nil(t, err, compression)
+		assert.Equal(t, source, decoded.Source)
+		assert.Equal(t, sourceMap, decoded.SourceMap)
+		assert.Equal(t, runtimesRegistry.ModuleFormatESM, decoded.Header.ModuleFormat)
+		assert.Equal(t, "hash", decoded.Header.BuildHash)
+		assert.Equal(t, "test", decoded.Header.Metadata["bundler"])
+		assert.Equal(t, len(source), decoded.Header.SourceLength)
+	}
+
+	// envelopes without a source map
+	encoded, _ := Encode(Envelope{Source: source})
+	decoded, err := Decode(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, CompressionGzip, decoded.Header.Compression)
+	assert.Nil(t, decoded.SourceMap)
+
+	_, err = Encode(Envelope{Header: Header{Compression: "brotli"}, Source: source})
+	assert.ErrorContains(t, err, `unsupported workflow envelope compression "brotli"`)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	encoded, _ := Encode(Envelope{Header: Header{Compression: CompressionZstd}, Source: []byte("source")})
+
+	_, err := Decode([]byte("module.exports = {}"))
+	assert.ErrorIs(t, err, ErrNotAnEnvelope)
+
+	laterVersion := append([]byte(nil), encoded...)
+	binary.BigEndian.PutUint16(laterVersion[4:], Version+1)
+	_, err = Decode(laterVersion)
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+
+	_, err = Decode(encoded[:12])
+	assert.ErrorContains(t, err, "workflow envelope header is truncated")
+
+	_, err = Decode(encoded[:len(encoded)-1])
+	assert.ErrorContains(t, err, "bytes of content")
+
+	// the decompressed source must have the declared length
+	lying, _ := Encode(Envelope{Header: Header{Compression: CompressionNone}, Source: []byte("source")})
+	lying = bytes.Replace(lying, []byte(`"source_length":6`), []byte(`"source_length":2`), 1)
+	_, err = Decode(lying)
+	assert.ErrorContains(t, err, "the header declares 2")
+
+	// lengths past MaxLength are refused before decompressing
+	huge, _ := Encode(Envelope{Header: Header{Compression: CompressionNone}, Source: []byte("source")})
+	huge = bytes.Replace(huge, []byte(`"source_length":6`), []byte(`"source_length":4294967296`), 1)
+	binary.BigEndian.PutUint32(huge[6:], binary.BigEndian.Uint32(huge[6:])+9)
+	_, err = Decode(huge)
+	assert.ErrorContains(t, err, "workflow envelope header declares more than 67108864 bytes")
+
+	_, err = Encode(Envelope{Source: make([]byte, MaxLength+1)})
+	assert.ErrorContains(t, err, "may not exceed")
+}
+
+func TestSourceMaps(t *testing.T) {
+	sourceMap := []byte(`{"version":3}`)
+	inlined := JoinSourceMap([]byte("code();"), sourceMap)
+	assert.Equal(t, "code();\n//# sourceMappingURL=data:application/json;base64,eyJ2ZXJzaW9uIjozfQ==\n", string(inlined))
+
+	source, split := SplitSourceMap(inlined)
+	assert.Equal(t, "code();", string(source))
+	assert.Equal(t, sourceMap, split)
+
+	source, split = SplitSourceMap([]byte("code();"))
+	assert.Equal(t, "code();", string(source))
+	assert.Nil(t, split)
+	assert.Equal(t, "code();", string(JoinSourceMap(source, nil)))
+}
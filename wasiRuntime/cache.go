@@ -0,0 +1,104 @@
+package wasi_runtime
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+)
+
+// maxCachedModules is how many modules a runner keeps the compiled code of
+const maxCachedModules = 32
+
+type (
+	// moduleCache keeps the code compiled for the modules a runner ran last. The runtimes of executions share a
+	// compilation cache, the code of a module is dropped from it once the module is evicted and no longer in use.
+	moduleCache struct {
+		compilation wazero.CompilationCache
+		maxModules  int
+		lock        sync.Mutex
+		modules     map[[sha256.Size]byte]*list.Element
+		// recent orders the cached modules from the most to the least recently compiled
+		recent *list.List
+	}
+
+	cachedModule struct {
+		key [sha256.Size]byte
+		// compiled is the module as first compiled, closing it drops the code shared by all the runtimes
+		compiled wazero.CompiledModule
+		// users counts the executions using the module
+		users int
+	}
+)
+
+func newModuleCache(maxModules int) *moduleCache {
+	return &moduleCache{
+		compilation: wazero.NewCompilationCache(),
+		maxModules:  maxModules,
+		modules:     map[[sha256.Size]byte]*list.Element{},
+		recent:      list.New(),
+	}
+}
+
+// compile compiles binary in runtime, which must use the compilation cache, reusing the code compiled by earlier
+// executions. release must be called once the execution is done with the module, it may be evicted then.
+func (cache *moduleCache) compile(ctx context.Context, runtime wazero.Runtime, binary []byte) (compiled wazero.CompiledModule, release func(), err error) {
+	key := sha256.Sum256(binary)
+	cache.lock.Lock()
+	element, ok := cache.modules[key]
+	if ok {
+		cache.recent.MoveToFront(element)
+	} else {
+		element = cache.recent.PushFront(&cachedModule{key: key})
+		cache.modules[key] = element
+	}
+	module := element.Value.(*cachedModule)
+	module.users++
+	cache.lock.Unlock()
+
+	release = func() {
+		cache.lock.Lock()
+		module.users--
+		evicted := cache.evict()
+		cache.lock.Unlock()
+		for _, compiled := range evicted {
+			compiled.Close(context.Background())
+		}
+	}
+
+	// compiling is not locked, wazero only compiles modules missing from the compilation cache
+	compiled, err = runtime.CompileModule(ctx, binary)
+	cache.lock.Lock()
+	if err == nil && module.compiled == nil {
+		module.compiled = compiled
+	}
+	cache.lock.Unlock()
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return compiled, release, nil
+}
+
+// evict removes the modules which failed to compile, then the least recently compiled modules not in use beyond
+// maxModules, and returns the compiled modules to close.
+func (cache *moduleCache) evict() []wazero.CompiledModule {
+	var evicted []wazero.CompiledModule
+	for _, failed := range []bool{true, false} {
+		for element := cache.recent.Back(); element != nil; {
+			previous := element.Prev()
+			module := element.Value.(*cachedModule)
+			if module.users == 0 && (failed && module.compiled == nil || !failed && cache.recent.Len() > cache.maxModules) {
+				cache.recent.Remove(element)
+				delete(cache.modules, module.key)
+				if module.compiled != nil {
+					evicted = append(evicted, module.compiled)
+				}
+			}
+			element = previous
+		}
+	}
+	return evicted
+}
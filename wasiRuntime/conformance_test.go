@@ -0,0 +1,43 @@
+package wasi_runtime
+
+import (
+	"encoding/binary"
+	"testing"
+
+	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
+	"github.com/kinde-oss/workflows-runtime/runnertest"
+)
+
+// wasiFixtures are the conformance workflows made of the exports of testModule.
+type wasiFixtures struct{}
+
+func (wasiFixtures) Adds() runnertest.Fixture {
+	return runnertest.Fixture{Workflow: workflow(runtimesRegistry.RuntimeLimits{}), EntryPoint: "add"}
+}
+
+func (wasiFixtures) Rejects() []runnertest.Rejection {
+	return []runnertest.Rejection{
+		{Fixture: runnertest.Fixture{Workflow: workflow(runtimesRegistry.RuntimeLimits{}), EntryPoint: "quit"}, Error: "workflow exited with code 3"},
+		{Fixture: runnertest.Fixture{Workflow: workflow(runtimesRegistry.RuntimeLimits{}), EntryPoint: "missing"}, Error: "could not find exported function missing"},
+	}
+}
+
+func (wasiFixtures) NeverFinishes() runnertest.Fixture {
+	return runnertest.Fixture{Workflow: workflow(runtimesRegistry.RuntimeLimits{}), EntryPoint: "spin"}
+}
+
+// ExportsSettings appends a workflowSettings custom section, which replaces the one of testModule.
+func (wasiFixtures) ExportsSettings() runtimesRegistry.WorkflowDescriptor {
+	settings := workflow(runtimesRegistry.RuntimeLimits{})
+	name := "workflowSettings"
+	data := `{"id":"introspected","bindings":{"kinde.fetch":{}}}`
+	contents := binary.AppendUvarint(nil, uint64(len(name)))
+	contents = append(append(contents, name...), data...)
+	section := binary.AppendUvarint([]byte{0}, uint64(len(contents)))
+	settings.ProcessedSource.Source = append(append(settings.ProcessedSource.Source, section...), contents...)
+	return settings
+}
+
+func TestRunnerConformance(t *testing.T) {
+	runnertest.RunWithFixtures(t, newWasiRunner, wasiFixtures{})
+}
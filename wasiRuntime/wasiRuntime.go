@@ -0,0 +1,565 @@
+package wasi_runtime
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/webassembly"
+	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
+	sourceEnvelope "github.com/kinde-oss/workflows-runtime/sourceEnvelope"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+const (
+	// pageSize is the size of a page of WebAssembly memory
+	pageSize = 65536
+	// maxPages is the most pages a memory can have
+	maxPages = 65536
+	// defaultMaxExecutionDuration bounds the executions of workflows which set no MaxExecutionDuration
+	defaultMaxExecutionDuration = 30 * time.Second
+	// maxOutputBytes bounds the result a command writes to stdout and each line a module logs
+	maxOutputBytes = 1 << 20
+)
+
+var (
+	ErrExecutionTimeExceeded = errors.New("execution time exceeded")
+	// ErrOutputLimitExceeded is returned when a command writes a result, or a module logs a line, longer than
+	// maxOutputBytes.
+	ErrOutputLimitExceeded = fmt.Errorf("output may not exceed %d bytes", maxOutputBytes)
+	// ErrBindingsUnsupported is returned when a workflow requests bindings or an execution overrides them, WASI
+	// modules make no native calls.
+	ErrBindingsUnsupported = errors.New("the wasi runtime does not support bindings")
+)
+
+type (
+	// WasiRunnerV1 runs WASI modules. A module is either a command, run through _start, or a reactor, initialised
+	// through _initialize when it exports it and then called through the function named by StartOptions.EntryPoint.
+	//
+	// Both read {"arguments": [...], "context": {...}} as JSON from stdin. A command writes its result to stdout,
+	// as JSON or as plain text, a function returns it. Other output is logged line by line, stdout at LogLevelInfo
+	// and stderr at LogLevelError. Results and lines are bounded by maxOutputBytes, executions by
+	// MaxExecutionDuration or defaultMaxExecutionDuration. Modules make no native calls, bindings are rejected.
+	//
+	// A runner keeps the code compiled for the modules it ran last, see maxCachedModules.
+	WasiRunnerV1 struct {
+		cacheOnce sync.Once
+		cache     *moduleCache
+	}
+
+	executionResult struct {
+		Context     *wasiContext                        `json:"context"`
+		ExitResult  interface{}                         `json:"exit_result"`
+		RunMetadata *runtimesRegistry.ExecutionMetadata `json:"run_metadata"`
+	}
+
+	// wasiContext holds the context an execution was started with, modules read it from stdin but cannot change it.
+	wasiContext struct {
+		values map[string]interface{}
+	}
+
+	introspectedExport struct {
+		value    interface{}
+		bindings map[string]runtimesRegistry.BindingSettings
+	}
+
+	introspectionResult struct {
+		exports map[string]introspectedExport
+	}
+
+	// lineLogger logs what is written to it line by line, lines longer than maxOutputBytes fail to be written.
+	lineLogger struct {
+		logger   runtimesRegistry.Logger
+		level    runtimesRegistry.LogLevel
+		lock     sync.Mutex
+		pending  []byte
+		exceeded bool
+	}
+
+	// outputBuffer holds what a command writes to stdout, up to maxOutputBytes.
+	outputBuffer struct {
+		bytes.Buffer
+		exceeded bool
+	}
+
+	// limitedOutput is where a module writes, it reports whether the module wrote more than it may.
+	limitedOutput interface {
+		limitExceeded() bool
+	}
+)
+
+func init() {
+	runtimesRegistry.RegisterRuntime("wasi", newWasiRunner)
+}
+
+func newWasiRunner() runtimesRegistry.Runner {
+	return &WasiRunnerV1{}
+}
+
+// ExecutionMetadata implements runtime_registry.ExecutionResult.
+func (r *executionResult) ExecutionMetadata() runtimesRegistry.ExecutionMetadata {
+	return *r.RunMetadata
+}
+
+// GetExitResult implements runtime_registry.ExecutionResult.
+func (r *executionResult) GetExitResult() interface{} {
+	return r.ExitResult
+}
+
+// GetContext implements runtime_registry.ExecutionResult.
+func (r *executionResult) GetContext() runtimesRegistry.RuntimeContext {
+	return r.Context
+}
+
+// GetAuditTrail implements runtime_registry.ExecutionResult, WASI modules make no native calls.
+func (r *executionResult) GetAuditTrail() []runtimesRegistry.AuditRecord {
+	return nil
+}
+
+func newWasiContext(initial map[string]interface{}, readOnly map[string]interface{}) *wasiContext {
+	values := map[string]interface{}{}
+	for key, value := range initial {
+		values[key] = value
+	}
+	for key, value := range readOnly {
+		values[key] = value
+	}
+	return &wasiContext{values: values}
+}
+
+// GetValues implements runtime_registry.RuntimeContext.
+func (c *wasiContext) GetValues() map[string]interface{} {
+	return c.values
+}
+
+// GetValueAsMap implements runtime_registry.RuntimeContext.
+func (c *wasiContext) GetValueAsMap(key string) (map[string]interface{}, error) {
+	value, ok := c.values[key].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("value for key %v is not a map", key)
+	}
+	return value, nil
+}
+
+// Snapshot implements runtime_registry.RuntimeContext.
+func (c *wasiContext) Snapshot() map[string]interface{} {
+	var snapshot map[string]interface{}
+	marshalled, _ := json.Marshal(c.values)
+	json.Unmarshal(marshalled, &snapshot)
+	if snapshot == nil {
+		snapshot = map[string]interface{}{}
+	}
+	return snapshot
+}
+
+// Diff implements runtime_registry.RuntimeContext, the context never changes.
+func (c *wasiContext) Diff() runtimesRegistry.ContextDiff {
+	return runtimesRegistry.ContextDiff{}
+}
+
+func (c *wasiContext) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.values)
+}
+
+// BindingsFrom implements runtime_registry.IntrospectedExport.
+func (i introspectedExport) BindingsFrom(exportName string) map[string]runtimesRegistry.BindingSettings {
+	return i.bindings
+}
+
+// HasExport implements runtime_registry.IntrospectedExport.
+func (i introspectedExport) HasExport() bool {
+	return i.value != nil
+}
+
+// Value implements runtime_registry.IntrospectedExport.
+func (i introspectedExport) Value() interface{} {
+	return i.value
+}
+
+// ValueAsMap implements runtime_registry.IntrospectedExport.
+func (i introspectedExport) ValueAsMap() map[string]interface{} {
+	value, ok := i.value.(map[string]interface{})
+	if !ok {
+		return map[string]interface{}{}
+	}
+	return value
+}
+
+// GetExport implements runtime_registry.IntrospectionResult.
+func (i introspectionResult) GetExport(name string) runtimesRegistry.IntrospectedExport {
+	return i.exports[name]
+}
+
+func (l *lineLogger) Write(p []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.pending = append(l.pending, p...)
+	for {
+		i := bytes.IndexByte(l.pending, '\n')
+		if i < 0 {
+			break
+		}
+		l.log(string(l.pending[:i]))
+		l.pending = l.pending[i+1:]
+	}
+	if len(l.pending) > maxOutputBytes {
+		l.exceeded = true
+		l.pending = nil
+		return 0, ErrOutputLimitExceeded
+	}
+	return len(p), nil
+}
+
+func (l *lineLogger) limitExceeded() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.exceeded
+}
+
+// flush logs the last line, when it does not end with a new line.
+func (l *lineLogger) flush() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if len(l.pending) > 0 {
+		l.log(string(l.pending))
+		l.pending = nil
+	}
+}
+
+func (l *lineLogger) log(line string) {
+	if l.logger != nil {
+		l.logger.Log(l.level, strings.TrimSuffix(line, "\r"))
+	}
+}
+
+func (o *outputBuffer) Write(p []byte) (int, error) {
+	if o.Len()+len(p) > maxOutputBytes {
+		o.exceeded = true
+		return 0, ErrOutputLimitExceeded
+	}
+	return o.Buffer.Write(p)
+}
+
+func (o *outputBuffer) limitExceeded() bool {
+	return o.exceeded
+}
+
+// outputError returns ErrOutputLimitExceeded when the module wrote more than it may to any of outputs. Modules may
+// ignore the failed writes, the execution fails regardless.
+func outputError(outputs ...limitedOutput) error {
+	for _, output := range outputs {
+		if output.limitExceeded() {
+			return ErrOutputLimitExceeded
+		}
+	}
+	return nil
+}
+
+// moduleBinary returns the WebAssembly module of workflow, which is either the source itself or compressed in an
+// envelope.
+func moduleBinary(workflow runtimesRegistry.WorkflowDescriptor) ([]byte, error) {
+	if workflow.ProcessedSource.SourceType != runtimesRegistry.Source_ContentType_Binary {
+		return nil, fmt.Errorf("the wasi runtime runs binary sources, the source of the workflow is text")
+	}
+	if !sourceEnvelope.IsEnvelope(workflow.ProcessedSource.Source) {
+		return workflow.ProcessedSource.Source, nil
+	}
+	envelope, err := sourceEnvelope.Decode(workflow.ProcessedSource.Source)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding source: %w", err)
+	}
+	return envelope.Source, nil
+}
+
+// modules returns the cache of the runner, runners declared as a WasiRunnerV1{} create it on first use.
+func (e *WasiRunnerV1) modules() *moduleCache {
+	e.cacheOnce.Do(func() {
+		if e.cache == nil {
+			e.cache = newModuleCache(maxCachedModules)
+		}
+	})
+	return e.cache
+}
+
+// newRuntime creates the wazero runtime of an execution, whose modules are closed once ctx is done.
+func (e *WasiRunnerV1) newRuntime(ctx context.Context, limits runtimesRegistry.RuntimeLimits) wazero.Runtime {
+	memoryLimitPages := uint32(maxPages)
+	if limits.MaxMemoryBytes > 0 {
+		// a limit below a page still allows a page, as the limit of the WebAssembly global of the goja runtime
+		pages := limits.MaxMemoryBytes / pageSize
+		if limits.MaxMemoryBytes%pageSize != 0 {
+			pages++
+		}
+		memoryLimitPages = uint32(min(pages, maxPages))
+	}
+	config := webassembly.NewRuntimeConfig().
+		WithMemoryLimitPages(memoryLimitPages).
+		WithCompilationCache(e.modules().compilation)
+	return wazero.NewRuntimeWithConfig(ctx, config)
+}
+
+func (e *WasiRunnerV1) Execute(ctx context.Context, workflow runtimesRegistry.WorkflowDescriptor, startOptions runtimesRegistry.StartOptions) (runtimesRegistry.ExecutionResult, error) {
+	result := &executionResult{
+		Context: newWasiContext(startOptions.InitialContext, startOptions.ReadOnlyContext),
+		RunMetadata: &runtimesRegistry.ExecutionMetadata{
+			StartedAt: time.Now(),
+		},
+	}
+	if len(workflow.RequestedBindings) > 0 || len(startOptions.BindingOverrides) > 0 {
+		return result, ErrBindingsUnsupported
+	}
+	binary, err := moduleBinary(workflow)
+	if err != nil {
+		return result, err
+	}
+	defer func() {
+		result.RunMetadata.ExecutionDuration = time.Since(result.RunMetadata.StartedAt)
+	}()
+
+	maxExecutionDuration := workflow.Limits.MaxExecutionDuration
+	if maxExecutionDuration <= 0 {
+		maxExecutionDuration = defaultMaxExecutionDuration
+	}
+	ctx, cancel := context.WithTimeoutCause(ctx, maxExecutionDuration, ErrExecutionTimeExceeded)
+	defer cancel()
+
+	runtime := e.newRuntime(ctx, workflow.Limits)
+	defer runtime.Close(context.Background())
+	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
+
+	compiled, release, err := e.modules().compile(ctx, runtime, binary)
+	if err != nil {
+		return result, fmt.Errorf("error compiling %w", err)
+	}
+	defer release()
+
+	input, err := json.Marshal(map[string]interface{}{
+		"arguments": startOptions.Arguments,
+		"context":   result.Context.values,
+	})
+	if err != nil {
+		return result, err
+	}
+	stdout := &outputBuffer{}
+	stdoutLogger := &lineLogger{logger: startOptions.Loggger, level: runtimesRegistry.LogLevelInfo}
+	stderrLogger := &lineLogger{logger: startOptions.Loggger, level: runtimesRegistry.LogLevelError}
+	defer stdoutLogger.flush()
+	defer stderrLogger.flush()
+
+	command := startOptions.EntryPoint == ""
+	config := wazero.NewModuleConfig().
+		WithStdin(bytes.NewReader(input)).
+		WithStderr(stderrLogger).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithStartFunctions()
+	// commands run while they are instantiated, reactors are initialised then
+	if command {
+		config = config.WithStdout(stdout).WithArgs("workflow").WithStartFunctions("_start")
+	} else {
+		config = config.WithStdout(stdoutLogger)
+		if _, ok := compiled.ExportedFunctions()["_initialize"]; ok {
+			config = config.WithStartFunctions("_initialize")
+		}
+	}
+	module, err := runtime.InstantiateModule(ctx, compiled, config)
+	if err := outputError(stdout, stdoutLogger, stderrLogger); err != nil {
+		return result, err
+	}
+	if err := executionError(ctx, err); err != nil {
+		return result, err
+	}
+
+	if command {
+		result.ExitResult = commandResult(stdout.Bytes())
+	} else {
+		result.ExitResult, err = callEntryPoint(ctx, module, startOptions.EntryPoint, startOptions.Arguments)
+		if err := outputError(stdoutLogger, stderrLogger); err != nil {
+			return result, err
+		}
+		if err != nil {
+			return result, err
+		}
+	}
+	result.RunMetadata.HasRunToCompletion = true
+	return result, nil
+}
+
+// executionError describes why running a module failed, nil when it did not or when it exited with code 0.
+func executionError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	var exit *sys.ExitError
+	if errors.As(err, &exit) {
+		if exit.ExitCode() == 0 {
+			return nil
+		}
+		return fmt.Errorf("workflow exited with code %d", exit.ExitCode())
+	}
+	return err
+}
+
+// commandResult decodes what a command wrote to stdout, as JSON when it is.
+func commandResult(stdout []byte) interface{} {
+	var result interface{}
+	if err := json.Unmarshal(stdout, &result); err == nil {
+		return result
+	}
+	return strings.TrimSuffix(string(stdout), "\n")
+}
+
+// callEntryPoint calls the function exported by module as name. Arguments and results are numbers, functions
+// returning several values return them as a slice.
+func callEntryPoint(ctx context.Context, module api.Module, name string, arguments []interface{}) (interface{}, error) {
+	fn := module.ExportedFunction(name)
+	if fn == nil {
+		return nil, fmt.Errorf("could not find exported function %v", name)
+	}
+	definition := fn.Definition()
+	params, resultTypes := definition.ParamTypes(), definition.ResultTypes()
+	if len(arguments) != len(params) {
+		return nil, fmt.Errorf("function %v takes %d arguments, %d were given", name, len(params), len(arguments))
+	}
+	stack := make([]uint64, len(params))
+	for i, t := range params {
+		value, err := encodeValue(arguments[i], t)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d of %v: %w", i, name, err)
+		}
+		stack[i] = value
+	}
+
+	results, err := fn.Call(ctx, stack...)
+	if err := executionError(ctx, err); err != nil {
+		return nil, err
+	}
+	if len(resultTypes) == 0 || results == nil {
+		return nil, nil
+	}
+	if len(resultTypes) == 1 {
+		return decodeValue(results[0], resultTypes[0]), nil
+	}
+	values := make([]interface{}, len(resultTypes))
+	for i, t := range resultTypes {
+		values[i] = decodeValue(results[i], t)
+	}
+	return values, nil
+}
+
+func encodeValue(value interface{}, t api.ValueType) (uint64, error) {
+	var number float64
+	switch v := value.(type) {
+	case int:
+		number = float64(v)
+	case int32:
+		number = float64(v)
+	case int64:
+		if t == api.ValueTypeI64 {
+			return uint64(v), nil
+		}
+		number = float64(v)
+	case float32:
+		number = float64(v)
+	case float64:
+		number = v
+	case json.Number:
+		if i, err := v.Int64(); err == nil && t == api.ValueTypeI64 {
+			return uint64(i), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return 0, err
+		}
+		number = f
+	default:
+		return 0, fmt.Errorf("%T is not a number", value)
+	}
+	switch t {
+	case api.ValueTypeI32:
+		return api.EncodeI32(int32(number)), nil
+	case api.ValueTypeI64:
+		return api.EncodeI64(int64(number)), nil
+	case api.ValueTypeF32:
+		return api.EncodeF32(float32(number)), nil
+	case api.ValueTypeF64:
+		return api.EncodeF64(number), nil
+	}
+	return 0, fmt.Errorf("%v values cannot be passed", api.ValueTypeName(t))
+}
+
+func decodeValue(value uint64, t api.ValueType) interface{} {
+	switch t {
+	case api.ValueTypeI32:
+		return api.DecodeI32(value)
+	case api.ValueTypeI64:
+		return int64(value)
+	case api.ValueTypeF32:
+		return api.DecodeF32(value)
+	case api.ValueTypeF64:
+		return api.DecodeF64(value)
+	}
+	return value
+}
+
+// Introspect reads exports from the custom sections of the module: an export is the JSON held by the custom
+// section of the same name, e.g. workflowSettings. The module is compiled but not run.
+func (e *WasiRunnerV1) Introspect(ctx context.Context, workflow runtimesRegistry.WorkflowDescriptor, options runtimesRegistry.IntrospectionOptions) (runtimesRegistry.IntrospectionResult, error) {
+	binary, err := moduleBinary(workflow)
+	if err != nil {
+		return nil, err
+	}
+	runtime := e.newRuntime(ctx, workflow.Limits)
+	defer runtime.Close(context.Background())
+	compiled, release, err := e.modules().compile(ctx, runtime, binary)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling %w", err)
+	}
+	defer release()
+
+	sections := map[string][]byte{}
+	for _, section := range compiled.CustomSections() {
+		sections[section.Name()] = section.Data()
+	}
+	result := introspectionResult{
+		exports: map[string]introspectedExport{},
+	}
+	for _, name := range options.Exports {
+		var value interface{}
+		if data, ok := sections[name]; ok {
+			if err := json.Unmarshal(data, &value); err != nil {
+				return nil, fmt.Errorf("custom section %v is not JSON: %w", name, err)
+			}
+		}
+		result.exports[name] = introspectedExport{
+			value:    value,
+			bindings: bindingsFrom(value),
+		}
+	}
+	return result, nil
+}
+
+// bindingsFrom reads the bindings key of an export, as the goja runtime does for workflowSettings.
+func bindingsFrom(value interface{}) map[string]runtimesRegistry.BindingSettings {
+	settings, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	marshalled, _ := json.Marshal(settings["bindings"])
+	bindings := map[string]runtimesRegistry.BindingSettings{}
+	if err := json.Unmarshal(marshalled, &bindings); err != nil {
+		return nil
+	}
+	return bindings
+}
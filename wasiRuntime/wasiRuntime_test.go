@@ -0,0 +1,221 @@
+package wasi_runtime
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kinde-oss/workflows-runtime/gojaRuntime/webassembly"
+	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
+	sourceEnvelope "github.com/kinde-oss/workflows-runtime/sourceEnvelope"
+	"github.com/stretchr/testify/assert"
+	"github.com/tetratelabs/wazero"
+)
+
+// testModule is a WASI command whose _start echoes stdin to stdout and writes "hello" to stderr. It exports
+// add (i32, i32) -> i32, spin, which loops forever, and quit, which exits with code 3. Its workflowSettings
+// custom section holds {"id":"wasiTest","bindings":{"kinde.fetch":{}}}.
+const testModule = "0061736d0100000001160460047f7f7f7f017f60017f0060000060027f7f017f02670316776173695f736e617073686f745f70726576696577310766645f72656164000016776173695f736e617073686f745f70726576696577310866645f7772697465000016776173695f736e617073686f745f70726576696577310970726f635f657869740001030504020302020503010001072705066d656d6f72790200065f73746172740003036164640004047370696e0005047175697400060a46042d00410041004101410810001a41004108280200360204410141004101410c10011a410241104101410c10011a0b0700200020016a0b070003400c000b0b0600410310020b0b26030041000b0840000000a00f00000041100b0820000000060000000041200b0668656c6c6f0a004010776f726b666c6f7753657474696e67737b226964223a227761736954657374222c2262696e64696e6773223a7b226b696e64652e6665746368223a7b7d7d7d"
+
+type testLogger struct {
+	lines []string
+}
+
+func (l *testLogger) Log(level runtimesRegistry.LogLevel, params ...interface{}) {
+	if level == runtimesRegistry.LogLevelError {
+		l.lines = append(l.lines, "error: "+params[0].(string))
+	} else {
+		l.lines = append(l.lines, params[0].(string))
+	}
+}
+
+func workflow(limits runtimesRegistry.RuntimeLimits) runtimesRegistry.WorkflowDescriptor {
+	source, _ := hex.DecodeString(testModule)
+	if limits.MaxExecutionDuration == 0 {
+		limits.MaxExecutionDuration = 5 * time.Second
+	}
+	return runtimesRegistry.WorkflowDescriptor{
+		ProcessedSource: runtimesRegistry.SourceDescriptor{
+			Source:     source,
+			SourceType: runtimesRegistry.Source_ContentType_Binary,
+		},
+		Limits: limits,
+	}
+}
+
+func TestCommand(t *testing.T) {
+	runner, err := runtimesRegistry.ResolveRuntime("wasi")
+	assert.Nil(t, err)
+
+	logger := &testLogger{}
+	result, err := runner.Execute(context.Background(), workflow(runtimesRegistry.RuntimeLimits{}), runtimesRegistry.StartOptions{
+		Arguments:       []interface{}{"kinde", 1},
+		ReadOnlyContext: map[string]interface{}{"tenant": "acme"},
+		Loggger:         logger,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"arguments": []interface{}{"kinde", float64(1)},
+		"context":   map[string]interface{}{"tenant": "acme"},
+	}, result.GetExitResult())
+	assert.Equal(t, []string{"error: hello"}, logger.lines)
+	assert.Equal(t, "acme", result.GetContext().GetValues()["tenant"])
+	assert.True(t, result.ExecutionMetadata().HasRunToCompletion)
+}
+
+func TestEntryPoint(t *testing.T) {
+	runner := newWasiRunner()
+	result, err := runner.Execute(context.Background(), workflow(runtimesRegistry.RuntimeLimits{}), runtimesRegistry.StartOptions{
+		EntryPoint: "add",
+		Arguments:  []interface{}{2, 3.0},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(5), result.GetExitResult())
+
+	_, err = runner.Execute(context.Background(), workflow(runtimesRegistry.RuntimeLimits{}), runtimesRegistry.StartOptions{EntryPoint: "add", Arguments: []interface{}{2}})
+	assert.ErrorContains(t, err, "function add takes 2 arguments, 1 were given")
+
+	_, err = runner.Execute(context.Background(), workflow(runtimesRegistry.RuntimeLimits{}), runtimesRegistry.StartOptions{EntryPoint: "missing"})
+	assert.ErrorContains(t, err, "could not find exported function missing")
+
+	_, err = runner.Execute(context.Background(), workflow(runtimesRegistry.RuntimeLimits{}), runtimesRegistry.StartOptions{EntryPoint: "quit"})
+	assert.ErrorContains(t, err, "workflow exited with code 3")
+}
+
+func TestLimits(t *testing.T) {
+	runner := newWasiRunner()
+	start := time.Now()
+	_, err := runner.Execute(context.Background(), workflow(runtimesRegistry.RuntimeLimits{MaxExecutionDuration: 50 * time.Millisecond}), runtimesRegistry.StartOptions{EntryPoint: "spin"})
+	assert.ErrorIs(t, err, ErrExecutionTimeExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// the module needs a page of memory, limits are rounded up to whole pages
+	_, err = runner.Execute(context.Background(), workflow(runtimesRegistry.RuntimeLimits{MaxMemoryBytes: 1024}), runtimesRegistry.StartOptions{})
+	assert.Nil(t, err)
+
+	// a module starting with two pages
+	large := workflow(runtimesRegistry.RuntimeLimits{MaxMemoryBytes: pageSize + 1024})
+	large.ProcessedSource.Source = bytes.Replace(large.ProcessedSource.Source, []byte{5, 3, 1, 0, 1}, []byte{5, 3, 1, 0, 2}, 1)
+	_, err = runner.Execute(context.Background(), large, runtimesRegistry.StartOptions{})
+	assert.Nil(t, err)
+	large.Limits.MaxMemoryBytes = pageSize
+	_, err = runner.Execute(context.Background(), large, runtimesRegistry.StartOptions{})
+	assert.ErrorContains(t, err, "over limit")
+}
+
+func TestOutputLimit(t *testing.T) {
+	stdout := &outputBuffer{}
+	_, err := stdout.Write([]byte(strings.Repeat("a", maxOutputBytes)))
+	assert.Nil(t, err)
+	_, err = stdout.Write([]byte("a"))
+	assert.ErrorIs(t, err, ErrOutputLimitExceeded)
+	assert.Equal(t, maxOutputBytes, stdout.Len())
+
+	// lines are bounded rather than what is logged
+	logger := &testLogger{}
+	lines := &lineLogger{logger: logger, level: runtimesRegistry.LogLevelInfo}
+	_, err = lines.Write([]byte(strings.Repeat("a", maxOutputBytes) + "\n" + strings.Repeat("b", maxOutputBytes)))
+	assert.Nil(t, err)
+	assert.False(t, lines.limitExceeded())
+	_, err = lines.Write([]byte("b"))
+	assert.ErrorIs(t, err, ErrOutputLimitExceeded)
+	assert.Len(t, logger.lines, 1)
+	assert.Equal(t, ErrOutputLimitExceeded, outputError(lines))
+}
+
+func TestBindingsRejected(t *testing.T) {
+	runner := newWasiRunner()
+	requested := workflow(runtimesRegistry.RuntimeLimits{})
+	requested.RequestedBindings = map[string]runtimesRegistry.BindingSettings{"kinde.fetch": {}}
+	_, err := runner.Execute(context.Background(), requested, runtimesRegistry.StartOptions{EntryPoint: "add", Arguments: []interface{}{1, 1}})
+	assert.ErrorIs(t, err, ErrBindingsUnsupported)
+
+	_, err = runner.Execute(context.Background(), workflow(runtimesRegistry.RuntimeLimits{}), runtimesRegistry.StartOptions{
+		EntryPoint:       "add",
+		Arguments:        []interface{}{1, 1},
+		BindingOverrides: map[string]interface{}{"kinde.fetch": nil},
+	})
+	assert.ErrorIs(t, err, ErrBindingsUnsupported)
+}
+
+func TestIntrospect(t *testing.T) {
+	runner := newWasiRunner()
+	result, err := runner.Introspect(context.Background(), workflow(runtimesRegistry.RuntimeLimits{}), runtimesRegistry.IntrospectionOptions{
+		Exports: []string{"workflowSettings", "missing"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "wasiTest", result.GetExport("workflowSettings").ValueAsMap()["id"])
+	assert.Contains(t, result.GetExport("workflowSettings").BindingsFrom("workflowSettings"), "kinde.fetch")
+	assert.False(t, result.GetExport("missing").HasExport())
+
+	// modules exporting neither _start nor _initialize run through an entry point
+	library := workflow(runtimesRegistry.RuntimeLimits{})
+	library.ProcessedSource.Source = bytes.Replace(library.ProcessedSource.Source, []byte("_start"), []byte("_begin"), 1)
+	result, err = runner.Introspect(context.Background(), library, runtimesRegistry.IntrospectionOptions{Exports: []string{"workflowSettings"}})
+	assert.Nil(t, err)
+	assert.True(t, result.GetExport("workflowSettings").HasExport())
+	executed, err := runner.Execute(context.Background(), library, runtimesRegistry.StartOptions{EntryPoint: "add", Arguments: []interface{}{2, 3}})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(5), executed.GetExitResult())
+
+	invalid := workflow(runtimesRegistry.RuntimeLimits{})
+	invalid.ProcessedSource.Source = invalid.ProcessedSource.Source[:7]
+	result, err = runner.Introspect(context.Background(), invalid, runtimesRegistry.IntrospectionOptions{Exports: []string{"workflowSettings"}})
+	assert.NotNil(t, err)
+	assert.Nil(t, result)
+
+	text := workflow(runtimesRegistry.RuntimeLimits{})
+	text.ProcessedSource.SourceType = runtimesRegistry.Source_ContentType_Text
+	_, err = runner.Introspect(context.Background(), text, runtimesRegistry.IntrospectionOptions{})
+	assert.ErrorContains(t, err, "the wasi runtime runs binary sources")
+}
+
+func TestEnvelope(t *testing.T) {
+	wrapped := workflow(runtimesRegistry.RuntimeLimits{})
+	wrapped.ProcessedSource.Source, _ = sourceEnvelope.Encode(sourceEnvelope.Envelope{
+		Header: sourceEnvelope.Header{Compression: sourceEnvelope.CompressionZstd},
+		Source: wrapped.ProcessedSource.Source,
+	})
+
+	result, err := newWasiRunner().Execute(context.Background(), wrapped, runtimesRegistry.StartOptions{
+		EntryPoint: "add",
+		Arguments:  []interface{}{1, 1},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), result.GetExitResult())
+}
+
+func TestModuleCache(t *testing.T) {
+	cache := newModuleCache(1)
+	runtime := wazero.NewRuntimeWithConfig(context.Background(), webassembly.NewRuntimeConfig().WithCompilationCache(cache.compilation))
+	defer runtime.Close(context.Background())
+	first, _ := hex.DecodeString(testModule)
+	second := bytes.Replace(first, []byte{5, 3, 1, 0, 1}, []byte{5, 3, 1, 0, 2}, 1)
+
+	_, releaseFirst, err := cache.compile(context.Background(), runtime, first)
+	assert.Nil(t, err)
+	_, releaseSecond, err := cache.compile(context.Background(), runtime, second)
+	assert.Nil(t, err)
+
+	// modules in use are kept beyond the bound
+	releaseSecond()
+	assert.Equal(t, 1, cache.recent.Len())
+	assert.Contains(t, cache.modules, sha256.Sum256(first))
+	releaseFirst()
+	assert.Equal(t, 1, cache.recent.Len())
+
+	_, release, err := cache.compile(context.Background(), runtime, second)
+	assert.Nil(t, err)
+	release()
+	assert.Equal(t, 1, cache.recent.Len())
+	assert.Contains(t, cache.modules, sha256.Sum256(second))
+
+	// modules failing to compile are not kept
+	_, _, err = cache.compile(context.Background(), runtime, []byte{0, 97, 115, 109})
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, cache.recent.Len())
+}
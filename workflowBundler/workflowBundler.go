@@ -8,12 +8,17 @@ import (
 	"time"
 
 	"github.com/evanw/esbuild/pkg/api"
-	_ "github.com/kinde-oss/workflows-runtime/gojaRuntime"
+	gojaRuntime "github.com/kinde-oss/workflows-runtime/gojaRuntime"
 	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
+	sourceEnvelope "github.com/kinde-oss/workflows-runtime/sourceEnvelope"
+	workflowSigning "github.com/kinde-oss/workflows-runtime/workflowSigning"
 )
 
 const pluginsKey bundlerContext = "bundlerPlugins"
 
+// kindeBindingRoot is the root of the bindings every bundle leaves to the runtime, e.g. "kinde:idToken"
+const kindeBindingRoot = "kinde"
+
 type (
 	bundlerContext string
 
@@ -23,10 +28,14 @@ type (
 	}
 
 	BundledContent[TSettings any] struct {
-		Source          []byte                      `json:"source"`
-		BundleHash      string                      `json:"hash"`
-		Settings        WorkflowSettings[TSettings] `json:"settings"`
-		BundlingOptions BundlerOptions[TSettings]   `json:"bundling_options"`
+		Source []byte `json:"source"`
+		// SourceType is runtimesRegistry.Source_ContentType_Binary when Source is an envelope
+		SourceType      runtimesRegistry.SourceContentType `json:"source_type"`
+		BundleHash      string                             `json:"hash"`
+		Settings        WorkflowSettings[TSettings]        `json:"settings"`
+		BundlingOptions BundlerOptions[TSettings]          `json:"bundling_options"`
+		// Signature signs the descriptor Descriptor returns, when the bundler was given a Signer
+		Signature *runtimesRegistry.WorkflowSignature `json:"signature,omitempty"`
 	}
 
 	BundlerResult[TSettings any] struct {
@@ -38,10 +47,23 @@ type (
 	}
 
 	BundlerOptions[TSettings any] struct {
-		WorkingFolder       string                                                      `json:"working_folder"`
-		EntryPoints         []string                                                    `json:"entry_points"`
-		IntrospectionExport string                                                      `json:"introspection_export"`
-		OnDiscovered        func(ctx context.Context, bundle *BundlerResult[TSettings]) `json:"-"`
+		WorkingFolder       string   `json:"working_folder"`
+		EntryPoints         []string `json:"entry_points"`
+		IntrospectionExport string   `json:"introspection_export"`
+		// ModuleFormat selects the format of the bundle, CommonJS unless it is runtimesRegistry.ModuleFormatESM,
+		// which workflows using top-level await need
+		ModuleFormat runtimesRegistry.ModuleFormat `json:"module_format,omitempty"`
+		// Compression emits the bundle as a binary envelope compressed this way, its source map carried separately.
+		// The bundle is text when it is empty.
+		Compression sourceEnvelope.Compression `json:"compression,omitempty"`
+		// BindingRoots are the roots of the native APIs, besides kinde, whose imports are left to the runtime,
+		// e.g. "acme" for "acme:users" and "@acme/runtime"
+		BindingRoots []string `json:"binding_roots,omitempty"`
+		// Limits are the limits the bundle runs with, signed together with its source and bindings
+		Limits runtimesRegistry.RuntimeLimits `json:"runtime_limits"`
+		// Signer signs the descriptor of the bundle, it is not kept in the bundling options of the result
+		Signer       *workflowSigning.Signer                                     `json:"-"`
+		OnDiscovered func(ctx context.Context, bundle *BundlerResult[TSettings]) `json:"-"`
 	}
 
 	WorkflowBundler[TSettings any] interface {
@@ -76,24 +98,32 @@ func (b *builder[TSettings]) getContextPlugins(ctx context.Context) []api.Plugin
 }
 
 func (b *builder[TSettings]) Bundle(ctx context.Context) BundlerResult[TSettings] {
+	format := api.FormatCommonJS
+	if b.bundleOptions.ModuleFormat == runtimesRegistry.ModuleFormatESM {
+		format = api.FormatESModule
+	}
 	opts := api.BuildOptions{
 		Loader: map[string]api.Loader{
 			".js":  api.LoaderJS,
 			".tsx": api.LoaderTSX,
 			".ts":  api.LoaderTS,
 			".jsx": api.LoaderJSX,
+			// imported .wasm files are Uint8Arrays to compile with WebAssembly
+			".wasm": api.LoaderBinary,
 		},
-		AbsWorkingDir:    b.bundleOptions.WorkingFolder,
-		Target:           api.ESNext,
-		Format:           api.FormatCommonJS,
-		Sourcemap:        api.SourceMapInline,
-		SourcesContent:   api.SourcesContentInclude,
-		LegalComments:    api.LegalCommentsNone,
-		Platform:         api.PlatformDefault,
-		LogLevel:         api.LogLevelSilent,
-		Charset:          api.CharsetUTF8,
-		EntryPoints:      b.bundleOptions.EntryPoints,
-		Bundle:           true,
+		AbsWorkingDir:  b.bundleOptions.WorkingFolder,
+		Target:         api.ESNext,
+		Format:         format,
+		Sourcemap:      api.SourceMapInline,
+		SourcesContent: api.SourcesContentInclude,
+		LegalComments:  api.LegalCommentsNone,
+		Platform:       api.PlatformDefault,
+		LogLevel:       api.LogLevelSilent,
+		Charset:        api.CharsetUTF8,
+		EntryPoints:    b.bundleOptions.EntryPoints,
+		Bundle:         true,
+		// bindings are imported from the runtime, e.g. "kinde:idToken"
+		External:         gojaRuntime.RootSpecifiers(append([]string{kindeBindingRoot}, b.bundleOptions.BindingRoots...)...),
 		Write:            false,
 		TreeShaking:      api.TreeShakingTrue,
 		MinifyWhitespace: true,
@@ -113,7 +143,7 @@ func (b *builder[TSettings]) Bundle(ctx context.Context) BundlerResult[TSettings
 
 		file := tr.OutputFiles[0]
 
-		settings, settingsDiscoveryWarn, discoveryErr := result.discoverSettingsAndExports(b.bundleOptions.IntrospectionExport, file.Contents)
+		settings, settingsDiscoveryWarn, discoveryErr := result.discoverSettingsAndExports(b.bundleOptions.IntrospectionExport, file.Contents, b.bundleOptions.ModuleFormat)
 
 		if settingsDiscoveryWarn != nil {
 			result.addWarning(settingsDiscoveryWarn)
@@ -125,11 +155,41 @@ func (b *builder[TSettings]) Bundle(ctx context.Context) BundlerResult[TSettings
 
 		result.Content = BundledContent[TSettings]{
 			Source:          file.Contents,
+			SourceType:      runtimesRegistry.Source_ContentType_Text,
 			BundleHash:      file.Hash,
 			Settings:        settings,
 			BundlingOptions: b.bundleOptions,
 		}
 
+		if b.bundleOptions.Compression != "" {
+			source, sourceMap := sourceEnvelope.SplitSourceMap(file.Contents)
+			envelope, err := sourceEnvelope.Encode(sourceEnvelope.Envelope{
+				Header: sourceEnvelope.Header{
+					Compression:  b.bundleOptions.Compression,
+					ModuleFormat: b.bundleOptions.ModuleFormat,
+					BuildHash:    file.Hash,
+				},
+				Source:    source,
+				SourceMap: sourceMap,
+			})
+			if err != nil {
+				result.addError(err)
+			} else {
+				result.Content.Source = envelope
+				result.Content.SourceType = runtimesRegistry.Source_ContentType_Binary
+			}
+		}
+
+		if b.bundleOptions.Signer != nil {
+			result.Content.BundlingOptions.Signer = nil
+			descriptor := result.Content.Descriptor()
+			if err := b.bundleOptions.Signer.Sign(&descriptor); err != nil {
+				result.addError(err)
+			} else {
+				result.Content.Signature = descriptor.Signature
+			}
+		}
+
 	}
 
 	for _, buildError := range tr.Errors {
@@ -143,6 +203,21 @@ func (b *builder[TSettings]) Bundle(ctx context.Context) BundlerResult[TSettings
 	return result
 }
 
+// Descriptor returns the workflow descriptor running the bundle, with its bindings, limits and signature.
+func (content BundledContent[TSettings]) Descriptor() runtimesRegistry.WorkflowDescriptor {
+	return runtimesRegistry.WorkflowDescriptor{
+		ProcessedSource: runtimesRegistry.SourceDescriptor{
+			Source:       content.Source,
+			SourceType:   content.SourceType,
+			BuildHash:    content.BundleHash,
+			ModuleFormat: content.BundlingOptions.ModuleFormat,
+		},
+		RequestedBindings: content.Settings.Bindings,
+		Limits:            content.BundlingOptions.Limits,
+		Signature:         content.Signature,
+	}
+}
+
 func (br *BundlerResult[TSettings]) HasOutput() bool {
 	return len(br.Content.Source) > 0
 }
@@ -159,13 +234,14 @@ func (br *BundlerResult[TSettings]) addWarning(warn error) {
 	br.Warnings = append(br.Warnings, warn.Error())
 }
 
-func (br *BundlerResult[TSettings]) discoverSettingsAndExports(exportName string, source []byte) (r WorkflowSettings[TSettings], warn error, err error) {
+func (br *BundlerResult[TSettings]) discoverSettingsAndExports(exportName string, source []byte, format runtimesRegistry.ModuleFormat) (r WorkflowSettings[TSettings], warn error, err error) {
 	goja, _ := runtimesRegistry.ResolveRuntime("goja")
 	introspectResult, err := goja.Introspect(context.Background(),
 		runtimesRegistry.WorkflowDescriptor{
 			ProcessedSource: runtimesRegistry.SourceDescriptor{
-				Source:     source,
-				SourceType: runtimesRegistry.Source_ContentType_Text,
+				Source:       source,
+				SourceType:   runtimesRegistry.Source_ContentType_Text,
+				ModuleFormat: format,
 			},
 			Limits: runtimesRegistry.RuntimeLimits{
 				MaxExecutionDuration: 30 * time.Second,
@@ -2,11 +2,19 @@ package builder
 
 import (
 	"context"
+	"crypto/ed25519"
+	"encoding/hex"
 	"encoding/json"
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/evanw/esbuild/pkg/api"
+	gojaRuntime "github.com/kinde-oss/workflows-runtime/gojaRuntime"
+	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
+	sourceEnvelope "github.com/kinde-oss/workflows-runtime/sourceEnvelope"
+	workflowSigning "github.com/kinde-oss/workflows-runtime/workflowSigning"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -51,3 +59,199 @@ func Test_WorkflowBundler(t *testing.T) {
 	assert.Equal("onTokenGeneration", bundlerResult.Content.Settings.Other.Trigger)
 	assert.NotEmpty(bundlerResult.Content.BundleHash)
 }
+
+func Test_WorkflowBundlerESM(t *testing.T) {
+
+	type workflowSettings struct {
+		ID string `json:"id"`
+	}
+
+	workflowPath, _ := filepath.Abs("../testData/kindeSrc/environment/workflows/evTest")
+
+	bundlerResult := NewWorkflowBundler(BundlerOptions[workflowSettings]{
+		WorkingFolder:       workflowPath,
+		EntryPoints:         []string{"tokensWorkflow.ts"},
+		IntrospectionExport: "workflowSettings",
+		ModuleFormat:        runtimesRegistry.ModuleFormatESM,
+	}).Bundle(context.Background())
+
+	assert := assert.New(t)
+	assert.Empty(bundlerResult.Errors)
+	assert.Contains(string(bundlerResult.Content.Source), "export")
+	assert.Equal("tokenGen", bundlerResult.Content.Settings.Other.ID)
+	assert.Equal(runtimesRegistry.ModuleFormatESM, bundlerResult.Content.BundlingOptions.ModuleFormat)
+}
+
+func Test_WorkflowBundlerLeavesBindingImports(t *testing.T) {
+
+	type workflowSettings struct {
+		ID string `json:"id"`
+	}
+
+	// kinde imports are always left to the runtime, the ones of other native APIs when their root is given.
+	// Only bundlerTest is registered, the discovery of the settings runs the static imports.
+	gojaRuntime.RegisterNativeAPI("bundlerTest").RegisterNativeAPI("idToken")
+	workflowPath := t.TempDir()
+	os.WriteFile(filepath.Join(workflowPath, "workflow.ts"), []byte(`import { idToken } from "bundlerTest:idToken";
+		import runtime from "@bundlerTest/runtime";
+		export const workflowSettings = { id: "bindings", bindings: { "bundlerTest.idToken": {} } };
+		export default async function() {
+			const [{ accessToken }, kinde, { users }] = await Promise.all([import("kinde:accessToken"), import("@kinde/runtime"), import("acme:users")]);
+			return [idToken, runtime, accessToken, kinde, users];
+		}`), 0o644)
+
+	options := BundlerOptions[workflowSettings]{
+		WorkingFolder:       workflowPath,
+		EntryPoints:         []string{"workflow.ts"},
+		IntrospectionExport: "workflowSettings",
+		ModuleFormat:        runtimesRegistry.ModuleFormatESM,
+		BindingRoots:        []string{"bundlerTest", "acme"},
+	}
+	bundlerResult := NewWorkflowBundler(options).Bundle(context.Background())
+
+	assert := assert.New(t)
+	assert.Empty(bundlerResult.Errors)
+	for _, specifier := range []string{"bundlerTest:idToken", "@bundlerTest/runtime", "kinde:accessToken", "@kinde/runtime", "acme:users"} {
+		assert.Contains(string(bundlerResult.Content.Source), `"`+specifier+`"`)
+	}
+
+	options.BindingRoots = []string{"bundlerTest"}
+	assert.NotEmpty(NewWorkflowBundler(options).Bundle(context.Background()).CompilationErrors)
+	assert.Equal("bindings", bundlerResult.Content.Settings.Other.ID)
+}
+
+func Test_WorkflowBundlerLoadsWasm(t *testing.T) {
+
+	type workflowSettings struct {
+		ID string `json:"id"`
+	}
+
+	// exports add (i32, i32) -> i32
+	wasm, _ := hex.DecodeString("0061736d0100000001070160027f7f017f030201000707010361646400000a09010700200020016a0b")
+	workflowPath := t.TempDir()
+	os.WriteFile(filepath.Join(workflowPath, "add.wasm"), wasm, 0o644)
+	os.WriteFile(filepath.Join(workflowPath, "workflow.ts"), []byte(`import bytes from "./add.wasm";
+		export const workflowSettings = { id: "wasm", bindings: { "webassembly": {} } };
+		export default async function() {
+			const { instance } = await WebAssembly.instantiate(bytes);
+			return instance.exports.add(2, 3);
+		}`), 0o644)
+
+	bundlerResult := NewWorkflowBundler(BundlerOptions[workflowSettings]{
+		WorkingFolder:       workflowPath,
+		EntryPoints:         []string{"workflow.ts"},
+		IntrospectionExport: "workflowSettings",
+	}).Bundle(context.Background())
+
+	assert := assert.New(t)
+	assert.Empty(bundlerResult.Errors)
+	assert.Equal("wasm", bundlerResult.Content.Settings.Other.ID)
+
+	runner, _ := runtimesRegistry.ResolveRuntime("goja")
+	result, err := runner.Execute(context.Background(), runtimesRegistry.WorkflowDescriptor{
+		ProcessedSource:   runtimesRegistry.SourceDescriptor{Source: bundlerResult.Content.Source},
+		RequestedBindings: bundlerResult.Content.Settings.Bindings,
+		Limits: runtimesRegistry.RuntimeLimits{
+			MaxExecutionDuration: 5 * time.Second,
+		},
+	}, runtimesRegistry.StartOptions{})
+	assert.Nil(err)
+	assert.EqualValues(5, result.GetExitResult())
+}
+
+func Test_WorkflowBundlerEmitsEnvelope(t *testing.T) {
+
+	type workflowSettings struct {
+		ID string `json:"id"`
+	}
+
+	workflowPath := t.TempDir()
+	os.WriteFile(filepath.Join(workflowPath, "workflow.ts"), []byte(`export const workflowSettings = { id: "envelope" };
+		export default async function(event: { fail: boolean }) {
+			if (event.fail) {
+				throw new Error("failed");
+			}
+			return "from envelope";
+		}`), 0o644)
+
+	bundlerResult := NewWorkflowBundler(BundlerOptions[workflowSettings]{
+		WorkingFolder:       workflowPath,
+		EntryPoints:         []string{"workflow.ts"},
+		IntrospectionExport: "workflowSettings",
+		ModuleFormat:        runtimesRegistry.ModuleFormatESM,
+		Compression:         sourceEnvelope.CompressionZstd,
+	}).Bundle(context.Background())
+
+	assert := assert.New(t)
+	assert.Empty(bundlerResult.Errors)
+	assert.Equal("envelope", bundlerResult.Content.Settings.Other.ID)
+	assert.EqualValues(runtimesRegistry.Source_ContentType_Binary, bundlerResult.Content.SourceType)
+
+	envelope, err := sourceEnvelope.Decode(bundlerResult.Content.Source)
+	assert.Nil(err)
+	assert.Equal(runtimesRegistry.ModuleFormatESM, envelope.Header.ModuleFormat)
+	assert.Equal(bundlerResult.Content.BundleHash, envelope.Header.BuildHash)
+	assert.NotContains(string(envelope.Source), "sourceMappingURL")
+	assert.Contains(string(envelope.SourceMap), "workflow.ts")
+
+	// the runner decodes the envelope, the module format comes from its header
+	workflow := runtimesRegistry.WorkflowDescriptor{
+		ProcessedSource: runtimesRegistry.SourceDescriptor{
+			Source:     bundlerResult.Content.Source,
+			SourceType: bundlerResult.Content.SourceType,
+		},
+		Limits: runtimesRegistry.RuntimeLimits{
+			MaxExecutionDuration: 5 * time.Second,
+		},
+	}
+	runner, _ := runtimesRegistry.ResolveRuntime("goja")
+	result, err := runner.Execute(context.Background(), workflow, runtimesRegistry.StartOptions{
+		Arguments: []interface{}{map[string]interface{}{"fail": false}},
+	})
+	assert.Nil(err)
+	assert.Equal("from envelope", result.GetExitResult())
+
+	// stack traces map to the original source
+	_, err = runner.Execute(context.Background(), workflow, runtimesRegistry.StartOptions{
+		Arguments: []interface{}{map[string]interface{}{"fail": true}},
+	})
+	assert.ErrorContains(err, "workflow.ts:4")
+}
+
+func Test_WorkflowBundlerSignsDescriptor(t *testing.T) {
+
+	type workflowSettings struct {
+		ID string `json:"id"`
+	}
+
+	public, private, _ := ed25519.GenerateKey(nil)
+	workflowPath := t.TempDir()
+	os.WriteFile(filepath.Join(workflowPath, "workflow.ts"), []byte(`export const workflowSettings = { id: "signed", bindings: { "console": {} } };
+		export default async function() { return "signed" }`), 0o644)
+
+	bundlerResult := NewWorkflowBundler(BundlerOptions[workflowSettings]{
+		WorkingFolder:       workflowPath,
+		EntryPoints:         []string{"workflow.ts"},
+		IntrospectionExport: "workflowSettings",
+		Compression:         sourceEnvelope.CompressionGzip,
+		Limits:              runtimesRegistry.RuntimeLimits{MaxExecutionDuration: 5 * time.Second},
+		Signer:              &workflowSigning.Signer{KeyID: "bundler", Key: private},
+	}).Bundle(context.Background())
+
+	assert := assert.New(t)
+	assert.Empty(bundlerResult.Errors)
+	assert.Equal("bundler", bundlerResult.Content.Signature.KeyID)
+	assert.Nil(bundlerResult.Content.BundlingOptions.Signer)
+
+	goja, _ := runtimesRegistry.ResolveRuntime("goja")
+	runner := workflowSigning.NewVerifyingRunner(goja, workflowSigning.NewTrustedKeys(workflowSigning.TrustedKey{ID: "bundler", Key: public}))
+
+	descriptor := bundlerResult.Content.Descriptor()
+	result, err := runner.Execute(context.Background(), descriptor, runtimesRegistry.StartOptions{})
+	assert.Nil(err)
+	assert.Equal("signed", result.GetExitResult())
+
+	descriptor.RequestedBindings["kinde.fetch"] = runtimesRegistry.BindingSettings{}
+	_, err = runner.Execute(context.Background(), descriptor, runtimesRegistry.StartOptions{})
+	assert.ErrorIs(err, workflowSigning.ErrInvalidSignature)
+}
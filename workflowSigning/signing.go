@@ -0,0 +1,156 @@
+// Package workflow_signing signs workflow descriptors with Ed25519 and verifies them against a set of trusted keys
+// before they run.
+package workflow_signing
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
+)
+
+var (
+	// ErrUnsigned is returned when verifying a descriptor without a signature.
+	ErrUnsigned = errors.New("workflow is not signed")
+	// ErrUntrustedKey is returned when the key of a signature is unknown, or not trusted at the time of verification.
+	ErrUntrustedKey = errors.New("workflow is signed by an untrusted key")
+	// ErrInvalidSignature is returned when the signature does not match the descriptor.
+	ErrInvalidSignature = errors.New("workflow signature does not match")
+)
+
+type (
+	// Signer signs descriptors with Key, recording KeyID in their signature.
+	Signer struct {
+		KeyID string
+		Key   ed25519.PrivateKey
+	}
+
+	// KeySet returns the public keys signatures are verified with.
+	KeySet interface {
+		// PublicKey returns the key with keyID, when it is trusted at the given time
+		PublicKey(keyID string, at time.Time) (ed25519.PublicKey, bool)
+	}
+
+	// TrustedKey is a public key and the time it is trusted for. A zero NotBefore or NotAfter leaves that end open.
+	TrustedKey struct {
+		ID        string
+		Key       ed25519.PublicKey
+		NotBefore time.Time
+		NotAfter  time.Time
+	}
+
+	// TrustedKeys is a KeySet which can change while runners use it. Keys are rotated by trusting the new key, signing
+	// with it, and setting NotAfter on the previous one once the descriptors it signed are replaced.
+	TrustedKeys struct {
+		lock sync.RWMutex
+		keys map[string]TrustedKey
+	}
+
+	verifyingRunner struct {
+		runner runtimesRegistry.Runner
+		keys   KeySet
+	}
+)
+
+// Sign sets the signature of workflow. The signature covers what WorkflowDescriptor.SigningPayload encodes, so the
+// descriptor must not change after it is signed.
+func (s Signer) Sign(workflow *runtimesRegistry.WorkflowDescriptor) error {
+	if len(s.Key) != ed25519.PrivateKeySize {
+		return fmt.Errorf("signing key %v is not an Ed25519 private key", s.KeyID)
+	}
+	payload, err := workflow.SigningPayload()
+	if err != nil {
+		return err
+	}
+	workflow.Signature = &runtimesRegistry.WorkflowSignature{
+		KeyID:     s.KeyID,
+		Signature: ed25519.Sign(s.Key, payload),
+	}
+	return nil
+}
+
+// Verify checks that workflow is signed by a key of keys trusted at the given time.
+func Verify(workflow runtimesRegistry.WorkflowDescriptor, keys KeySet, at time.Time) error {
+	signature := workflow.Signature
+	if signature == nil {
+		return ErrUnsigned
+	}
+	key, ok := keys.PublicKey(signature.KeyID, at)
+	if !ok || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w %v", ErrUntrustedKey, signature.KeyID)
+	}
+	payload, err := workflow.SigningPayload()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, payload, signature.Signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// NewTrustedKeys returns a key set trusting keys.
+func NewTrustedKeys(keys ...TrustedKey) *TrustedKeys {
+	trusted := &TrustedKeys{keys: map[string]TrustedKey{}}
+	for _, key := range keys {
+		trusted.Trust(key)
+	}
+	return trusted
+}
+
+// Trust adds key to the set, replacing the key with the same ID.
+func (t *TrustedKeys) Trust(key TrustedKey) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.keys[key.ID] = key
+}
+
+// Revoke removes the key with keyID, descriptors it signed no longer verify.
+func (t *TrustedKeys) Revoke(keyID string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.keys, keyID)
+}
+
+// PublicKey implements KeySet.
+func (t *TrustedKeys) PublicKey(keyID string, at time.Time) (ed25519.PublicKey, bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	key, ok := t.keys[keyID]
+	if !ok {
+		return nil, false
+	}
+	if !key.NotBefore.IsZero() && at.Before(key.NotBefore) {
+		return nil, false
+	}
+	if !key.NotAfter.IsZero() && !at.Before(key.NotAfter) {
+		return nil, false
+	}
+	return key.Key, true
+}
+
+// NewVerifyingRunner wraps runner so that it refuses descriptors which are unsigned, or whose signature does not
+// verify with keys. Introspection is verified too, as it evaluates the workflow source.
+func NewVerifyingRunner(runner runtimesRegistry.Runner, keys KeySet) runtimesRegistry.Runner {
+	return &verifyingRunner{runner: runner, keys: keys}
+}
+
+// Execute implements runtimesRegistry.Runner.
+func (v *verifyingRunner) Execute(ctx context.Context, workflow runtimesRegistry.WorkflowDescriptor, startOptions runtimesRegistry.StartOptions) (runtimesRegistry.ExecutionResult, error) {
+	if err := Verify(workflow, v.keys, time.Now()); err != nil {
+		return nil, fmt.Errorf("refusing to execute workflow: %w", err)
+	}
+	return v.runner.Execute(ctx, workflow, startOptions)
+}
+
+// Introspect implements runtimesRegistry.Runner.
+func (v *verifyingRunner) Introspect(ctx context.Context, workflow runtimesRegistry.WorkflowDescriptor, options runtimesRegistry.IntrospectionOptions) (runtimesRegistry.IntrospectionResult, error) {
+	if err := Verify(workflow, v.keys, time.Now()); err != nil {
+		return nil, fmt.Errorf("refusing to introspect workflow: %w", err)
+	}
+	return v.runner.Introspect(ctx, workflow, options)
+}
@@ -0,0 +1,155 @@
+package workflow_signing
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"testing"
+	"time"
+
+	runtimesRegistry "github.com/kinde-oss/workflows-runtime/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+type testRunner struct {
+	executed, introspected int
+}
+
+func (r *testRunner) Execute(ctx context.Context, workflow runtimesRegistry.WorkflowDescriptor, startOptions runtimesRegistry.StartOptions) (runtimesRegistry.ExecutionResult, error) {
+	r.executed++
+	return nil, nil
+}
+
+func (r *testRunner) Introspect(ctx context.Context, workflow runtimesRegistry.WorkflowDescriptor, options runtimesRegistry.IntrospectionOptions) (runtimesRegistry.IntrospectionResult, error) {
+	r.introspected++
+	return nil, nil
+}
+
+func newKey(t *testing.T, id string) (Signer, TrustedKey) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Signer{KeyID: id, Key: private}, TrustedKey{ID: id, Key: public}
+}
+
+func signedWorkflow(t *testing.T, signer Signer) runtimesRegistry.WorkflowDescriptor {
+	workflow := runtimesRegistry.WorkflowDescriptor{
+		ProcessedSource: runtimesRegistry.SourceDescriptor{
+			Source: []byte(`module.exports = { default: async () => "signed" }`),
+		},
+		RequestedBindings: map[string]runtimesRegistry.BindingSettings{
+			"kinde.fetch": {},
+			"kinde.env":   {Settings: map[string]interface{}{"limits": map[string]interface{}{"max_calls": 2}}},
+		},
+		Limits: runtimesRegistry.RuntimeLimits{MaxExecutionDuration: time.Second},
+		Modules: map[string]runtimesRegistry.SourceDescriptor{
+			"lib/shared.js": {Source: []byte(`exports.value = 1`)},
+		},
+	}
+	if err := signer.Sign(&workflow); err != nil {
+		t.Fatal(err)
+	}
+	return workflow
+}
+
+func TestVerify(t *testing.T) {
+	signer, trusted := newKey(t, "2026-10")
+	keys := NewTrustedKeys(trusted)
+	now := time.Now()
+
+	workflow := signedWorkflow(t, signer)
+	assert.Nil(t, Verify(workflow, keys, now))
+
+	// descriptors still verify after a JSON round trip
+	encoded, _ := json.Marshal(workflow)
+	decoded := runtimesRegistry.WorkflowDescriptor{}
+	assert.Nil(t, json.Unmarshal(encoded, &decoded))
+	assert.Nil(t, Verify(decoded, keys, now))
+
+	tampered := []func(w *runtimesRegistry.WorkflowDescriptor){
+		func(w *runtimesRegistry.WorkflowDescriptor) { w.ProcessedSource.Source = []byte(`module.exports = {}`) },
+		func(w *runtimesRegistry.WorkflowDescriptor) {
+			w.ProcessedSource.ModuleFormat = runtimesRegistry.ModuleFormatESM
+		},
+		func(w *runtimesRegistry.WorkflowDescriptor) {
+			w.RequestedBindings = map[string]runtimesRegistry.BindingSettings{"kinde.fetch": {}, "kinde.secureFetch": {}}
+		},
+		func(w *runtimesRegistry.WorkflowDescriptor) {
+			w.RequestedBindings = map[string]runtimesRegistry.BindingSettings{"kinde.fetch": {}, "kinde.env": {}}
+		},
+		func(w *runtimesRegistry.WorkflowDescriptor) { w.Limits.MaxExecutionDuration = time.Hour },
+		func(w *runtimesRegistry.WorkflowDescriptor) {
+			w.Modules = map[string]runtimesRegistry.SourceDescriptor{"lib/shared.js": {Source: []byte(`exports.value = 2`)}}
+		},
+		func(w *runtimesRegistry.WorkflowDescriptor) { w.Conditions = []string{"worker"} },
+	}
+	for i, tamper := range tampered {
+		workflow := signedWorkflow(t, signer)
+		tamper(&workflow)
+		assert.ErrorIs(t, Verify(workflow, keys, now), ErrInvalidSignature, "tampering %d", i)
+	}
+
+	workflow.Signature = nil
+	assert.ErrorIs(t, Verify(workflow, keys, now), ErrUnsigned)
+
+	other, _ := newKey(t, "other")
+	assert.ErrorIs(t, Verify(signedWorkflow(t, other), keys, now), ErrUntrustedKey)
+
+	// a signature by a trusted key ID made with another private key
+	forged := signedWorkflow(t, Signer{KeyID: "2026-10", Key: other.Key})
+	assert.ErrorIs(t, Verify(forged, keys, now), ErrInvalidSignature)
+
+	assert.ErrorContains(t, Signer{KeyID: "empty"}.Sign(&workflow), "signing key empty is not an Ed25519 private key")
+}
+
+func TestKeyRotation(t *testing.T) {
+	previousSigner, previous := newKey(t, "previous")
+	nextSigner, next := newKey(t, "next")
+	rotatedAt := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	previous.NotAfter = rotatedAt.Add(30 * 24 * time.Hour)
+	next.NotBefore = rotatedAt
+	keys := NewTrustedKeys(previous, next)
+
+	signedBefore := signedWorkflow(t, previousSigner)
+	signedAfter := signedWorkflow(t, nextSigner)
+
+	// during the rotation both keys are trusted
+	during := rotatedAt.Add(time.Hour)
+	assert.Nil(t, Verify(signedBefore, keys, during))
+	assert.Nil(t, Verify(signedAfter, keys, during))
+
+	// the next key is not trusted before it becomes valid, the previous one not once it expired
+	assert.ErrorIs(t, Verify(signedAfter, keys, rotatedAt.Add(-time.Hour)), ErrUntrustedKey)
+	after := previous.NotAfter
+	assert.ErrorIs(t, Verify(signedBefore, keys, after), ErrUntrustedKey)
+	assert.Nil(t, Verify(signedAfter, keys, after))
+
+	keys.Revoke("next")
+	assert.ErrorIs(t, Verify(signedAfter, keys, after), ErrUntrustedKey)
+}
+
+func TestVerifyingRunner(t *testing.T) {
+	signer, trusted := newKey(t, "key")
+	runner := &testRunner{}
+	verifying := NewVerifyingRunner(runner, NewTrustedKeys(trusted))
+
+	workflow := signedWorkflow(t, signer)
+	_, err := verifying.Execute(context.Background(), workflow, runtimesRegistry.StartOptions{})
+	assert.Nil(t, err)
+	_, err = verifying.Introspect(context.Background(), workflow, runtimesRegistry.IntrospectionOptions{})
+	assert.Nil(t, err)
+
+	workflow.Limits.MaxExecutionDuration = time.Hour
+	_, err = verifying.Execute(context.Background(), workflow, runtimesRegistry.StartOptions{})
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	assert.ErrorContains(t, err, "refusing to execute workflow")
+
+	workflow.Signature = nil
+	_, err = verifying.Introspect(context.Background(), workflow, runtimesRegistry.IntrospectionOptions{})
+	assert.ErrorIs(t, err, ErrUnsigned)
+
+	assert.Equal(t, 1, runner.executed)
+	assert.Equal(t, 1, runner.introspected)
+}